@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// azureGlobalKeywords are the top-level keys of azure-pipelines.yml
+var azureGlobalKeywords = map[string]bool{
+	"name": true, "trigger": true, "pr": true, "schedules": true, "resources": true, "variables": true,
+	"parameters": true, "pool": true, "stages": true, "jobs": true, "steps": true, "extends": true,
+	"lockBehavior": true, "appendCommitMessageToRunName": true, "container": true, "services": true,
+	"workspace": true, "strategy": true, "timeoutInMinutes": true,
+}
+
+// azureStepKeywords identify a step; every step has exactly one of them
+var azureStepKeywords = []string{
+	"script", "bash", "pwsh", "powershell", "task", "checkout", "template", "download",
+	"downloadBuild", "getPackage", "publish", "reviewApp",
+}
+
+// validateAzurePipelines checks an azure-pipelines.yml: stages, jobs, steps and dependsOn references
+func validateAzurePipelines(config string) []string {
+	root, problems := parseYAMLMapping(config)
+	if root == nil {
+		return problems
+	}
+	if mappingValue(root, "on") != nil && mappingValue(root, "jobs") != nil {
+		return []string{`this looks like a GitHub Actions workflow ("on" and "jobs"), not azure-pipelines.yml`}
+	}
+	for _, key := range mappingKeys(root) {
+		if !azureGlobalKeywords[key] {
+			problems = append(problems, fmt.Sprintf("unknown top-level keyword %q", key))
+		}
+	}
+
+	stages, jobs, steps := mappingValue(root, "stages"), mappingValue(root, "jobs"), mappingValue(root, "steps")
+	set := 0
+	for _, n := range []*yaml.Node{stages, jobs, steps} {
+		if n != nil {
+			set++
+		}
+	}
+	switch {
+	case set == 0 && mappingValue(root, "extends") == nil:
+		return append(problems, "pipeline has no stages, jobs or steps")
+	case set > 1:
+		problems = append(problems, "use only one of stages, jobs or steps at the top level")
+	}
+
+	if stages != nil {
+		names := map[string]bool{}
+		for _, stage := range stages.Content {
+			if n := mappingValue(stage, "stage"); n != nil {
+				names[n.Value] = true
+			}
+		}
+		for _, stage := range stages.Content {
+			n := mappingValue(stage, "stage")
+			if n == nil {
+				if mappingValue(stage, "template") == nil {
+					problems = append(problems, "stage entry needs stage: or template:")
+				}
+				continue
+			}
+			name := n.Value
+			problems = append(problems, azureDependsOn("stage", name, mappingValue(stage, "dependsOn"), names)...)
+			problems = append(problems, validateAzureJobs("stage "+name, mappingValue(stage, "jobs"))...)
+		}
+	}
+	if jobs != nil {
+		problems = append(problems, validateAzureJobs("pipeline", jobs)...)
+	}
+	if steps != nil {
+		problems = append(problems, validateAzureSteps("pipeline", steps)...)
+	}
+	return problems
+}
+
+// validateAzureJobs checks the jobs of a stage, or of a pipeline without stages
+func validateAzureJobs(scope string, jobs *yaml.Node) []string {
+	if jobs == nil || jobs.Kind != yaml.SequenceNode || len(jobs.Content) == 0 {
+		return []string{scope + " has no jobs"}
+	}
+	var problems []string
+	names := map[string]bool{}
+	for _, job := range jobs.Content {
+		for _, key := range []string{"job", "deployment"} {
+			if n := mappingValue(job, key); n != nil {
+				names[n.Value] = true
+			}
+		}
+	}
+	for _, job := range jobs.Content {
+		job = deref(job)
+		if mappingValue(job, "template") != nil {
+			continue
+		}
+		name := ""
+		if n := mappingValue(job, "job"); n != nil {
+			name = n.Value
+			problems = append(problems, validateAzureSteps("job "+name, mappingValue(job, "steps"))...)
+		} else if n := mappingValue(job, "deployment"); n != nil {
+			name = n.Value
+			if mappingValue(job, "environment") == nil {
+				problems = append(problems, fmt.Sprintf("deployment %q has no environment", name))
+			}
+			if mappingValue(job, "strategy") == nil {
+				problems = append(problems, fmt.Sprintf("deployment %q has no strategy", name))
+			}
+		} else {
+			problems = append(problems, scope+": job entry needs job:, deployment: or template:")
+			continue
+		}
+		problems = append(problems, azureDependsOn("job", name, mappingValue(job, "dependsOn"), names)...)
+	}
+	return problems
+}
+
+// validateAzureSteps checks that each step is exactly one kind of step
+func validateAzureSteps(scope string, steps *yaml.Node) []string {
+	if steps == nil || steps.Kind != yaml.SequenceNode || len(steps.Content) == 0 {
+		return []string{scope + " has no steps"}
+	}
+	var problems []string
+	for i, step := range steps.Content {
+		var kinds []string
+		for _, key := range azureStepKeywords {
+			if mappingValue(step, key) != nil {
+				kinds = append(kinds, key)
+			}
+		}
+		switch {
+		case len(kinds) == 0:
+			if mappingValue(step, "uses") != nil || mappingValue(step, "run") != nil {
+				problems = append(problems, fmt.Sprintf("%s step %d uses GitHub Actions syntax (uses/run); use task/script", scope, i+1))
+			} else {
+				problems = append(problems, fmt.Sprintf("%s step %d has no script, task, checkout or template", scope, i+1))
+			}
+		case len(kinds) > 1:
+			problems = append(problems, fmt.Sprintf("%s step %d mixes %s", scope, i+1, strings.Join(kinds, " and ")))
+		}
+	}
+	return problems
+}
+
+// azureDependsOn checks that dependsOn names stages or jobs defined alongside
+func azureDependsOn(kind, name string, dependsOn *yaml.Node, defined map[string]bool) []string {
+	if dependsOn == nil {
+		return nil
+	}
+	var problems []string
+	for _, dependency := range scalarValues(dependsOn) {
+		if !defined[dependency] && !strings.Contains(dependency, "${{") {
+			problems = append(problems, fmt.Sprintf("%s %q depends on %q, which is not defined", kind, name, dependency))
+		}
+	}
+	return problems
+}
+
+// FormatAzureServicesYAML renders services as container resources and a job-level services: map.
+// Ports are mapped to the agent, so jobs running on the host reach services on localhost.
+func FormatAzureServicesYAML(services []ServiceRequirement) string {
+	if len(services) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("resources:\n  containers:\n")
+	for _, svc := range services {
+		fmt.Fprintf(&b, "    - container: %s\n", svc.Name)
+		fmt.Fprintf(&b, "      image: %s\n", svc.Image)
+		if len(svc.Ports) > 0 {
+			b.WriteString("      ports:\n")
+			for _, port := range svc.Ports {
+				fmt.Fprintf(&b, "        - %s\n", port)
+			}
+		}
+		if len(svc.Env) > 0 {
+			b.WriteString("      env:\n")
+			keys := make([]string, 0, len(svc.Env))
+			for k := range svc.Env {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				fmt.Fprintf(&b, "        %s: %q\n", k, svc.Env[k])
+			}
+		}
+		if svc.HealthCheck != "" {
+			fmt.Fprintf(&b, "      options: --health-cmd %q --health-interval 10s --health-timeout 5s --health-retries 10\n", svc.HealthCheck)
+		}
+	}
+	b.WriteString("# In each job that runs tests:\nservices:\n")
+	for _, svc := range services {
+		fmt.Fprintf(&b, "  %s: %s\n", svc.Name, svc.Name)
+	}
+	return b.String()
+}
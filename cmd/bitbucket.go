@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// bitbucketGlobalKeywords are the top-level keys of bitbucket-pipelines.yml
+var bitbucketGlobalKeywords = map[string]bool{
+	"image": true, "clone": true, "options": true, "definitions": true, "pipelines": true, "labels": true, "export": true,
+}
+
+// bitbucketPipelineKinds are the keys allowed under pipelines:
+var bitbucketPipelineKinds = map[string]bool{
+	"default": true, "branches": true, "tags": true, "bookmarks": true, "pull-requests": true, "custom": true,
+}
+
+// bitbucketPredefinedCaches need no definitions.caches entry
+var bitbucketPredefinedCaches = map[string]bool{
+	"docker": true, "composer": true, "dotnetcore": true, "gradle": true, "ivy2": true, "maven": true,
+	"node": true, "pip": true, "sbt": true,
+}
+
+// validateBitbucketPipelines checks a bitbucket-pipelines.yml: pipelines, steps, services and caches
+func validateBitbucketPipelines(config string) []string {
+	root, problems := parseYAMLMapping(config)
+	if root == nil {
+		return problems
+	}
+	for _, key := range mappingKeys(root) {
+		if !bitbucketGlobalKeywords[key] {
+			problems = append(problems, fmt.Sprintf("unknown top-level keyword %q", key))
+		}
+	}
+
+	pipelines := mappingValue(root, "pipelines")
+	if len(mappingKeys(pipelines)) == 0 {
+		return append(problems, `missing "pipelines"`)
+	}
+	definitions := mappingValue(root, "definitions")
+	services := mappingValue(definitions, "services")
+	caches := mappingValue(definitions, "caches")
+
+	check := func(where string, items *yaml.Node) {
+		if items == nil || items.Kind != yaml.SequenceNode || len(items.Content) == 0 {
+			problems = append(problems, where+" has no steps")
+			return
+		}
+		for _, step := range bitbucketSteps(items) {
+			name := where
+			if n := mappingValue(step, "name"); n != nil {
+				name = fmt.Sprintf("%s step %q", where, n.Value)
+			}
+			if script := mappingValue(step, "script"); script == nil || script.Kind != yaml.SequenceNode || len(script.Content) == 0 {
+				problems = append(problems, name+" needs a script list")
+			}
+			if list := mappingValue(step, "services"); list != nil {
+				for _, svc := range scalarValues(list) {
+					if svc != "docker" && mappingValue(services, svc) == nil {
+						problems = append(problems, fmt.Sprintf("%s uses service %q, which is not in definitions.services", name, svc))
+					}
+				}
+			}
+			if list := mappingValue(step, "caches"); list != nil {
+				for _, cache := range scalarValues(list) {
+					if !bitbucketPredefinedCaches[cache] && mappingValue(caches, cache) == nil {
+						problems = append(problems, fmt.Sprintf("%s uses cache %q, which is not predefined or in definitions.caches", name, cache))
+					}
+				}
+			}
+		}
+	}
+
+	for _, kind := range mappingKeys(pipelines) {
+		if !bitbucketPipelineKinds[kind] {
+			problems = append(problems, fmt.Sprintf("unknown pipeline type %q", kind))
+			continue
+		}
+		value := mappingValue(pipelines, kind)
+		if kind == "default" {
+			check("default pipeline", value)
+			continue
+		}
+		for _, pattern := range mappingKeys(value) {
+			check(fmt.Sprintf("%s %q", kind, pattern), mappingValue(value, pattern))
+		}
+	}
+	return problems
+}
+
+// bitbucketSteps flattens a pipeline's step, parallel and stage items into its steps
+func bitbucketSteps(items *yaml.Node) []*yaml.Node {
+	var steps []*yaml.Node
+	for _, item := range items.Content {
+		item = deref(item)
+		if step := mappingValue(item, "step"); step != nil {
+			steps = append(steps, step)
+			continue
+		}
+		if parallel := mappingValue(item, "parallel"); parallel != nil {
+			if nested := mappingValue(parallel, "steps"); nested != nil {
+				parallel = nested
+			}
+			steps = append(steps, bitbucketSteps(parallel)...)
+			continue
+		}
+		if stage := mappingValue(item, "stage"); stage != nil {
+			if nested := mappingValue(stage, "steps"); nested != nil {
+				steps = append(steps, bitbucketSteps(nested)...)
+			}
+		}
+	}
+	return steps
+}
+
+// FormatBitbucketServicesYAML renders services as definitions.services and the step-level list;
+// Bitbucket runs them alongside the step on localhost
+func FormatBitbucketServicesYAML(services []ServiceRequirement) string {
+	if len(services) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("definitions:\n  services:\n")
+	for _, svc := range services {
+		fmt.Fprintf(&b, "    %s:\n", svc.Name)
+		fmt.Fprintf(&b, "      image: %s\n", svc.Image)
+		if len(svc.Env) > 0 {
+			b.WriteString("      variables:\n")
+			keys := make([]string, 0, len(svc.Env))
+			for k := range svc.Env {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				fmt.Fprintf(&b, "        %s: %q\n", k, svc.Env[k])
+			}
+		}
+	}
+	b.WriteString("# In each step that runs tests:\nservices:\n")
+	for _, svc := range services {
+		fmt.Fprintf(&b, "  - %s\n", svc.Name)
+	}
+	return b.String()
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Run static checks against your project and workflows",
+	Long:  `Run static checks against your project and workflows, without calling the AI.`,
+}
+
+var checkDockerCmd = &cobra.Command{
+	Use:   "docker",
+	Short: "Verify Docker build contexts used by your workflows",
+	Long: `Verify that every COPY/ADD source of the Dockerfiles built by your workflows exists
+in the build context (docker/build-push-action context:/file:, or docker build commands)
+and is not excluded by .dockerignore.`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          checkDocker,
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+	checkCmd.AddCommand(checkDockerCmd)
+
+	checkDockerCmd.Flags().StringSliceP("workflow", "w", nil, "Workflow file(s) to check (default: all files in .github/workflows)")
+	checkDockerCmd.Flags().StringP("dir", "d", "", "Project directory the workflows build from (default: current directory)")
+}
+
+func checkDocker(cmd *cobra.Command, args []string) error {
+	workflows, _ := cmd.Flags().GetStringSlice("workflow")
+	workingDir, _ := cmd.Flags().GetString("dir")
+	if workingDir == "" {
+		workingDir = GetWorkingDirectory()
+	}
+	workingDir, _ = filepath.Abs(workingDir)
+
+	if len(workflows) == 0 {
+		workflows = listWorkflowFiles(workingDir)
+	}
+	if len(workflows) == 0 {
+		cmd.Println("ℹ️  No workflows found in .github/workflows")
+		return nil
+	}
+
+	findings, err := CheckDockerBuildContexts(workingDir, workflows)
+	if err != nil {
+		return err
+	}
+
+	cmd.Println("\n🐳 Docker Build Context Check:")
+	cmd.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	if len(findings) == 0 {
+		cmd.Printf("✅ No problems found in %d workflow(s)\n", len(workflows))
+		cmd.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		return nil
+	}
+
+	for _, f := range findings {
+		cmd.Printf("\n❌ %s:%d (job %s, step %q)\n", f.Workflow, f.Line, f.Job, f.Step)
+		cmd.Printf("   %s\n", f.Problem)
+		cmd.Printf("   🔧 %s\n", f.Fix)
+	}
+	cmd.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+
+	return fmt.Errorf("%d Docker build context problem(s) found", len(findings))
+}
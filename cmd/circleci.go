@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// circleciJobKeywords are the keys CircleCI's config schema allows in a job
+var circleciJobKeywords = map[string]bool{
+	"docker": true, "machine": true, "macos": true, "executor": true, "resource_class": true, "steps": true,
+	"parallelism": true, "environment": true, "working_directory": true, "shell": true, "parameters": true,
+	"circleci_ip_ranges": true, "description": true, "type": true, "retention": true,
+}
+
+// circleciJobTypes are the job types that run without steps or an executor
+var circleciJobTypes = map[string]bool{"approval": true, "no-op": true, "release": true, "lock": true, "unlock": true}
+
+// validateCircleCI checks a .circleci/config.yml: version, jobs, executors and workflow references
+func validateCircleCI(config string) []string {
+	root, problems := parseYAMLMapping(config)
+	if root == nil {
+		return problems
+	}
+	if version := mappingValue(root, "version"); version == nil {
+		problems = append(problems, `missing "version" (use 2.1)`)
+	} else if version.Value != "2.1" && version.Value != "2" && version.Value != "2.0" {
+		problems = append(problems, fmt.Sprintf("unsupported version %q (use 2.1)", version.Value))
+	}
+
+	jobs := mappingValue(root, "jobs")
+	executors := mappingValue(root, "executors")
+	for _, name := range mappingKeys(jobs) {
+		job := mappingValue(jobs, name)
+		if job == nil || job.Kind != yaml.MappingNode {
+			problems = append(problems, fmt.Sprintf("job %q must be a mapping", name))
+			continue
+		}
+		for _, key := range mappingKeys(job) {
+			if !circleciJobKeywords[key] && key != "<<" {
+				problems = append(problems, fmt.Sprintf("job %q: unknown keyword %q", name, key))
+			}
+		}
+		if kind := mappingValue(job, "type"); kind != nil && circleciJobTypes[kind.Value] {
+			continue
+		}
+		if steps := mappingValue(job, "steps"); steps == nil || steps.Kind != yaml.SequenceNode || len(steps.Content) == 0 {
+			problems = append(problems, fmt.Sprintf(`job %q has no "steps"`, name))
+		}
+		executor := mappingValue(job, "executor")
+		if executor == nil && mappingValue(job, "docker") == nil && mappingValue(job, "machine") == nil && mappingValue(job, "macos") == nil {
+			problems = append(problems, fmt.Sprintf("job %q has no executor (docker, machine, macos or executor)", name))
+		}
+		if executor != nil {
+			ref := executor.Value
+			if executor.Kind == yaml.MappingNode {
+				if n := mappingValue(executor, "name"); n != nil {
+					ref = n.Value
+				}
+			}
+			if !strings.Contains(ref, "/") && mappingValue(executors, ref) == nil && !strings.Contains(ref, "<<") {
+				problems = append(problems, fmt.Sprintf("job %q uses executor %q, which is not defined", name, ref))
+			}
+		}
+	}
+
+	workflows := mappingValue(root, "workflows")
+	if len(mappingKeys(workflows)) == 0 {
+		return append(problems, `missing "workflows"`)
+	}
+	for _, workflow := range mappingKeys(workflows) {
+		if workflow == "version" {
+			continue // version: 2 under workflows, from config 2.0
+		}
+		list := mappingValue(mappingValue(workflows, workflow), "jobs")
+		if list == nil || list.Kind != yaml.SequenceNode || len(list.Content) == 0 {
+			problems = append(problems, fmt.Sprintf("workflow %q has no jobs", workflow))
+			continue
+		}
+
+		// Jobs can be renamed with name: so requires refers to the workflow's own names
+		type entry struct {
+			job      string
+			requires []string
+			approval bool // type: approval jobs exist only in the workflow
+		}
+		var entries []entry
+		names := map[string]bool{}
+		for _, item := range list.Content {
+			item = deref(item)
+			e := entry{job: item.Value}
+			names[item.Value] = true
+			if item.Kind == yaml.MappingNode && len(item.Content) == 2 {
+				e.job = item.Content[0].Value
+				names[e.job] = true
+				params := deref(item.Content[1])
+				if n := mappingValue(params, "name"); n != nil {
+					names[n.Value] = true
+				}
+				if requires := mappingValue(params, "requires"); requires != nil {
+					e.requires = scalarValues(requires)
+				}
+				if t := mappingValue(params, "type"); t != nil && t.Value == "approval" {
+					e.approval = true
+				}
+			}
+			entries = append(entries, e)
+		}
+		for _, e := range entries {
+			if !e.approval && !strings.Contains(e.job, "/") && mappingValue(jobs, e.job) == nil {
+				problems = append(problems, fmt.Sprintf("workflow %q runs job %q, which is not defined", workflow, e.job))
+			}
+			for _, required := range e.requires {
+				if !names[strings.SplitN(required, ":", 2)[0]] {
+					problems = append(problems, fmt.Sprintf("workflow %q: %q requires %q, which is not in the workflow", workflow, e.job, required))
+				}
+			}
+		}
+	}
+	return problems
+}
+
+// circleciRuntimes map a primary language to its CircleCI convenience image
+var circleciRuntimes = map[string]string{"Go": "go", "JavaScript/TypeScript": "node", "Python": "python"}
+
+// circleciPrimaryImage is the convenience image for the project's runtime and
+// version. cimg images have no latest tag, so without a detected version the
+// job gets the base image and sets up its runtime in a step.
+func circleciPrimaryImage(ctx ProjectContext) string {
+	runtime, ok := circleciRuntimes[ctx.PrimaryLang]
+	if !ok || ctx.RuntimeVersions[runtime] == "" {
+		return "cimg/base:stable"
+	}
+	return "cimg/" + runtime + ":" + ctx.RuntimeVersions[runtime]
+}
+
+// FormatCircleCIServicesYAML renders services as secondary images of a docker executor,
+// listed after the job's primary image; CircleCI reaches them on localhost
+func FormatCircleCIServicesYAML(services []ServiceRequirement, primaryImage string) string {
+	if len(services) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("docker:\n")
+	fmt.Fprintf(&b, "  - image: %s\n", primaryImage)
+	for _, svc := range services {
+		fmt.Fprintf(&b, "  - image: %s\n", svc.Image)
+		fmt.Fprintf(&b, "    name: %s\n", svc.Name)
+		if len(svc.Env) > 0 {
+			b.WriteString("    environment:\n")
+			keys := make([]string, 0, len(svc.Env))
+			for k := range svc.Env {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				fmt.Fprintf(&b, "      %s: %q\n", k, svc.Env[k])
+			}
+		}
+	}
+	return b.String()
+}
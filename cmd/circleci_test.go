@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCircleCIServicesUseRuntimeImage(t *testing.T) {
+	services := []ServiceRequirement{{Name: "postgres", Image: "postgres:16"}}
+	tests := []struct {
+		name string
+		ctx  ProjectContext
+		want string
+	}{
+		{name: "go version", ctx: ProjectContext{PrimaryLang: "Go", RuntimeVersions: map[string]string{"go": "1.22"}}, want: "cimg/go:1.22"},
+		{name: "node version", ctx: ProjectContext{PrimaryLang: "JavaScript/TypeScript", RuntimeVersions: map[string]string{"node": "20"}}, want: "cimg/node:20"},
+		{name: "no version", ctx: ProjectContext{PrimaryLang: "Python"}, want: "cimg/base:stable"},
+		{name: "unknown language", ctx: ProjectContext{PrimaryLang: "Rust"}, want: "cimg/base:stable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.ctx.Services = services
+			target, _ := findPipelineTarget("circleci")
+			got := target.Services(tt.ctx)
+			if !strings.HasPrefix(got, "docker:\n  - image: "+tt.want+"\n  - image: postgres:16\n") {
+				t.Errorf("services block =\n%s\nwant primary image %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCircleCIFiltersKeepPushBranches(t *testing.T) {
+	tests := []struct {
+		name     string
+		triggers PipelineTriggers
+		want     string
+	}{
+		{name: "push to main and pull requests", triggers: PipelineTriggers{PushBranches: []string{"main"}, PullRequests: true}, want: "branches:\n  only: [main]\n"},
+		{name: "pull requests only", triggers: PipelineTriggers{PullRequests: true}, want: ""},
+		{name: "every branch", triggers: PipelineTriggers{PushBranches: []string{"*"}}, want: ""},
+		{name: "tags only", triggers: PipelineTriggers{Tags: []string{"v*"}}, want: "branches:\n  ignore: /.*/\ntags:\n  only: [/v.*/]\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var todos []string
+			got := ""
+			if filters := circleciJobFilters(tt.triggers, PipelineJob{ID: "test"}, &todos); filters != nil {
+				var err error
+				if got, err = encodeYAML(filters); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got != tt.want {
+				t.Errorf("filters =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// composeFileNames are the file names docker compose looks for, in order of preference
+var composeFileNames = []string{"compose.yaml", "compose.yml", "docker-compose.yml", "docker-compose.yaml"}
+
+// composeFile is the subset of the compose specification Fluxion reads
+type composeFile struct {
+	Services map[string]composeService `yaml:"services"`
+}
+
+type composeService struct {
+	Image       string             `yaml:"image"`
+	Build       composeBuild       `yaml:"build"`
+	DependsOn   composeNames       `yaml:"depends_on"`
+	Ports       composePorts       `yaml:"ports"`
+	Environment composeEnvironment `yaml:"environment"`
+	Healthcheck struct {
+		Test composeCommand `yaml:"test"`
+	} `yaml:"healthcheck"`
+}
+
+// composeBuild accepts both "build: ./dir" and "build: {context, dockerfile}"
+type composeBuild struct {
+	Context    string `yaml:"context"`
+	Dockerfile string `yaml:"dockerfile"`
+}
+
+func (b *composeBuild) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		b.Context = node.Value
+		return nil
+	}
+	type plain composeBuild
+	if err := node.Decode((*plain)(b)); err != nil {
+		return err
+	}
+	if b.Context == "" {
+		b.Context = "."
+	}
+	return nil
+}
+
+// composeNames accepts both the list and the map form of depends_on
+type composeNames []string
+
+func (n *composeNames) UnmarshalYAML(node *yaml.Node) error {
+	var names composeNames
+	switch node.Kind {
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			names = append(names, item.Value)
+		}
+	case yaml.MappingNode:
+		for i := 0; i < len(node.Content); i += 2 {
+			names = append(names, node.Content[i].Value)
+		}
+	default:
+		return fmt.Errorf("line %d: depends_on must be a list or a map", node.Line)
+	}
+	*n = names
+	return nil
+}
+
+// composeEnvironment accepts both the list ("KEY=value") and map forms
+type composeEnvironment map[string]string
+
+func (e *composeEnvironment) UnmarshalYAML(node *yaml.Node) error {
+	env := composeEnvironment{}
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			env[node.Content[i].Value] = node.Content[i+1].Value
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			key, value, _ := strings.Cut(item.Value, "=")
+			env[key] = value
+		}
+	default:
+		return fmt.Errorf("line %d: environment must be a list or a map", node.Line)
+	}
+	*e = env
+	return nil
+}
+
+// composePorts accepts short ("8080:80", 80) and long ({target, published}) port syntax
+type composePorts []string
+
+func (p *composePorts) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.SequenceNode {
+		return fmt.Errorf("line %d: ports must be a list", node.Line)
+	}
+	var ports composePorts
+	for _, item := range node.Content {
+		if item.Kind == yaml.ScalarNode {
+			ports = append(ports, item.Value)
+			continue
+		}
+		var long struct {
+			Target    string `yaml:"target"`
+			Published string `yaml:"published"`
+		}
+		if err := item.Decode(&long); err != nil {
+			return err
+		}
+		if long.Published != "" {
+			ports = append(ports, long.Published+":"+long.Target)
+		} else {
+			ports = append(ports, long.Target)
+		}
+	}
+	*p = ports
+	return nil
+}
+
+// composeCommand accepts both the string and the exec-array form of a command
+type composeCommand string
+
+func (c *composeCommand) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		*c = composeCommand(node.Value)
+	case yaml.SequenceNode:
+		var parts []string
+		if err := node.Decode(&parts); err != nil {
+			return err
+		}
+		// ["CMD", "pg_isready"] and ["CMD-SHELL", "pg_isready"] both run the remaining words
+		if len(parts) > 0 && (parts[0] == "CMD" || parts[0] == "CMD-SHELL" || parts[0] == "NONE") {
+			parts = parts[1:]
+		}
+		*c = composeCommand(strings.Join(parts, " "))
+	}
+	return nil
+}
+
+// findComposeFile returns the compose file docker compose would use in workingDir
+func findComposeFile(workingDir string) string {
+	for _, name := range composeFileNames {
+		if _, err := os.Stat(filepath.Join(workingDir, name)); err == nil {
+			return name
+		}
+	}
+	return ""
+}
+
+func parseComposeFile(path string) (composeFile, error) {
+	var compose composeFile
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return compose, fmt.Errorf("failed to read compose file: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &compose); err != nil {
+		return compose, fmt.Errorf("failed to parse %s: %w", filepath.Base(path), err)
+	}
+	return compose, nil
+}
+
+// serviceNames returns the compose service names in a stable order
+func (c composeFile) serviceNames() []string {
+	names := make([]string, 0, len(c.Services))
+	for name := range c.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/openai/openai-go"
+	"gopkg.in/yaml.v3"
+)
+
+// projectConfigFiles are the names Fluxion looks for at the repository root
+var projectConfigFiles = []string{".fluxion.yml", ".fluxion.yaml"}
+
+// ProjectConfig is the optional .fluxion.yml at the repository root.
+//
+// Example:
+//
+//	context:                      # Overrides any ProjectContext field, by its `fluxion context --format json` name
+//	  primary_language: Go
+//	  build_command: make build
+//	  runtime_versions: {go: "1.22"}
+//	notes:
+//	  - Integration tests need the staging VPN and only run on main
+//	conventions:
+//	  runner_labels: [self-hosted, linux, x64]
+//	  required_steps: [uses: acme/security-scan@v2]
+//	  secrets: {registry_password: ACME_REGISTRY_TOKEN}
+//	detectors:                    # External detector plugins, see PluginDetector
+//	  - ./tools/fluxion-detector-bazel
+//	templates:                    # Shared template directories, besides .fluxion/templates; see UserTemplate
+//	  - ~/src/acme-ci-templates
+//	defaults:
+//	  provider: openai
+//	  model: gpt-4o
+//	  target: github
+//	  output: .github/workflows/ci.yml
+type ProjectConfig struct {
+	Path        string         `yaml:"-"`           // File the config was read from
+	Context     yaml.Node      `yaml:"context"`     // ProjectContext overrides, kept as YAML until merged
+	Notes       []string       `yaml:"notes"`       // Extra context for the AI
+	Conventions OrgConventions `yaml:"conventions"` // Organisation rules every workflow must follow
+	Detectors   []string       `yaml:"detectors"`   // Detector plugins: paths relative to the repository root, or names on PATH
+	Templates   []string       `yaml:"templates"`   // Shared workflow template directories, relative to the repository root or ~
+	Defaults    ConfigDefaults `yaml:"defaults"`    // Default command settings
+}
+
+// OrgConventions are organisation rules generated workflows must follow
+type OrgConventions struct {
+	RunnerLabels  []string          `yaml:"runner_labels" json:"runner_labels,omitempty"`   // runs-on labels every job must use
+	RequiredSteps []string          `yaml:"required_steps" json:"required_steps,omitempty"` // Steps every job must include, e.g. "uses: acme/security-scan@v2"
+	Secrets       map[string]string `yaml:"secrets" json:"secrets,omitempty"`               // Purpose → secret name, e.g. "npm_token" → "NPM_PUBLISH_TOKEN"
+}
+
+// IsEmpty reports whether no conventions were declared
+func (c OrgConventions) IsEmpty() bool {
+	return len(c.RunnerLabels) == 0 && len(c.RequiredSteps) == 0 && len(c.Secrets) == 0
+}
+
+// ConfigDefaults are default settings for generate and debug, used when the flags are not set
+type ConfigDefaults struct {
+	Provider string `yaml:"provider"` // AI provider; only "openai" is supported
+	Model    string `yaml:"model"`    // Model name, e.g. "gpt-4o"
+	Target   string `yaml:"target"`   // Platform generate writes for, e.g. "gitlab"
+	Output   string `yaml:"output"`   // Output path for generated configuration, for the default target
+}
+
+// ChatModel returns the model to use, checking the provider is supported
+func (d ConfigDefaults) ChatModel() (openai.ChatModel, error) {
+	if d.Provider != "" && !strings.EqualFold(d.Provider, "openai") {
+		return "", fmt.Errorf("unsupported provider %q in .fluxion.yml (supported: openai)", d.Provider)
+	}
+	if d.Model == "" {
+		return openai.ChatModelGPT4o, nil
+	}
+	return openai.ChatModel(d.Model), nil
+}
+
+// LoadProjectConfig reads .fluxion.yml from the working directory.
+// It returns false when the project has no config file.
+func LoadProjectConfig(workingDir string) (ProjectConfig, bool, error) {
+	config := ProjectConfig{}
+	for _, name := range projectConfigFiles {
+		path := filepath.Join(workingDir, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if err := yaml.Unmarshal(data, &config); err != nil {
+			config.Path = name
+			return config, true, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+		config.Path = name
+		return config, true, nil
+	}
+	return config, false, nil
+}
+
+// Apply merges the config into a detected context: overridden fields replace
+// what the detectors found, notes and conventions are added
+func (config ProjectConfig) Apply(ctx *ProjectContext) error {
+	if config.Context.Kind != 0 {
+		var overrides map[string]interface{}
+		if err := config.Context.Decode(&overrides); err != nil {
+			return fmt.Errorf("invalid context in %s: %w", config.Path, err)
+		}
+
+		// Overrides use the JSON field names, so decode them through encoding/json
+		// into an empty context, then replace each overridden field whole: decoding
+		// onto the detected context would merge into its slices and maps. Fields
+		// not mentioned keep their detected values.
+		data, err := json.Marshal(overrides)
+		if err != nil {
+			return fmt.Errorf("invalid context in %s: %w", config.Path, err)
+		}
+		var overridden ProjectContext
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&overridden); err != nil {
+			return fmt.Errorf("invalid context in %s: %w", config.Path, err)
+		}
+		detected, replacement := reflect.ValueOf(ctx).Elem(), reflect.ValueOf(overridden)
+		for i := 0; i < detected.NumField(); i++ {
+			name, _, _ := strings.Cut(detected.Type().Field(i).Tag.Get("json"), ",")
+			if _, ok := overrides[name]; ok {
+				detected.Field(i).Set(replacement.Field(i))
+			}
+		}
+
+		fields := make([]string, 0, len(overrides))
+		for field := range overrides {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			ctx.record(field, "config", "overridden in "+config.Path)
+		}
+		if _, ok := overrides["primary_language"]; ok && !slices.Contains(ctx.Languages, ctx.PrimaryLang) {
+			ctx.Languages = append([]string{ctx.PrimaryLang}, ctx.Languages...)
+		}
+		if _, ok := overrides["services"]; ok {
+			for i, svc := range ctx.Services {
+				ctx.Services[i] = completeService(svc, "declared in "+config.Path)
+			}
+		}
+	}
+
+	if len(config.Notes) > 0 {
+		ctx.Notes = append(ctx.Notes, config.Notes...)
+		ctx.record("notes", "config", "declared in "+config.Path)
+	}
+	if !config.Conventions.IsEmpty() {
+		ctx.Conventions = config.Conventions
+		ctx.record("conventions", "config", "declared in "+config.Path)
+	}
+	return nil
+}
+
+// FormatConventions summarises the organisation conventions for prompts
+func (c OrgConventions) FormatConventions() []string {
+	var lines []string
+	if len(c.RunnerLabels) > 0 {
+		lines = append(lines, fmt.Sprintf("Runner Labels (every job): runs-on: [%s]", strings.Join(c.RunnerLabels, ", ")))
+	}
+	for _, step := range c.RequiredSteps {
+		lines = append(lines, "Required Step (every job): "+step)
+	}
+	if len(c.Secrets) > 0 {
+		var secrets []string
+		for _, purpose := range sortedMapKeys(c.Secrets) {
+			secrets = append(secrets, fmt.Sprintf("%s → secrets.%s", purpose, c.Secrets[purpose]))
+		}
+		lines = append(lines, "Secret Names: "+strings.Join(secrets, ", "))
+	}
+	return lines
+}
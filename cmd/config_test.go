@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestApplyReplacesOverriddenFields(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "go.mod"), "module example.com/app\n\ngo 1.22\n\nrequire github.com/lib/pq v1.10.9\n")
+	writeTestFile(t, filepath.Join(dir, "main.go"), "package main\n\nfunc main() {}\n")
+	writeTestFile(t, filepath.Join(dir, ".fluxion.yml"), `context:
+  services:
+    - name: redis
+  runtime_versions:
+    go: "1.23"
+`)
+
+	ctx, err := DetectProjectContext(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ServiceRequirement{{
+		Name:          "redis",
+		Image:         "redis:7",
+		Ports:         []string{"6379:6379"},
+		HealthCheck:   "redis-cli ping",
+		Env:           map[string]string{},
+		ConnectionURL: "redis://localhost:6379",
+		Reason:        "declared in .fluxion.yml",
+	}}
+	if !reflect.DeepEqual(ctx.Services, want) {
+		t.Errorf("Services = %+v\nwant %+v", ctx.Services, want)
+	}
+	if got := ctx.RuntimeVersions; !reflect.DeepEqual(got, map[string]string{"go": "1.23"}) {
+		t.Errorf("RuntimeVersions = %v, want only the override", got)
+	}
+	if ctx.PrimaryLang != "Go" {
+		t.Errorf("PrimaryLang = %q, want the detected Go", ctx.PrimaryLang)
+	}
+}
+
+func TestApplyRejectsUnknownFields(t *testing.T) {
+	var config ProjectConfig
+	if err := yaml.Unmarshal([]byte("context:\n  no_such_field: 1\n"), &config); err != nil {
+		t.Fatal(err)
+	}
+	var ctx ProjectContext
+	if err := config.Apply(&ctx); err == nil {
+		t.Error("Apply accepted an unknown context field")
+	}
+}
@@ -2,27 +2,46 @@ package cmd
 
 import (
 	"bufio"
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
 )
 
 // ProjectContext contains detected information about the project
 type ProjectContext struct {
-	Languages      []string // e.g., ["Go", "JavaScript"]
-	PrimaryLang    string   // Most likely primary language
-	Framework      string   // e.g., "Cobra CLI", "Express", "Flask"
-	Dependencies   []string // Key dependencies detected
-	HasTests       bool     // Whether test files were found
-	BuildCommand   string   // Suggested build command
-	TestCommand    string   // Suggested test command
-	PackageManager string   // e.g., "go mod", "npm", "pip"
-	Structure      string   // Project structure description
-	DockerFiles    []string // Dockerfile, docker-compose.yml
-	ConfigFiles    []string // Detected config files
-	HasCI          bool     // Has existing CI/CD workflows
-	ExistingCI     []string // Existing workflow files
+	Languages       []string             `json:"languages"`                  // e.g., ["Go", "JavaScript"]
+	PrimaryLang     string               `json:"primary_language"`           // Most likely primary language
+	Framework       string               `json:"framework,omitempty"`        // e.g., "Cobra CLI", "Express", "Flask"
+	Dependencies    []string             `json:"dependencies,omitempty"`     // Key dependencies detected
+	Packages        []string             `json:"packages,omitempty"`         // All declared dependencies, across languages
+	Tests           TestInfo             `json:"tests,omitzero"`             // Test frameworks, directories and coverage tooling
+	Go              *GoModuleInfo        `json:"go,omitempty"`               // Binaries, cgo, go generate, tools and private modules
+	BuildCommand    string               `json:"build_command,omitempty"`    // Suggested build command
+	TestCommand     string               `json:"test_command,omitempty"`     // Suggested test command
+	PackageManager  string               `json:"package_manager,omitempty"`  // e.g., "go mod", "npm", "pip"
+	Structure       string               `json:"structure,omitempty"`        // Project structure description
+	DockerFiles     []string             `json:"docker_files,omitempty"`     // Dockerfile, docker-compose.yml
+	Docker          DockerContext        `json:"docker,omitzero"`            // Parsed Dockerfiles and compose files
+	ConfigFiles     []string             `json:"config_files,omitempty"`     // Detected config files
+	HasCI           bool                 `json:"has_ci"`                     // Has existing CI/CD workflows
+	ExistingCI      []string             `json:"existing_ci,omitempty"`      // Existing workflow files
+	Workflows       []WorkflowSummary    `json:"workflows,omitempty"`        // Parsed summaries of the existing workflows
+	Services        []ServiceRequirement `json:"services,omitempty"`         // Backing services CI must run (databases, queues)
+	Deployments     []DeploymentTarget   `json:"deployments,omitempty"`      // Deployment platforms the project is configured for
+	Release         ReleaseInfo          `json:"release,omitzero"`           // Release tooling and commit conventions
+	Git             GitInfo              `json:"git,omitzero"`               // Branches, remote, tags, submodules and LFS from .git
+	EnvVars         []EnvVar             `json:"env_vars,omitempty"`         // Environment variables the application reads
+	RuntimeVersions map[string]string    `json:"runtime_versions,omitempty"` // Runtime versions to set up, e.g. {"go": "1.22"}
+	Notes           []string             `json:"notes,omitempty"`            // Extra context from .fluxion.yml
+	Conventions     OrgConventions       `json:"conventions,omitzero"`       // Organisation rules from .fluxion.yml
+	Diagnostics     []Diagnostic         `json:"diagnostics,omitempty"`      // Detector failures, parse problems and timings
+	Provenance      []FieldSource        `json:"provenance,omitempty"`       // Which detector produced each field, and why
 }
 
 // LanguageDetector interface for language-specific detection
@@ -33,20 +52,35 @@ type ProjectContext struct {
 // Think of it like a template:
 // - Name() returns the language name (e.g., "Go", "Python")
 // - Detect() checks if this is that language's project and returns info
+//
+// Detect receives the shared index of the project's files, so detectors
+// that need to search the tree don't walk it again, and a context that is
+// cancelled when the detector times out. It returns nil, nil when the project
+// is not in its language, and an error only when detection itself failed.
 type LanguageDetector interface {
 	Name() string
-	Detect(workingDir string) (*LanguageContext, error)
+	Detect(ctx context.Context, workingDir string, files *FileIndex) (*LanguageContext, error)
 }
 
 // LanguageContext contains language-specific detection results
 type LanguageContext struct {
-	Language       string
-	Framework      string
-	Dependencies   []string
-	BuildCommand   string
-	TestCommand    string
-	PackageManager string
-	HasTests       bool
+	Language       string   `json:"language"`
+	Framework      string   `json:"framework,omitempty"`
+	Dependencies   []string `json:"dependencies,omitempty"`
+	Packages       []string `json:"packages,omitempty"`
+	BuildCommand   string   `json:"build_command,omitempty"`
+	TestCommand    string   `json:"test_command,omitempty"`
+	PackageManager string   `json:"package_manager,omitempty"`
+	Tests          TestInfo `json:"tests,omitzero"`
+
+	// Go holds module details only the Go detector fills in
+	Go *GoModuleInfo `json:"go,omitempty"`
+
+	// Evidence maps a field's JSON name to why it was set, e.g. "framework" → "github.com/spf13/cobra in go.mod"
+	Evidence map[string]string `json:"evidence,omitempty"`
+
+	// Diagnostics are problems with the files the detector read that did not stop detection
+	Diagnostics []Diagnostic `json:"diagnostics,omitempty"`
 }
 
 // Registry of language detectors
@@ -75,48 +109,191 @@ func DetectProjectContext(workingDir string) (ProjectContext, error) {
 		ExistingCI:   make([]string, 0),
 	}
 
-	// Run all language detectors
-	for _, detector := range languageDetectors {
-		if langCtx, err := detector.Detect(workingDir); err == nil && langCtx != nil {
-			ctx.Languages = append(ctx.Languages, langCtx.Language)
-
-			// First detected language becomes primary
-			if ctx.PrimaryLang == "" {
-				ctx.PrimaryLang = langCtx.Language
-				ctx.Framework = langCtx.Framework
-				ctx.Dependencies = langCtx.Dependencies
-				ctx.BuildCommand = langCtx.BuildCommand
-				ctx.TestCommand = langCtx.TestCommand
-				ctx.PackageManager = langCtx.PackageManager
-				ctx.HasTests = langCtx.HasTests
-			} else {
-				// Merge additional language info
-				ctx.HasTests = ctx.HasTests || langCtx.HasTests
+	config, hasConfig, configErr := LoadProjectConfig(workingDir)
+
+	// Walk the tree once; every detector below queries this index
+	files := NewFileIndex(workingDir)
+	if files.Truncated {
+		ctx.Diagnostics = append(ctx.Diagnostics, Diagnostic{Detector: "files", Severity: SeverityWarning,
+			Message: fmt.Sprintf("stopped indexing at %d files or %d directory levels; detection may be incomplete", defaultWalkLimits.MaxFiles, defaultWalkLimits.MaxDepth)})
+	}
+
+	// Start every independent detector at once. Results are merged below in a
+	// fixed order, so the context is the same however the detectors are scheduled.
+	background := context.Background()
+
+	// Language detectors, built-in first, then plugins
+	plugins, pluginDiags := pluginDetectors(workingDir, config, allowPlugins)
+	ctx.Diagnostics = append(ctx.Diagnostics, pluginDiags...)
+	detectors := append(append([]LanguageDetector{}, languageDetectors...), plugins...)
+	languages := make([]*detection[*LanguageContext], len(detectors))
+	for i, detector := range detectors {
+		languages[i] = startDetection(background, detector.Name(), func(c context.Context) (*LanguageContext, []Diagnostic, error) {
+			langCtx, err := detector.Detect(c, workingDir, files)
+			if langCtx == nil {
+				return nil, nil, err
+			}
+			return langCtx, langCtx.Diagnostics, err
+		})
+	}
+
+	// Docker: Dockerfiles, compose files and .dockerignore, anywhere in the tree
+	docker := startDetection(background, "docker", func(c context.Context) (DockerContext, []Diagnostic, error) {
+		info, diags := DetectDocker(c, workingDir, files)
+		return info, diags, c.Err()
+	})
+
+	// Existing CI/CD and what it already uses
+	workflows := startDetection(background, "workflows", func(c context.Context) ([]WorkflowSummary, []Diagnostic, error) {
+		summaries, diags := DetectWorkflows(c, workingDir)
+		return summaries, diags, c.Err()
+	})
+
+	// Deployment platforms (Vercel, Netlify, Fly.io, ...) from their config files
+	deployments := startDetection(background, "deployment", func(c context.Context) ([]DeploymentTarget, []Diagnostic, error) {
+		return DetectDeploymentTargets(c, workingDir), nil, c.Err()
+	})
+
+	// Release tooling (GoReleaser, semantic-release, ...) and commit conventions
+	release := startDetection(background, "release", func(c context.Context) (ReleaseInfo, []Diagnostic, error) {
+		return DetectRelease(c, workingDir), nil, c.Err()
+	})
+
+	// Repository metadata from .git (no network)
+	git := startDetection(background, "git", func(c context.Context) (*GitInfo, []Diagnostic, error) {
+		if info, ok := DetectGit(c, workingDir); ok {
+			return &info, nil, nil
+		}
+		return nil, nil, c.Err()
+	})
+
+	// Project structure
+	structure := startDetection(background, "structure", func(c context.Context) (string, []Diagnostic, error) {
+		return detectProjectStructure(c, workingDir), nil, c.Err()
+	})
+
+	for i, detection := range languages {
+		langCtx, ok := detection.wait(&ctx.Diagnostics)
+		if !ok {
+			continue
+		}
+		if langCtx == nil {
+			ctx.Diagnostics = append(ctx.Diagnostics, Diagnostic{Detector: detectors[i].Name(), Severity: SeverityInfo, Message: "not detected"})
+			continue
+		}
+
+		ctx.Languages = append(ctx.Languages, langCtx.Language)
+		ctx.Packages = appendUnique(ctx.Packages, langCtx.Packages...)
+
+		// First detected language becomes primary
+		ctx.recordLanguage(detectors[i].Name(), langCtx, ctx.PrimaryLang == "")
+		if ctx.PrimaryLang == "" {
+			ctx.PrimaryLang = langCtx.Language
+			ctx.Framework = langCtx.Framework
+			ctx.Dependencies = langCtx.Dependencies
+			ctx.BuildCommand = langCtx.BuildCommand
+			ctx.TestCommand = langCtx.TestCommand
+			ctx.PackageManager = langCtx.PackageManager
+			ctx.Tests = langCtx.Tests
+		} else {
+			// Merge additional language info
+			ctx.Tests = mergeTestInfo(ctx.Tests, langCtx.Tests)
+		}
+
+		if langCtx.Go != nil {
+			ctx.Go = langCtx.Go
+			ctx.record("go", detectors[i].Name(), "go.mod and the module's Go sources")
+			if langCtx.Go.GoVersion != "" {
+				if ctx.RuntimeVersions == nil {
+					ctx.RuntimeVersions = map[string]string{}
+				}
+				ctx.RuntimeVersions["go"] = langCtx.Go.GoVersion
+				ctx.record("runtime_versions", detectors[i].Name(), "go directive in go.mod")
 			}
 		}
 	}
 
-	// Check for Docker
-	dockerFiles := []string{"Dockerfile", "docker-compose.yml", "docker-compose.yaml"}
-	for _, df := range dockerFiles {
-		if _, err := os.Stat(filepath.Join(workingDir, df)); err == nil {
-			ctx.DockerFiles = append(ctx.DockerFiles, df)
+	if info, ok := docker.wait(&ctx.Diagnostics); ok {
+		ctx.Docker = info
+		ctx.DockerFiles = append(ctx.DockerFiles, ctx.Docker.Files()...)
+		if files := ctx.Docker.Files(); len(files) > 0 {
+			ctx.record("docker", "docker", "found "+strings.Join(files, ", "))
 		}
 	}
 
-	// Check for existing CI/CD
-	ciPath := filepath.Join(workingDir, ".github", "workflows")
-	if entries, err := os.ReadDir(ciPath); err == nil {
-		ctx.HasCI = true
-		for _, entry := range entries {
-			if !entry.IsDir() && (strings.HasSuffix(entry.Name(), ".yml") || strings.HasSuffix(entry.Name(), ".yaml")) {
-				ctx.ExistingCI = append(ctx.ExistingCI, entry.Name())
+	// These need the languages' packages and the compose files, so start them now
+	dockerInfo, packages := ctx.Docker, ctx.Packages
+	envVars := startDetection(background, "env", func(c context.Context) ([]EnvVar, []Diagnostic, error) {
+		return DetectEnvVars(c, workingDir, files, dockerInfo), nil, c.Err()
+	})
+	services := startDetection(background, "services", func(c context.Context) ([]ServiceRequirement, []Diagnostic, error) {
+		return InferServices(c, workingDir, packages), nil, c.Err()
+	})
+
+	if summaries, ok := workflows.wait(&ctx.Diagnostics); ok {
+		if _, err := os.Stat(filepath.Join(workingDir, ".github", "workflows")); err == nil {
+			ctx.HasCI = true
+			for _, path := range listWorkflowFiles(workingDir) {
+				ctx.ExistingCI = append(ctx.ExistingCI, filepath.Base(path))
 			}
+			ctx.Workflows = summaries
+			ctx.record("has_ci", "workflows", ".github/workflows exists")
+			if len(ctx.Workflows) > 0 {
+				ctx.record("workflows", "workflows", fmt.Sprintf("parsed %d workflow file(s)", len(ctx.Workflows)))
+			}
+		}
+	}
+
+	// Inventory the environment variables the application reads
+	if vars, ok := envVars.wait(&ctx.Diagnostics); ok {
+		ctx.EnvVars = vars
+		if len(ctx.EnvVars) > 0 {
+			ctx.record("env_vars", "env", "env example files, environment lookups in source and compose environments")
+		}
+	}
+
+	// Backing services (databases, caches, queues) tests will need
+	if required, ok := services.wait(&ctx.Diagnostics); ok {
+		ctx.Services = required
+		for _, svc := range ctx.Services {
+			ctx.record("services", "services", fmt.Sprintf("%s: %s", svc.Name, svc.Reason))
+		}
+	}
+
+	if targets, ok := deployments.wait(&ctx.Diagnostics); ok {
+		ctx.Deployments = targets
+		for _, target := range ctx.Deployments {
+			ctx.record("deployments", "deployment", fmt.Sprintf("%s: %s", target.Platform, target.ConfigFile))
 		}
 	}
 
-	// Detect project structure
-	ctx.Structure = detectProjectStructure(workingDir)
+	if info, ok := release.wait(&ctx.Diagnostics); ok {
+		ctx.Release = info
+		for _, tool := range ctx.Release.Tools {
+			ctx.record("release", "release", fmt.Sprintf("%s: %s", tool.Name, tool.ConfigFile))
+		}
+	}
+
+	if info, ok := git.wait(&ctx.Diagnostics); ok && info != nil {
+		ctx.Git = *info
+		ctx.record("git", "git", "read .git (HEAD, config, refs)")
+	}
+
+	if layout, ok := structure.wait(&ctx.Diagnostics); ok && layout != "" {
+		ctx.Structure = layout
+		ctx.record("structure", "structure", "top-level directory layout")
+	}
+
+	// Apply .fluxion.yml last: what the team declares wins over what was detected
+	if hasConfig {
+		if configErr == nil {
+			configErr = config.Apply(&ctx)
+		}
+		if configErr != nil {
+			ctx.Diagnostics = append(ctx.Diagnostics, Diagnostic{Detector: "config", Severity: SeverityError, File: config.Path, Message: configErr.Error()})
+			return ctx, configErr
+		}
+	}
 
 	return ctx, nil
 }
@@ -131,10 +308,10 @@ func (d *GoDetector) Name() string {
 	return "Go"
 }
 
-func (d *GoDetector) Detect(workingDir string) (*LanguageContext, error) {
+func (d *GoDetector) Detect(c context.Context, workingDir string, files *FileIndex) (*LanguageContext, error) {
 	goModPath := filepath.Join(workingDir, "go.mod")
 	if _, err := os.Stat(goModPath); err != nil {
-		return nil, err
+		return nil, nil
 	}
 
 	ctx := &LanguageContext{
@@ -144,14 +321,18 @@ func (d *GoDetector) Detect(workingDir string) (*LanguageContext, error) {
 		PackageManager: "go mod",
 		Dependencies:   make([]string, 0),
 	}
+	ctx.because("language", "go.mod found")
+	ctx.because("package_manager", "go.mod found")
 
 	// Parse go.mod for dependencies and frameworks
 	file, err := os.Open(goModPath)
 	if err != nil {
+		ctx.warn("go.mod", "could not be read: %v", err)
 		return ctx, nil
 	}
 	defer file.Close()
 
+	goModContent, _ := readFileString(goModPath)
 	scanner := bufio.NewScanner(file)
 	inRequire := false
 
@@ -166,28 +347,35 @@ func (d *GoDetector) Detect(workingDir string) (*LanguageContext, error) {
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
 
+		singleRequire := false
 		if strings.HasPrefix(line, "require") {
-			inRequire = true
 			if strings.Contains(line, "(") {
+				inRequire = true
 				continue
 			}
+			// Single-line form: require module/path v1.2.3
+			line = strings.TrimSpace(strings.TrimPrefix(line, "require"))
+			singleRequire = true
 		}
 
-		if inRequire {
+		if inRequire || singleRequire {
 			if strings.Contains(line, ")") {
 				inRequire = false
 				continue
 			}
 
+			// Indirect requires are other modules' dependencies, not the project's
 			parts := strings.Fields(line)
-			if len(parts) >= 1 && !strings.HasPrefix(parts[0], "//") {
+			if len(parts) >= 1 && !strings.HasPrefix(parts[0], "//") && !strings.Contains(line, "// indirect") {
 				dep := parts[0]
+				ctx.Packages = append(ctx.Packages, dep)
 
 				// Check for known frameworks
 				for key, framework := range frameworks {
 					if strings.Contains(dep, key) {
 						ctx.Framework = framework
 						ctx.Dependencies = append(ctx.Dependencies, key)
+						ctx.because("framework", dep+" in go.mod")
 						break
 					}
 				}
@@ -195,21 +383,35 @@ func (d *GoDetector) Detect(workingDir string) (*LanguageContext, error) {
 		}
 	}
 
-	// Check for test files
-	filepath.Walk(workingDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil
-		}
-		if !info.IsDir() && strings.HasSuffix(info.Name(), "_test.go") {
-			ctx.HasTests = true
-			return filepath.SkipAll
-		}
-		return nil
-	})
+	// Check for test files, frameworks and coverage tooling
+	ctx.Tests = detectGoTests(c, workingDir, files, goModContent)
 
-	// Check for main.go to determine if it's a binary
-	if _, err := os.Stat(filepath.Join(workingDir, "main.go")); err == nil {
-		ctx.BuildCommand = "go build -o app"
+	// Binaries, cgo, code generation, tools, vendoring and private modules
+	var diags []Diagnostic
+	ctx.Go, diags = detectGoModule(c, workingDir, files, goModContent, ctx.Packages)
+	ctx.Diagnostics = append(ctx.Diagnostics, diags...)
+
+	// Both walks stop early when cancelled; a partial result would be wrong, not just incomplete
+	if err := c.Err(); err != nil {
+		return nil, err
+	}
+
+	// Build each main package into its own binary; libraries just need to compile
+	switch binaries := ctx.Go.Binaries; len(binaries) {
+	case 0:
+		ctx.BuildCommand = "go build ./..."
+		ctx.because("build_command", "no main packages")
+	case 1:
+		ctx.BuildCommand = binaries[0].BuildCommand
+		ctx.because("build_command", "main package in "+binaries[0].Package)
+	default:
+		var commands, packages []string
+		for _, b := range binaries {
+			commands = append(commands, b.BuildCommand)
+			packages = append(packages, b.Package)
+		}
+		ctx.BuildCommand = strings.Join(commands, " && ")
+		ctx.because("build_command", "main packages in "+strings.Join(packages, ", "))
 	}
 
 	return ctx, nil
@@ -225,10 +427,10 @@ func (d *NodeDetector) Name() string {
 	return "JavaScript/TypeScript"
 }
 
-func (d *NodeDetector) Detect(workingDir string) (*LanguageContext, error) {
+func (d *NodeDetector) Detect(c context.Context, workingDir string, files *FileIndex) (*LanguageContext, error) {
 	packageJsonPath := filepath.Join(workingDir, "package.json")
 	if _, err := os.Stat(packageJsonPath); err != nil {
-		return nil, err
+		return nil, nil
 	}
 
 	ctx := &LanguageContext{
@@ -236,24 +438,42 @@ func (d *NodeDetector) Detect(workingDir string) (*LanguageContext, error) {
 		Dependencies: make([]string, 0),
 	}
 
+	ctx.because("language", "package.json found")
+
 	// Detect package manager
 	if _, err := os.Stat(filepath.Join(workingDir, "package-lock.json")); err == nil {
 		ctx.PackageManager = "npm"
+		ctx.because("package_manager", "package-lock.json found")
 	} else if _, err := os.Stat(filepath.Join(workingDir, "yarn.lock")); err == nil {
 		ctx.PackageManager = "yarn"
+		ctx.because("package_manager", "yarn.lock found")
 	} else if _, err := os.Stat(filepath.Join(workingDir, "pnpm-lock.yaml")); err == nil {
 		ctx.PackageManager = "pnpm"
+		ctx.because("package_manager", "pnpm-lock.yaml found")
 	} else {
 		ctx.PackageManager = "npm"
+		ctx.because("package_manager", "no lockfile, defaulting to npm")
 	}
 
 	// Read package.json
 	data, err := os.ReadFile(packageJsonPath)
 	if err != nil {
+		ctx.warn("package.json", "could not be read: %v", err)
 		return ctx, nil
 	}
 
 	content := string(data)
+	var pkg packageJSON
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		ctx.warn("package.json", "invalid JSON, dependencies and test setup not detected: %v", err)
+	}
+	for name := range pkg.Dependencies {
+		ctx.Packages = append(ctx.Packages, name)
+	}
+	for name := range pkg.DevDependencies {
+		ctx.Packages = appendUnique(ctx.Packages, name)
+	}
+	sort.Strings(ctx.Packages)
 
 	// Detect frameworks
 	frameworks := map[string]string{
@@ -271,6 +491,7 @@ func (d *NodeDetector) Detect(workingDir string) (*LanguageContext, error) {
 		if strings.Contains(content, key) {
 			ctx.Framework = framework
 			ctx.Dependencies = append(ctx.Dependencies, strings.Trim(key, "\""))
+			ctx.because("framework", key+" in package.json")
 			break
 		}
 	}
@@ -278,11 +499,16 @@ func (d *NodeDetector) Detect(workingDir string) (*LanguageContext, error) {
 	// Detect scripts
 	if strings.Contains(content, "\"build\"") {
 		ctx.BuildCommand = fmt.Sprintf("%s run build", ctx.PackageManager)
+		ctx.because("build_command", "\"build\" script in package.json")
 	}
 	if strings.Contains(content, "\"test\"") {
 		ctx.TestCommand = fmt.Sprintf("%s test", ctx.PackageManager)
-		ctx.HasTests = true
+		ctx.because("test_command", "\"test\" script in package.json")
 	}
+	if err := c.Err(); err != nil {
+		return nil, err
+	}
+	ctx.Tests = detectNodeTests(workingDir, pkg, ctx.PackageManager)
 
 	// Check for TypeScript
 	if strings.Contains(content, "\"typescript\"") {
@@ -302,19 +528,19 @@ func (d *PythonDetector) Name() string {
 	return "Python"
 }
 
-func (d *PythonDetector) Detect(workingDir string) (*LanguageContext, error) {
+func (d *PythonDetector) Detect(c context.Context, workingDir string, files *FileIndex) (*LanguageContext, error) {
 	// Check for Python project indicators
 	indicators := []string{"requirements.txt", "setup.py", "pyproject.toml", "Pipfile"}
-	found := false
+	found := ""
 	for _, indicator := range indicators {
 		if _, err := os.Stat(filepath.Join(workingDir, indicator)); err == nil {
-			found = true
+			found = indicator
 			break
 		}
 	}
 
-	if !found {
-		return nil, fmt.Errorf("no Python project indicators found")
+	if found == "" {
+		return nil, nil
 	}
 
 	ctx := &LanguageContext{
@@ -323,6 +549,7 @@ func (d *PythonDetector) Detect(workingDir string) (*LanguageContext, error) {
 		PackageManager: "pip",
 		TestCommand:    "pytest",
 	}
+	ctx.because("language", found+" found")
 
 	// Check for requirements.txt
 	reqPath := filepath.Join(workingDir, "requirements.txt")
@@ -341,39 +568,118 @@ func (d *PythonDetector) Detect(workingDir string) (*LanguageContext, error) {
 			if strings.Contains(content, key) {
 				ctx.Framework = framework
 				ctx.Dependencies = append(ctx.Dependencies, key)
+				ctx.because("framework", key+" in requirements.txt")
 			}
 		}
-
-		if strings.Contains(content, "pytest") {
-			ctx.HasTests = true
-		}
 	}
+	ctx.Packages = pythonPackages(workingDir)
 
 	// Check for Pipfile (Pipenv)
 	if _, err := os.Stat(filepath.Join(workingDir, "Pipfile")); err == nil {
 		ctx.PackageManager = "pipenv"
+		ctx.because("package_manager", "Pipfile found")
 	}
 
 	// Check for pyproject.toml (Poetry)
 	if data, err := os.ReadFile(filepath.Join(workingDir, "pyproject.toml")); err == nil {
 		if strings.Contains(string(data), "[tool.poetry]") {
 			ctx.PackageManager = "poetry"
+			ctx.because("package_manager", "[tool.poetry] in pyproject.toml")
 		}
 	}
 
-	// Check for test directory
-	if _, err := os.Stat(filepath.Join(workingDir, "tests")); err == nil {
-		ctx.HasTests = true
+	// Check for test frameworks, directories and coverage tooling
+	if err := c.Err(); err != nil {
+		return nil, err
+	}
+	var manifests string
+	for _, manifest := range []string{"requirements.txt", "requirements-dev.txt", "dev-requirements.txt", "requirements-test.txt", "setup.py", "setup.cfg", "pyproject.toml", "Pipfile"} {
+		if content, ok := readFileString(filepath.Join(workingDir, manifest)); ok {
+			manifests += strings.ToLower(content) + "\n"
+		}
+	}
+	ctx.Tests = detectPythonTests(workingDir, manifests)
+	if slices.Contains(ctx.Tests.Frameworks, "unittest") {
+		ctx.TestCommand = "python -m unittest discover"
+		ctx.because("test_command", "unittest tests without pytest")
 	}
 
 	return ctx, nil
 }
 
+var pythonRequirementName = regexp.MustCompile(`^"?([A-Za-z0-9][A-Za-z0-9._\-]*)`)
+
+// pythonPackages lists the distributions declared in requirements files,
+// pyproject.toml dependency arrays/tables and the Pipfile
+func pythonPackages(workingDir string) []string {
+	var packages []string
+	add := func(entry string) {
+		if m := pythonRequirementName.FindStringSubmatch(strings.TrimSpace(entry)); m != nil && strings.ToLower(m[1]) != "python" {
+			packages = appendUnique(packages, strings.ToLower(m[1]))
+		}
+	}
+
+	for _, name := range []string{"requirements.txt", "requirements-dev.txt", "dev-requirements.txt", "requirements-test.txt"} {
+		content, ok := readFileString(filepath.Join(workingDir, name))
+		if !ok {
+			continue
+		}
+		for _, line := range strings.Split(content, "\n") {
+			line = strings.TrimSpace(line)
+			if line != "" && !strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "-") {
+				add(line)
+			}
+		}
+	}
+
+	for _, name := range []string{"pyproject.toml", "Pipfile"} {
+		content, ok := readFileString(filepath.Join(workingDir, name))
+		if !ok {
+			continue
+		}
+		section, inArray := "", false
+		for _, line := range strings.Split(content, "\n") {
+			line = strings.TrimSpace(line)
+			switch {
+			case strings.HasPrefix(line, "["):
+				section, inArray = line, false
+			case inArray:
+				// "requests>=2.0", inside a PEP 621 dependency array
+				if strings.HasPrefix(line, "]") {
+					inArray = false
+				} else {
+					add(line)
+				}
+			case strings.Contains(section, "dependencies") || section == "[packages]" || section == "[dev-packages]":
+				// name = "^1.0" in a Poetry/Pipfile table, or name = [ in optional-dependencies
+				if key, value, ok := strings.Cut(line, "="); ok {
+					if strings.HasPrefix(strings.TrimSpace(value), "[") {
+						inArray = !strings.Contains(value, "]")
+						for _, item := range strings.Split(strings.Trim(strings.TrimSpace(value), "[]"), ",") {
+							add(item)
+						}
+					} else {
+						add(key)
+					}
+				}
+			case section == "[project]" && strings.HasPrefix(line, "dependencies"):
+				_, value, _ := strings.Cut(line, "=")
+				inArray = !strings.Contains(value, "]")
+				for _, item := range strings.Split(strings.Trim(strings.TrimSpace(value), "[]"), ",") {
+					add(item)
+				}
+			}
+		}
+	}
+
+	return packages
+}
+
 // =============================================================================
 // Helper Functions
 // =============================================================================
 
-func detectProjectStructure(workingDir string) string {
+func detectProjectStructure(ctx context.Context, workingDir string) string {
 	structures := []string{}
 
 	// Check common structure patterns
@@ -388,6 +694,9 @@ func detectProjectStructure(workingDir string) string {
 	}
 
 	for dir, description := range patterns {
+		if ctx.Err() != nil {
+			break // The caller reports the cancellation
+		}
 		if _, err := os.Stat(filepath.Join(workingDir, dir)); err == nil {
 			structures = append(structures, description)
 		}
@@ -437,23 +746,177 @@ func (ctx *ProjectContext) FormatContext() string {
 		parts = append(parts, fmt.Sprintf("- Key Dependencies: %s", strings.Join(deps, ", ")))
 	}
 
-	parts = append(parts, fmt.Sprintf("- Has Tests: %v", ctx.HasTests))
+	parts = append(parts, fmt.Sprintf("- Has Tests: %v", ctx.Tests.HasTests()))
+
+	if len(ctx.Tests.Frameworks) > 0 {
+		parts = append(parts, fmt.Sprintf("- Test Frameworks: %s", strings.Join(ctx.Tests.Frameworks, ", ")))
+	}
+
+	if len(ctx.Tests.Directories) > 0 {
+		dirs := ctx.Tests.Directories
+		if len(dirs) > 5 {
+			dirs = append(dirs[:5:5], fmt.Sprintf("... (%d more)", len(ctx.Tests.Directories)-5))
+		}
+		parts = append(parts, fmt.Sprintf("- Test Directories: %s", strings.Join(dirs, ", ")))
+	}
+
+	if ctx.Tests.IntegrationCommand != "" {
+		parts = append(parts, fmt.Sprintf("- Integration Tests: %s (selected by %s)", ctx.Tests.IntegrationCommand, strings.Join(ctx.Tests.IntegrationTags, ", ")))
+	}
+
+	if len(ctx.Tests.CoverageTools) > 0 {
+		parts = append(parts, fmt.Sprintf("- Coverage Tooling: %s", strings.Join(ctx.Tests.CoverageTools, ", ")))
+	}
+
+	if ctx.Tests.CoverageCommand != "" {
+		parts = append(parts, fmt.Sprintf("- Coverage Command: %s (report: %s)", ctx.Tests.CoverageCommand, ctx.Tests.CoverageReport))
+	}
+
+	if len(ctx.Tests.CoverageConfig) > 0 {
+		parts = append(parts, fmt.Sprintf("- Coverage Config: %s", strings.Join(ctx.Tests.CoverageConfig, ", ")))
+	}
+
+	if ctx.Tests.CoverageThreshold != "" {
+		parts = append(parts, fmt.Sprintf("- Coverage Threshold: %s", ctx.Tests.CoverageThreshold))
+	}
+
+	if ctx.Go != nil {
+		if lines := ctx.Go.FormatGo(); len(lines) > 0 {
+			parts = append(parts, "- Go Module:")
+			for _, line := range lines {
+				parts = append(parts, "  - "+line)
+			}
+		}
+	}
 
 	if ctx.Structure != "" {
 		parts = append(parts, fmt.Sprintf("- Project Structure: %s", ctx.Structure))
 	}
 
+	if git := ctx.Git.FormatGit(); len(git) > 0 {
+		parts = append(parts, "- Git:")
+		for _, line := range git {
+			parts = append(parts, "  - "+line)
+		}
+	}
+
 	if len(ctx.DockerFiles) > 0 {
 		parts = append(parts, fmt.Sprintf("- Docker: %s", strings.Join(ctx.DockerFiles, ", ")))
+		for _, line := range ctx.Docker.FormatDocker() {
+			parts = append(parts, "  - "+line)
+		}
 	}
 
 	if ctx.HasCI {
 		parts = append(parts, fmt.Sprintf("- Existing CI/CD: %s", strings.Join(ctx.ExistingCI, ", ")))
+		for _, wf := range ctx.Workflows {
+			parts = append(parts, "  - "+wf.FormatWorkflow())
+		}
+		if conventions := ctx.ciConventions(); conventions != "" {
+			parts = append(parts, "- Established CI Conventions: "+conventions)
+		}
+	}
+
+	if env := FormatEnvVars(ctx.EnvVars); len(env) > 0 {
+		parts = append(parts, "- Environment Variables:")
+		for _, line := range env {
+			parts = append(parts, "  - "+line)
+		}
+	}
+
+	if len(ctx.Deployments) > 0 {
+		parts = append(parts, "- Deployment Targets:")
+		for _, target := range ctx.Deployments {
+			parts = append(parts, "  - "+target.FormatDeployment())
+		}
+	}
+
+	if release := ctx.Release.FormatRelease(); len(release) > 0 {
+		parts = append(parts, "- Release Tooling:")
+		for _, line := range release {
+			parts = append(parts, "  - "+line)
+		}
+	}
+
+	if len(ctx.Services) > 0 {
+		var services []string
+		for _, svc := range ctx.Services {
+			services = append(services, fmt.Sprintf("%s (%s, %s)", svc.Name, svc.Image, svc.Reason))
+		}
+		parts = append(parts, fmt.Sprintf("- Required Services: %s", strings.Join(services, "; ")))
+	}
+
+	if len(ctx.RuntimeVersions) > 0 {
+		var runtimes []string
+		for _, name := range sortedMapKeys(ctx.RuntimeVersions) {
+			runtimes = append(runtimes, fmt.Sprintf("%s %s", name, ctx.RuntimeVersions[name]))
+		}
+		parts = append(parts, fmt.Sprintf("- Runtime Versions: %s", strings.Join(runtimes, ", ")))
+	}
+
+	if conventions := ctx.Conventions.FormatConventions(); len(conventions) > 0 {
+		parts = append(parts, "- Organisation Conventions (mandatory):")
+		for _, line := range conventions {
+			parts = append(parts, "  - "+line)
+		}
+	}
+
+	if len(ctx.Notes) > 0 {
+		parts = append(parts, "- Notes from the team:")
+		for _, note := range ctx.Notes {
+			parts = append(parts, "  - "+note)
+		}
 	}
 
 	return strings.Join(parts, "\n")
 }
 
+// readFileString reads a file and reports whether it could be read
+func readFileString(path string) (string, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+// appendUnique appends values that are not already present in items
+func appendUnique(items []string, values ...string) []string {
+	for _, value := range values {
+		if !slices.Contains(items, value) {
+			items = append(items, value)
+		}
+	}
+	return items
+}
+
+// ciConventions aggregates the secret names, variables and runner labels existing workflows already use
+func (ctx *ProjectContext) ciConventions() string {
+	var secrets, vars, runners []string
+	for _, wf := range ctx.Workflows {
+		for _, secret := range wf.Secrets {
+			// GITHUB_TOKEN is provided automatically, it is not a convention
+			if secret != "GITHUB_TOKEN" {
+				secrets = appendUnique(secrets, secret)
+			}
+		}
+		vars = appendUnique(vars, wf.Vars...)
+		runners = appendUnique(runners, wf.RunnerLabels...)
+	}
+
+	var conventions []string
+	if len(secrets) > 0 {
+		conventions = append(conventions, fmt.Sprintf("secrets %s", strings.Join(secrets, ", ")))
+	}
+	if len(vars) > 0 {
+		conventions = append(conventions, fmt.Sprintf("vars %s", strings.Join(vars, ", ")))
+	}
+	if len(runners) > 0 {
+		conventions = append(conventions, fmt.Sprintf("runners %s", strings.Join(runners, ", ")))
+	}
+	return strings.Join(conventions, "; ")
+}
+
 // GetWorkingDirectory gets the current working directory, handles errors gracefully
 func GetWorkingDirectory() string {
 	dir, err := os.Getwd()
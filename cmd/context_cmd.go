@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var contextCmd = &cobra.Command{
+	Use:   "context",
+	Short: "Show what Fluxion detects about your project",
+	Long: `Show the full project context Fluxion detects and sends to the AI: languages,
+tests, Docker, services, deployments, releases, git metadata and environment variables,
+together with which detector produced each field and why, and any problems
+detectors ran into (add --verbose for timings).
+
+Use --format json or --format yaml for machine-readable output.`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          showContext,
+}
+
+func init() {
+	rootCmd.AddCommand(contextCmd)
+
+	contextCmd.Flags().StringP("format", "f", "human", "Output format: human, json or yaml")
+	contextCmd.Flags().StringP("dir", "d", "", "Project directory to scan (default: current directory)")
+}
+
+func showContext(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	workingDir, _ := cmd.Flags().GetString("dir")
+	if workingDir == "" {
+		workingDir = GetWorkingDirectory()
+	}
+	workingDir, _ = filepath.Abs(workingDir)
+	if info, err := os.Stat(workingDir); err != nil {
+		return fmt.Errorf("cannot scan %s: %w", workingDir, err)
+	} else if !info.IsDir() {
+		return fmt.Errorf("cannot scan %s: not a directory", workingDir)
+	}
+
+	switch format {
+	case "human", "json", "yaml":
+	default:
+		return fmt.Errorf("unknown format %q (expected human, json or yaml)", format)
+	}
+
+	projectContext, err := DetectProjectContext(workingDir)
+	if err != nil {
+		return fmt.Errorf("failed to detect project context: %w", err)
+	}
+
+	// Timings and "not detected" notes vary between runs; only show them when asked
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	if !verbose {
+		projectContext.Diagnostics = projectContext.Problems()
+	}
+
+	out := cmd.OutOrStdout()
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(projectContext, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode context: %w", err)
+		}
+		fmt.Fprintln(out, string(data))
+	case "yaml":
+		data, err := toYAML(projectContext)
+		if err != nil {
+			return fmt.Errorf("failed to encode context: %w", err)
+		}
+		fmt.Fprint(out, data)
+	default:
+		fmt.Fprintf(out, "\n🔍 Detected Project Context (%s):\n", workingDir)
+		fmt.Fprintln(out, "───────────────────────────────────────────────────────────────")
+		fmt.Fprintln(out, projectContext.FormatContext())
+		fmt.Fprintln(out, "───────────────────────────────────────────────────────────────")
+		if provenance := projectContext.FormatProvenance(); len(provenance) > 0 {
+			fmt.Fprintln(out, "\n🧭 Detected By:")
+			for _, line := range provenance {
+				fmt.Fprintf(out, "  - %s\n", line)
+			}
+		}
+		if len(projectContext.Diagnostics) > 0 {
+			fmt.Fprintln(out, "\n🩺 Diagnostics:")
+			for _, d := range projectContext.Diagnostics {
+				fmt.Fprintf(out, "  - %s\n", d)
+			}
+		}
+	}
+	return nil
+}
+
+// toYAML encodes v as YAML using its JSON field names and order.
+// Going through JSON keeps the json tags (omitempty, omitzero) as the single
+// source of truth for the machine-readable context.
+func toYAML(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return "", err
+	}
+	clearYAMLStyle(&node)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return "", err
+	}
+	encoder.Close()
+	return buf.String(), nil
+}
+
+// clearYAMLStyle drops the flow/quoted styles inherited from JSON so the output reads as block YAML
+func clearYAMLStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearYAMLStyle(child)
+	}
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestContextRejectsMissingDirectory(t *testing.T) {
+	dir := t.TempDir()
+	for _, path := range []string{filepath.Join(dir, "missing"), filepath.Join(dir, "go.mod")} {
+		if filepath.Base(path) == "go.mod" {
+			writeTestFile(t, path, "module example.com/app\n")
+		}
+		rootCmd.SetArgs([]string{"context", "--dir", path})
+		rootCmd.SetOut(io.Discard)
+		rootCmd.SetErr(io.Discard)
+		if err := rootCmd.Execute(); err == nil {
+			t.Errorf("context --dir %s succeeded, want an error", path)
+		}
+	}
+}
+
+func TestLanguageDetectorsStopWhenCancelled(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "go.mod"), "module example.com/app\n\ngo 1.22\n")
+	writeTestFile(t, filepath.Join(dir, "main.go"), "package main\n\nfunc main() {}\n")
+	writeTestFile(t, filepath.Join(dir, "package.json"), `{"scripts": {"test": "jest"}}`)
+	writeTestFile(t, filepath.Join(dir, "requirements.txt"), "pytest\n")
+	files := NewFileIndex(dir)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, detector := range languageDetectors {
+		t.Run(detector.Name(), func(t *testing.T) {
+			langCtx, err := detector.Detect(cancelled, dir, files)
+			if !errors.Is(err, context.Canceled) || langCtx != nil {
+				t.Errorf("Detect = %v, %v; want nil, context.Canceled", langCtx, err)
+			}
+		})
+	}
+}
+
+func TestProjectContextJSONOmitsEmptySections(t *testing.T) {
+	data, err := json.Marshal(ProjectContext{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"tests", "docker", "release", "git", "conventions"} {
+		if strings.Contains(string(data), `"`+key+`"`) {
+			t.Errorf("empty %s is in the JSON: %s", key, data)
+		}
+	}
+}
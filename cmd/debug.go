@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
@@ -83,21 +84,48 @@ func debugPipeline(cmd *cobra.Command, args []string) {
 
 	// Detect project context (helpful for better debugging)
 	workingDir := GetWorkingDirectory()
+	config, _, err := LoadProjectConfig(workingDir)
+	if err != nil {
+		cmd.PrintErrln("❌ Error in project config:", err)
+		return
+	}
+	model, err := config.Defaults.ChatModel()
+	if err != nil {
+		cmd.PrintErrln("❌ Error in project config:", err)
+		return
+	}
 	projectContext, err := DetectProjectContext(workingDir)
 	if err != nil {
 		// Non-fatal: continue without context
 		projectContext = ProjectContext{} // Empty context
 	}
+	printDiagnostics(cmd, projectContext)
+
+	// Docker build context errors can be diagnosed statically, without the AI
+	if findings, err := CheckDockerBuildContexts(workingDir, []string{file}); err == nil && len(findings) > 0 {
+		if isDockerBuildFailure(errorLogs) {
+			printPipelineAnalysis(cmd, "🔍 Pipeline Analysis (static Docker check):", staticDockerDiagnosis(findings))
+			return
+		}
+		cmd.Println("\n⚠️  Static checks found Docker build context problems:")
+		for _, f := range findings {
+			cmd.Println("   - " + f.String())
+		}
+	}
 
 	// Debug the pipeline configuration using AI
-	analysis, err := analyzePipelineWithOpenAI(pipelineConfig, errorLogs, projectContext)
+	analysis, err := analyzePipelineWithOpenAI(pipelineConfig, errorLogs, projectContext, model)
 	if err != nil {
 		cmd.PrintErrln("Error analyzing pipeline configuration:", err)
 		return
 	}
 
 	// Output the analysis results
-	cmd.Println("\n🔍 Pipeline Analysis:")
+	printPipelineAnalysis(cmd, "🔍 Pipeline Analysis:", analysis)
+}
+
+func printPipelineAnalysis(cmd *cobra.Command, title string, analysis DebugResult) {
+	cmd.Println("\n" + title)
 	cmd.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 	cmd.Printf("\n📌 Root Cause:\n%s\n\n", analysis.RootCause)
 	cmd.Printf("🔧 Fix:\n%s\n\n", analysis.Fix)
@@ -105,13 +133,26 @@ func debugPipeline(cmd *cobra.Command, args []string) {
 	cmd.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 }
 
+// isDockerBuildFailure reports whether the logs show a failed docker build,
+// i.e. whether static Docker findings explain the failure
+func isDockerBuildFailure(logs string) bool {
+	lower := strings.ToLower(logs)
+	markers := []string{"failed to solve", "failed to compute cache key", "failed to read dockerfile", "buildx failed", "unable to prepare context"}
+	for _, marker := range markers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
 type DebugResult struct {
 	RootCause   string `json:"root_cause"`
 	Fix         string `json:"fix"`
 	Explanation string `json:"explanation"`
 }
 
-func analyzePipelineWithOpenAI(pipelineConfig string, errorLogs string, projectContext ProjectContext) (DebugResult, error) {
+func analyzePipelineWithOpenAI(pipelineConfig string, errorLogs string, projectContext ProjectContext, model openai.ChatModel) (DebugResult, error) {
 	if pipelineConfig == "" {
 		return DebugResult{}, fmt.Errorf("pipeline configuration is empty")
 	}
@@ -157,7 +198,7 @@ Provide the root cause, exact fix, and brief explanation.`, pipelineConfig, erro
 	resp, err := client.Chat.Completions.New(
 		context.Background(),
 		openai.ChatCompletionNewParams{
-			Model: openai.ChatModelGPT4o,
+			Model: model,
 			Messages: []openai.ChatCompletionMessageParamUnion{
 				openai.SystemMessage(debugSystemPrompt),
 				openai.UserMessage(userPrompt),
@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DeploymentTarget is a deployment platform the project is already configured for
+type DeploymentTarget struct {
+	Platform    string   `json:"platform,omitempty"`    // e.g., "Vercel", "Fly.io", "GitHub Pages"
+	ConfigFile  string   `json:"config_file,omitempty"` // File that revealed the target
+	Action      string   `json:"action,omitempty"`      // Official action or CLI a deploy job should use
+	Secrets     []string `json:"secrets,omitempty"`     // Repository secrets the deploy job needs
+	Permissions []string `json:"permissions,omitempty"` // Workflow permissions the deploy job needs
+	Notes       string   `json:"notes,omitempty"`       // Extra detail, e.g. the static site generator in use
+}
+
+// deploymentRule detects one deployment platform from its config files
+type deploymentRule struct {
+	Platform    string
+	Files       []string
+	Match       func(content string) bool // Optional: the config file must also contain this
+	Action      string
+	Secrets     []string
+	Permissions []string
+}
+
+// Registry of deployment platforms
+//
+// Rules are checked in order; a platform is recorded once, for the first
+// config file that matches.
+var deploymentRules = []deploymentRule{
+	{
+		Platform: "Vercel",
+		Files:    []string{"vercel.json", ".vercel/project.json"},
+		Action:   "Vercel CLI: npx vercel pull --yes --environment=production && npx vercel build --prod && npx vercel deploy --prebuilt --prod",
+		Secrets:  []string{"VERCEL_TOKEN", "VERCEL_ORG_ID", "VERCEL_PROJECT_ID"},
+	},
+	{
+		Platform: "Netlify",
+		Files:    []string{"netlify.toml"},
+		Action:   "Netlify CLI: npx netlify-cli deploy --prod",
+		Secrets:  []string{"NETLIFY_AUTH_TOKEN", "NETLIFY_SITE_ID"},
+	},
+	{
+		Platform: "Fly.io",
+		Files:    []string{"fly.toml"},
+		Action:   "superfly/flyctl-actions/setup-flyctl@master, then flyctl deploy --remote-only",
+		Secrets:  []string{"FLY_API_TOKEN"},
+	},
+	{
+		Platform: "Heroku",
+		Files:    []string{"heroku.yml", "Procfile"},
+		Action:   "Heroku CLI (preinstalled on ubuntu runners): heroku container:push / git push to the Heroku remote",
+		Secrets:  []string{"HEROKU_API_KEY", "HEROKU_APP_NAME"},
+	},
+	{
+		Platform:    "Google App Engine",
+		Files:       []string{"app.yaml", "app.yml"},
+		Match:       func(content string) bool { return strings.Contains(content, "runtime:") },
+		Action:      "google-github-actions/auth@v2 (Workload Identity Federation), then google-github-actions/deploy-appengine@v2",
+		Secrets:     []string{"GCP_WORKLOAD_IDENTITY_PROVIDER", "GCP_SERVICE_ACCOUNT"},
+		Permissions: []string{"contents: read", "id-token: write"},
+	},
+	{
+		Platform:    "Google Cloud Run",
+		Files:       []string{"service.yaml", "service.yml", "cloudrun.yaml", "cloudrun.yml"},
+		Match:       func(content string) bool { return strings.Contains(content, "serving.knative.dev") },
+		Action:      "google-github-actions/auth@v2 (Workload Identity Federation), then google-github-actions/deploy-cloudrun@v2",
+		Secrets:     []string{"GCP_WORKLOAD_IDENTITY_PROVIDER", "GCP_SERVICE_ACCOUNT"},
+		Permissions: []string{"contents: read", "id-token: write"},
+	},
+	{
+		Platform:    "AWS Lambda (Serverless Framework)",
+		Files:       []string{"serverless.yml", "serverless.yaml", "serverless.ts"},
+		Action:      "aws-actions/configure-aws-credentials@v4 (OIDC role), then npx serverless deploy",
+		Secrets:     []string{"AWS_ROLE_ARN", "SERVERLESS_ACCESS_KEY"},
+		Permissions: []string{"contents: read", "id-token: write"},
+	},
+	{
+		Platform: "AWS Lambda (SAM)",
+		Files:    []string{"template.yaml", "template.yml", "samconfig.toml"},
+		Match: func(content string) bool {
+			return strings.Contains(content, "AWS::Serverless") || strings.Contains(content, "[default")
+		},
+		Action:      "aws-actions/configure-aws-credentials@v4 (OIDC role) and aws-actions/setup-sam@v2, then sam build && sam deploy --no-confirm-changeset --no-fail-on-empty-changeset",
+		Secrets:     []string{"AWS_ROLE_ARN"},
+		Permissions: []string{"contents: read", "id-token: write"},
+	},
+	{
+		Platform:    "AWS (CDK)",
+		Files:       []string{"cdk.json"},
+		Action:      "aws-actions/configure-aws-credentials@v4 (OIDC role), then npx cdk deploy --require-approval never",
+		Secrets:     []string{"AWS_ROLE_ARN"},
+		Permissions: []string{"contents: read", "id-token: write"},
+	},
+	{
+		Platform:    "GitHub Pages",
+		Files:       []string{"CNAME", "docs/CNAME", "static/CNAME", "public/CNAME"},
+		Action:      "actions/configure-pages@v5, actions/upload-pages-artifact@v3, actions/deploy-pages@v4",
+		Permissions: []string{"contents: read", "pages: write", "id-token: write"},
+	},
+}
+
+// staticSiteGenerators map config files to generators whose output is usually published to GitHub Pages
+var staticSiteGenerators = []struct {
+	File      string
+	Generator string
+}{
+	{"_config.yml", "Jekyll"},
+	{"hugo.toml", "Hugo"},
+	{"hugo.yaml", "Hugo"},
+	{"mkdocs.yml", "MkDocs"},
+	{"docusaurus.config.js", "Docusaurus"},
+	{"docusaurus.config.ts", "Docusaurus"},
+	{"astro.config.mjs", "Astro"},
+	{"book.toml", "mdBook"},
+}
+
+// DetectDeploymentTargets finds the deployment platforms the project is configured for;
+// it stops early when ctx is cancelled
+func DetectDeploymentTargets(ctx context.Context, workingDir string) []DeploymentTarget {
+	var targets []DeploymentTarget
+
+	for _, rule := range deploymentRules {
+		if ctx.Err() != nil {
+			return targets // The caller reports the cancellation
+		}
+		for _, file := range rule.Files {
+			content, ok := readFileString(filepath.Join(workingDir, file))
+			if !ok || (rule.Match != nil && !rule.Match(content)) {
+				continue
+			}
+			targets = append(targets, DeploymentTarget{
+				Platform:    rule.Platform,
+				ConfigFile:  file,
+				Action:      rule.Action,
+				Secrets:     rule.Secrets,
+				Permissions: rule.Permissions,
+			})
+			break
+		}
+	}
+
+	// A static site generator alone is a strong hint for GitHub Pages
+	for _, ssg := range staticSiteGenerators {
+		if _, err := os.Stat(filepath.Join(workingDir, ssg.File)); err != nil {
+			continue
+		}
+		notes := fmt.Sprintf("site built with %s (%s)", ssg.Generator, ssg.File)
+		if i := findTarget(targets, "GitHub Pages"); i >= 0 {
+			targets[i].Notes = notes
+		} else {
+			rule := deploymentRuleFor("GitHub Pages")
+			targets = append(targets, DeploymentTarget{
+				Platform:    rule.Platform,
+				ConfigFile:  ssg.File,
+				Action:      rule.Action,
+				Permissions: rule.Permissions,
+				Notes:       notes,
+			})
+		}
+		break
+	}
+
+	return targets
+}
+
+func deploymentRuleFor(platform string) deploymentRule {
+	for _, rule := range deploymentRules {
+		if rule.Platform == platform {
+			return rule
+		}
+	}
+	return deploymentRule{Platform: platform}
+}
+
+func findTarget(targets []DeploymentTarget, platform string) int {
+	for i, target := range targets {
+		if target.Platform == platform {
+			return i
+		}
+	}
+	return -1
+}
+
+// FormatDeployment summarises a deployment target for prompts
+func (t DeploymentTarget) FormatDeployment() string {
+	line := fmt.Sprintf("%s (%s): deploy with %s", t.Platform, t.ConfigFile, t.Action)
+	if len(t.Secrets) > 0 {
+		line += fmt.Sprintf("; secrets: %s", strings.Join(t.Secrets, ", "))
+	}
+	if len(t.Permissions) > 0 {
+		line += fmt.Sprintf("; permissions: %s", strings.Join(t.Permissions, ", "))
+	}
+	if t.Notes != "" {
+		line += "; " + t.Notes
+	}
+	return line
+}
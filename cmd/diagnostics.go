@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Diagnostic severities
+const (
+	SeverityError   = "error"   // A detector failed or timed out; its result is missing
+	SeverityWarning = "warning" // A file could not be read or parsed; the result may be incomplete
+	SeverityInfo    = "info"    // Timing and "not detected" notes, shown with --verbose
+)
+
+// Diagnostic is a problem or note reported while detecting the project context
+type Diagnostic struct {
+	Detector string `json:"detector"`       // e.g. "Go", "docker", "workflows"
+	Severity string `json:"severity"`       // error, warning or info
+	File     string `json:"file,omitempty"` // File the diagnostic is about, relative to the project root
+	Message  string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	if d.File != "" {
+		return fmt.Sprintf("[%s] %s: %s: %s", d.Severity, d.Detector, d.File, d.Message)
+	}
+	return fmt.Sprintf("[%s] %s: %s", d.Severity, d.Detector, d.Message)
+}
+
+// warn records a problem with one of the files a language detector reads
+func (c *LanguageContext) warn(file string, format string, args ...interface{}) {
+	c.Diagnostics = append(c.Diagnostics, Diagnostic{Severity: SeverityWarning, File: file, Message: fmt.Sprintf(format, args...)})
+}
+
+// Problems returns the error and warning diagnostics, leaving out info notes
+func (ctx *ProjectContext) Problems() []Diagnostic {
+	var problems []Diagnostic
+	for _, d := range ctx.Diagnostics {
+		if d.Severity != SeverityInfo {
+			problems = append(problems, d)
+		}
+	}
+	return problems
+}
+
+// detectorTimeout bounds how long a single detector may run
+const detectorTimeout = 15 * time.Second
+
+// detection is a detector running in the background
+type detection[T any] struct {
+	name    string
+	ctx     context.Context
+	cancel  context.CancelFunc
+	done    chan struct{}
+	value   T
+	diags   []Diagnostic
+	err     error
+	elapsed time.Duration
+}
+
+// startDetection runs fn concurrently under a detectorTimeout deadline.
+// A panic in fn is reported as the detector's error instead of crashing Fluxion.
+func startDetection[T any](parent context.Context, name string, fn func(ctx context.Context) (T, []Diagnostic, error)) *detection[T] {
+	ctx, cancel := context.WithTimeout(parent, detectorTimeout)
+	d := &detection[T]{name: name, ctx: ctx, cancel: cancel, done: make(chan struct{})}
+	go func() {
+		start := time.Now()
+		defer close(d.done)
+		defer func() {
+			if r := recover(); r != nil {
+				d.err = fmt.Errorf("panic: %v", r)
+			}
+			d.elapsed = time.Since(start)
+		}()
+		d.value, d.diags, d.err = fn(ctx)
+	}()
+	return d
+}
+
+// wait returns the detector's result, appending its diagnostics to diags.
+// ok is false when the detector failed or timed out, in which case its result is discarded.
+func (d *detection[T]) wait(diags *[]Diagnostic) (value T, ok bool) {
+	defer d.cancel()
+
+	select {
+	case <-d.done:
+	case <-d.ctx.Done():
+		select {
+		case <-d.done: // Finished just as the deadline passed
+		default:
+			*diags = append(*diags, Diagnostic{Detector: d.name, Severity: SeverityError,
+				Message: fmt.Sprintf("timed out after %s; its results are missing", detectorTimeout)})
+			return value, false
+		}
+	}
+
+	for _, diag := range d.diags {
+		if diag.Detector == "" {
+			diag.Detector = d.name
+		}
+		*diags = append(*diags, diag)
+	}
+	if d.err != nil {
+		*diags = append(*diags, Diagnostic{Detector: d.name, Severity: SeverityError, Message: d.err.Error()})
+		return value, false
+	}
+	*diags = append(*diags, Diagnostic{Detector: d.name, Severity: SeverityInfo,
+		Message: fmt.Sprintf("finished in %s", d.elapsed.Round(time.Microsecond))})
+	return d.value, true
+}
+
+// printDiagnostics shows detection diagnostics: all of them with --verbose,
+// otherwise only a count of the problems
+func printDiagnostics(cmd *cobra.Command, projectContext ProjectContext) {
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	if verbose {
+		cmd.Println("\n🩺 Detection Diagnostics:")
+		for _, d := range projectContext.Diagnostics {
+			cmd.Println("  - " + d.String())
+		}
+		return
+	}
+	if problems := projectContext.Problems(); len(problems) > 0 {
+		cmd.PrintErrf("⚠️  %d detection problem(s); run with --verbose for details\n", len(problems))
+	}
+}
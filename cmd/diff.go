@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is how many unchanged lines surround each change in a unified diff
+const diffContext = 3
+
+// diffOp is one line of a line diff: ' ' unchanged, '-' removed or '+' added
+type diffOp struct {
+	Kind byte
+	Line string
+}
+
+// diffLines computes a shortest line diff from a to b with the longest common subsequence
+func diffLines(a, b []string) []diffOp {
+	// Keep the table small: strip the common prefix and suffix first
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []diffOp
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	x, y := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(x)*len(y) > 4_000_000 {
+		// Too large to compare line by line: replace the whole middle
+		for _, line := range x {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range y {
+			ops = append(ops, diffOp{'+', line})
+		}
+	} else {
+		lcs := make([][]int, len(x)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(y)+1)
+		}
+		for i := len(x) - 1; i >= 0; i-- {
+			for j := len(y) - 1; j >= 0; j-- {
+				if x[i] == y[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < len(x) || j < len(y) {
+			switch {
+			case i < len(x) && j < len(y) && x[i] == y[j]:
+				ops = append(ops, diffOp{' ', x[i]})
+				i, j = i+1, j+1
+			case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+				ops = append(ops, diffOp{'-', x[i]})
+				i++
+			default:
+				ops = append(ops, diffOp{'+', y[j]})
+				j++
+			}
+		}
+	}
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+// splitLines splits text into lines without their newlines
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// noNewlineMarker follows a last line that has no newline, as in diff and git diff
+const noNewlineMarker = "\n\\ No newline at end of file"
+
+// diffInput splits text into lines for unifiedDiff. A last line without a newline
+// carries noNewlineMarker, so it differs from the same line with one and the
+// marker is printed after it.
+func diffInput(text string) []string {
+	lines := splitLines(text)
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		lines[len(lines)-1] += noNewlineMarker
+	}
+	return lines
+}
+
+// unifiedDiff renders the changes from one text to another as a unified diff,
+// or "" when they are the same
+func unifiedDiff(fromName, toName, from, to string) string {
+	if from == to {
+		return ""
+	}
+	ops := diffLines(diffInput(from), diffInput(to))
+
+	// Line numbers in each text before each op
+	fromLine := make([]int, len(ops)+1)
+	toLine := make([]int, len(ops)+1)
+	fromLine[0], toLine[0] = 1, 1
+	for k, op := range ops {
+		fromLine[k+1], toLine[k+1] = fromLine[k], toLine[k]
+		if op.Kind != '+' {
+			fromLine[k+1]++
+		}
+		if op.Kind != '-' {
+			toLine[k+1]++
+		}
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+	for i := 0; i < len(ops); {
+		for i < len(ops) && ops[i].Kind == ' ' {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+
+		// A hunk runs until the changes are more than two contexts apart
+		start, end := max(i-diffContext, 0), i
+		for end < len(ops) {
+			if ops[end].Kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].Kind == ' ' {
+				run++
+			}
+			if run == len(ops) || run-end > 2*diffContext {
+				end = min(end+diffContext, len(ops))
+				break
+			}
+			end = run
+		}
+
+		fromCount, toCount := fromLine[end]-fromLine[start], toLine[end]-toLine[start]
+		fromStart, toStart := fromLine[start], toLine[start]
+		if fromCount == 0 {
+			fromStart--
+		}
+		if toCount == 0 {
+			toStart--
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", fromStart, fromCount, toStart, toCount)
+		for _, op := range ops[start:end] {
+			out.WriteByte(op.Kind)
+			out.WriteString(op.Line)
+			out.WriteByte('\n')
+		}
+		i = end
+	}
+	return out.String()
+}
@@ -0,0 +1,363 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DockerContext contains the parsed Docker setup of the project
+type DockerContext struct {
+	Dockerfiles  []DockerfileInfo `json:"dockerfiles,omitempty"`   // Every Dockerfile found, including nested and Dockerfile.* variants
+	ComposeFiles []ComposeInfo    `json:"compose_files,omitempty"` // compose.yaml / docker-compose*.yml files
+	IgnoreFiles  []string         `json:"ignore_files,omitempty"`  // .dockerignore files
+}
+
+// DockerfileInfo is the parsed model of a single Dockerfile
+type DockerfileInfo struct {
+	Path         string        `json:"path,omitempty"`          // Relative to the project root
+	Stages       []DockerStage `json:"stages,omitempty"`        // FROM instructions, in order
+	BuildArgs    []string      `json:"build_args,omitempty"`    // ARG declarations, as NAME or NAME=default
+	ExposedPorts []string      `json:"exposed_ports,omitempty"` // EXPOSE values
+	Copies       []DockerCopy  `json:"copies,omitempty"`        // COPY and ADD instructions
+	ParseError   string        `json:"parse_error,omitempty"`   // Why the Dockerfile could not be parsed; the other fields are then partial
+}
+
+// DockerStage is one FROM instruction of a (multi-stage) build
+type DockerStage struct {
+	Name      string `json:"name,omitempty"` // Stage name from "AS name", empty if unnamed
+	BaseImage string `json:"base_image,omitempty"`
+	Line      int    `json:"line,omitempty"`
+}
+
+// DockerCopy is a COPY or ADD instruction
+type DockerCopy struct {
+	Instruction string   `json:"instruction,omitempty"` // COPY or ADD
+	Sources     []string `json:"sources,omitempty"`     // Source paths, relative to the build context
+	Destination string   `json:"destination,omitempty"`
+	FromStage   string   `json:"from_stage,omitempty"` // --from value; empty when copying from the build context
+	Stage       int      `json:"stage,omitempty"`      // Index of the stage the instruction belongs to
+	Line        int      `json:"line,omitempty"`
+}
+
+// ComposeInfo is the parsed model of a compose file
+type ComposeInfo struct {
+	Path     string           `json:"path,omitempty"` // Relative to the project root
+	Services []ComposeService `json:"services,omitempty"`
+}
+
+// ComposeService is one service of a compose file
+type ComposeService struct {
+	Name         string   `json:"name,omitempty"`
+	Image        string   `json:"image,omitempty"`
+	BuildContext string   `json:"build_context,omitempty"` // Relative to the compose file; empty if the service uses a prebuilt image
+	Dockerfile   string   `json:"dockerfile,omitempty"`    // Relative to BuildContext
+	DependsOn    []string `json:"depends_on,omitempty"`
+	Ports        []string `json:"ports,omitempty"`
+}
+
+// isDockerfileName reports whether a file name is a Dockerfile variant:
+// Dockerfile, Dockerfile.prod, api.Dockerfile
+func isDockerfileName(name string) bool {
+	lower := strings.ToLower(name)
+	if strings.HasSuffix(lower, ".dockerignore") {
+		return false
+	}
+	return lower == "dockerfile" ||
+		strings.HasPrefix(lower, "dockerfile.") ||
+		strings.HasSuffix(lower, ".dockerfile")
+}
+
+// isComposeFileName reports whether a file name is a compose file, including overrides
+func isComposeFileName(name string) bool {
+	lower := strings.ToLower(name)
+	if !strings.HasSuffix(lower, ".yml") && !strings.HasSuffix(lower, ".yaml") {
+		return false
+	}
+	return strings.HasPrefix(lower, "docker-compose") || strings.HasPrefix(lower, "compose.")
+}
+
+// DetectDocker finds and parses Dockerfiles, compose files and .dockerignore files;
+// it stops early when ctx is cancelled
+func DetectDocker(ctx context.Context, workingDir string, files *FileIndex) (DockerContext, []Diagnostic) {
+	docker := DockerContext{}
+	var diags []Diagnostic
+
+	for _, rel := range files.Files() {
+		if ctx.Err() != nil {
+			break // The caller reports the cancellation
+		}
+		name := filepath.Base(rel)
+		path := files.Abs(rel)
+		switch {
+		case isDockerfileName(name):
+			// A broken Dockerfile is kept, so checks and prompts can point at it
+			info, err := parseDockerfile(path)
+			if err != nil {
+				diags = append(diags, Diagnostic{Severity: SeverityWarning, File: rel, Message: err.Error()})
+				info.ParseError = err.Error()
+			}
+			info.Path = rel
+			docker.Dockerfiles = append(docker.Dockerfiles, info)
+		case isComposeFileName(name):
+			info, err := parseComposeInfo(path)
+			if err != nil {
+				diags = append(diags, Diagnostic{Severity: SeverityWarning, File: rel, Message: err.Error()})
+				continue
+			}
+			info.Path = rel
+			docker.ComposeFiles = append(docker.ComposeFiles, info)
+		case strings.HasSuffix(name, ".dockerignore"):
+			docker.IgnoreFiles = append(docker.IgnoreFiles, rel)
+		}
+	}
+
+	return docker, diags
+}
+
+// Parsed lists the Dockerfiles that could be parsed
+func (d DockerContext) Parsed() []DockerfileInfo {
+	var parsed []DockerfileInfo
+	for _, df := range d.Dockerfiles {
+		if df.ParseError == "" {
+			parsed = append(parsed, df)
+		}
+	}
+	return parsed
+}
+
+// Files lists every Docker-related file found, for display
+func (d DockerContext) Files() []string {
+	var files []string
+	for _, df := range d.Dockerfiles {
+		files = append(files, df.Path)
+	}
+	for _, cf := range d.ComposeFiles {
+		files = append(files, cf.Path)
+	}
+	return append(files, d.IgnoreFiles...)
+}
+
+// =============================================================================
+// Dockerfile parsing
+// =============================================================================
+
+// dockerInstruction is a logical Dockerfile line with continuations joined
+type dockerInstruction struct {
+	Keyword string
+	Args    string
+	Line    int
+}
+
+// readDockerInstructions splits a Dockerfile into instructions, honouring line
+// continuations, comments, the escape parser directive and heredoc bodies.
+func readDockerInstructions(path string) ([]dockerInstruction, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var instructions []dockerInstruction
+	escape := `\`
+	scanner := bufio.NewScanner(file)
+	lineNo, start := 0, 0
+	var current strings.Builder
+	heredoc := ""
+	directives := true
+
+	for scanner.Scan() {
+		lineNo++
+		raw := scanner.Text()
+		trimmed := strings.TrimSpace(raw)
+
+		if heredoc != "" {
+			if trimmed == heredoc {
+				heredoc = ""
+			}
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "#") {
+			// Parser directives are only valid before the first instruction
+			if directives {
+				if key, value, ok := strings.Cut(strings.TrimSpace(trimmed[1:]), "="); ok && strings.EqualFold(strings.TrimSpace(key), "escape") {
+					escape = strings.TrimSpace(value)
+				}
+			}
+			continue
+		}
+		if trimmed == "" && current.Len() == 0 {
+			continue
+		}
+		directives = false
+
+		if current.Len() == 0 {
+			start = lineNo
+		}
+		if strings.HasSuffix(trimmed, escape) {
+			current.WriteString(strings.TrimSuffix(trimmed, escape) + " ")
+			continue
+		}
+		current.WriteString(trimmed)
+
+		keyword, args, _ := strings.Cut(strings.TrimSpace(current.String()), " ")
+		instructions = append(instructions, dockerInstruction{
+			Keyword: strings.ToUpper(keyword),
+			Args:    strings.TrimSpace(args),
+			Line:    start,
+		})
+		current.Reset()
+
+		// RUN <<EOF ... EOF: skip the heredoc body
+		if idx := strings.Index(args, "<<"); idx >= 0 {
+			marker := strings.Fields(args[idx+2:])
+			if len(marker) > 0 {
+				heredoc = strings.Trim(strings.TrimPrefix(marker[0], "-"), `"'`)
+			}
+		}
+	}
+
+	return instructions, scanner.Err()
+}
+
+func parseDockerfile(path string) (DockerfileInfo, error) {
+	info := DockerfileInfo{}
+	instructions, err := readDockerInstructions(path)
+	if err != nil {
+		return info, fmt.Errorf("failed to read Dockerfile: %w", err)
+	}
+
+	for _, inst := range instructions {
+		switch inst.Keyword {
+		case "FROM":
+			_, words := splitDockerFlags(inst.Args)
+			stage := DockerStage{Line: inst.Line}
+			if len(words) > 0 {
+				stage.BaseImage = words[0]
+			}
+			if len(words) >= 3 && strings.EqualFold(words[1], "AS") {
+				stage.Name = words[2]
+			}
+			info.Stages = append(info.Stages, stage)
+		case "ARG":
+			info.BuildArgs = append(info.BuildArgs, strings.Fields(inst.Args)...)
+		case "EXPOSE":
+			info.ExposedPorts = append(info.ExposedPorts, strings.Fields(inst.Args)...)
+		case "COPY", "ADD":
+			flags, words := splitDockerFlags(inst.Args)
+			if len(words) < 2 {
+				continue
+			}
+			info.Copies = append(info.Copies, DockerCopy{
+				Instruction: inst.Keyword,
+				Sources:     words[:len(words)-1],
+				Destination: words[len(words)-1],
+				FromStage:   flags["from"],
+				Stage:       len(info.Stages) - 1,
+				Line:        inst.Line,
+			})
+		}
+	}
+
+	if len(info.Stages) == 0 {
+		return info, fmt.Errorf("no FROM instruction found")
+	}
+	return info, nil
+}
+
+// splitDockerFlags separates leading --flag=value options from the arguments,
+// accepting both the shell form and the JSON exec form of the arguments
+func splitDockerFlags(args string) (map[string]string, []string) {
+	flags := map[string]string{}
+	fields := strings.Fields(args)
+	i := 0
+	for ; i < len(fields) && strings.HasPrefix(fields[i], "--"); i++ {
+		key, value, _ := strings.Cut(strings.TrimPrefix(fields[i], "--"), "=")
+		flags[strings.ToLower(key)] = value
+	}
+
+	rest := strings.TrimSpace(strings.Join(fields[i:], " "))
+	if strings.HasPrefix(rest, "[") {
+		var words []string
+		if err := json.Unmarshal([]byte(rest), &words); err == nil {
+			return flags, words
+		}
+	}
+	return flags, fields[i:]
+}
+
+// =============================================================================
+// Compose parsing
+// =============================================================================
+
+func parseComposeInfo(path string) (ComposeInfo, error) {
+	info := ComposeInfo{}
+	compose, err := parseComposeFile(path)
+	if err != nil {
+		return info, err
+	}
+
+	for _, name := range compose.serviceNames() {
+		svc := compose.Services[name]
+		info.Services = append(info.Services, ComposeService{
+			Name:         name,
+			Image:        svc.Image,
+			BuildContext: svc.Build.Context,
+			Dockerfile:   svc.Build.Dockerfile,
+			DependsOn:    svc.DependsOn,
+			Ports:        svc.Ports,
+		})
+	}
+	return info, nil
+}
+
+// FormatDocker summarises the Docker setup for prompts
+func (d DockerContext) FormatDocker() []string {
+	var lines []string
+	for _, df := range d.Dockerfiles {
+		if df.ParseError != "" {
+			lines = append(lines, fmt.Sprintf("%s: could not be parsed (%s)", df.Path, df.ParseError))
+			continue
+		}
+		var stages []string
+		for _, stage := range df.Stages {
+			if stage.Name != "" {
+				stages = append(stages, fmt.Sprintf("%s (%s)", stage.Name, stage.BaseImage))
+			} else {
+				stages = append(stages, stage.BaseImage)
+			}
+		}
+		line := fmt.Sprintf("%s: stages %s", df.Path, strings.Join(stages, " → "))
+		if len(df.ExposedPorts) > 0 {
+			line += fmt.Sprintf("; exposes %s", strings.Join(df.ExposedPorts, ", "))
+		}
+		if len(df.BuildArgs) > 0 {
+			line += fmt.Sprintf("; build args %s", strings.Join(df.BuildArgs, ", "))
+		}
+		lines = append(lines, line)
+	}
+
+	for _, cf := range d.ComposeFiles {
+		var services []string
+		for _, svc := range cf.Services {
+			desc := svc.Name
+			switch {
+			case svc.BuildContext != "":
+				desc += fmt.Sprintf(" (build %s)", svc.BuildContext)
+			case svc.Image != "":
+				desc += fmt.Sprintf(" (%s)", svc.Image)
+			}
+			if len(svc.DependsOn) > 0 {
+				desc += fmt.Sprintf(" depends on %s", strings.Join(svc.DependsOn, ", "))
+			}
+			services = append(services, desc)
+		}
+		lines = append(lines, fmt.Sprintf("%s: services %s", cf.Path, strings.Join(services, "; ")))
+	}
+	return lines
+}
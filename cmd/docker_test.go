@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetectDockerKeepsUnparsableDockerfiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"Dockerfile":        "FROM golang:1.22 AS build\nCOPY . .\nRUN go build -o /app\n\nFROM alpine:3.20\nCOPY --from=build /app /app\n",
+		"Dockerfile.broken": "# no base image\nRUN echo hi\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	docker, diags := DetectDocker(context.Background(), dir, NewFileIndex(dir))
+	if len(docker.Dockerfiles) != 2 {
+		t.Fatalf("Dockerfiles = %+v, want both", docker.Dockerfiles)
+	}
+	var broken DockerfileInfo
+	for _, df := range docker.Dockerfiles {
+		if df.Path == "Dockerfile.broken" {
+			broken = df
+		}
+	}
+	if broken.ParseError == "" {
+		t.Errorf("Dockerfile.broken has no ParseError")
+	}
+	if len(diags) != 1 || diags[0].File != "Dockerfile.broken" {
+		t.Errorf("diagnostics = %+v, want one for Dockerfile.broken", diags)
+	}
+	if parsed := docker.Parsed(); len(parsed) != 1 || parsed[0].Path != "Dockerfile" {
+		t.Errorf("Parsed() = %+v, want only Dockerfile", parsed)
+	}
+	if summary := strings.Join(docker.FormatDocker(), "\n"); !strings.Contains(summary, "Dockerfile.broken: could not be parsed") {
+		t.Errorf("FormatDocker() does not mention the broken Dockerfile:\n%s", summary)
+	}
+}
+
+func TestDetectDockerStopsWhenCancelled(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "Dockerfile"), "FROM alpine:3.20\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if docker, _ := DetectDocker(ctx, dir, NewFileIndex(dir)); len(docker.Files()) != 0 {
+		t.Errorf("files = %v, want none after cancellation", docker.Files())
+	}
+}
@@ -0,0 +1,273 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DockerFinding is a Docker build problem found statically, without running the build
+type DockerFinding struct {
+	Workflow   string // Workflow file, relative to the project root
+	Line       int    // Line of the step that runs the build
+	Job        string
+	Step       string
+	Dockerfile string // Dockerfile used by the build, relative to the project root
+	Problem    string
+	Fix        string
+}
+
+func (f DockerFinding) String() string {
+	return fmt.Sprintf("%s:%d (job %s, step %q): %s", f.Workflow, f.Line, f.Job, f.Step, f.Problem)
+}
+
+// dockerBuild is a docker build invoked by a workflow step
+type dockerBuild struct {
+	Context    string // Build context, relative to the repository root
+	Dockerfile string // Dockerfile path, relative to the repository root
+}
+
+// dockerBuildFlagsWithValue are docker build flags that consume the next argument
+var dockerBuildFlagsWithValue = map[string]bool{
+	"-t": true, "--tag": true, "--build-arg": true, "--target": true, "--platform": true,
+	"--label": true, "--cache-from": true, "--cache-to": true, "--secret": true, "--ssh": true,
+	"-o": true, "--output": true, "--iidfile": true, "--metadata-file": true, "--progress": true,
+	"--network": true, "--builder": true, "--build-context": true, "--attest": true,
+}
+
+// dockerBuildsInStep extracts the docker builds a workflow step performs, either
+// through docker/build-push-action or a "docker build" command in run:
+func dockerBuildsInStep(step workflowStep) []dockerBuild {
+	var builds []dockerBuild
+
+	if strings.HasPrefix(step.Uses, "docker/build-push-action") {
+		build := dockerBuild{Context: step.With["context"], Dockerfile: step.With["file"]}
+		if build.Context == "" {
+			build.Context = "."
+		}
+		if build.Dockerfile == "" {
+			build.Dockerfile = filepath.Join(build.Context, "Dockerfile")
+		}
+		builds = append(builds, build)
+	}
+
+	for _, line := range strings.Split(step.Run, "\n") {
+		for _, command := range strings.FieldsFunc(line, func(r rune) bool { return r == ';' || r == '&' || r == '|' }) {
+			fields := strings.Fields(command)
+			start := -1
+			for i := 0; i+1 < len(fields); i++ {
+				if fields[i] == "docker" && fields[i+1] == "build" {
+					start = i + 2
+				} else if i+2 < len(fields) && fields[i] == "docker" && fields[i+1] == "buildx" && fields[i+2] == "build" {
+					start = i + 3
+				}
+				if start >= 0 {
+					break
+				}
+			}
+			if start < 0 {
+				continue
+			}
+
+			build := dockerBuild{}
+			for i := start; i < len(fields); i++ {
+				arg := fields[i]
+				switch {
+				case arg == "-f" || arg == "--file":
+					if i+1 < len(fields) {
+						build.Dockerfile = fields[i+1]
+						i++
+					}
+				case strings.HasPrefix(arg, "--file="):
+					build.Dockerfile = strings.TrimPrefix(arg, "--file=")
+				case dockerBuildFlagsWithValue[arg]:
+					i++
+				case strings.HasPrefix(arg, "-"):
+				default:
+					build.Context = arg
+				}
+			}
+			if build.Context == "" {
+				continue
+			}
+			if build.Dockerfile == "" {
+				build.Dockerfile = filepath.Join(build.Context, "Dockerfile")
+			}
+			builds = append(builds, build)
+		}
+	}
+
+	return builds
+}
+
+// isDynamicPath reports whether a path depends on expressions or variables only known at run time
+func isDynamicPath(path string) bool {
+	return strings.Contains(path, "${{") || strings.Contains(path, "$") || strings.Contains(path, "://")
+}
+
+// CheckDockerBuildContexts verifies, for every docker build in the given workflows,
+// that the Dockerfile exists and that each COPY/ADD source is present in the build
+// context and not excluded by .dockerignore.
+func CheckDockerBuildContexts(workingDir string, workflowPaths []string) ([]DockerFinding, error) {
+	var findings []DockerFinding
+	var files *FileIndex
+	var known []DockerfileInfo
+
+	for _, path := range workflowPaths {
+		content, err := loadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		wf, err := parseWorkflow(content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		rel := path
+		if r, err := filepath.Rel(workingDir, path); err == nil && !strings.HasPrefix(r, "..") {
+			rel = r
+		}
+
+		for _, jobID := range wf.jobIDs() {
+			for _, step := range wf.Jobs[jobID].Steps {
+				for _, build := range dockerBuildsInStep(step) {
+					if isDynamicPath(build.Context) || isDynamicPath(build.Dockerfile) {
+						continue
+					}
+					base := DockerFinding{Workflow: rel, Line: step.Line, Job: jobID, Step: step.label(), Dockerfile: filepath.Clean(build.Dockerfile)}
+
+					if files == nil {
+						files = NewFileIndex(workingDir)
+						docker, _ := DetectDocker(context.Background(), workingDir, files)
+						known = docker.Parsed()
+					}
+					findings = append(findings, checkDockerBuild(workingDir, files, build, base, known)...)
+				}
+			}
+		}
+	}
+
+	return findings, nil
+}
+
+func checkDockerBuild(workingDir string, files *FileIndex, build dockerBuild, base DockerFinding, known []DockerfileInfo) []DockerFinding {
+	var findings []DockerFinding
+	report := func(problem, fix string) {
+		f := base
+		f.Problem, f.Fix = problem, fix
+		findings = append(findings, f)
+	}
+
+	contextDir := filepath.Join(workingDir, build.Context)
+	if fi, err := os.Stat(contextDir); err != nil || !fi.IsDir() {
+		report(fmt.Sprintf("build context %q does not exist", build.Context),
+			"Set context: to a directory that exists in the repository (usually \".\")")
+		return findings
+	}
+
+	dockerfilePath := filepath.Join(workingDir, build.Dockerfile)
+	if _, err := os.Stat(dockerfilePath); err != nil {
+		fix := "Point file: at an existing Dockerfile"
+		if len(known) > 0 {
+			var candidates []string
+			for _, df := range known {
+				candidates = append(candidates, "./"+df.Path)
+			}
+			fix = fmt.Sprintf("Point file: at an existing Dockerfile: %s", strings.Join(candidates, ", "))
+		}
+		report(fmt.Sprintf("Dockerfile %q does not exist", build.Dockerfile), fix)
+		return findings
+	}
+
+	info, err := parseDockerfile(dockerfilePath)
+	if err != nil {
+		report(fmt.Sprintf("Dockerfile %q could not be parsed: %v", build.Dockerfile, err), "Fix the Dockerfile syntax")
+		return findings
+	}
+
+	// BuildKit prefers <Dockerfile>.dockerignore next to the Dockerfile over the context's .dockerignore
+	ignore, ignoreFile := dockerIgnore{}, ""
+	for _, candidate := range []string{dockerfilePath + ".dockerignore", filepath.Join(contextDir, ".dockerignore")} {
+		if content, ok := readFileString(candidate); ok {
+			ignore = parseDockerIgnore(content)
+			ignoreFile, _ = filepath.Rel(workingDir, candidate)
+			break
+		}
+	}
+
+	for _, cp := range info.Copies {
+		if cp.FromStage != "" {
+			continue // Copies from another stage or image, not from the build context
+		}
+		for _, src := range cp.Sources {
+			if isDynamicPath(src) || strings.HasPrefix(src, "<<") || strings.HasPrefix(src, "git@") {
+				continue
+			}
+			where := fmt.Sprintf("%s line %d: %s %s", build.Dockerfile, cp.Line, cp.Instruction, src)
+			clean := filepath.Clean(src)
+			if filepath.IsAbs(clean) {
+				clean = strings.TrimPrefix(clean, string(filepath.Separator))
+			}
+			if clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+				report(fmt.Sprintf("%s reaches outside the build context %q", where, build.Context),
+					"Widen context: so it contains the file, and adjust the COPY source accordingly")
+				continue
+			}
+
+			matches, _ := filepath.Glob(filepath.Join(contextDir, clean))
+			if len(matches) == 0 {
+				report(fmt.Sprintf("%s: %q not found in build context %q", where, src, build.Context),
+					missingSourceFix(workingDir, files, clean))
+				continue
+			}
+
+			included := false
+			for _, match := range matches {
+				relMatch, _ := filepath.Rel(contextDir, match)
+				if !ignore.Excluded(relMatch) {
+					included = true
+					break
+				}
+			}
+			if !included {
+				report(fmt.Sprintf("%s: %q is excluded by %s", where, src, ignoreFile),
+					fmt.Sprintf("Remove the matching pattern from %s or add an exception (!%s)", ignoreFile, filepath.ToSlash(clean)))
+			}
+		}
+	}
+
+	return findings
+}
+
+// missingSourceFix suggests a fix for a COPY source that is not in the build context
+func missingSourceFix(workingDir string, files *FileIndex, src string) string {
+	name := filepath.Base(src)
+	found := files.FindNamed(func(n string) bool { return n == name })
+	if len(found) > 3 {
+		found = found[:3]
+	}
+
+	if len(found) > 0 {
+		return fmt.Sprintf("The file exists at %s: set context: to its directory or fix the COPY path", strings.Join(found, ", "))
+	}
+	if _, err := os.Stat(filepath.Join(workingDir, ".gitignore")); err == nil {
+		return fmt.Sprintf("Commit %s to the repository (check it is not gitignored) or generate it in a step before the build", src)
+	}
+	return fmt.Sprintf("Commit %s to the repository or generate it in a step before the build", src)
+}
+
+// staticDockerDiagnosis turns static findings into a debug result, so debug can answer without the LLM
+func staticDockerDiagnosis(findings []DockerFinding) DebugResult {
+	var causes, fixes []string
+	for _, f := range findings {
+		causes = append(causes, f.String())
+		fixes = append(fixes, f.Fix)
+	}
+	return DebugResult{
+		RootCause:   strings.Join(causes, "\n"),
+		Fix:         strings.Join(fixes, "\n"),
+		Explanation: "Docker can only COPY/ADD files that exist inside the build context and are not excluded by .dockerignore; these were found by checking the workflow's build steps against the repository, without running the build.",
+	}
+}
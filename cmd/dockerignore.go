@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// dockerIgnore matches paths against .dockerignore rules
+//
+// Rules follow Docker's semantics: patterns are relative to the build context,
+// "**" matches any number of directories, "!" re-includes a path, and the last
+// matching rule wins. A path is excluded when it or one of its parents matches.
+type dockerIgnore struct {
+	rules []dockerIgnoreRule
+}
+
+type dockerIgnoreRule struct {
+	pattern *regexp.Regexp
+	exclude bool // false for "!" exceptions
+}
+
+func parseDockerIgnore(content string) dockerIgnore {
+	var ignore dockerIgnore
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		exclude := true
+		if strings.HasPrefix(line, "!") {
+			exclude = false
+			line = strings.TrimSpace(line[1:])
+		}
+		line = strings.TrimPrefix(filepath.ToSlash(filepath.Clean(line)), "/")
+		if re, err := regexp.Compile(globToRegexp(line)); err == nil {
+			ignore.rules = append(ignore.rules, dockerIgnoreRule{pattern: re, exclude: exclude})
+		}
+	}
+	return ignore
+}
+
+// Excluded reports whether path (slash-separated, relative to the context) is left out of the build context
+func (d dockerIgnore) Excluded(path string) bool {
+	path = strings.TrimPrefix(filepath.ToSlash(filepath.Clean(path)), "./")
+
+	excluded := false
+	for _, rule := range d.rules {
+		if rule.matches(path) {
+			excluded = rule.exclude
+		}
+	}
+	return excluded
+}
+
+func (r dockerIgnoreRule) matches(path string) bool {
+	// A rule matching a parent directory applies to everything below it
+	for candidate := path; candidate != "." && candidate != ""; candidate = filepath.ToSlash(filepath.Dir(candidate)) {
+		if r.pattern.MatchString(candidate) {
+			return true
+		}
+		if !strings.Contains(candidate, "/") {
+			break
+		}
+	}
+	return false
+}
+
+// globToRegexp converts a Go filepath.Match pattern extended with "**" into an anchored regexp
+func globToRegexp(pattern string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '*' && i+1 < len(pattern) && pattern[i+1] == '*':
+			// "**/" matches zero or more directories, a trailing "**" matches everything
+			i++
+			if i+1 < len(pattern) && pattern[i+1] == '/' {
+				i++
+				b.WriteString("(.*/)?")
+			} else {
+				b.WriteString(".*")
+			}
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+end]
+			if strings.HasPrefix(class, "^") || strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
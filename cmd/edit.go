@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/spf13/cobra"
+)
+
+var editCmd = &cobra.Command{
+	Use:   "edit <workflow> [instruction]",
+	Short: "Change an existing CI/CD configuration with an instruction",
+	Long: `Change an existing workflow or pipeline configuration with a natural-language instruction,
+e.g. fluxion edit .github/workflows/ci.yml "add a macOS runner to the test matrix".
+
+The AI answers with a patch to the entries that change. Fluxion applies it to the file's text, so
+comments, ordering and formatting of everything else stay as they were. The result is validated
+and shown as a diff before it is written. Without an instruction, you are asked for one.`,
+	Args:          cobra.RangeArgs(1, 2),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          editConfiguration,
+}
+
+func init() {
+	rootCmd.AddCommand(editCmd)
+
+	editCmd.Flags().StringP("target", "t", "", "Platform of the file: "+strings.Join(pipelineTargetNames(), ", ")+" (default: detected from the path)")
+	editCmd.Flags().StringP("output", "o", "", "Write the edited configuration here instead of in place")
+	editCmd.Flags().Bool("force", false, "Write without asking for confirmation, and write edits that fail validation")
+	editCmd.Flags().Bool("dry-run", false, "Show the diff without writing")
+}
+
+// EditResult is the AI's change to a configuration
+type EditResult struct {
+	Mode       string     `json:"mode"` // patch or replace
+	Operations []YAMLEdit `json:"operations"`
+	Config     string     `json:"config"`
+	Summary    string     `json:"summary"`
+	Notes      []string   `json:"notes"`
+}
+
+func editConfiguration(cmd *cobra.Command, args []string) error {
+	targetName, _ := cmd.Flags().GetString("target")
+	output, _ := cmd.Flags().GetString("output")
+	force, _ := cmd.Flags().GetBool("force")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	file := args[0]
+	target := targetForFile(file)
+	if targetName != "" {
+		var err error
+		if target, err = findPipelineTarget(targetName); err != nil {
+			return err
+		}
+	}
+	original, err := loadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %w", file, err)
+	}
+	if _, problems := parseYAMLMapping(original); len(problems) > 0 {
+		return fmt.Errorf("%s: %s", file, problems[0])
+	}
+
+	var instruction string
+	if len(args) == 2 {
+		instruction = strings.TrimSpace(args[1])
+	} else {
+		instruction, err = runInputInteractiveMode(TextInteractive{
+			Title:       "Edit " + filepath.Base(file),
+			Description: "Describe the change to make.",
+			Placeholder: "e.g., add a macOS runner to the test matrix",
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if instruction == "" {
+		return fmt.Errorf("instruction cannot be empty")
+	}
+
+	workingDir := GetWorkingDirectory()
+	projectConfig, _, err := LoadProjectConfig(workingDir)
+	if err != nil {
+		return fmt.Errorf("error in project config: %w", err)
+	}
+	model, err := projectConfig.Defaults.ChatModel()
+	if err != nil {
+		return fmt.Errorf("error in project config: %w", err)
+	}
+
+	cmd.Printf("🤖 Editing %s...\n", file)
+	result, err := editWithOpenAI(target, file, original, instruction, model)
+	if err != nil {
+		return err
+	}
+
+	edited := result.Config
+	if result.Mode == "patch" {
+		if edited, err = applyYAMLEdits(original, result.Operations); err != nil {
+			return fmt.Errorf("the AI's patch does not apply, nothing was written: %w", err)
+		}
+	}
+
+	// Problems the file already had are not the edit's fault
+	existing := map[string]bool{}
+	for _, problem := range target.Validate(original) {
+		existing[problem] = true
+	}
+	var problems []string
+	for _, problem := range target.Validate(edited) {
+		if !existing[problem] {
+			problems = append(problems, problem)
+		}
+	}
+
+	diff := unifiedDiff(file, file+" (edited)", original, edited)
+	if diff == "" {
+		cmd.Println("ℹ️  The AI made no changes")
+		return nil
+	}
+	cmd.Println("───────────────────────────────────────────────────────────────")
+	cmd.Print(colorDiff(cmd.OutOrStdout(), diff))
+	cmd.Println("───────────────────────────────────────────────────────────────")
+	if result.Summary != "" {
+		cmd.Println("📋 " + result.Summary)
+	}
+	if result.Mode == "replace" {
+		cmd.Println("ℹ️  The AI rewrote the whole file; check that comments and formatting were kept")
+	}
+	if len(result.Notes) > 0 {
+		cmd.Println("\n💭 Notes:")
+		for i, note := range result.Notes {
+			cmd.Printf("   %d. %s\n", i+1, note)
+		}
+	}
+
+	if len(problems) > 0 {
+		cmd.PrintErrln("\n❌ The edited configuration failed validation:")
+		for _, problem := range problems {
+			cmd.PrintErrln("   - " + problem)
+		}
+		if !force && !dryRun {
+			return fmt.Errorf("nothing was written: the edit introduces %d validation problem(s); use --force to write it anyway", len(problems))
+		}
+	}
+	if dryRun {
+		return nil
+	}
+	if !force {
+		ok, err := runConfirmInteractiveMode("Apply these changes?", "The diff above is written to "+file+".")
+		if err != nil {
+			return fmt.Errorf("nothing was written: %w; use --force to write without asking", err)
+		}
+		if !ok {
+			return fmt.Errorf("nothing was written: the changes were declined")
+		}
+	}
+
+	// The diff above was against file; another output is compared again unless --force
+	overwrite := force || output == "" || output == file
+	if output == "" {
+		output = file
+	}
+	outputPath, _ := filepath.Abs(output)
+	if err := safeWrite(cmd, outputPath, edited, overwrite); err != nil {
+		return err
+	}
+	cmd.Printf("✅ Saved to: %s\n", outputPath)
+	return nil
+}
+
+// editWithOpenAI asks the AI to change a configuration as instructed
+func editWithOpenAI(target PipelineTarget, file, config, instruction string, model openai.ChatModel) (EditResult, error) {
+	client := openai.NewClient(
+		option.WithAPIKey(os.Getenv("OPENAI_API_KEY")),
+	)
+
+	userPrompt := fmt.Sprintf(`Edit this %s.
+
+INSTRUCTION:
+%s
+
+CURRENT CONFIGURATION (%s):
+%s`, target.Artifact, instruction, filepath.Base(file), config)
+
+	resp, err := client.Chat.Completions.New(
+		context.Background(),
+		openai.ChatCompletionNewParams{
+			Model: model,
+			Messages: []openai.ChatCompletionMessageParamUnion{
+				openai.SystemMessage(editSystemPrompt),
+				openai.UserMessage(userPrompt),
+			},
+			ResponseFormat: openai.ChatCompletionNewParamsResponseFormatUnion{
+				OfJSONSchema: &openai.ResponseFormatJSONSchemaParam{
+					JSONSchema: openai.ResponseFormatJSONSchemaJSONSchemaParam{
+						Name:   "edit_result",
+						Schema: editSchema(),
+						Strict: openai.Bool(true),
+					},
+				},
+			},
+		},
+	)
+	if err != nil {
+		return EditResult{}, fmt.Errorf("OpenAI API error: %w", err)
+	}
+
+	var result EditResult
+	if err := json.Unmarshal([]byte(resp.Choices[0].Message.Content), &result); err != nil {
+		return EditResult{}, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if result.Mode == "replace" && strings.TrimSpace(result.Config) == "" {
+		return EditResult{}, fmt.Errorf("the AI returned an empty configuration")
+	}
+	return result, nil
+}
@@ -0,0 +1,342 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Helpers shared by the emitters. Pipelines spell variables and expressions
+// the GitHub Actions way; emitters for other platforms translate them.
+
+var (
+	expressionPattern = regexp.MustCompile(`\$\{\{\s*(.*?)\s*\}\}`)
+	contextRefPattern = regexp.MustCompile(`^(secrets|matrix|env|vars)\.([A-Za-z_][A-Za-z0-9_]*)$`)
+	hashFilesPattern  = regexp.MustCompile(`\$\{\{\s*hashFiles\(([^)]*)\)\s*\}\}|hashFiles\(([^)]*)\)`)
+	cacheNamePattern  = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+)
+
+// platformDialect is how a platform spells the pipeline's variables
+type platformDialect struct {
+	vars map[string]string                 // GitHub variable → the platform's, e.g. GITHUB_SHA → CI_COMMIT_SHA
+	ref  func(context, name string) string // How a value refers to a secret, matrix or env variable
+}
+
+// script renames the GitHub variables a script reads
+func (d platformDialect) script(script string) string {
+	script, _ = d.value(script)
+	return rewriteVars(script, d.vars)
+}
+
+// value rewrites ${{ secrets.X }}, ${{ matrix.X }}, ${{ env.X }} and ${{ vars.X }};
+// it returns the expressions the platform has no equivalent for
+func (d platformDialect) value(value string) (string, []string) {
+	var untranslated []string
+	value = expressionPattern.ReplaceAllStringFunc(value, func(expr string) string {
+		inner := expressionPattern.FindStringSubmatch(expr)[1]
+		m := contextRefPattern.FindStringSubmatch(inner)
+		if m == nil {
+			untranslated = append(untranslated, expr)
+			return expr
+		}
+		return d.ref(m[1], m[2])
+	})
+	return value, untranslated
+}
+
+// env translates variables; a secret exposed under its own name is dropped,
+// as platforms other than GitHub put secret variables in every job's environment
+func (d platformDialect) env(vars []PipelineVar, todos *[]string) []PipelineVar {
+	var translated []PipelineVar
+	for _, v := range vars {
+		if strings.TrimSpace(v.Value) == "${{ secrets."+v.Name+" }}" {
+			continue
+		}
+		value, untranslated := d.value(v.Value)
+		for _, expr := range untranslated {
+			*todos = append(*todos, fmt.Sprintf("%s: no equivalent for %s", v.Name, expr))
+		}
+		translated = append(translated, PipelineVar{Name: v.Name, Value: rewriteVars(value, d.vars)})
+	}
+	return translated
+}
+
+// parseCacheKey splits a cache key into its literal text and the files it hashes,
+// e.g. "go-${{ hashFiles('**/go.sum') }}" → "go", ["**/go.sum"]
+func parseCacheKey(key string) (literal string, files []string) {
+	literal = hashFilesPattern.ReplaceAllStringFunc(key, func(call string) string {
+		m := hashFilesPattern.FindStringSubmatch(call)
+		for _, file := range strings.Split(m[1]+m[2], ",") {
+			if file = strings.Trim(strings.TrimSpace(file), `'"`); file != "" {
+				files = append(files, file)
+			}
+		}
+		return ""
+	})
+	literal = strings.ReplaceAll(literal, "${{ runner.os }}", "linux")
+
+	// Whitespace separates the parts of a key, except inside the expressions left for the dialect
+	exprs := expressionPattern.FindAllString(literal, -1)
+	literal = expressionPattern.ReplaceAllString(literal, "\x00")
+	literal = strings.Trim(strings.Join(strings.Fields(literal), "-"), "-_")
+	for strings.Contains(literal, "--") {
+		literal = strings.ReplaceAll(literal, "--", "-")
+	}
+	for _, expr := range exprs {
+		literal = strings.Replace(literal, "\x00", expr, 1)
+	}
+	return literal, files
+}
+
+// homeCaches are the tool caches kept in the home directory, the variable that moves
+// each one and where it moves to, relative to the project directory
+var homeCaches = map[string]struct{ env, dir string }{
+	"~/go/pkg/mod":      {"GOMODCACHE", ".cache/go-mod"},
+	"~/.cache/go-build": {"GOCACHE", ".cache/go-build"},
+	"~/.npm":            {"npm_config_cache", ".cache/npm"},
+	"~/.cache/yarn":     {"YARN_CACHE_FOLDER", ".cache/yarn"},
+	"~/.cache/pip":      {"PIP_CACHE_DIR", ".cache/pip"},
+}
+
+// cacheName names a cache path for platforms that key each path separately:
+// go-mod for ~/go/pkg/mod, m2-repository for ~/.m2/repository
+func cacheName(dir string) string {
+	home := "~/" + strings.TrimPrefix(strings.TrimPrefix(dir, "$HOME/"), "~/")
+	if cache, ok := homeCaches[strings.TrimRight(home, "/")]; ok {
+		return path.Base(cache.dir)
+	}
+	name := strings.Trim(cacheNamePattern.ReplaceAllString(strings.TrimPrefix(strings.TrimPrefix(dir, "$HOME"), "~"), "-"), "-")
+	if name == "" {
+		return "cache"
+	}
+	return strings.ToLower(name)
+}
+
+// projectCachePaths moves cache paths into the project directory, for platforms that only
+// cache paths under it. It returns the paths to cache, relative to the project directory,
+// the variables that point the tools at them below root, and the paths it could not move.
+func projectCachePaths(paths []string, root string) (moved []string, vars []PipelineVar, outside []string) {
+	for _, dir := range paths {
+		home := "~/" + strings.TrimPrefix(strings.TrimPrefix(dir, "$HOME/"), "~/")
+		switch cache, ok := homeCaches[strings.TrimRight(home, "/")]; {
+		case ok:
+			moved = append(moved, cache.dir)
+			vars = append(vars, PipelineVar{Name: cache.env, Value: root + "/" + cache.dir})
+		case strings.HasPrefix(dir, "~") || strings.HasPrefix(dir, "$HOME") || path.IsAbs(dir):
+			outside = append(outside, dir)
+			moved = append(moved, dir)
+		default:
+			moved = append(moved, dir)
+		}
+	}
+	return moved, vars, outside
+}
+
+// runtimeImages are the official images that provide a runtime on container-based platforms
+var runtimeImages = map[string]string{
+	"go": "golang", "node": "node", "python": "python", "java": "eclipse-temurin", "ruby": "ruby",
+}
+
+// runtimeImage is the image for a job's first setup step, and the setup steps it does not cover
+func runtimeImage(job PipelineJob) (image string, uncovered []PipelineStep) {
+	for _, step := range job.Steps {
+		if step.Kind != StepSetup {
+			continue
+		}
+		name, ok := runtimeImages[step.Runtime]
+		if !ok || image != "" {
+			uncovered = append(uncovered, step)
+			continue
+		}
+		version := step.Version
+		if version == "" {
+			version = "latest"
+		}
+		image = name + ":" + version
+	}
+	return image, uncovered
+}
+
+// jobLevels groups jobs by dependency depth: the first level needs nothing, the
+// next needs only jobs in the first, and so on. A need that closes a cycle is ignored.
+func jobLevels(jobs []PipelineJob) [][]PipelineJob {
+	level := map[string]int{}
+	var depth func(id string, seen map[string]bool) int
+	depth = func(id string, seen map[string]bool) int {
+		if d, ok := level[id]; ok {
+			return d
+		}
+		if seen[id] {
+			return 0
+		}
+		seen[id] = true
+		d := 0
+		for _, job := range jobs {
+			if job.ID != id {
+				continue
+			}
+			for _, need := range job.Needs {
+				d = max(d, depth(need, seen)+1)
+			}
+		}
+		level[id] = d
+		return d
+	}
+	var levels [][]PipelineJob
+	for _, job := range jobs {
+		d := depth(job.ID, map[string]bool{})
+		for len(levels) <= d {
+			levels = append(levels, nil)
+		}
+		levels[d] = append(levels[d], job)
+	}
+	return levels
+}
+
+// matrixCombinations expands a job matrix into one variable set per combination
+func matrixCombinations(axes []PipelineAxis) [][]PipelineVar {
+	combinations := [][]PipelineVar{nil}
+	for _, axis := range axes {
+		var next [][]PipelineVar
+		for _, combination := range combinations {
+			for _, value := range axis.Values {
+				next = append(next, append(append([]PipelineVar(nil), combination...), PipelineVar{Name: axis.Name, Value: value}))
+			}
+		}
+		combinations = next
+	}
+	return combinations
+}
+
+// globRegexp converts a branch or tag glob to an anchored regular expression
+func globRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+			}
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// hostedRunner reports GitHub-hosted runner labels, which other platforms spell differently
+func hostedRunner(label string) (os string, ok bool) {
+	for _, name := range []string{"ubuntu", "macos", "windows"} {
+		if strings.HasPrefix(label, name+"-") {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// shellCommand is a step as one shell command; a step with its own environment, directory or
+// error handling runs in a subshell so it does not leak into the next one
+func (d platformDialect) shellCommand(step PipelineStep, jobDir string, todos *[]string) string {
+	command := strings.TrimRight(d.script(step.Run), "\n")
+	dir := step.WorkingDirectory
+	if dir == "" {
+		dir = jobDir
+	}
+	env := d.env(step.Env, todos)
+	if len(env) == 0 && dir == "" && !step.ContinueOnError {
+		return command
+	}
+	var b strings.Builder
+	b.WriteString("(\n")
+	for _, v := range env {
+		fmt.Fprintf(&b, "  export %s=%s\n", v.Name, shellDoubleQuote(v.Value))
+	}
+	if dir != "" {
+		fmt.Fprintf(&b, "  cd %s\n", shellDoubleQuote(dir))
+	}
+	b.WriteString(indentLines(command+"\n", "  "))
+	b.WriteString(")")
+	if step.ContinueOnError {
+		b.WriteString(" || true")
+	}
+	return b.String() + "\n"
+}
+
+// portable turns an action into a run step when it says what other platforms run instead
+func portable(step PipelineStep) PipelineStep {
+	if step.Kind == StepAction && step.Run != "" {
+		step.Kind = StepRun
+	}
+	return step
+}
+
+// todoEntry is a script entry that fails the job until a construct is translated by hand
+func todoEntry(step PipelineStep) *yaml.Node {
+	what := step.Name
+	if step.Kind == StepAction {
+		what = "action " + step.Uses
+		step.Source = "uses: " + step.Uses
+		for _, w := range step.With {
+			step.Source += fmt.Sprintf("\n  %s: %s", w.Name, w.Value)
+		}
+	}
+	entry := yamlString(fmt.Sprintf("echo %s && exit 1", shellDoubleQuote("Not translated: "+what)))
+	comment := "TODO(fluxion): translate by hand. Source:"
+	for _, line := range strings.Split(strings.TrimRight(step.Source, "\n"), "\n") {
+		comment += "\n  " + line
+	}
+	if step.Comment != "" {
+		comment = step.Comment + "\n" + comment
+	}
+	entry.HeadComment = comment
+	return entry
+}
+
+var localhostPattern = regexp.MustCompile(`\b(?:localhost|127\.0\.0\.1)(?::(\d+))?`)
+
+// serviceHost replaces localhost in a connection string with the service listening on its port
+func serviceHost(value string, services []PipelineService) string {
+	return localhostPattern.ReplaceAllStringFunc(value, func(host string) string {
+		port := localhostPattern.FindStringSubmatch(host)[1]
+		for _, svc := range services {
+			for _, mapping := range svc.Ports {
+				if port != "" && (mapping == port || strings.HasSuffix(mapping, ":"+port)) {
+					return svc.Name + ":" + port
+				}
+			}
+		}
+		if len(services) == 1 {
+			return strings.Replace(host, strings.SplitN(host, ":", 2)[0], services[0].Name, 1)
+		}
+		return host
+	})
+}
+
+// shellDoubleQuote quotes a value for sh, keeping variable references expanding
+func shellDoubleQuote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "`", "\\`").Replace(value) + `"`
+}
+
+// indentLines prefixes every non-empty line
+func indentLines(text, prefix string) string {
+	lines := strings.SplitAfter(text, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// shellSingleQuote quotes a value for sh so that nothing in it expands
+func shellSingleQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
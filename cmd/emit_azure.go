@@ -0,0 +1,477 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// azureDialect spells the pipeline's variables as Azure Pipelines' predefined
+// ones; secrets, matrix values and variables are $(name) macros
+var azureDialect = platformDialect{
+	vars: map[string]string{
+		"GITHUB_SHA":        "BUILD_SOURCEVERSION",
+		"GITHUB_REF":        "BUILD_SOURCEBRANCH",
+		"GITHUB_REF_NAME":   "BUILD_SOURCEBRANCHNAME",
+		"GITHUB_WORKSPACE":  "BUILD_SOURCESDIRECTORY",
+		"GITHUB_RUN_NUMBER": "BUILD_BUILDNUMBER",
+		"GITHUB_RUN_ID":     "BUILD_BUILDID",
+		"GITHUB_REPOSITORY": "BUILD_REPOSITORY_NAME",
+		"GITHUB_ACTOR":      "BUILD_REQUESTEDFOR",
+		"GITHUB_JOB":        "SYSTEM_JOBNAME",
+		"GITHUB_HEAD_REF":   "SYSTEM_PULLREQUEST_SOURCEBRANCH",
+		"GITHUB_BASE_REF":   "SYSTEM_PULLREQUEST_TARGETBRANCH",
+		"RUNNER_TEMP":       "AGENT_TEMPDIRECTORY",
+		"RUNNER_OS":         "AGENT_OS",
+	},
+	ref: func(context, name string) string { return "$(" + name + ")" },
+}
+
+// azureSetupTasks install a runtime on a Microsoft-hosted agent
+var azureSetupTasks = map[string]struct{ task, input string }{
+	"go":     {"GoTool@0", "version"},
+	"node":   {"UseNode@1", "version"},
+	"python": {"UsePythonVersion@0", "versionSpec"},
+	"java":   {"JavaToolInstaller@0", "versionSpec"},
+	"ruby":   {"UseRubyVersion@0", "versionSpec"},
+}
+
+var azureNamePattern = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// azureReasons are the Build.Reason values of the pipeline's events
+var azureReasons = map[string][]string{
+	"push": {"IndividualCI", "BatchedCI"}, "pull_request": {"PullRequest"}, "schedule": {"Schedule"}, "manual": {"Manual"},
+}
+
+// RenderAzurePipelines renders a pipeline as an azure-pipelines.yml. Jobs that
+// deploy to an environment or wait for approval become deployment jobs.
+func RenderAzurePipelines(p Pipeline) (string, error) {
+	root := yamlMapping()
+	todos := append([]string(nil), p.TODOs...)
+	if p.Name != "" {
+		todos = append(todos, "pipeline name: "+p.Name+" (set it when creating the pipeline in Azure DevOps)")
+	}
+	if len(p.Secrets) > 0 {
+		todos = append(todos, "add these as secret pipeline variables or to a variable group: "+strings.Join(p.Secrets, ", "))
+	}
+
+	azureTriggers(root, p.Triggers)
+	if vars := azureDialect.env(p.Env, &todos); len(vars) > 0 {
+		yamlSet(root, "variables", yamlVars(vars))
+	}
+
+	// Service containers are declared once under resources and referenced by name
+	containers := &yaml.Node{Kind: yaml.SequenceNode}
+	declared := map[string]string{}
+	serviceNames := map[string]map[string]string{}
+	for _, job := range p.Jobs {
+		serviceNames[job.ID] = map[string]string{}
+		for _, svc := range job.Services {
+			name := svc.Name
+			if image, ok := declared[name]; ok && image != svc.Image {
+				name = svc.Name + "-" + job.ID
+			}
+			serviceNames[job.ID][svc.Name] = name
+			if _, ok := declared[name]; ok {
+				continue
+			}
+			declared[name] = svc.Image
+			container := yamlMapping()
+			yamlSet(container, "container", yamlString(name))
+			yamlSet(container, "image", yamlString(svc.Image))
+			if env := azureDialect.env(svc.Env, &todos); len(env) > 0 {
+				yamlSet(container, "env", yamlVars(env))
+			}
+			if len(svc.Ports) > 0 {
+				yamlSet(container, "ports", yamlList(svc.Ports))
+			}
+			containers.Content = append(containers.Content, container)
+		}
+	}
+	if len(containers.Content) > 0 {
+		resources := yamlMapping()
+		yamlSet(resources, "containers", containers)
+		yamlSet(root, "resources", resources)
+	}
+
+	secrets := map[string]bool{}
+	for _, name := range p.Secrets {
+		secrets[name] = true
+	}
+	jobs := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, job := range p.Jobs {
+		jobs.Content = append(jobs.Content, azureJob(job, serviceNames[job.ID], secrets))
+	}
+	yamlSet(root, "jobs", jobs)
+
+	if len(todos) > 0 {
+		root.HeadComment = todoComment(todos)
+	}
+	return encodeYAML(root)
+}
+
+func azureTriggers(root *yaml.Node, t PipelineTriggers) {
+	include := func(patterns []string) *yaml.Node {
+		filter := yamlMapping()
+		mapped := make([]string, 0, len(patterns))
+		for _, pattern := range patterns {
+			mapped = append(mapped, strings.ReplaceAll(pattern, "**", "*"))
+		}
+		yamlSet(filter, "include", yamlList(mapped))
+		return filter
+	}
+
+	if len(t.PushBranches) == 0 && len(t.Tags) == 0 {
+		yamlSet(root, "trigger", yamlString("none"))
+	} else {
+		trigger := yamlMapping()
+		if len(t.PushBranches) > 0 {
+			yamlSet(trigger, "branches", include(t.PushBranches))
+		} else {
+			exclude := yamlMapping()
+			yamlSet(exclude, "exclude", yamlList([]string{"*"}))
+			yamlSet(trigger, "branches", exclude)
+		}
+		if len(t.Tags) > 0 {
+			yamlSet(trigger, "tags", include(t.Tags))
+		}
+		yamlSet(root, "trigger", trigger)
+	}
+
+	if t.PullRequests {
+		pr := yamlMapping()
+		yamlSet(pr, "branches", include([]string{"*"}))
+		yamlSet(root, "pr", pr)
+	} else {
+		yamlSet(root, "pr", yamlString("none"))
+	}
+
+	if len(t.Schedules) > 0 {
+		branch := "main"
+		for _, pattern := range t.PushBranches {
+			if !strings.ContainsAny(pattern, "*?") {
+				branch = pattern
+				break
+			}
+		}
+		schedules := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, cron := range t.Schedules {
+			schedule := yamlMapping()
+			yamlSet(schedule, "cron", yamlString(cron))
+			yamlSet(schedule, "displayName", yamlString("Scheduled run"))
+			yamlSet(schedule, "branches", include([]string{branch}))
+			yamlSet(schedule, "always", yamlBool(true))
+			schedules.Content = append(schedules.Content, schedule)
+		}
+		yamlSet(root, "schedules", schedules)
+	}
+}
+
+func azureJob(job PipelineJob, services map[string]string, secrets map[string]bool) *yaml.Node {
+	node := yamlMapping()
+	todos := append([]string(nil), job.TODOs...)
+
+	environment := job.Environment
+	if job.Approval {
+		if environment == "" {
+			environment = job.ID
+		}
+		todos = append(todos, fmt.Sprintf("add an Approvals check to the %q environment; the job waits for it", environment))
+	}
+	kind := "job"
+	if environment != "" {
+		kind = "deployment"
+	}
+	yamlSet(node, kind, yamlString(strings.ReplaceAll(job.ID, "-", "_")))
+	if job.Name != "" && job.Name != job.ID {
+		yamlSet(node, "displayName", yamlString(job.Name))
+	}
+	if len(job.Needs) > 0 {
+		needs := make([]string, 0, len(job.Needs))
+		for _, need := range job.Needs {
+			needs = append(needs, strings.ReplaceAll(need, "-", "_"))
+		}
+		yamlSet(node, "dependsOn", yamlList(needs))
+	}
+	status, condition := azureJobStatus[job.When], ""
+	if job.If != nil {
+		condition = azureCondition(*job.If, status, &todos)
+	}
+	if condition == "" && job.When != "" {
+		condition = status
+	}
+	if condition != "" {
+		yamlSet(node, "condition", yamlString(condition))
+	}
+	yamlSet(node, "pool", azurePool(job.RunsOn, &todos))
+	if job.Image != "" {
+		image, _ := azureDialect.value(job.Image)
+		yamlSet(node, "container", yamlString(image))
+	}
+	if len(job.Services) > 0 {
+		refs := yamlMapping()
+		for _, svc := range job.Services {
+			yamlSet(refs, svc.Name, yamlString(services[svc.Name]))
+		}
+		yamlSet(node, "services", refs)
+	}
+	if environment != "" {
+		yamlSet(node, "environment", yamlString(environment))
+	}
+	if len(job.Matrix) > 0 && kind == "deployment" {
+		todos = append(todos, "deployment jobs cannot use a matrix; add a job per combination")
+	} else if len(job.Matrix) > 0 {
+		matrix := yamlMapping()
+		for _, combination := range matrixCombinations(job.Matrix) {
+			// Matrix entry names allow letters, digits and underscores
+			var names []string
+			for _, v := range combination {
+				names = append(names, v.Name, v.Value)
+			}
+			yamlSet(matrix, azureNamePattern.ReplaceAllString(strings.Join(names, "_"), "_"), yamlVars(combination))
+		}
+		strategy := yamlMapping()
+		yamlSet(strategy, "matrix", matrix)
+		yamlSet(node, "strategy", strategy)
+	}
+	if job.TimeoutMinutes > 0 {
+		yamlSet(node, "timeoutInMinutes", yamlInt(job.TimeoutMinutes))
+	}
+	if job.AllowFailure {
+		yamlSet(node, "continueOnError", yamlBool(true))
+	}
+	if vars := azureDialect.env(job.Env, &todos); len(vars) > 0 {
+		yamlSet(node, "variables", yamlVars(vars))
+	}
+
+	// Secret variables are not in the environment; steps map the ones they read
+	for _, v := range job.Env {
+		if strings.TrimSpace(v.Value) == "${{ secrets."+v.Name+" }}" {
+			secrets[v.Name] = true
+		}
+	}
+	steps := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, step := range job.Steps {
+		steps.Content = append(steps.Content, azureSteps(step, job, secrets, &todos)...)
+	}
+	if kind == "deployment" {
+		deploy := yamlMapping()
+		yamlSet(deploy, "steps", steps)
+		runOnce := yamlMapping()
+		yamlSet(runOnce, "deploy", deploy)
+		strategy := yamlMapping()
+		yamlSet(strategy, "runOnce", runOnce)
+		yamlSet(node, "strategy", strategy)
+	} else {
+		yamlSet(node, "steps", steps)
+	}
+
+	if len(todos) > 0 {
+		node.HeadComment = todoComment(todos)
+	}
+	return node
+}
+
+// azurePool picks a Microsoft-hosted image or a self-hosted pool with demands
+func azurePool(labels []string, todos *[]string) *yaml.Node {
+	pool := yamlMapping()
+	var demands []string
+	for _, label := range labels {
+		if _, hosted := hostedRunner(label); hosted {
+			yamlSet(pool, "vmImage", yamlString(label))
+			return pool
+		}
+		if label != "self-hosted" {
+			demands = append(demands, label)
+		}
+	}
+	if len(labels) == 0 {
+		yamlSet(pool, "vmImage", yamlString("ubuntu-latest"))
+		return pool
+	}
+	*todos = append(*todos, "self-hosted runner: set the agent pool name")
+	yamlSet(pool, "name", yamlString("Default"))
+	if len(demands) > 0 {
+		yamlSet(pool, "demands", yamlList(demands))
+	}
+	return pool
+}
+
+// azureJobStatus are the status checks for a job's When; a job's condition
+// replaces Azure's default succeeded(), so it must include one
+var azureJobStatus = map[string]string{"": "succeeded()", "success": "not(or(failed(), canceled()))", "failure": "failed()", "always": "always()"}
+
+// azureCondition builds a job condition expression from Build.SourceBranch and Build.Reason
+func azureCondition(c PipelineCondition, status string, todos *[]string) string {
+	var parts []string
+	ref := func(prefix, pattern string) string {
+		switch {
+		case pattern == "*" || pattern == "**":
+			return fmt.Sprintf("startsWith(variables['Build.SourceBranch'], '%s')", prefix)
+		case strings.HasSuffix(pattern, "*") && !strings.ContainsAny(strings.TrimRight(pattern, "*"), "*?"):
+			return fmt.Sprintf("startsWith(variables['Build.SourceBranch'], '%s%s')", prefix, strings.TrimRight(pattern, "*"))
+		case strings.ContainsAny(pattern, "*?"):
+			*todos = append(*todos, "condition pattern not translated: "+pattern)
+			return ""
+		}
+		return fmt.Sprintf("eq(variables['Build.SourceBranch'], '%s%s')", prefix, pattern)
+	}
+	if c.DefaultBranch {
+		parts = append(parts, ref("refs/heads/", "main"))
+		*todos = append(*todos, "condition assumes the default branch is main")
+	}
+	for _, branch := range c.Branches {
+		parts = append(parts, ref("refs/heads/", branch))
+	}
+	for _, tag := range c.Tags {
+		parts = append(parts, ref("refs/tags/", tag))
+	}
+	for _, event := range c.Events {
+		if reasons, ok := azureReasons[event]; ok {
+			parts = append(parts, fmt.Sprintf("in(variables['Build.Reason'], '%s')", strings.Join(reasons, "', '")))
+		}
+	}
+	if c.Expr != "" {
+		*todos = append(*todos, "condition not translated: "+c.Expr)
+	}
+	var nonEmpty []string
+	for _, part := range parts {
+		if part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+	switch len(nonEmpty) {
+	case 0:
+		return ""
+	case 1:
+		return "and(" + status + ", " + nonEmpty[0] + ")"
+	}
+	return "and(" + status + ", or(" + strings.Join(nonEmpty, ", ") + "))"
+}
+
+// azureSteps translates a step; uploads of several paths copy them to the staging directory first
+func azureSteps(step PipelineStep, job PipelineJob, secrets map[string]bool, todos *[]string) []*yaml.Node {
+	step = portable(step)
+	node := yamlMapping()
+	var steps []*yaml.Node
+	var env []PipelineVar
+	switch step.Kind {
+	case StepCheckout:
+		yamlSet(node, "checkout", yamlString("self"))
+	case StepSetup:
+		setup, ok := azureSetupTasks[step.Runtime]
+		if !ok || job.Image != "" {
+			if job.Image == "" {
+				*todos = append(*todos, fmt.Sprintf("no setup task for %s %s; install it in a script", step.Runtime, step.Version))
+			}
+			return nil
+		}
+		yamlSet(node, "task", yamlString(setup.task))
+		inputs := yamlMapping()
+		version, _ := azureDialect.value(step.Version)
+		if version == "" {
+			*todos = append(*todos, fmt.Sprintf("%s: pin a version", setup.task))
+		} else {
+			yamlSet(inputs, setup.input, yamlString(version))
+		}
+		if step.Runtime == "java" {
+			yamlSet(inputs, "jdkArchitectureOption", yamlString("x64"))
+			yamlSet(inputs, "jdkSourceOption", yamlString("PreInstalled"))
+		}
+		if len(inputs.Content) > 0 {
+			yamlSet(node, "inputs", inputs)
+		}
+		if step.Name == "" {
+			step.Name = fmt.Sprintf("Set up %s %s", step.Runtime, step.Version)
+		}
+	case StepCache:
+		literal, files := parseCacheKey(step.Key)
+		literal, _ = azureDialect.value(literal)
+		for _, p := range step.Paths {
+			// Cache@2 caches one path per task, and each needs its own key
+			key := append([]string{fmt.Sprintf("%q", literal), fmt.Sprintf("%q", cacheName(p)), `"$(Agent.OS)"`}, files...)
+			cache := yamlMapping()
+			yamlSet(cache, "task", yamlString("Cache@2"))
+			inputs := yamlMapping()
+			yamlSet(inputs, "key", yamlString(strings.Join(key, " | ")))
+			yamlSet(inputs, "path", yamlString(p))
+			yamlSet(cache, "inputs", inputs)
+			steps = append(steps, cache)
+		}
+		return steps
+	case StepUpload:
+		target := strings.Join(step.Paths, "")
+		if len(step.Paths) != 1 || strings.ContainsAny(target, "*?[") {
+			target = "$(Build.ArtifactStagingDirectory)/" + step.Artifact
+			collect := yamlMapping()
+			yamlSet(collect, "bash", yamlString(fmt.Sprintf("shopt -s globstar nullglob\nmkdir -p %s\ncp -r --parents %s %s\n",
+				shellDoubleQuote(target), strings.Join(step.Paths, " "), shellDoubleQuote(target))))
+			yamlSet(collect, "displayName", yamlString("Collect "+step.Artifact))
+			azureStepCondition(collect, step.When)
+			steps = append(steps, collect)
+		}
+		if len(job.Matrix) > 0 {
+			*todos = append(*todos, fmt.Sprintf("artifact %q is published once per matrix job; add the matrix values to its name", step.Artifact))
+		}
+		yamlSet(node, "publish", yamlString(target))
+		yamlSet(node, "artifact", yamlString(step.Artifact))
+	case StepDownload:
+		yamlSet(node, "task", yamlString("DownloadPipelineArtifact@2"))
+		inputs := yamlMapping()
+		yamlSet(inputs, "artifact", yamlString(step.Artifact))
+		dir := "."
+		if len(step.Paths) > 0 {
+			dir = step.Paths[0]
+		}
+		yamlSet(inputs, "path", yamlString(path.Join("$(Build.SourcesDirectory)", dir)))
+		yamlSet(node, "inputs", inputs)
+	case StepRun:
+		yamlSet(node, "bash", yamlString(azureDialect.script(step.Run)))
+		env = azureDialect.env(step.Env, todos)
+		for _, m := range shellVarPattern.FindAllStringSubmatch(step.Run, -1) {
+			if name := m[1] + m[2]; secrets[name] && !hasVar(env, name) {
+				env = append(env, PipelineVar{Name: name, Value: "$(" + name + ")"})
+			}
+		}
+		dir := step.WorkingDirectory
+		if dir == "" {
+			dir = job.WorkingDirectory
+		}
+		if dir != "" {
+			yamlSet(node, "workingDirectory", yamlString(dir))
+		}
+	default:
+		entry := todoEntry(step)
+		yamlSet(node, "bash", yamlString(entry.Value))
+		node.HeadComment = entry.HeadComment
+		step.Name = "TODO: " + step.Name
+	}
+
+	if step.Name != "" {
+		name, _ := azureDialect.value(step.Name) // ${{ }} would be a template expression
+		yamlSet(node, "displayName", yamlString(name))
+	}
+	if len(env) > 0 {
+		yamlSet(node, "env", yamlVars(env))
+	}
+	azureStepCondition(node, step.When)
+	if step.ContinueOnError {
+		yamlSet(node, "continueOnError", yamlBool(true))
+	}
+	if step.Comment != "" && node.HeadComment == "" {
+		node.HeadComment = step.Comment
+	}
+	return append(steps, node)
+}
+
+func azureStepCondition(node *yaml.Node, when string) {
+	switch when {
+	case "always":
+		yamlSet(node, "condition", yamlString("always()"))
+	case "failure":
+		yamlSet(node, "condition", yamlString("failed()"))
+	}
+}
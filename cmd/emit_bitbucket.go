@@ -0,0 +1,399 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// bitbucketDialect spells the pipeline's variables as Bitbucket's default ones
+var bitbucketDialect = platformDialect{
+	vars: map[string]string{
+		"GITHUB_SHA":        "BITBUCKET_COMMIT",
+		"GITHUB_REF_NAME":   "BITBUCKET_BRANCH",
+		"GITHUB_WORKSPACE":  "BITBUCKET_CLONE_DIR",
+		"GITHUB_RUN_NUMBER": "BITBUCKET_BUILD_NUMBER",
+		"GITHUB_RUN_ID":     "BITBUCKET_PIPELINE_UUID",
+		"GITHUB_REPOSITORY": "BITBUCKET_REPO_FULL_NAME",
+		"GITHUB_HEAD_REF":   "BITBUCKET_BRANCH",
+		"GITHUB_BASE_REF":   "BITBUCKET_PR_DESTINATION_BRANCH",
+	},
+	// Secrets are repository variables; matrix jobs are expanded with their values exported
+	ref: func(context, name string) string { return "$" + name },
+}
+
+// bitbucketSection is one entry under pipelines:, e.g. branches: main
+type bitbucketSection struct {
+	kind, pattern string
+	jobs          []PipelineJob
+}
+
+// RenderBitbucketPipelines renders a pipeline as a bitbucket-pipelines.yml.
+// Bitbucket cannot skip steps at run time, so each trigger and condition gets
+// its own pipeline with the jobs that apply to it.
+func RenderBitbucketPipelines(p Pipeline) (string, error) {
+	root := yamlMapping()
+	todos := append([]string(nil), p.TODOs...)
+	if len(p.Secrets) > 0 {
+		todos = append(todos, "add these as secured repository variables: "+strings.Join(p.Secrets, ", "))
+	}
+
+	definitions := yamlMapping()
+	services := yamlMapping()
+	caches := yamlMapping()
+	cacheNames := map[string]string{}
+	for _, job := range p.Jobs {
+		for _, svc := range job.Services {
+			if mappingValue(services, svc.Name) != nil {
+				continue
+			}
+			service := yamlMapping()
+			yamlSet(service, "image", yamlString(svc.Image))
+			if env := bitbucketDialect.env(svc.Env, &todos); len(env) > 0 {
+				yamlSet(service, "variables", yamlVars(env))
+			}
+			yamlSet(services, svc.Name, service)
+		}
+		for _, step := range job.Steps {
+			if step.Kind != StepCache {
+				continue
+			}
+			// Caches move into the clone directory, so they do not depend on the image's home directory
+			moved, _, _ := projectCachePaths(step.Paths, "$BITBUCKET_CLONE_DIR")
+			for i, dir := range moved {
+				id := step.Key + "\x00" + step.Paths[i]
+				if _, ok := cacheNames[id]; ok {
+					continue
+				}
+				name := jobID(path.Base(dir))
+				for i := 2; mappingValue(caches, name) != nil; i++ {
+					name = fmt.Sprintf("%s-%d", jobID(path.Base(dir)), i)
+				}
+				cacheNames[id] = name
+				yamlSet(caches, name, bitbucketCache(step.Key, dir))
+			}
+		}
+	}
+	if len(services.Content) > 0 {
+		yamlSet(definitions, "services", services)
+	}
+	if len(caches.Content) > 0 {
+		yamlSet(definitions, "caches", caches)
+	}
+	if len(definitions.Content) > 0 {
+		yamlSet(root, "definitions", definitions)
+	}
+
+	pipelines := yamlMapping()
+	kinds := map[string]*yaml.Node{}
+	for _, section := range bitbucketSections(p, &todos) {
+		items := bitbucketItems(p, section.jobs, cacheNames, &todos)
+		if len(items.Content) == 0 {
+			continue
+		}
+		if section.kind == "default" {
+			yamlSet(pipelines, "default", items)
+			continue
+		}
+		if kinds[section.kind] == nil {
+			kinds[section.kind] = yamlMapping()
+			yamlSet(pipelines, section.kind, kinds[section.kind])
+		}
+		yamlSet(kinds[section.kind], section.pattern, items)
+	}
+	yamlSet(root, "pipelines", pipelines)
+
+	if len(todos) > 0 {
+		root.HeadComment = todoComment(todos)
+	}
+	return encodeYAML(root)
+}
+
+// bitbucketSections decides which pipelines to write and the jobs in each: the
+// unconditional jobs, plus the jobs whose condition the section's trigger meets
+func bitbucketSections(p Pipeline, todos *[]string) []bitbucketSection {
+	var sections []bitbucketSection
+	add := func(kind, pattern string, matches func(c PipelineCondition) bool) {
+		for _, s := range sections {
+			if s.kind == kind && s.pattern == pattern {
+				return
+			}
+		}
+		section := bitbucketSection{kind: kind, pattern: pattern}
+		for _, job := range p.Jobs {
+			if job.If == nil || matches(*job.If) {
+				section.jobs = append(section.jobs, job)
+			}
+		}
+		sections = append(sections, section)
+	}
+	hasEvent := func(c PipelineCondition, event string) bool { return slices.Contains(c.Events, event) }
+	branch := func(name string) func(c PipelineCondition) bool {
+		return func(c PipelineCondition) bool {
+			return hasEvent(c, "push") || slices.Contains(c.Branches, name) || c.DefaultBranch && name == "main"
+		}
+	}
+
+	for _, job := range p.Jobs {
+		if job.If == nil {
+			continue
+		}
+		if job.If.DefaultBranch {
+			*todos = append(*todos, fmt.Sprintf("job %q runs on the default branch; the pipeline assumes it is main", job.ID))
+		}
+		if job.If.Expr != "" {
+			*todos = append(*todos, fmt.Sprintf("job %q: condition not translated: %s", job.ID, job.If.Expr))
+		}
+	}
+
+	for _, pattern := range p.Triggers.PushBranches {
+		if pattern == "*" || pattern == "**" {
+			add("default", "", func(c PipelineCondition) bool { return hasEvent(c, "push") })
+		} else {
+			add("branches", pattern, branch(pattern))
+		}
+	}
+	// Branch conditions get their own pipelines; they also run the unconditional jobs
+	for _, job := range p.Jobs {
+		if job.If == nil {
+			continue
+		}
+		names := job.If.Branches
+		if job.If.DefaultBranch {
+			names = append([]string{"main"}, names...)
+		}
+		for _, name := range names {
+			add("branches", name, branch(name))
+		}
+	}
+
+	tags := append([]string(nil), p.Triggers.Tags...)
+	for _, job := range p.Jobs {
+		if job.If != nil {
+			tags = append(tags, job.If.Tags...)
+		}
+	}
+	for _, pattern := range tags {
+		if pattern == "*" {
+			pattern = "**"
+		}
+		add("tags", pattern, func(c PipelineCondition) bool {
+			return slices.Contains(c.Tags, pattern) || slices.Contains(c.Tags, "*") || slices.Contains(c.Tags, "**")
+		})
+	}
+	if p.Triggers.PullRequests {
+		add("pull-requests", "**", func(c PipelineCondition) bool { return hasEvent(c, "pull_request") })
+	}
+	if p.Triggers.Manual {
+		add("custom", "run", func(c PipelineCondition) bool { return hasEvent(c, "manual") })
+	}
+	if len(p.Triggers.Schedules) > 0 {
+		add("custom", "scheduled", func(c PipelineCondition) bool { return hasEvent(c, "schedule") })
+		*todos = append(*todos, "schedule the \"scheduled\" custom pipeline (Repository settings > Schedules) for: "+strings.Join(p.Triggers.Schedules, ", "))
+	}
+	return sections
+}
+
+// bitbucketItems lays out jobs as steps, running the jobs at the same dependency
+// depth in parallel; the jobs a job needs are always in an earlier item
+func bitbucketItems(p Pipeline, jobs []PipelineJob, caches map[string]string, todos *[]string) *yaml.Node {
+	included := map[string]bool{}
+	for _, job := range jobs {
+		included[job.ID] = true
+	}
+	items := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, level := range jobLevels(jobs) {
+		var steps []*yaml.Node
+		for _, job := range level {
+			for _, need := range job.Needs {
+				if !included[need] {
+					*todos = appendUnique(*todos, fmt.Sprintf("job %q needs %q, which does not run in every pipeline it is in", job.ID, need))
+				}
+			}
+			combinations := matrixCombinations(job.Matrix)
+			for _, combination := range combinations {
+				steps = append(steps, bitbucketStep(p, job, combination, caches))
+			}
+		}
+		if len(steps) == 1 {
+			items.Content = append(items.Content, steps[0])
+			continue
+		}
+		parallel := yamlMapping()
+		yamlSet(parallel, "parallel", &yaml.Node{Kind: yaml.SequenceNode, Content: steps})
+		items.Content = append(items.Content, parallel)
+	}
+	return items
+}
+
+// bitbucketStep renders one job, or one combination of a matrix job
+func bitbucketStep(p Pipeline, job PipelineJob, combination []PipelineVar, caches map[string]string) *yaml.Node {
+	node := yamlMapping()
+	todos := append([]string(nil), job.TODOs...)
+
+	// Matrix values are known here, so substitute them rather than export them
+	matrix := func(value string) string {
+		return expressionPattern.ReplaceAllStringFunc(value, func(expr string) string {
+			m := contextRefPattern.FindStringSubmatch(expressionPattern.FindStringSubmatch(expr)[1])
+			for _, v := range combination {
+				if m != nil && m[1] == "matrix" && m[2] == v.Name {
+					return v.Value
+				}
+			}
+			return expr
+		})
+	}
+
+	name := job.Name
+	if name == "" {
+		name = job.ID
+	}
+	if len(combination) > 0 {
+		var values []string
+		for _, v := range combination {
+			values = append(values, v.Value)
+		}
+		name += " (" + strings.Join(values, ", ") + ")"
+	}
+	yamlSet(node, "name", yamlString(name))
+
+	image := job.Image
+	if image == "" {
+		image, _ = runtimeImage(job)
+	}
+	for _, step := range job.Steps {
+		if step.Kind == StepSetup && !strings.Contains(image, runtimeImages[step.Runtime]+":") {
+			todos = append(todos, fmt.Sprintf("no image provides %s %s; install it in the script", step.Runtime, matrix(step.Version)))
+		}
+	}
+	if image != "" {
+		yamlSet(node, "image", yamlString(matrix(image)))
+	}
+
+	var labels []string
+	for _, label := range job.RunsOn {
+		if os, hosted := hostedRunner(label); hosted {
+			if os != "ubuntu" {
+				todos = append(todos, fmt.Sprintf("runs-on %s: Bitbucket Cloud runs Linux containers; use a %s self-hosted runner", label, os))
+			}
+		} else if label != "self-hosted" {
+			labels = append(labels, label)
+		}
+	}
+	if len(labels) > 0 {
+		yamlSet(node, "runs-on", yamlList(append([]string{"self.hosted", "linux"}, labels...)))
+	}
+	if job.Approval {
+		yamlSet(node, "trigger", yamlString("manual"))
+	}
+	if job.Environment != "" {
+		yamlSet(node, "deployment", yamlString(job.Environment))
+	}
+	if job.TimeoutMinutes > 0 {
+		yamlSet(node, "max-time", yamlInt(job.TimeoutMinutes))
+	}
+	if job.AllowFailure {
+		todos = append(todos, "Bitbucket cannot let a step fail without failing the pipeline; add || true to the commands that may fail")
+	}
+	if job.When == "failure" || job.When == "always" {
+		todos = append(todos, "Bitbucket stops at the first failed step; move these commands to the after-script of the steps before this one")
+	}
+	if len(job.Services) > 0 {
+		var names []string
+		for _, svc := range job.Services {
+			names = append(names, svc.Name)
+		}
+		yamlSet(node, "services", yamlList(names))
+	}
+
+	// Steps have no environment of their own: export the pipeline's and the job's variables first
+	script := &yaml.Node{Kind: yaml.SequenceNode}
+	afterScript := &yaml.Node{Kind: yaml.SequenceNode}
+	env := append(append([]PipelineVar(nil), p.Env...), job.Env...)
+	for _, v := range combination {
+		env = setVar(env, v.Name, v.Value)
+	}
+	for _, step := range job.Steps {
+		if step.Kind == StepCache {
+			_, cacheVars, _ := projectCachePaths(step.Paths, "$BITBUCKET_CLONE_DIR")
+			for _, v := range cacheVars {
+				if !hasVar(env, v.Name) {
+					env = append(env, v)
+				}
+			}
+		}
+	}
+	for i := range env {
+		env[i].Value = matrix(env[i].Value)
+	}
+	for _, v := range bitbucketDialect.env(env, &todos) {
+		script.Content = append(script.Content, yamlString(fmt.Sprintf("export %s=%s", v.Name, shellDoubleQuote(v.Value))))
+	}
+
+	var cacheList, artifacts []string
+	for _, step := range job.Steps {
+		step = portable(step)
+		step.Run = matrix(step.Run)
+		switch step.Kind {
+		case StepCheckout, StepSetup, StepDownload:
+			// Bitbucket clones the repository, the image provides the runtime, and artifacts pass to later steps
+		case StepCache:
+			for _, dir := range step.Paths {
+				cacheList = appendUnique(cacheList, caches[step.Key+"\x00"+dir])
+			}
+		case StepUpload:
+			artifacts = append(artifacts, step.Paths...)
+		case StepRun:
+			entry := yamlString(bitbucketDialect.shellCommand(step, job.WorkingDirectory, &todos))
+			entry.HeadComment = step.Comment
+			switch step.When {
+			case "always":
+				afterScript.Content = append(afterScript.Content, entry)
+			case "failure":
+				entry.Value = "if [ \"$BITBUCKET_EXIT_CODE\" != \"0\" ]; then\n" + indentLines(entry.Value, "  ") + "fi\n"
+				entry.Style = yaml.LiteralStyle
+				afterScript.Content = append(afterScript.Content, entry)
+			default:
+				script.Content = append(script.Content, entry)
+			}
+		default:
+			script.Content = append(script.Content, todoEntry(step))
+		}
+	}
+	if len(cacheList) > 0 {
+		yamlSet(node, "caches", yamlList(cacheList))
+	}
+	if len(script.Content) == 0 {
+		script.Content = append(script.Content, yamlString("true"))
+	}
+	yamlSet(node, "script", script)
+	if len(afterScript.Content) > 0 {
+		yamlSet(node, "after-script", afterScript)
+	}
+	if len(artifacts) > 0 {
+		yamlSet(node, "artifacts", yamlList(artifacts))
+	}
+
+	if len(todos) > 0 {
+		node.HeadComment = todoComment(todos)
+	}
+	step := yamlMapping()
+	yamlSet(step, "step", node)
+	return step
+}
+
+// bitbucketCache defines a cache, keyed on the files the step's key hashes
+func bitbucketCache(key, dir string) *yaml.Node {
+	cache := yamlMapping()
+	_, files := parseCacheKey(key)
+	if len(files) > 0 {
+		k := yamlMapping()
+		yamlSet(k, "files", yamlList(files))
+		yamlSet(cache, "key", k)
+	}
+	yamlSet(cache, "path", yamlString(dir))
+	return cache
+}
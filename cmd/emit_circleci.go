@@ -0,0 +1,436 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// circleciDialect spells the pipeline's variables as CircleCI's built-in ones;
+// matrix values are job parameters
+var circleciDialect = platformDialect{
+	vars: map[string]string{
+		"GITHUB_SHA":        "CIRCLE_SHA1",
+		"GITHUB_REF_NAME":   "CIRCLE_BRANCH",
+		"GITHUB_WORKSPACE":  "CIRCLE_WORKING_DIRECTORY",
+		"GITHUB_RUN_NUMBER": "CIRCLE_BUILD_NUM",
+		"GITHUB_RUN_ID":     "CIRCLE_WORKFLOW_ID",
+		"GITHUB_REPOSITORY": "${CIRCLE_PROJECT_USERNAME}/${CIRCLE_PROJECT_REPONAME}",
+		"GITHUB_ACTOR":      "CIRCLE_USERNAME",
+		"GITHUB_JOB":        "CIRCLE_JOB",
+		"GITHUB_HEAD_REF":   "CIRCLE_BRANCH",
+	},
+	ref: func(context, name string) string {
+		if context == "matrix" {
+			return "<< parameters." + name + " >>"
+		}
+		return "${" + name + "}"
+	},
+}
+
+// RenderCircleCI renders a pipeline as a .circleci/config.yml with one workflow.
+// Approvals become approval jobs the gated job requires.
+func RenderCircleCI(p Pipeline) (string, error) {
+	root := yamlMapping()
+	todos := append([]string(nil), p.TODOs...)
+	yamlSet(root, "version", yamlString("2.1"))
+
+	if len(p.Secrets) > 0 {
+		todos = append(todos, "add these as project environment variables or to a context: "+strings.Join(p.Secrets, ", "))
+	}
+	if p.Triggers.PullRequests {
+		todos = append(todos, "enable \"Build forked pull requests\" in Project Settings > Advanced if pull requests come from forks")
+		switch branches := p.Triggers.PushBranches; {
+		case len(branches) == 0:
+			todos = append(todos, "enable \"Only build pull requests\" in Project Settings > Advanced so branches without a pull request do not build")
+		case !slices.Contains(branches, "*") && !slices.Contains(branches, "**"):
+			todos = append(todos, "CircleCI builds pull requests as pushes to their branch, so the branch filters skip pull requests from other branches; "+
+				"to build them, remove the filters and enable \"Only build pull requests\" in Project Settings > Advanced")
+		}
+	}
+	if len(p.Triggers.Schedules) > 0 {
+		todos = append(todos, "create scheduled pipelines (Project Settings > Triggers) for: "+strings.Join(p.Triggers.Schedules, ", "))
+	}
+
+	uploaded := map[string]bool{}
+	for _, job := range p.Jobs {
+		for _, step := range job.Steps {
+			if step.Kind == StepDownload {
+				uploaded[step.Artifact] = true
+			}
+		}
+	}
+
+	jobs := yamlMapping()
+	workflowJobs := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, job := range p.Jobs {
+		yamlSet(jobs, job.ID, circleciJobNode(p, job, uploaded))
+
+		requires := job.Needs
+		filters := circleciJobFilters(p.Triggers, job, &todos)
+		if job.Approval {
+			hold := yamlMapping()
+			yamlSet(hold, "type", yamlString("approval"))
+			if len(requires) > 0 {
+				yamlSet(hold, "requires", yamlList(requires))
+			}
+			if filters != nil {
+				yamlSet(hold, "filters", filters)
+			}
+			workflowJobs.Content = append(workflowJobs.Content, circleciWorkflowJob("hold-"+job.ID, hold))
+			requires = []string{"hold-" + job.ID}
+		}
+
+		entry := yamlMapping()
+		if len(requires) > 0 {
+			yamlSet(entry, "requires", yamlList(requires))
+		}
+		if len(job.Matrix) > 0 {
+			parameters := yamlMapping()
+			for _, axis := range job.Matrix {
+				yamlSet(parameters, axis.Name, yamlList(axis.Values))
+			}
+			matrix := yamlMapping()
+			yamlSet(matrix, "parameters", parameters)
+			yamlSet(entry, "matrix", matrix)
+		}
+		if filters != nil {
+			yamlSet(entry, "filters", filters)
+		}
+		workflowJobs.Content = append(workflowJobs.Content, circleciWorkflowJob(job.ID, entry))
+	}
+	yamlSet(root, "jobs", jobs)
+
+	workflow := yamlMapping()
+	yamlSet(workflow, "jobs", workflowJobs)
+	workflows := yamlMapping()
+	name := jobID(p.Name)
+	if p.Name == "" {
+		name = "pipeline"
+	}
+	yamlSet(workflows, name, workflow)
+	yamlSet(root, "workflows", workflows)
+
+	if len(todos) > 0 {
+		root.HeadComment = todoComment(todos)
+	}
+	return encodeYAML(root)
+}
+
+// circleciWorkflowJob is a workflow jobs: entry, a bare name when it has no settings
+func circleciWorkflowJob(name string, settings *yaml.Node) *yaml.Node {
+	if len(settings.Content) == 0 {
+		return yamlString(name)
+	}
+	entry := yamlMapping()
+	yamlSet(entry, name, settings)
+	return entry
+}
+
+// circleciJobFilters limits a job to its condition's branches and tags, or to the
+// pipeline's push triggers. CircleCI only runs jobs for tags when a tags filter allows it.
+func circleciJobFilters(t PipelineTriggers, job PipelineJob, todos *[]string) *yaml.Node {
+	branches, tags := t.PushBranches, t.Tags
+	if t.PullRequests && len(branches) == 0 {
+		branches = []string{"*"} // CircleCI builds pull requests as pushes to their branch
+	}
+	if c := job.If; c != nil {
+		branches, tags = c.Branches, c.Tags
+		if c.DefaultBranch {
+			branches = append([]string{"main"}, branches...)
+			*todos = append(*todos, fmt.Sprintf("job %q: filter assumes the default branch is main", job.ID))
+		}
+		for _, event := range c.Events {
+			switch event {
+			case "push":
+				branches = appendUnique(branches, "*")
+			default:
+				*todos = append(*todos, fmt.Sprintf("job %q: CircleCI filters cannot select %s events", job.ID, event))
+			}
+		}
+		if c.Expr != "" {
+			*todos = append(*todos, fmt.Sprintf("job %q: condition not translated: %s", job.ID, c.Expr))
+		}
+		if len(branches) == 0 && len(tags) == 0 {
+			branches = t.PushBranches // Nothing the filters can express
+		}
+	}
+
+	filters := yamlMapping()
+	switch {
+	case len(branches) == 0:
+		only := yamlMapping()
+		yamlSet(only, "ignore", yamlString("/.*/"))
+		yamlSet(filters, "branches", only)
+	case !slices.Contains(branches, "*") && !slices.Contains(branches, "**"):
+		only := yamlMapping()
+		yamlSet(only, "only", yamlList(circleciPatterns(branches)))
+		yamlSet(filters, "branches", only)
+	}
+	if len(tags) > 0 {
+		only := yamlMapping()
+		yamlSet(only, "only", yamlList(circleciPatterns(tags)))
+		yamlSet(filters, "tags", only)
+	}
+	if len(filters.Content) == 0 {
+		return nil
+	}
+	return filters
+}
+
+// circleciPatterns turns globs into the /regex/ filters CircleCI accepts
+func circleciPatterns(patterns []string) []string {
+	var mapped []string
+	for _, pattern := range patterns {
+		if strings.ContainsAny(pattern, "*?") {
+			pattern = "/" + strings.TrimSuffix(strings.TrimPrefix(globRegexp(pattern), "^"), "$") + "/"
+		}
+		mapped = append(mapped, pattern)
+	}
+	return mapped
+}
+
+func circleciJobNode(p Pipeline, job PipelineJob, downloaded map[string]bool) *yaml.Node {
+	node := yamlMapping()
+	todos := append([]string(nil), job.TODOs...)
+
+	image := job.Image
+	runtime, uncovered := runtimeImage(job)
+	if image == "" {
+		image = runtime
+	} else {
+		uncovered = nil
+		for _, step := range job.Steps {
+			if step.Kind == StepSetup {
+				uncovered = append(uncovered, step)
+			}
+		}
+	}
+	for _, step := range uncovered {
+		todos = append(todos, fmt.Sprintf("no image provides %s %s; install it in a run step", step.Runtime, step.Version))
+	}
+	if image == "" {
+		image = "cimg/base:stable"
+	}
+
+	var machine string
+	var labels []string
+	for _, label := range job.RunsOn {
+		if os, hosted := hostedRunner(label); hosted {
+			if os != "ubuntu" {
+				machine = os
+			}
+		} else if label != "self-hosted" {
+			labels = append(labels, label)
+		}
+	}
+	switch {
+	case machine == "macos":
+		macos := yamlMapping()
+		yamlSet(macos, "xcode", yamlString("15.4.0"))
+		yamlSet(node, "macos", macos)
+		todos = append(todos, "runs on macOS: pick the Xcode version the project needs")
+	case machine == "windows":
+		todos = append(todos, "runs on Windows: use the circleci/windows orb's executor")
+		yamlSet(node, "docker", circleciContainers(image, job.Services, &todos))
+	case len(labels) > 0:
+		yamlSet(node, "machine", yamlBool(true))
+		yamlSet(node, "resource_class", yamlString("<namespace>/"+strings.Join(labels, "-")))
+		todos = append(todos, "self-hosted runner: set resource_class to your runner's namespace/class")
+	default:
+		yamlSet(node, "docker", circleciContainers(image, job.Services, &todos))
+	}
+
+	if len(job.Matrix) > 0 {
+		parameters := yamlMapping()
+		for _, axis := range job.Matrix {
+			parameter := yamlMapping()
+			yamlSet(parameter, "type", yamlString("string"))
+			yamlSet(parameters, axis.Name, parameter)
+		}
+		yamlSet(node, "parameters", parameters)
+	}
+	if job.Environment != "" {
+		todos = append(todos, fmt.Sprintf("CircleCI has no deployment environments; restrict the %q secrets with a context", job.Environment))
+	}
+	if job.AllowFailure {
+		todos = append(todos, "CircleCI cannot let a job fail without failing the workflow; add || true to the commands that may fail")
+	}
+	if job.When == "failure" || job.When == "always" {
+		todos = append(todos, "CircleCI only runs a job when the jobs it requires succeed; move these steps to when: "+map[string]string{"failure": "on_fail", "always": "always"}[job.When]+" steps of those jobs")
+	}
+
+	// environment: values are literal; values with variable references are exported at the start of the job
+	literal, exported := circleciEnv(append(append([]PipelineVar(nil), p.Env...), job.Env...), &todos)
+	if len(literal) > 0 {
+		yamlSet(node, "environment", yamlVars(literal))
+	}
+	if job.WorkingDirectory != "" {
+		yamlSet(node, "working_directory", yamlString("~/project/"+job.WorkingDirectory))
+	}
+
+	steps := &yaml.Node{Kind: yaml.SequenceNode}
+	var saves []*yaml.Node
+	exportedAt := 0
+	for _, step := range job.Steps {
+		step = portable(step)
+		switch step.Kind {
+		case StepCheckout:
+			steps.Content = append(steps.Content, yamlString("checkout"))
+			exportedAt = len(steps.Content)
+		case StepSetup:
+			// The image provides the runtime
+		case StepCache:
+			restore, save := circleciCache(step, &todos)
+			steps.Content = append(steps.Content, restore)
+			saves = append(saves, save)
+		case StepUpload:
+			for _, p := range step.Paths {
+				dir := p
+				if glob := strings.IndexAny(p, "*?["); glob >= 0 {
+					dir = path.Dir(p[:glob] + "x")
+				}
+				store := yamlMapping()
+				yamlSet(store, "path", yamlString(dir))
+				steps.Content = append(steps.Content, circleciStepNode("store_artifacts", store))
+			}
+			if downloaded[step.Artifact] {
+				persist := yamlMapping()
+				yamlSet(persist, "root", yamlString("."))
+				yamlSet(persist, "paths", yamlList(step.Paths))
+				steps.Content = append(steps.Content, circleciStepNode("persist_to_workspace", persist))
+			}
+		case StepDownload:
+			attach := yamlMapping()
+			yamlSet(attach, "at", yamlString("."))
+			steps.Content = append(steps.Content, circleciStepNode("attach_workspace", attach))
+		case StepRun:
+			steps.Content = append(steps.Content, circleciRunStep(step, job.TimeoutMinutes, &todos))
+		default:
+			entry := todoEntry(step)
+			run := yamlMapping()
+			yamlSet(run, "name", yamlString("TODO: "+step.Name))
+			yamlSet(run, "command", yamlString(entry.Value))
+			wrapped := circleciStepNode("run", run)
+			wrapped.HeadComment = entry.HeadComment
+			steps.Content = append(steps.Content, wrapped)
+		}
+	}
+	steps.Content = append(steps.Content, saves...)
+	if len(exported) > 0 {
+		var b strings.Builder
+		for _, v := range exported {
+			fmt.Fprintf(&b, "echo %s >> \"$BASH_ENV\"\n", shellSingleQuote("export "+v.Name+"="+shellDoubleQuote(v.Value)))
+		}
+		run := yamlMapping()
+		yamlSet(run, "name", yamlString("Set environment"))
+		yamlSet(run, "command", yamlString(b.String()))
+		step := circleciStepNode("run", run)
+		steps.Content = append(steps.Content[:exportedAt], append([]*yaml.Node{step}, steps.Content[exportedAt:]...)...)
+	}
+	yamlSet(node, "steps", steps)
+
+	if len(todos) > 0 {
+		node.HeadComment = todoComment(todos)
+	}
+	return node
+}
+
+// circleciContainers lists the job image followed by its service containers, which share localhost
+func circleciContainers(image string, services []PipelineService, todos *[]string) *yaml.Node {
+	docker := &yaml.Node{Kind: yaml.SequenceNode}
+	primary := yamlMapping()
+	image, _ = circleciDialect.value(image)
+	yamlSet(primary, "image", yamlString(image))
+	docker.Content = append(docker.Content, primary)
+	for _, svc := range services {
+		container := yamlMapping()
+		yamlSet(container, "image", yamlString(svc.Image))
+		yamlSet(container, "name", yamlString(svc.Name))
+		if env := circleciDialect.env(svc.Env, todos); len(env) > 0 {
+			yamlSet(container, "environment", yamlVars(env))
+		}
+		docker.Content = append(docker.Content, container)
+	}
+	return docker
+}
+
+// circleciEnv splits variables into literal values and values that reference other variables
+func circleciEnv(vars []PipelineVar, todos *[]string) (literal, exported []PipelineVar) {
+	for _, v := range circleciDialect.env(vars, todos) {
+		if strings.Contains(strings.ReplaceAll(v.Value, "<< parameters.", ""), "$") {
+			exported = setVar(exported, v.Name, v.Value)
+		} else {
+			literal = setVar(literal, v.Name, v.Value)
+		}
+	}
+	return literal, exported
+}
+
+func circleciRunStep(step PipelineStep, timeout int, todos *[]string) *yaml.Node {
+	run := yamlMapping()
+	if step.Name != "" {
+		yamlSet(run, "name", yamlString(step.Name))
+	}
+	command := circleciDialect.script(step.Run)
+	literal, exported := circleciEnv(step.Env, todos)
+	for i := len(exported) - 1; i >= 0; i-- {
+		command = fmt.Sprintf("export %s=%s\n", exported[i].Name, shellDoubleQuote(exported[i].Value)) + command
+	}
+	if step.ContinueOnError {
+		command = "(\n" + indentLines(command, "  ") + ") || true\n"
+	}
+	yamlSet(run, "command", yamlString(command))
+	if len(literal) > 0 {
+		yamlSet(run, "environment", yamlVars(literal))
+	}
+	if step.WorkingDirectory != "" {
+		yamlSet(run, "working_directory", yamlString(step.WorkingDirectory))
+	}
+	switch step.When {
+	case "always":
+		yamlSet(run, "when", yamlString("always"))
+	case "failure":
+		yamlSet(run, "when", yamlString("on_fail"))
+	}
+	if timeout > 0 {
+		yamlSet(run, "no_output_timeout", yamlString(fmt.Sprintf("%dm", timeout)))
+	}
+	node := circleciStepNode("run", run)
+	node.HeadComment = step.Comment
+	return node
+}
+
+// circleciCache restores a cache where the step is and saves it at the end of the job
+func circleciCache(step PipelineStep, todos *[]string) (restore, save *yaml.Node) {
+	literal, files := parseCacheKey(step.Key)
+	literal, _ = circleciDialect.value(literal)
+	key := literal
+	for _, file := range files {
+		if strings.ContainsAny(file, "*?[") {
+			*todos = append(*todos, fmt.Sprintf("cache key: checksum needs a file path, not the pattern %q", file))
+			continue
+		}
+		key += fmt.Sprintf(`-{{ checksum "%s" }}`, file)
+	}
+	key = strings.TrimPrefix(key, "-")
+	if key == "" {
+		key = "cache-{{ .Branch }}"
+	}
+
+	keys := yamlMapping()
+	yamlSet(keys, "keys", yamlList([]string{key}))
+	saved := yamlMapping()
+	yamlSet(saved, "key", yamlString(key))
+	yamlSet(saved, "paths", yamlList(step.Paths))
+	return circleciStepNode("restore_cache", keys), circleciStepNode("save_cache", saved)
+}
+
+func circleciStepNode(name string, settings *yaml.Node) *yaml.Node {
+	step := yamlMapping()
+	yamlSet(step, name, settings)
+	return step
+}
@@ -0,0 +1,390 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// setupActions install a language runtime on a GitHub-hosted runner
+var setupActions = map[string]struct{ uses, input string }{
+	"go":     {"actions/setup-go@v5", "go-version"},
+	"node":   {"actions/setup-node@v4", "node-version"},
+	"python": {"actions/setup-python@v5", "python-version"},
+	"java":   {"actions/setup-java@v4", "java-version"},
+	"ruby":   {"ruby/setup-ruby@v1", "ruby-version"},
+}
+
+// RenderGitHubActions renders a pipeline as a GitHub Actions workflow
+func RenderGitHubActions(p Pipeline) (string, error) {
+	root := yamlMapping()
+	if len(p.TODOs) > 0 {
+		root.HeadComment = todoComment(p.TODOs)
+	}
+	if p.Name != "" {
+		yamlSet(root, "name", yamlString(p.Name))
+	}
+	yamlSet(root, "on", githubTriggers(p.Triggers))
+	if len(p.Env) > 0 {
+		yamlSet(root, "env", yamlVars(p.Env))
+	}
+
+	jobs := yamlMapping()
+	for _, job := range p.Jobs {
+		yamlSet(jobs, job.ID, githubJob(job))
+	}
+	yamlSet(root, "jobs", jobs)
+	return encodeYAML(root)
+}
+
+// encodeYAML renders a document built with the yaml* builders
+func encodeYAML(root *yaml.Node) (string, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return "", fmt.Errorf("failed to render configuration: %w", err)
+	}
+	encoder.Close()
+	return buf.String(), nil
+}
+
+func githubTriggers(t PipelineTriggers) *yaml.Node {
+	on := yamlMapping()
+	if len(t.PushBranches) > 0 || len(t.Tags) > 0 {
+		push := yamlMapping()
+		if len(t.PushBranches) > 0 {
+			yamlSet(push, "branches", yamlList(githubPatterns(t.PushBranches)))
+		}
+		if len(t.Tags) > 0 {
+			yamlSet(push, "tags", yamlList(githubPatterns(t.Tags)))
+		}
+		yamlSet(on, "push", push)
+	}
+	if t.PullRequests {
+		yamlSet(on, "pull_request", yamlNull())
+	}
+	if len(t.Schedules) > 0 {
+		schedule := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, cron := range t.Schedules {
+			entry := yamlMapping()
+			yamlSet(entry, "cron", yamlString(cron))
+			schedule.Content = append(schedule.Content, entry)
+		}
+		yamlSet(on, "schedule", schedule)
+	}
+	if t.Manual || len(on.Content) == 0 {
+		yamlSet(on, "workflow_dispatch", yamlNull())
+	}
+	return on
+}
+
+// githubPatterns maps "*" (any branch or tag) to GitHub's "**", which also matches slashes
+func githubPatterns(patterns []string) []string {
+	mapped := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		if pattern == "*" {
+			pattern = "**"
+		}
+		mapped = append(mapped, pattern)
+	}
+	return mapped
+}
+
+func githubJob(job PipelineJob) *yaml.Node {
+	node := yamlMapping()
+	todos := job.TODOs
+
+	if job.Name != "" && job.Name != job.ID {
+		yamlSet(node, "name", yamlString(job.Name))
+	}
+	switch len(job.RunsOn) {
+	case 0:
+		yamlSet(node, "runs-on", yamlString("ubuntu-latest"))
+	case 1:
+		yamlSet(node, "runs-on", yamlString(job.RunsOn[0]))
+	default:
+		yamlSet(node, "runs-on", yamlList(job.RunsOn))
+	}
+	if len(job.Needs) > 0 {
+		yamlSet(node, "needs", yamlList(job.Needs))
+	}
+	condition := ""
+	if job.If != nil {
+		expr, todo := githubCondition(*job.If)
+		condition = expr
+		if todo != "" {
+			todos = append(todos, todo)
+		}
+	}
+	if status := githubJobStatus[job.When]; status != "" && condition != "" {
+		condition = status + " && (" + condition + ")"
+	} else if status != "" {
+		condition = status
+	}
+	if condition != "" {
+		yamlSet(node, "if", yamlString(condition))
+	}
+
+	environment := job.Environment
+	if job.Approval {
+		if environment == "" {
+			environment = job.ID
+		}
+		todos = append(todos, fmt.Sprintf("add required reviewers to the %q environment; the source waited for a manual approval here", environment))
+	}
+	if environment != "" {
+		yamlSet(node, "environment", yamlString(environment))
+	}
+	if job.TimeoutMinutes > 0 {
+		yamlSet(node, "timeout-minutes", yamlInt(job.TimeoutMinutes))
+	}
+	if job.AllowFailure {
+		yamlSet(node, "continue-on-error", yamlBool(true))
+	}
+	if len(job.Matrix) > 0 {
+		matrix := yamlMapping()
+		for _, axis := range job.Matrix {
+			yamlSet(matrix, axis.Name, yamlList(axis.Values))
+		}
+		strategy := yamlMapping()
+		yamlSet(strategy, "fail-fast", yamlBool(false))
+		yamlSet(strategy, "matrix", matrix)
+		yamlSet(node, "strategy", strategy)
+	}
+	if job.Image != "" {
+		yamlSet(node, "container", yamlString(job.Image))
+	}
+	if len(job.Services) > 0 {
+		services := yamlMapping()
+		for _, svc := range job.Services {
+			service := yamlMapping()
+			yamlSet(service, "image", yamlString(svc.Image))
+			if len(svc.Env) > 0 {
+				yamlSet(service, "env", yamlVars(svc.Env))
+			}
+			if len(svc.Ports) > 0 {
+				yamlSet(service, "ports", yamlList(svc.Ports))
+			}
+			yamlSet(services, svc.Name, service)
+		}
+		yamlSet(node, "services", services)
+	}
+	if len(job.Env) > 0 {
+		yamlSet(node, "env", yamlVars(job.Env))
+	}
+	if job.WorkingDirectory != "" {
+		run := yamlMapping()
+		yamlSet(run, "working-directory", yamlString(job.WorkingDirectory))
+		defaults := yamlMapping()
+		yamlSet(defaults, "run", run)
+		yamlSet(node, "defaults", defaults)
+	}
+
+	cached := slices.ContainsFunc(job.Steps, func(step PipelineStep) bool { return step.Kind == StepCache })
+	steps := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, step := range job.Steps {
+		node := githubStep(step)
+		if cached && step.Kind == StepSetup && step.Runtime == "go" {
+			// setup-go caches the module and build caches by default, which the cache step already restores
+			with := mappingValue(node, "with")
+			if with == nil {
+				with = yamlMapping()
+				yamlSet(node, "with", with)
+			}
+			yamlSet(with, "cache", yamlBool(false))
+		}
+		steps.Content = append(steps.Content, node)
+	}
+	yamlSet(node, "steps", steps)
+
+	if len(todos) > 0 {
+		node.HeadComment = todoComment(todos)
+	}
+	return node
+}
+
+// githubJobStatus are the status checks for a job's When; without one, a job
+// only runs when every job it needs succeeded
+var githubJobStatus = map[string]string{"success": "!failure() && !cancelled()", "failure": "failure()", "always": "always()"}
+
+// githubCondition builds an if: expression; todo explains what could not be translated
+func githubCondition(c PipelineCondition) (expr string, todo string) {
+	var parts []string
+	if c.DefaultBranch {
+		parts = append(parts, "github.ref_name == github.event.repository.default_branch")
+	}
+	for _, branch := range c.Branches {
+		parts = append(parts, githubRefCondition("refs/heads/", branch))
+	}
+	for _, tag := range c.Tags {
+		parts = append(parts, githubRefCondition("refs/tags/", tag))
+	}
+	events := map[string]string{"push": "push", "pull_request": "pull_request", "schedule": "schedule", "manual": "workflow_dispatch"}
+	for _, event := range c.Events {
+		if name, ok := events[event]; ok {
+			parts = append(parts, fmt.Sprintf("github.event_name == '%s'", name))
+		}
+	}
+	if c.Expr != "" {
+		todo = "condition not translated: " + c.Expr
+	}
+	return strings.Join(parts, " || "), todo
+}
+
+// githubRefCondition matches a branch or tag pattern; only trailing wildcards are supported
+func githubRefCondition(prefix, pattern string) string {
+	if pattern == "*" || pattern == "**" {
+		return fmt.Sprintf("startsWith(github.ref, '%s')", prefix)
+	}
+	if strings.HasSuffix(pattern, "*") && !strings.ContainsAny(strings.TrimRight(pattern, "*"), "*?[") {
+		return fmt.Sprintf("startsWith(github.ref, '%s%s')", prefix, strings.TrimRight(pattern, "*"))
+	}
+	return fmt.Sprintf("github.ref == '%s%s'", prefix, pattern)
+}
+
+func githubStep(step PipelineStep) *yaml.Node {
+	node := yamlMapping()
+	name := step.Name
+	with := append([]PipelineVar(nil), step.With...)
+
+	switch step.Kind {
+	case StepCheckout:
+		yamlSet(node, "uses", yamlString("actions/checkout@v4"))
+	case StepSetup:
+		action, ok := setupActions[step.Runtime]
+		if !ok {
+			return githubStep(PipelineStep{Kind: StepTODO, Name: "set up " + step.Runtime + " " + step.Version,
+				Source: "setup " + step.Runtime + " " + step.Version, Comment: step.Comment})
+		}
+		if name == "" {
+			name = fmt.Sprintf("Set up %s %s", step.Runtime, step.Version)
+		}
+		yamlSet(node, "uses", yamlString(action.uses))
+		if step.Runtime == "java" {
+			with = setVar(with, "distribution", "temurin")
+		}
+		if step.Version != "" {
+			with = setVar(with, action.input, step.Version)
+		}
+	case StepCache:
+		yamlSet(node, "uses", yamlString("actions/cache@v4"))
+		with = setVar(with, "path", strings.Join(step.Paths, "\n"))
+		with = setVar(with, "key", step.Key)
+	case StepUpload:
+		yamlSet(node, "uses", yamlString("actions/upload-artifact@v4"))
+		with = setVar(with, "name", step.Artifact)
+		with = setVar(with, "path", strings.Join(step.Paths, "\n"))
+	case StepDownload:
+		yamlSet(node, "uses", yamlString("actions/download-artifact@v4"))
+		with = setVar(with, "name", step.Artifact)
+		if len(step.Paths) > 0 {
+			with = setVar(with, "path", step.Paths[0])
+		}
+	case StepAction:
+		yamlSet(node, "uses", yamlString(step.Uses))
+	case StepTODO:
+		name = "TODO: " + step.Name
+		message := strings.ReplaceAll(fmt.Sprintf("Not migrated: %s", step.Name), `"`, `'`)
+		yamlSet(node, "run", yamlString(fmt.Sprintf("echo \"::error::%s\"\nexit 1\n", message)))
+		comment := "TODO(fluxion): translate by hand, or rerun with --ai. Source:"
+		for _, line := range strings.Split(strings.TrimRight(step.Source, "\n"), "\n") {
+			comment += "\n  " + line
+		}
+		if step.Comment != "" {
+			comment = step.Comment + "\n" + comment
+		}
+		node.HeadComment = comment
+	default:
+		yamlSet(node, "run", yamlString(step.Run))
+	}
+
+	// name goes first, as workflows are usually written
+	if name != "" {
+		node.Content = append([]*yaml.Node{{Kind: yaml.ScalarNode, Value: "name"}, yamlString(name)}, node.Content...)
+	}
+	switch step.When {
+	case "always":
+		yamlSet(node, "if", yamlString("always()"))
+	case "failure":
+		yamlSet(node, "if", yamlString("failure()"))
+	}
+	if len(with) > 0 {
+		yamlSet(node, "with", yamlVars(with))
+	}
+	if len(step.Env) > 0 {
+		yamlSet(node, "env", yamlVars(step.Env))
+	}
+	if step.WorkingDirectory != "" {
+		yamlSet(node, "working-directory", yamlString(step.WorkingDirectory))
+	}
+	if step.ContinueOnError {
+		yamlSet(node, "continue-on-error", yamlBool(true))
+	}
+	if step.Comment != "" && node.HeadComment == "" {
+		node.HeadComment = step.Comment
+	}
+	return node
+}
+
+// todoComment formats notes for review as YAML comment lines
+func todoComment(todos []string) string {
+	lines := make([]string, 0, len(todos))
+	for _, todo := range todos {
+		lines = append(lines, "TODO(fluxion): "+todo)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// YAML node builders, for output that keeps key order and comments
+
+func yamlMapping() *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode}
+}
+
+// yamlSet appends key: value to a mapping
+func yamlSet(mapping *yaml.Node, key string, value *yaml.Node) {
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+}
+
+// yamlString is a string scalar; the encoder quotes values that would read as another type
+func yamlString(value string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+	if strings.Contains(strings.TrimRight(value, "\n"), "\n") {
+		node.Style = yaml.LiteralStyle
+	}
+	return node
+}
+
+func yamlInt(value int) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(value)}
+}
+
+func yamlBool(value bool) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(value)}
+}
+
+func yamlNull() *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}
+}
+
+// yamlList is a flow-style list of strings, e.g. [main, develop]
+func yamlList(values []string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+	for _, value := range values {
+		node.Content = append(node.Content, yamlString(value))
+	}
+	return node
+}
+
+func yamlVars(vars []PipelineVar) *yaml.Node {
+	node := yamlMapping()
+	for _, v := range vars {
+		yamlSet(node, v.Name, yamlString(v.Value))
+	}
+	return node
+}
@@ -0,0 +1,377 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// gitlabDialect spells the pipeline's variables as GitLab CI's predefined ones
+var gitlabDialect = platformDialect{
+	vars: map[string]string{
+		"GITHUB_SHA":        "CI_COMMIT_SHA",
+		"GITHUB_REF_NAME":   "CI_COMMIT_REF_NAME",
+		"GITHUB_WORKSPACE":  "CI_PROJECT_DIR",
+		"GITHUB_RUN_NUMBER": "CI_PIPELINE_IID",
+		"GITHUB_RUN_ID":     "CI_PIPELINE_ID",
+		"GITHUB_REPOSITORY": "CI_PROJECT_PATH",
+		"GITHUB_SERVER_URL": "CI_SERVER_URL",
+		"GITHUB_ACTOR":      "GITLAB_USER_LOGIN",
+		"GITHUB_JOB":        "CI_JOB_NAME",
+		"GITHUB_HEAD_REF":   "CI_MERGE_REQUEST_SOURCE_BRANCH_NAME",
+		"GITHUB_BASE_REF":   "CI_MERGE_REQUEST_TARGET_BRANCH_NAME",
+		"GITHUB_EVENT_NAME": "CI_PIPELINE_SOURCE",
+		"RUNNER_TEMP":       "CI_BUILDS_DIR",
+	},
+	// Secrets are CI/CD variables and matrix values are job variables
+	ref: func(context, name string) string { return "$" + name },
+}
+
+// gitlabEvents are the $CI_PIPELINE_SOURCE values of the pipeline's events
+var gitlabEvents = map[string]string{
+	"push": "push", "pull_request": "merge_request_event", "schedule": "schedule", "manual": "web",
+}
+
+// RenderGitLabCI renders a pipeline as a .gitlab-ci.yml. Stages follow the
+// jobs' dependency depth and needs: keeps the exact dependencies.
+func RenderGitLabCI(p Pipeline) (string, error) {
+	root := yamlMapping()
+	todos := append([]string(nil), p.TODOs...)
+
+	yamlSet(root, "workflow", gitlabWorkflow(p.Triggers, &todos))
+
+	levels := jobLevels(p.Jobs)
+	stages := make([]string, 0, len(levels))
+	stageOf := map[string]string{}
+	for _, level := range levels {
+		stage := level[0].ID
+		stages = append(stages, stage)
+		for _, job := range level {
+			stageOf[job.ID] = stage
+		}
+	}
+	yamlSet(root, "stages", yamlList(stages))
+
+	if vars := gitlabDialect.env(p.Env, &todos); len(vars) > 0 {
+		yamlSet(root, "variables", yamlVars(vars))
+	}
+	uploads := map[string]string{}
+	for _, job := range p.Jobs {
+		for _, step := range job.Steps {
+			if step.Kind == StepUpload {
+				uploads[step.Artifact] = job.ID
+			}
+		}
+	}
+	for _, job := range p.Jobs {
+		yamlSet(root, job.ID, gitlabJobNode(p, job, stageOf[job.ID], uploads))
+	}
+
+	if len(todos) > 0 {
+		root.HeadComment = todoComment(todos)
+	}
+	return encodeYAML(root)
+}
+
+// gitlabWorkflow decides which pipelines are created, from the pipeline triggers
+func gitlabWorkflow(t PipelineTriggers, todos *[]string) *yaml.Node {
+	rules := &yaml.Node{Kind: yaml.SequenceNode}
+	rule := func(expr, when string) {
+		entry := yamlMapping()
+		yamlSet(entry, "if", yamlString(expr))
+		if when != "" {
+			yamlSet(entry, "when", yamlString(when))
+		}
+		rules.Content = append(rules.Content, entry)
+	}
+	if t.PullRequests {
+		rule(`$CI_PIPELINE_SOURCE == "merge_request_event"`, "")
+		if len(t.PushBranches) > 0 {
+			// Run merge request pipelines instead of branch pipelines while a merge request is open
+			rule(`$CI_COMMIT_BRANCH && $CI_OPEN_MERGE_REQUESTS`, "never")
+		}
+	}
+	if len(t.PushBranches) > 0 {
+		rule(gitlabRefExpr("$CI_COMMIT_BRANCH", t.PushBranches), "")
+	}
+	if len(t.Tags) > 0 {
+		rule(gitlabRefExpr("$CI_COMMIT_TAG", t.Tags), "")
+	}
+	if len(t.Schedules) > 0 {
+		rule(`$CI_PIPELINE_SOURCE == "schedule"`, "")
+		*todos = append(*todos, "create pipeline schedules (Build > Pipeline schedules) for: "+strings.Join(t.Schedules, ", "))
+	}
+	if t.Manual {
+		rule(`$CI_PIPELINE_SOURCE == "web"`, "")
+	}
+	workflow := yamlMapping()
+	yamlSet(workflow, "rules", rules)
+	return workflow
+}
+
+// gitlabRefExpr matches a branch or tag variable against patterns; "*" matches any
+func gitlabRefExpr(variable string, patterns []string) string {
+	var exprs []string
+	for _, pattern := range patterns {
+		switch {
+		case pattern == "*" || pattern == "**":
+			return variable
+		case strings.ContainsAny(pattern, "*?"):
+			exprs = append(exprs, variable+" =~ /"+strings.ReplaceAll(globRegexp(pattern), "/", `\/`)+"/")
+		default:
+			exprs = append(exprs, fmt.Sprintf("%s == %q", variable, pattern))
+		}
+	}
+	return strings.Join(exprs, " || ")
+}
+
+func gitlabJobNode(p Pipeline, job PipelineJob, stage string, uploads map[string]string) *yaml.Node {
+	node := yamlMapping()
+	todos := append([]string(nil), job.TODOs...)
+	yamlSet(node, "stage", yamlString(stage))
+
+	image := job.Image
+	runtime, uncovered := runtimeImage(job)
+	if image == "" {
+		image = runtime
+	} else {
+		uncovered = nil
+		for _, step := range job.Steps {
+			if step.Kind == StepSetup {
+				uncovered = append(uncovered, step)
+			}
+		}
+	}
+	for _, step := range uncovered {
+		todos = append(todos, fmt.Sprintf("no image provides %s %s; install it in before_script", step.Runtime, step.Version))
+	}
+	if image != "" {
+		image, _ = gitlabDialect.value(image)
+		yamlSet(node, "image", yamlString(image))
+	}
+
+	var tags []string
+	for _, label := range job.RunsOn {
+		if os, hosted := hostedRunner(label); hosted {
+			if os != "ubuntu" {
+				todos = append(todos, fmt.Sprintf("runs-on %s: use a %s runner (e.g. GitLab's saas-%s-* tags)", label, os, os))
+			}
+		} else if label != "self-hosted" {
+			tags = append(tags, label)
+		}
+	}
+	if len(tags) > 0 {
+		yamlSet(node, "tags", yamlList(tags))
+	}
+	if len(job.Needs) > 0 {
+		yamlSet(node, "needs", yamlList(job.Needs))
+	}
+
+	environment := job.Environment
+	if job.Approval && environment == "" {
+		environment = job.ID
+	}
+	when := gitlabJobWhen[job.When]
+	if job.Approval {
+		when = "manual"
+	}
+	if job.If != nil {
+		yamlSet(node, "rules", gitlabJobRules(*job.If, when, &todos))
+	} else if when != "on_success" {
+		yamlSet(node, "when", yamlString(when))
+		if job.Approval {
+			yamlSet(node, "allow_failure", yamlBool(false)) // Block the pipeline until it is run
+		}
+	}
+	if len(job.Matrix) > 0 {
+		axes := yamlMapping()
+		for _, axis := range job.Matrix {
+			yamlSet(axes, axis.Name, yamlList(axis.Values))
+		}
+		parallel := yamlMapping()
+		yamlSet(parallel, "matrix", &yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{axes}})
+		yamlSet(node, "parallel", parallel)
+	}
+
+	// GitLab reaches services by alias, not on localhost
+	vars := gitlabDialect.env(job.Env, &todos)
+	if len(job.Services) > 0 {
+		for _, v := range append(append([]PipelineVar(nil), p.Env...), job.Env...) {
+			if hosted := serviceHost(v.Value, job.Services); hosted != v.Value {
+				vars = setVar(vars, v.Name, hosted)
+			}
+		}
+		for _, svc := range job.Services {
+			vars = append(vars, gitlabDialect.env(svc.Env, &todos)...)
+		}
+	}
+	// GitLab only caches paths in the project directory, so tool caches move there
+	for _, step := range job.Steps {
+		if step.Kind != StepCache {
+			continue
+		}
+		_, cacheVars, _ := projectCachePaths(step.Paths, "$CI_PROJECT_DIR")
+		for _, v := range cacheVars {
+			if !hasVar(vars, v.Name) {
+				vars = append(vars, v)
+			}
+		}
+	}
+	if len(vars) > 0 {
+		yamlSet(node, "variables", yamlVars(vars))
+	}
+	if len(job.Services) > 0 {
+		services := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, svc := range job.Services {
+			service := yamlMapping()
+			yamlSet(service, "name", yamlString(svc.Image))
+			yamlSet(service, "alias", yamlString(svc.Name))
+			services.Content = append(services.Content, service)
+		}
+		yamlSet(node, "services", services)
+	}
+	if environment != "" {
+		yamlSet(node, "environment", yamlString(environment))
+	}
+	if job.AllowFailure {
+		yamlSet(node, "allow_failure", yamlBool(true))
+	}
+	if job.TimeoutMinutes > 0 {
+		yamlSet(node, "timeout", yamlString(fmt.Sprintf("%d minutes", job.TimeoutMinutes)))
+	}
+
+	caches := &yaml.Node{Kind: yaml.SequenceNode}
+	script := &yaml.Node{Kind: yaml.SequenceNode}
+	afterScript := &yaml.Node{Kind: yaml.SequenceNode}
+	var artifacts []string
+	artifactsWhen := ""
+	for _, step := range job.Steps {
+		step = portable(step)
+		switch step.Kind {
+		case StepCheckout, StepSetup:
+			// GitLab clones the repository, and the image provides the runtime
+		case StepCache:
+			caches.Content = append(caches.Content, gitlabCache(step, &todos))
+		case StepUpload:
+			artifacts = append(artifacts, step.Paths...)
+			if step.When == "always" || artifactsWhen == "" && step.When == "failure" {
+				artifactsWhen = map[string]string{"always": "always", "failure": "on_failure"}[step.When]
+			}
+		case StepDownload:
+			if from, ok := uploads[step.Artifact]; ok && !slices.Contains(job.Needs, from) {
+				todos = append(todos, fmt.Sprintf("artifact %q comes from %q, which this job does not need", step.Artifact, from))
+			}
+		case StepRun:
+			entry := yamlString(gitlabDialect.shellCommand(step, job.WorkingDirectory, &todos))
+			entry.HeadComment = step.Comment
+			switch step.When {
+			case "always":
+				afterScript.Content = append(afterScript.Content, entry)
+			case "failure":
+				entry.Value = "if [ \"$CI_JOB_STATUS\" = \"failed\" ]; then\n" + indentLines(entry.Value, "  ") + "fi\n"
+				entry.Style = yaml.LiteralStyle
+				afterScript.Content = append(afterScript.Content, entry)
+			default:
+				script.Content = append(script.Content, entry)
+			}
+		default:
+			script.Content = append(script.Content, todoEntry(step))
+		}
+	}
+	if len(caches.Content) > 0 {
+		yamlSet(node, "cache", caches)
+	}
+	if len(script.Content) == 0 {
+		script.Content = append(script.Content, yamlString("true"))
+	}
+	yamlSet(node, "script", script)
+	if len(afterScript.Content) > 0 {
+		yamlSet(node, "after_script", afterScript)
+	}
+	if len(artifacts) > 0 {
+		artifact := yamlMapping()
+		yamlSet(artifact, "paths", yamlList(artifacts))
+		if artifactsWhen != "" {
+			yamlSet(artifact, "when", yamlString(artifactsWhen))
+		}
+		yamlSet(node, "artifacts", artifact)
+	}
+
+	if len(todos) > 0 {
+		node.HeadComment = todoComment(todos)
+	}
+	return node
+}
+
+// gitlabJobWhen maps a job's When to GitLab's when:
+var gitlabJobWhen = map[string]string{"": "on_success", "success": "on_success", "failure": "on_failure", "always": "always"}
+
+// gitlabJobRules runs a job when any part of its condition matches, with when
+// as each rule's when:, e.g. manual for an approval
+func gitlabJobRules(c PipelineCondition, when string, todos *[]string) *yaml.Node {
+	var exprs []string
+	if c.DefaultBranch {
+		exprs = append(exprs, "$CI_COMMIT_BRANCH == $CI_DEFAULT_BRANCH")
+	}
+	if len(c.Branches) > 0 {
+		exprs = append(exprs, gitlabRefExpr("$CI_COMMIT_BRANCH", c.Branches))
+	}
+	if len(c.Tags) > 0 {
+		exprs = append(exprs, gitlabRefExpr("$CI_COMMIT_TAG", c.Tags))
+	}
+	for _, event := range c.Events {
+		if source, ok := gitlabEvents[event]; ok {
+			exprs = append(exprs, fmt.Sprintf("$CI_PIPELINE_SOURCE == %q", source))
+		}
+	}
+	if c.Expr != "" {
+		*todos = append(*todos, "condition not translated: "+c.Expr)
+	}
+
+	rules := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, expr := range exprs {
+		rule := yamlMapping()
+		yamlSet(rule, "if", yamlString(expr))
+		if when != "on_success" {
+			yamlSet(rule, "when", yamlString(when))
+		}
+		rules.Content = append(rules.Content, rule)
+	}
+	if len(exprs) == 0 {
+		rule := yamlMapping()
+		yamlSet(rule, "when", yamlString(when))
+		rules.Content = append(rules.Content, rule)
+	}
+	return rules
+}
+
+// gitlabCache keys a cache on up to two files, which is all cache:key:files allows.
+// Its paths are the ones projectCachePaths moves into the project directory.
+func gitlabCache(step PipelineStep, todos *[]string) *yaml.Node {
+	cache := yamlMapping()
+	literal, files := parseCacheKey(step.Key)
+	literal, _ = gitlabDialect.value(literal)
+	literal = rewriteVars(literal, gitlabDialect.vars)
+	if len(files) > 0 {
+		if len(files) > 2 {
+			*todos = append(*todos, "GitLab keys a cache on at most two files; dropped "+strings.Join(files[2:], ", "))
+			files = files[:2]
+		}
+		key := yamlMapping()
+		if literal != "" {
+			yamlSet(key, "prefix", yamlString(literal))
+		}
+		yamlSet(key, "files", yamlList(files))
+		yamlSet(cache, "key", key)
+	} else if literal != "" {
+		yamlSet(cache, "key", yamlString(literal))
+	}
+	paths, _, outside := projectCachePaths(step.Paths, "$CI_PROJECT_DIR")
+	for _, dir := range outside {
+		*todos = append(*todos, fmt.Sprintf("GitLab only caches paths in the project directory; point the tool's cache at one instead of %s", dir))
+	}
+	yamlSet(cache, "paths", yamlList(paths))
+	return cache
+}
@@ -0,0 +1,240 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// EnvVar is an environment variable the application needs
+type EnvVar struct {
+	Name    string   `json:"name,omitempty"`    // Variable name, e.g. "DATABASE_URL"
+	Secret  bool     `json:"secret"`            // Looks like a credential: wire it from secrets, not vars
+	Sources []string `json:"sources,omitempty"` // Where it was found, e.g. ".env.example", "internal/config/config.go"
+}
+
+// envExampleFiles document the variables an application expects
+var envExampleFiles = []string{".env.example", ".env.sample", ".env.template", ".env.dist", "example.env", "sample.env"}
+
+// envUsagePatterns find environment lookups in source code, keyed by file extension
+var envUsagePatterns = map[string][]*regexp.Regexp{
+	".go": {
+		regexp.MustCompile(`os\.(?:Getenv|LookupEnv)\("([A-Za-z_][A-Za-z0-9_]*)"\)`),
+		// Struct tags used by caarlos0/env, kelseyhightower/envconfig and cleanenv
+		regexp.MustCompile(`\b(?:env|envconfig):"([A-Za-z_][A-Za-z0-9_]*)[",]`),
+	},
+	".js":  jsEnvPatterns,
+	".mjs": jsEnvPatterns,
+	".cjs": jsEnvPatterns,
+	".jsx": jsEnvPatterns,
+	".ts":  jsEnvPatterns,
+	".tsx": jsEnvPatterns,
+	".py": {
+		regexp.MustCompile(`os\.environ\[\s*['"]([A-Za-z_][A-Za-z0-9_]*)['"]\s*\]`),
+		regexp.MustCompile(`os\.(?:environ\.get|getenv)\(\s*['"]([A-Za-z_][A-Za-z0-9_]*)['"]`),
+	},
+}
+
+var jsEnvPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`process\.env\.([A-Za-z_][A-Za-z0-9_]*)`),
+	regexp.MustCompile(`process\.env\[\s*['"]([A-Za-z_][A-Za-z0-9_]*)['"]\s*\]`),
+	regexp.MustCompile(`import\.meta\.env\.([A-Za-z_][A-Za-z0-9_]*)`),
+}
+
+// composeInterpolation matches ${VAR}, ${VAR:-default} and $VAR in compose files
+var composeInterpolation = regexp.MustCompile(`\$\{?([A-Za-z_][A-Za-z0-9_]*)`)
+
+// ignoredEnvVars are provided by the OS or the CI runner and never need wiring
+var ignoredEnvVars = map[string]bool{
+	"HOME": true, "PATH": true, "USER": true, "PWD": true, "SHELL": true, "TMPDIR": true,
+	"TEMP": true, "TMP": true, "LANG": true, "TERM": true, "HOSTNAME": true, "CI": true,
+	"NODE_ENV": true, "DEV": true, "PROD": true, "MODE": true, "SSR": true, "BASE_URL": true,
+}
+
+// secretMarkers are name fragments that identify credentials
+var secretMarkers = []string{"SECRET", "TOKEN", "PASSWORD", "PASSWD", "PRIVATE", "CREDENTIAL", "API_KEY", "APIKEY", "ACCESS_KEY", "AUTH", "DSN", "DATABASE_URL", "CONNECTION_STRING"}
+
+// isSecretName reports whether an environment variable name looks like a credential
+func isSecretName(name string) bool {
+	upper := strings.ToUpper(name)
+	if strings.HasSuffix(upper, "_KEY") {
+		return true
+	}
+	for _, marker := range secretMarkers {
+		if strings.Contains(upper, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// DetectEnvVars builds an inventory of the environment variables the application needs
+// from .env examples, source code lookups, config struct tags and compose environments;
+// it stops early when ctx is cancelled
+func DetectEnvVars(ctx context.Context, workingDir string, files *FileIndex, docker DockerContext) []EnvVar {
+	found := map[string]*EnvVar{}
+	add := func(name, source string) {
+		if ignoredEnvVars[name] || strings.HasPrefix(name, "GITHUB_") || strings.HasPrefix(name, "RUNNER_") {
+			return
+		}
+		v, ok := found[name]
+		if !ok {
+			v = &EnvVar{Name: name, Secret: isSecretName(name)}
+			found[name] = v
+		}
+		if len(v.Sources) < 3 {
+			v.Sources = appendUnique(v.Sources, source)
+		}
+	}
+
+	for _, name := range envExampleFiles {
+		content, ok := readFileString(filepath.Join(workingDir, name))
+		if !ok {
+			continue
+		}
+		for _, line := range strings.Split(content, "\n") {
+			line = strings.TrimPrefix(strings.TrimSpace(line), "export ")
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if key, _, ok := strings.Cut(line, "="); ok {
+				add(strings.TrimSpace(key), name)
+			}
+		}
+	}
+
+	for _, rel := range files.Files() {
+		if ctx.Err() != nil {
+			break // The caller reports the cancellation
+		}
+		patterns, ok := envUsagePatterns[filepath.Ext(rel)]
+		if !ok || underDir(rel, "dist", "build") {
+			continue
+		}
+		for _, m := range scanEnvUsage(files.Abs(rel), patterns) {
+			add(m, rel)
+		}
+	}
+
+	// Compose: every key of an application service's environment, plus host
+	// variables interpolated into any service
+	for _, cf := range docker.ComposeFiles {
+		if ctx.Err() != nil {
+			break
+		}
+		compose, err := parseComposeFile(filepath.Join(workingDir, cf.Path))
+		if err != nil {
+			continue
+		}
+		for _, svcName := range compose.serviceNames() {
+			svc := compose.Services[svcName]
+			for key, value := range svc.Environment {
+				if svc.Build.Context != "" {
+					add(key, cf.Path)
+				}
+				for _, m := range composeInterpolation.FindAllStringSubmatch(value, -1) {
+					add(m[1], cf.Path)
+				}
+			}
+		}
+	}
+
+	vars := make([]EnvVar, 0, len(found))
+	for _, v := range found {
+		vars = append(vars, *v)
+	}
+	sort.Slice(vars, func(i, j int) bool { return vars[i].Name < vars[j].Name })
+	return vars
+}
+
+// scanEnvUsage returns the variable names a source file looks up outside comments,
+// skipping files over 1 MiB (bundles, generated code)
+func scanEnvUsage(path string, patterns []*regexp.Regexp) []string {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	if fi, err := file.Stat(); err != nil || fi.Size() > 1<<20 {
+		return nil
+	}
+
+	var names []string
+	hash := filepath.Ext(path) == ".py"
+	inBlock := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := stripComments(scanner.Text(), hash, &inBlock)
+		for _, pattern := range patterns {
+			for _, m := range pattern.FindAllStringSubmatch(line, -1) {
+				names = append(names, m[1])
+			}
+		}
+	}
+	return names
+}
+
+// stripComments removes the comments from a line of source code: # comments when hash
+// is set, // and /* */ comments otherwise. inBlock tracks a block comment that is still
+// open at the end of the line. Quoted strings are kept, so "http://" is not a comment.
+func stripComments(line string, hash bool, inBlock *bool) string {
+	var out strings.Builder
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case *inBlock:
+			if strings.HasPrefix(line[i:], "*/") {
+				*inBlock = false
+				out.WriteByte(' ')
+				i++
+			}
+		case quote != 0:
+			out.WriteByte(c)
+			if c == '\\' && i+1 < len(line) {
+				i++
+				out.WriteByte(line[i])
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+			out.WriteByte(c)
+		case hash && c == '#', !hash && strings.HasPrefix(line[i:], "//"):
+			return out.String()
+		case !hash && strings.HasPrefix(line[i:], "/*"):
+			*inBlock = true
+			i++
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.String()
+}
+
+// FormatEnvVars summarises the environment inventory for prompts, secrets first
+func FormatEnvVars(vars []EnvVar) []string {
+	var secrets, config []string
+	for _, v := range vars {
+		entry := fmt.Sprintf("%s (%s)", v.Name, strings.Join(v.Sources, ", "))
+		if v.Secret {
+			secrets = append(secrets, entry)
+		} else {
+			config = append(config, entry)
+		}
+	}
+
+	var lines []string
+	if len(secrets) > 0 {
+		lines = append(lines, "Secrets: "+strings.Join(secrets, "; "))
+	}
+	if len(config) > 0 {
+		lines = append(lines, "Configuration: "+strings.Join(config, "; "))
+	}
+	return lines
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestScanEnvUsageSkipsComments(t *testing.T) {
+	dir := t.TempDir()
+	goFile := filepath.Join(dir, "main.go")
+	writeTestFile(t, goFile, `package main
+
+// apiKey = os.Getenv("COMMENTED_KEY")
+/*
+	token := os.Getenv("BLOCK_TOKEN")
+*/
+var url = "http://" + os.Getenv("HOST") // os.Getenv("TRAILING")
+var port = /* os.Getenv("INLINE") */ os.Getenv("PORT")
+
+type Config struct {
+	Token string `+"`env:\"API_TOKEN\"`"+`
+}
+`)
+	if got, want := scanEnvUsage(goFile, envUsagePatterns[".go"]), []string{"HOST", "PORT", "API_TOKEN"}; !slices.Equal(got, want) {
+		t.Errorf("go: got %v, want %v", got, want)
+	}
+
+	pyFile := filepath.Join(dir, "settings.py")
+	writeTestFile(t, pyFile, `# SECRET = os.environ["COMMENTED"]
+DEBUG = os.getenv("DEBUG")  # os.getenv("TRAILING")
+URL = os.environ.get("URL", "#fragment")
+`)
+	if got, want := scanEnvUsage(pyFile, envUsagePatterns[".py"]), []string{"DEBUG", "URL"}; !slices.Equal(got, want) {
+		t.Errorf("python: got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileIndex is a single-pass listing of the project's files shared by the detectors,
+// so the tree is walked once instead of once per detector
+//
+// The walk honours .gitignore files (root, nested and .git/info/exclude), never
+// descends into dependency or tool directories, follows symlinks only when they
+// stay inside the project and have not been visited yet, and stops at WalkLimits.
+type FileIndex struct {
+	Root      string
+	Truncated bool // The walk stopped at MaxDepth or MaxFiles, so the index is incomplete
+
+	files []string        // Slash-separated paths relative to Root, in lexical walk order
+	dirs  map[string]bool // Directories walked, same form as files
+}
+
+// WalkLimits bound how much of the tree a FileIndex covers
+type WalkLimits struct {
+	MaxDepth int // Directory levels below the root
+	MaxFiles int // Files recorded before the walk stops
+}
+
+// defaultWalkLimits keep detection fast on large monorepos
+var defaultWalkLimits = WalkLimits{MaxDepth: 12, MaxFiles: 50000}
+
+// skippedDirs are never walked: VCS metadata, installed dependencies and tool caches
+var skippedDirs = map[string]bool{
+	".git": true, ".hg": true, ".svn": true,
+	"node_modules": true, "vendor": true, "bower_components": true,
+	"__pycache__": true, ".venv": true, "venv": true, ".tox": true, ".mypy_cache": true, ".pytest_cache": true,
+	".next": true, ".nuxt": true, ".terraform": true, ".gradle": true, ".idea": true,
+}
+
+// NewFileIndex walks workingDir with the default limits
+func NewFileIndex(workingDir string) *FileIndex {
+	return BuildFileIndex(workingDir, defaultWalkLimits)
+}
+
+// BuildFileIndex walks root once and records every file that is not ignored
+func BuildFileIndex(root string, limits WalkLimits) *FileIndex {
+	idx := &FileIndex{Root: root, dirs: map[string]bool{"": true}}
+
+	var ignores gitIgnoreStack
+	if content, ok := readFileString(filepath.Join(root, ".git", "info", "exclude")); ok {
+		ignores = ignores.with("", content)
+	}
+
+	realRoot := root
+	if real, err := filepath.EvalSymlinks(root); err == nil {
+		realRoot = real
+	}
+	w := indexWalk{idx: idx, realRoot: realRoot, visited: map[string]bool{realRoot: true}, limits: limits}
+	w.walk("", realRoot, 0, ignores)
+	return idx
+}
+
+// indexWalk is the state of one BuildFileIndex walk
+type indexWalk struct {
+	idx      *FileIndex
+	realRoot string          // Root with symlinks resolved
+	visited  map[string]bool // Real paths of the directories walked, to break symlink loops
+	limits   WalkLimits
+	full     bool // MaxFiles reached
+}
+
+// walk indexes the directory rel, whose symlink-free path is realDir
+func (w *indexWalk) walk(rel, realDir string, depth int, ignores gitIgnoreStack) {
+	idx := w.idx
+	dir := filepath.Join(idx.Root, filepath.FromSlash(rel))
+	if content, ok := readFileString(filepath.Join(dir, ".gitignore")); ok {
+		ignores = ignores.with(rel, content)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if w.full {
+			return
+		}
+		name := entry.Name()
+		childRel := name
+		if rel != "" {
+			childRel = rel + "/" + name
+		}
+
+		isDir := entry.IsDir()
+		real := filepath.Join(realDir, name)
+		if entry.Type()&os.ModeSymlink != 0 {
+			// Follow links that stay inside the project
+			resolved, err := filepath.EvalSymlinks(filepath.Join(dir, name))
+			if err != nil || (resolved != w.realRoot && !strings.HasPrefix(resolved, w.realRoot+string(filepath.Separator))) {
+				continue
+			}
+			fi, err := os.Stat(resolved)
+			if err != nil {
+				continue
+			}
+			isDir, real = fi.IsDir(), resolved
+		}
+		if isDir {
+			if w.visited[real] {
+				continue
+			}
+			w.visited[real] = true
+		}
+
+		if isDir && skippedDirs[name] {
+			continue
+		}
+		if ignores.Ignored(childRel, isDir) {
+			continue
+		}
+
+		if isDir {
+			if depth+1 > w.limits.MaxDepth {
+				idx.Truncated = true
+				continue
+			}
+			idx.dirs[childRel] = true
+			w.walk(childRel, real, depth+1, ignores)
+			continue
+		}
+
+		if len(idx.files) >= w.limits.MaxFiles {
+			idx.Truncated, w.full = true, true
+			return
+		}
+		idx.files = append(idx.files, childRel)
+	}
+}
+
+// Files returns every indexed file, relative to Root and slash-separated
+func (idx *FileIndex) Files() []string {
+	return idx.files
+}
+
+// Find returns the indexed files for which match returns true
+func (idx *FileIndex) Find(match func(rel string) bool) []string {
+	var found []string
+	for _, rel := range idx.files {
+		if match(rel) {
+			found = append(found, rel)
+		}
+	}
+	return found
+}
+
+// FindNamed returns the indexed files whose base name satisfies match
+func (idx *FileIndex) FindNamed(match func(name string) bool) []string {
+	return idx.Find(func(rel string) bool {
+		return match(rel[strings.LastIndex(rel, "/")+1:])
+	})
+}
+
+// HasDir reports whether rel is a directory in the index
+func (idx *FileIndex) HasDir(rel string) bool {
+	return idx.dirs[filepath.ToSlash(rel)]
+}
+
+// Abs returns the absolute path of an indexed file
+func (idx *FileIndex) Abs(rel string) string {
+	return filepath.Join(idx.Root, filepath.FromSlash(rel))
+}
+
+// underDir reports whether rel lies inside a directory with one of the given names
+func underDir(rel string, names ...string) bool {
+	parts := strings.Split(rel, "/")
+	for _, part := range parts[:len(parts)-1] {
+		for _, name := range names {
+			if part == name {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// writtenFile is one line of the manifest generate prints
+type writtenFile struct {
+	Path     string // As shown to the user, relative to the repository root when inside it
+	Purpose  string
+	Status   string // created, updated, unchanged, kept, skipped (failed validation) or failed
+	Problems []string
+}
+
+// resolveGeneratedPath turns a generated file's path into an absolute path
+// inside the repository, refusing absolute paths and paths that escape it
+func resolveGeneratedPath(workingDir, path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("file has no path")
+	}
+	if filepath.IsAbs(path) || strings.HasPrefix(path, "/") {
+		return "", fmt.Errorf("%s: path must be relative to the repository root", path)
+	}
+	clean := filepath.Clean(filepath.FromSlash(path))
+	if clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s: path leaves the repository", path)
+	}
+	return filepath.Join(workingDir, clean), nil
+}
+
+// validateGeneratedFile returns the problems in a generated file, checked
+// according to what its path says it is
+func validateGeneratedFile(file GeneratedFile) []string {
+	path := filepath.ToSlash(filepath.Clean(file.Path))
+	base := filepath.Base(path)
+	isYAML := strings.HasSuffix(path, ".yml") || strings.HasSuffix(path, ".yaml")
+	switch {
+	case isYAML && strings.HasPrefix(path, ".github/workflows/"):
+		return validateGitHubWorkflow(file.Content)
+	case path == ".github/dependabot.yml" || path == ".github/dependabot.yaml":
+		return validateDependabot(file.Content)
+	case base == "action.yml" || base == "action.yaml":
+		return validateAction(file.Content)
+	case targetForFile(path).Name != pipelineTargets[0].Name:
+		return targetForFile(path).Validate(file.Content)
+	case isYAML:
+		_, problems := parseYAMLMapping(file.Content)
+		return problems
+	case strings.HasSuffix(path, ".json") && !json.Valid([]byte(file.Content)):
+		return []string{"invalid JSON"}
+	}
+	return nil
+}
+
+// validateDependabot checks a .github/dependabot.yml
+func validateDependabot(config string) []string {
+	root, problems := parseYAMLMapping(config)
+	if root == nil {
+		return problems
+	}
+	if version := mappingValue(root, "version"); version == nil || version.Value != "2" {
+		problems = append(problems, `"version" must be 2`)
+	}
+	updates := mappingValue(root, "updates")
+	if updates == nil || updates.Kind != yaml.SequenceNode || len(updates.Content) == 0 {
+		return append(problems, `missing "updates"`)
+	}
+	for i, update := range updates.Content {
+		if update.Kind != yaml.MappingNode {
+			problems = append(problems, fmt.Sprintf("update %d must be a mapping", i+1))
+			continue
+		}
+		for _, key := range []string{"package-ecosystem", "directory", "schedule"} {
+			if mappingValue(update, key) == nil && (key != "directory" || mappingValue(update, "directories") == nil) {
+				problems = append(problems, fmt.Sprintf(`update %d has no %q`, i+1, key))
+			}
+		}
+		if schedule := mappingValue(update, "schedule"); schedule != nil && mappingValue(schedule, "interval") == nil {
+			problems = append(problems, fmt.Sprintf(`update %d has no "schedule.interval"`, i+1))
+		}
+	}
+	return problems
+}
+
+// validateAction checks an action.yml, such as a composite action
+func validateAction(config string) []string {
+	root, problems := parseYAMLMapping(config)
+	if root == nil {
+		return problems
+	}
+	if mappingValue(root, "name") == nil {
+		problems = append(problems, `missing "name"`)
+	}
+	runs := mappingValue(root, "runs")
+	if runs == nil || runs.Kind != yaml.MappingNode {
+		return append(problems, `missing "runs"`)
+	}
+	using := mappingValue(runs, "using")
+	if using == nil {
+		return append(problems, `missing "runs.using"`)
+	}
+	if using.Value != "composite" {
+		return problems
+	}
+	steps := mappingValue(runs, "steps")
+	if steps == nil || steps.Kind != yaml.SequenceNode || len(steps.Content) == 0 {
+		return append(problems, `composite action has no "runs.steps"`)
+	}
+	for i, step := range steps.Content {
+		if step.Kind == yaml.MappingNode && mappingValue(step, "run") != nil && mappingValue(step, "shell") == nil {
+			problems = append(problems, fmt.Sprintf(`step %d runs a command without "shell", which composite actions require`, i+1))
+		}
+	}
+	return problems
+}
+
+// writeGeneratedFiles validates the files generated alongside the main
+// configuration and writes them with safeWrite. Files that cannot be placed
+// in the repository, or that repeat a path, are reported as failed; files that
+// fail validation are skipped, as the main configuration is, unless force.
+func writeGeneratedFiles(cmd *cobra.Command, workingDir, mainPath string, files []GeneratedFile, force bool) []writtenFile {
+	var written []writtenFile
+	seen := map[string]bool{mainPath: true}
+	for _, file := range files {
+		entry := writtenFile{Path: file.Path, Purpose: file.Purpose}
+		path, err := resolveGeneratedPath(workingDir, file.Path)
+		switch {
+		case err != nil:
+		case path == mainPath:
+			err = fmt.Errorf("%s: duplicates the main configuration", file.Path)
+		case seen[path]:
+			err = fmt.Errorf("%s: another generated file has the same path", file.Path)
+		}
+		if err != nil {
+			entry.Status = "failed"
+			entry.Problems = []string{err.Error()}
+			written = append(written, entry)
+			continue
+		}
+		seen[path] = true
+		entry.Path = displayPath(workingDir, path)
+		entry.Problems = validateGeneratedFile(file)
+		if len(entry.Problems) > 0 && !force {
+			entry.Status = "skipped"
+			written = append(written, entry)
+			continue
+		}
+		entry.Status = writeStatus(path, file.Content)
+		if err := safeWrite(cmd, path, file.Content, force); errors.Is(err, errWriteDeclined) {
+			entry.Status = "kept"
+		} else if err != nil {
+			entry.Status = "failed"
+			entry.Problems = append(entry.Problems, err.Error())
+		}
+		written = append(written, entry)
+	}
+	return written
+}
+
+// writeStatus says what writing content to path does
+func writeStatus(path, content string) string {
+	existing, err := os.ReadFile(path)
+	switch {
+	case err != nil:
+		return "created"
+	case string(existing) == content:
+		return "unchanged"
+	}
+	return "updated"
+}
+
+// displayPath shows path relative to the repository root when it is inside it
+func displayPath(workingDir, path string) string {
+	if rel, err := filepath.Rel(workingDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// printFileManifest lists the files generate wrote
+func printFileManifest(cmd *cobra.Command, files []writtenFile) {
+	width := 0
+	for _, file := range files {
+		width = max(width, len(file.Path))
+	}
+	cmd.Println("📁 Files:")
+	for _, file := range files {
+		icon := "✅"
+		switch {
+		case file.Status == "failed" || file.Status == "skipped":
+			icon = "❌"
+		case file.Status == "kept":
+			icon = "⏭️ "
+		case len(file.Problems) > 0:
+			icon = "⚠️ "
+		}
+		cmd.Printf("   %s %-*s  %-9s  %s\n", icon, width, file.Path, file.Status, file.Purpose)
+	}
+	cmd.Println()
+}
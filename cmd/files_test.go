@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestWriteGeneratedFiles(t *testing.T) {
+	files := []GeneratedFile{
+		{Path: "config/settings.json", Purpose: "Settings", Content: `{"retries": 3}`},
+		{Path: "config/./settings.json", Purpose: "Settings again", Content: `{"retries": 5}`},
+		{Path: "config/broken.json", Purpose: "Broken", Content: `{"retries":`},
+		{Path: "../outside.json", Purpose: "Outside", Content: `{}`},
+		{Path: ".github/workflows/ci.yml", Purpose: "Main again", Content: "on: push\n"},
+	}
+	tests := []struct {
+		name        string
+		force       bool
+		wantStatus  []string
+		wantWritten map[string]string // Relative path → content; "" for not written
+	}{
+		{
+			name:       "invalid files are skipped",
+			wantStatus: []string{"created", "failed", "skipped", "failed", "failed"},
+			wantWritten: map[string]string{
+				"config/settings.json": `{"retries": 3}`,
+				"config/broken.json":   "",
+			},
+		},
+		{
+			name:       "force writes invalid files",
+			force:      true,
+			wantStatus: []string{"created", "failed", "created", "failed", "failed"},
+			wantWritten: map[string]string{
+				"config/settings.json": `{"retries": 3}`,
+				"config/broken.json":   `{"retries":`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			cmd := &cobra.Command{}
+			cmd.SetOut(&bytes.Buffer{})
+			cmd.SetErr(&bytes.Buffer{})
+			written := writeGeneratedFiles(cmd, dir, filepath.Join(dir, ".github", "workflows", "ci.yml"), files, tt.force)
+
+			if len(written) != len(tt.wantStatus) {
+				t.Fatalf("got %d manifest entries, want %d", len(written), len(tt.wantStatus))
+			}
+			for i, entry := range written {
+				if entry.Status != tt.wantStatus[i] {
+					t.Errorf("%s: status %q, want %q (problems: %v)", files[i].Path, entry.Status, tt.wantStatus[i], entry.Problems)
+				}
+			}
+			for rel, want := range tt.wantWritten {
+				content, err := os.ReadFile(filepath.Join(dir, rel))
+				switch {
+				case want == "" && err == nil:
+					t.Errorf("%s was written", rel)
+				case want != "" && string(content) != want:
+					t.Errorf("%s = %q, want %q", rel, content, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAdditionalFilesPromptFollowsTarget(t *testing.T) {
+	for _, target := range pipelineTargets {
+		prompt := additionalFilesPrompt(target)
+		if target.Name != "github" && strings.Contains(prompt, ".github/") {
+			t.Errorf("%s: prompt names GitHub files: %s", target.Name, prompt)
+		}
+		if (target.AdditionalFiles == "") != (prompt == "") {
+			t.Errorf("%s: prompt = %q", target.Name, prompt)
+		}
+	}
+}
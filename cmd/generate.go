@@ -1,40 +1,184 @@
 package cmd
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/openai/openai-go"
-	"github.com/openai/openai-go/option"
 	"github.com/spf13/cobra"
 )
 
 var (
-	outputPath string
-	promptPath string
+	outputPath   string
+	promptPath   string
+	targetName   string
+	directMode   bool
+	offline      bool
+	irPath       string
+	templateName string
+	refine       bool
+	force        bool
 )
 
 var generateCmd = &cobra.Command{
 	Use:   "generate",
 	Short: "Generate CI/CD pipeline/workflow configuration",
-	Long:  `Generate CI/CD pipeline/workflow configuration based on user specifications.`,
-	Run:   generateConfiguration,
+	Long: `Generate CI/CD pipeline/workflow configuration based on user specifications.
+
+Use --target to choose the platform: github (GitHub Actions, the default), gitlab (.gitlab-ci.yml),
+circleci (.circleci/config.yml), azure (azure-pipelines.yml) or bitbucket (bitbucket-pipelines.yml).
+
+The model describes the pipeline once in Fluxion's platform-neutral format, and the
+target's emitter renders it deterministically, so every target gets the same jobs.
+Use --ir to keep that description and "fluxion render" to render it for other targets,
+or --direct to have the model write the target's YAML itself.
+
+With --offline, no AI is used: the pipeline is built from Fluxion's templates for Go,
+Node.js and Python projects and Docker images, chosen from the detected project context.
+
+Teams can add their own templates (Go text/template files rendered with the project context)
+to .fluxion/templates or a shared directory listed under templates in .fluxion.yml. Matching
+templates are offered in interactive mode or picked with --template; the AI builds on the
+chosen template, or with --offline it is rendered as is.
+
+With --refine, the result is shown before anything is written: type follow-up instructions
+("add a macOS runner", "cache pip") to revise it, see the diff of each revision, and accept
+it to write the file.`,
+	Run: generateConfiguration,
 }
 
 func init() {
 	rootCmd.AddCommand(generateCmd)
 
-	generateCmd.Flags().StringVarP(&outputPath, "output", "o", "./generated_pipeline.yml", "Output path for the generated configuration file")
+	generateCmd.Flags().StringVarP(&outputPath, "output", "o", "", "Output path for the generated configuration file (default depends on --target, e.g. ./generated_pipeline.yml for github)")
+	generateCmd.Flags().StringVarP(&targetName, "target", "t", "", "CI/CD platform to generate for: "+strings.Join(pipelineTargetNames(), ", ")+" (default: github)")
 	generateCmd.Flags().StringVarP(&promptPath, "prompt_file", "p", "", "Path to a file containing the pipeline description prompt")
+	generateCmd.Flags().BoolVar(&directMode, "direct", false, "Have the model write the target's YAML directly instead of a platform-neutral pipeline")
+	generateCmd.Flags().BoolVar(&offline, "offline", false, "Build the pipeline from Fluxion's built-in templates for the detected project, without the AI")
+	generateCmd.Flags().StringVar(&templateName, "template", "", "Team template to build on, from .fluxion/templates or the templates directories in .fluxion.yml")
+	generateCmd.Flags().BoolVar(&refine, "refine", false, "After generating, refine the result with follow-up instructions and write it only when you accept it")
+	generateCmd.Flags().BoolVar(&force, "force", false, "Overwrite existing files without asking, and write configurations that fail validation")
+	generateCmd.Flags().StringVar(&irPath, "ir", "", "Also save the platform-neutral pipeline as JSON to this path, for use with fluxion render")
 }
 
 func generateConfiguration(cmd *cobra.Command, args []string) {
 	var prompt string
 	var err error
-	if promptPath == "" {
+	if offline && directMode {
+		cmd.PrintErrln("❌ Error: --offline builds the pipeline from templates and cannot be used with --direct")
+		return
+	}
+	if directMode && irPath != "" {
+		cmd.PrintErrln("❌ Error: --ir needs the platform-neutral pipeline and cannot be used with --direct")
+		return
+	}
+	if refine && offline {
+		cmd.PrintErrln("❌ Error: --refine revises the result with the AI and cannot be used with --offline")
+		return
+	}
+
+	// Defaults from .fluxion.yml apply when the flags are not given
+	workingDir := GetWorkingDirectory()
+	config, _, err := LoadProjectConfig(workingDir)
+	if err != nil {
+		cmd.PrintErrln("❌ Error in project config:", err)
+		return
+	}
+	model, err := config.Defaults.ChatModel()
+	if err != nil {
+		cmd.PrintErrln("❌ Error in project config:", err)
+		return
+	}
+	name := targetName
+	if !cmd.Flags().Changed("target") {
+		name = config.Defaults.Target
+	}
+	target, err := findPipelineTarget(name)
+	if err != nil {
+		cmd.PrintErrln("❌ Error:", err)
+		return
+	}
+	configTarget, _ := findPipelineTarget(config.Defaults.Target)
+
+	// The configured output belongs to the configured target; other targets use their own default
+	output := target.DefaultOutput
+	if cmd.Flags().Changed("output") {
+		output = outputPath
+	} else if config.Defaults.Output != "" && configTarget.Name == target.Name {
+		output = config.Defaults.Output
+	}
+	outputPath, _ := filepath.Abs(output)
+
+	// Detect project context
+	projectContext, err := DetectProjectContext(workingDir)
+	if err != nil {
+		// Non-fatal: continue without context
+		cmd.PrintErrln("⚠️  Warning: Could not detect project context:", err)
+	}
+	printDiagnostics(cmd, projectContext)
+
+	// Show detected context to user
+	if projectContext.PrimaryLang != "" {
+		cmd.Println("\n🔍 Detected Project Context:")
+		cmd.Println("───────────────────────────────────────────────────────────────")
+		cmd.Println(projectContext.FormatContext())
+		cmd.Println("───────────────────────────────────────────────────────────────")
+		cmd.Println()
+	}
+
+	// The team's own templates for this target
+	userTemplates, warnings := LoadUserTemplates(workingDir, config)
+	for _, warning := range warnings {
+		cmd.PrintErrln("⚠️  Warning:", warning)
+	}
+	matching := matchingUserTemplates(userTemplates, projectContext, target, workingDir)
+	var chosen *UserTemplate
+	switch {
+	case templateName != "":
+		t, err := findUserTemplate(userTemplates, templateName)
+		if err == nil && !strings.EqualFold(t.Target, target.Name) {
+			err = fmt.Errorf("template %q is for --target %s", t.Name, t.Target)
+		}
+		if err != nil {
+			cmd.PrintErrln("❌ Error:", err)
+			return
+		}
+		chosen = &t
+	case len(matching) > 0 && promptPath == "" && !offline:
+		options := []SelectOption{{Label: "None - generate from scratch", Value: ""}}
+		for _, t := range matching {
+			options = append(options, SelectOption{Label: t.Name + " - " + t.Description, Value: t.Name})
+		}
+		picked, err := runSelectInteractiveMode("Template", "Your team's templates that match this project. The AI keeps the template's structure and adds what you describe.", options)
+		if err != nil {
+			cmd.PrintErrln("❌ Error during interactive prompt:", err)
+			return
+		}
+		if picked != "" {
+			t, _ := findUserTemplate(matching, picked)
+			chosen = &t
+		}
+	case len(matching) > 0:
+		cmd.Println("📚 Team templates that match this project (use --template NAME):")
+		for _, t := range matching {
+			cmd.Printf("   - %s: %s\n", t.Name, t.Description)
+		}
+		cmd.Println()
+	}
+	if chosen != nil && irPath != "" {
+		cmd.PrintErrln("❌ Error: --ir needs the platform-neutral pipeline and cannot be used with --template")
+		return
+	}
+
+	if offline {
+		// Templates are chosen from the project context; there is nothing to describe
+		if promptPath != "" {
+			cmd.PrintErrln("⚠️  Warning: --offline does not use the prompt file")
+		}
+	} else if promptPath == "" {
 		values, err := runTextInteractiveMode([]TextInteractive{
 			{
 				Title:       "Pipeline Description",
@@ -57,37 +201,87 @@ func generateConfiguration(cmd *cobra.Command, args []string) {
 		}
 	}
 
-	outputPath, _ := filepath.Abs(outputPath)
-
-	// Detect project context
-	workingDir := GetWorkingDirectory()
-	projectContext, err := DetectProjectContext(workingDir)
+	var generatedConfig GenerateResult
+	var session *generateSession
+	switch {
+	case chosen != nil && offline:
+		generatedConfig.PipelineDescription = fmt.Sprintf("Rendered from the %s template: %s", chosen.Name, chosen.Description)
+		generatedConfig.PipelineConfig, err = chosen.Render(projectContext)
+	case chosen != nil:
+		var skeleton string
+		if skeleton, err = chosen.Render(projectContext); err == nil {
+			generatedConfig, session, err = generatePipelineConfig(prompt, projectContext, model, target, skeleton)
+		}
+	case offline:
+		generatedConfig, err = generateFromTemplates(projectContext)
+		if err == nil {
+			generatedConfig.PipelineConfig, err = target.Render(*generatedConfig.Pipeline)
+		}
+	case directMode:
+		generatedConfig, session, err = generatePipelineConfig(prompt, projectContext, model, target, "")
+	default:
+		generatedConfig, session, err = generatePipelineModel(prompt, projectContext, model, target)
+	}
 	if err != nil {
-		// Non-fatal: continue without context
-		cmd.PrintErrln("⚠️  Warning: Could not detect project context:", err)
+		cmd.PrintErrln("❌ Error generating pipeline configuration:", err)
+		return
 	}
 
-	// Show detected context to user
-	if projectContext.PrimaryLang != "" {
-		cmd.Println("\n🔍 Detected Project Context:")
-		cmd.Println("───────────────────────────────────────────────────────────────")
-		cmd.Println(projectContext.FormatContext())
-		cmd.Println("───────────────────────────────────────────────────────────────")
-		cmd.Println()
+	// Accepting a refined version that fails validation already asked whether to write it
+	confirmed := false
+	if refine {
+		var accepted bool
+		generatedConfig, accepted, err = refineConfiguration(cmd, session, generatedConfig, force)
+		if err != nil {
+			cmd.PrintErrln("❌ Error during refinement:", err)
+			return
+		}
+		if !accepted {
+			cmd.Println("🗑️  Discarded; nothing was written")
+			return
+		}
+		confirmed = session != nil
 	}
 
-	generatedConfig, err := generatePipelineConfig(prompt, projectContext)
-	if err != nil {
-		cmd.PrintErrln("❌ Error generating pipeline configuration:", err)
+	// A configuration the platform would reject is not written unless --force
+	problems := target.Validate(generatedConfig.PipelineConfig)
+	if len(problems) > 0 && !force && !confirmed {
+		cmd.PrintErrf("❌ The generated %s failed validation, nothing was written:\n", target.Artifact)
+		for _, problem := range problems {
+			cmd.PrintErrln("   - " + problem)
+		}
+		cmd.PrintErrln("   Run again, or use --force to write it anyway")
 		return
 	}
 
 	// Write the generated configuration to the specified output file
-	err = writeFile(outputPath, generatedConfig.PipelineConfig)
+	status := writeStatus(outputPath, generatedConfig.PipelineConfig)
+	err = safeWrite(cmd, outputPath, generatedConfig.PipelineConfig, force)
+	if errors.Is(err, errWriteDeclined) {
+		cmd.Println("🗑️  Kept the existing file; nothing was written")
+		return
+	}
 	if err != nil {
 		cmd.PrintErrln("❌ Error writing generated configuration to file:", err)
 		return
 	}
+	var files []writtenFile
+	if len(generatedConfig.Files) > 0 {
+		files = append(files, writtenFile{Path: displayPath(workingDir, outputPath), Purpose: target.Artifact, Status: status, Problems: problems})
+		files = append(files, writeGeneratedFiles(cmd, workingDir, outputPath, generatedConfig.Files, force)...)
+	}
+	var lint []string
+	var irOutput string
+	if generatedConfig.Pipeline != nil {
+		lint = LintPipeline(*generatedConfig.Pipeline)
+		if irPath != "" {
+			irOutput, _ = filepath.Abs(irPath)
+			if err := writePipelineJSON(cmd, irOutput, *generatedConfig.Pipeline, force); err != nil {
+				cmd.PrintErrln("❌ Error writing pipeline:", err)
+				return
+			}
+		}
+	}
 
 	// Display summary to user with embellished formatting
 	cmd.Println("\n" + "═══════════════════════════════════════════════════════════════")
@@ -123,11 +317,55 @@ func generateConfiguration(cmd *cobra.Command, args []string) {
 		cmd.Println()
 	}
 
+	if len(files) > 0 {
+		printFileManifest(cmd, files)
+	}
+
 	cmd.Println("───────────────────────────────────────────────────────────────")
 	cmd.Printf("✅ Configuration saved to: %s\n", outputPath)
+	if irOutput != "" {
+		cmd.Printf("🧩 Pipeline saved to: %s\n", irOutput)
+	}
+	if todos := strings.Count(generatedConfig.PipelineConfig, "TODO(fluxion)"); todos > 0 {
+		cmd.Printf("📝 %d construct(s) could not be rendered for %s; search for TODO(fluxion)\n", todos, target.Name)
+	}
 	cmd.Println("───────────────────────────────────────────────────────────────")
 	cmd.Println()
 
+	if len(lint) > 0 {
+		cmd.PrintErrln("⚠️  Pipeline checks found problems; review them before committing:")
+		for _, problem := range lint {
+			cmd.PrintErrln("   - " + problem)
+		}
+		cmd.PrintErrln()
+	}
+
+	if len(problems) > 0 {
+		cmd.PrintErrf("⚠️  The generated %s failed validation and was written because of --force; fix it before committing:\n", target.Artifact)
+		for _, problem := range problems {
+			cmd.PrintErrln("   - " + problem)
+		}
+		cmd.PrintErrln()
+	}
+
+	for _, file := range files[min(1, len(files)):] {
+		if len(file.Problems) == 0 {
+			continue
+		}
+		switch file.Status {
+		case "skipped":
+			cmd.PrintErrf("❌ %s failed validation and was not written; run again, or use --force to write it anyway:\n", file.Path)
+		case "failed":
+			cmd.PrintErrf("❌ %s was not written:\n", file.Path)
+		default:
+			cmd.PrintErrf("⚠️  %s has problems; review it before committing:\n", file.Path)
+		}
+		for _, problem := range file.Problems {
+			cmd.PrintErrln("   - " + problem)
+		}
+		cmd.PrintErrln()
+	}
+
 }
 
 type GenerateResult struct {
@@ -136,65 +374,138 @@ type GenerateResult struct {
 	Assumptions         []string `json:"assumptions"`
 	Requirements        []string `json:"requirements"`
 	NextSteps           []string `json:"next_steps"`
+
+	// Files are generated alongside PipelineConfig, e.g. a release workflow or .github/dependabot.yml
+	Files []GeneratedFile `json:"files"`
+
+	// Pipeline is the platform-neutral pipeline PipelineConfig was rendered from; nil with --direct
+	Pipeline *Pipeline `json:"pipeline,omitempty"`
 }
 
-func generatePipelineConfig(prompt string, projectContext ProjectContext) (GenerateResult, error) {
-	openAiApiKey := os.Getenv("OPENAI_API_KEY")
-	client := openai.NewClient(
-		option.WithAPIKey(openAiApiKey),
-	)
+// GeneratedFile is a file generate writes besides the main configuration
+type GeneratedFile struct {
+	Path    string `json:"path"` // Relative to the repository root
+	Content string `json:"content"`
+	Purpose string `json:"purpose"` // What the file is for, shown in the manifest
+}
 
-	schemaParam := openai.ResponseFormatJSONSchemaJSONSchemaParam{
-		Name:   "generate_result",
-		Schema: generateSchema,
-		Strict: openai.Bool(true),
+// generatePipelineConfig asks the model for the target platform's configuration directly;
+// a non-empty skeleton is a team template the configuration must be built on
+func generatePipelineConfig(prompt string, projectContext ProjectContext, model openai.ChatModel, target PipelineTarget, skeleton string) (GenerateResult, *generateSession, error) {
+	services := ""
+	if len(projectContext.Services) > 0 {
+		services = fmt.Sprintf(`The project's tests depend on these backing services. Add this block to every job that runs tests:
+%s
+Connection details for the application:
+%s`, target.Services(projectContext), formatServiceConnections(projectContext.Services, target.ServicesByAlias))
+	}
+	userPrompt := generateUserPrompt(prompt, projectContext, target.Artifact, services, target)
+	if skeleton != "" {
+		userPrompt += fmt.Sprintf(`
+
+REQUIRED SKELETON:
+The team's template for this kind of project. Build on it: keep its jobs, steps, runner labels, triggers and
+comments as they are, and only add to it or fill it in as the request asks.
+%s`, skeleton)
 	}
 
-	// Build enhanced user prompt with project context
-	var userPrompt string
-	if projectContext.PrimaryLang != "" {
-		userPrompt = fmt.Sprintf(`Create a GitHub Actions workflow for this project.
+	session := newGenerateSession(model, target, false)
+	result, err := session.send(userPrompt)
+	return result, session, err
+}
 
-USER REQUEST:
+// generatePipelineModel asks the model for a platform-neutral Pipeline and
+// renders it for the target with the target's emitter
+func generatePipelineModel(prompt string, projectContext ProjectContext, model openai.ChatModel, target PipelineTarget) (GenerateResult, *generateSession, error) {
+	services := ""
+	if len(projectContext.Services) > 0 {
+		services = fmt.Sprintf(`The project's tests depend on these backing services. Add them to every job that runs tests:
 %s
+Connection details for the application:
+%s`, formatPipelineServices(projectContext.Services), formatServiceConnections(projectContext.Services, false))
+	}
+	userPrompt := generateUserPrompt(prompt, projectContext, "CI/CD pipeline", services, target)
 
-PROJECT CONTEXT:
-%s
+	// The built-in template is a tested starting point the model only has to customize
+	if baseline, err := generateFromTemplates(projectContext); err == nil {
+		if content, err := json.Marshal(baseline.Pipeline); err == nil {
+			userPrompt += fmt.Sprintf(`
 
-Generate a workflow that is specifically tailored to this project type, uses the correct build/test commands, and follows best practices.`,
-			prompt, projectContext.FormatContext())
-	} else {
+BASELINE PIPELINE:
+Fluxion's built-in template for this project. Start from it: keep its jobs, commands and triggers unless the
+request changes them, remove what the request does not want, and add what it asks for.
+%s`, content)
+		}
+	}
+
+	session := newGenerateSession(model, target, true)
+	result, err := session.send(userPrompt)
+	return result, session, err
+}
+
+// generateUserPrompt builds the user prompt for artifact, enhanced with the project context
+func generateUserPrompt(prompt string, projectContext ProjectContext, artifact, services string, target PipelineTarget) string {
+	if projectContext.PrimaryLang == "" {
 		// Fallback to simple prompt if no context detected
-		userPrompt = "Create a GitHub Actions workflow based on the following prompt:\n" + prompt
+		return "Create a " + artifact + " based on the following prompt:\n" + prompt + additionalFilesPrompt(target)
 	}
+	userPrompt := fmt.Sprintf(`Create a %s for this project.
 
-	resp, err := client.Chat.Completions.New(
-		context.Background(),
-		openai.ChatCompletionNewParams{
-			Model: openai.ChatModelGPT4o,
-			Messages: []openai.ChatCompletionMessageParamUnion{
-				openai.SystemMessage(generateSystemPrompt),
-				openai.UserMessage(userPrompt),
-			},
-			ResponseFormat: openai.ChatCompletionNewParamsResponseFormatUnion{
-				OfJSONSchema: &openai.ResponseFormatJSONSchemaParam{
-					JSONSchema: schemaParam,
-				},
-			},
-		},
-	)
+USER REQUEST:
+%s
 
-	if err != nil {
-		return GenerateResult{}, fmt.Errorf("OpenAI API error: %w", err)
+PROJECT CONTEXT:
+%s
+
+Generate a configuration that is specifically tailored to this project type, uses the correct build/test commands, and follows best practices.`,
+		artifact, prompt, projectContext.FormatContext())
+	if services != "" {
+		userPrompt += "\n\nSERVICE CONTAINERS:\n" + services
 	}
+	return userPrompt + additionalFilesPrompt(target)
+}
 
-	// Parse the response
-	var result GenerateResult
-	if err := json.Unmarshal([]byte(resp.Choices[0].Message.Content), &result); err != nil {
-		return GenerateResult{}, fmt.Errorf("failed to parse OpenAI response: %w\nRaw content: %s",
-			err, resp.Choices[0].Message.Content)
+// additionalFilesPrompt asks for the files a request needs besides the main configuration,
+// with the target's own examples; targets configured in a single file get none
+func additionalFilesPrompt(target PipelineTarget) string {
+	if target.AdditionalFiles == "" {
+		return ""
 	}
+	return fmt.Sprintf(`
 
-	return result, nil
+ADDITIONAL FILES:
+When the request needs more than one file, such as %s,
+put each of them in files with its path from the repository root and a one-line purpose.
+Leave files empty when the main configuration is enough.`, target.AdditionalFiles)
+}
+
+// formatPipelineServices lists service containers as pipeline services
+func formatPipelineServices(services []ServiceRequirement) string {
+	var lines []string
+	for _, svc := range services {
+		line := fmt.Sprintf("- name: %s, image: %s", svc.Name, svc.Image)
+		if len(svc.Ports) > 0 {
+			line += ", ports: " + strings.Join(svc.Ports, ", ")
+		}
+		for _, name := range sortedMapKeys(svc.Env) {
+			line += fmt.Sprintf(", env %s=%s", name, svc.Env[name])
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
 
+// formatServiceConnections lists how the application reaches each service container;
+// byAlias gives the URLs that reach a service by its name on the container's own port,
+// for platforms that network services that way
+func formatServiceConnections(services []ServiceRequirement, byAlias bool) string {
+	var lines []string
+	for _, svc := range services {
+		url := svc.ConnectionURL
+		if byAlias {
+			url = serviceAliasURL(svc)
+		}
+		lines = append(lines, fmt.Sprintf("- %s: %s", svc.Name, url))
+	}
+	return strings.Join(lines, "\n")
 }
@@ -0,0 +1,275 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GitInfo is repository metadata read from the local .git directory (no network access)
+type GitInfo struct {
+	DefaultBranch string   `json:"default_branch,omitempty"` // Branch origin/HEAD points at, e.g. "main"
+	CurrentBranch string   `json:"current_branch,omitempty"` // Branch checked out locally
+	RemoteHost    string   `json:"remote_host,omitempty"`    // e.g., "github.com"
+	Owner         string   `json:"owner,omitempty"`          // Repository owner or organisation
+	Repo          string   `json:"repo,omitempty"`           // Repository name
+	TagStyle      string   `json:"tag_style,omitempty"`      // "v-prefixed semver", "plain semver" or "custom"
+	RecentTags    []string `json:"recent_tags,omitempty"`    // Highest tags, newest first
+	Submodules    []string `json:"submodules,omitempty"`     // Submodule paths from .gitmodules
+	UsesLFS       bool     `json:"uses_lfs"`                 // Whether Git LFS tracks files in the repository
+	IsFork        bool     `json:"is_fork"`                  // Whether an upstream remote points at another owner's copy
+	Upstream      string   `json:"upstream,omitempty"`       // owner/repo of the upstream, for forks
+}
+
+// remoteURLPattern matches https, ssh and scp-style remote URLs
+var remoteURLPattern = regexp.MustCompile(`^(?:[a-z+]+://)?(?:[^@/]+@)?([^/:]+)(?::\d+)?[:/](.+?)(?:\.git)?/?$`)
+
+// parseRemoteURL splits a remote URL into host and path ("owner/repo")
+func parseRemoteURL(url string) (host string, path string) {
+	m := remoteURLPattern.FindStringSubmatch(strings.TrimSpace(url))
+	if m == nil {
+		return "", ""
+	}
+	return m[1], m[2]
+}
+
+// findGitDir resolves the .git directory, following "gitdir:" files used by worktrees and submodules
+func findGitDir(workingDir string) (string, bool) {
+	gitPath := filepath.Join(workingDir, ".git")
+	fi, err := os.Stat(gitPath)
+	if err != nil {
+		return "", false
+	}
+	if fi.IsDir() {
+		return gitPath, true
+	}
+
+	content, ok := readFileString(gitPath)
+	if !ok || !strings.HasPrefix(content, "gitdir:") {
+		return "", false
+	}
+	dir := strings.TrimSpace(strings.TrimPrefix(content, "gitdir:"))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(workingDir, dir)
+	}
+	return dir, true
+}
+
+// DetectGit reads branch, remote, tag, submodule and LFS information from the repository;
+// it stops early when ctx is cancelled
+func DetectGit(ctx context.Context, workingDir string) (GitInfo, bool) {
+	info := GitInfo{}
+	gitDir, ok := findGitDir(workingDir)
+	if !ok {
+		return info, false
+	}
+
+	// Worktrees keep refs and config in the main repository's directory
+	commonDir := gitDir
+	if content, ok := readFileString(filepath.Join(gitDir, "commondir")); ok {
+		commonDir = filepath.Join(gitDir, strings.TrimSpace(content))
+	}
+
+	if head, ok := readFileString(filepath.Join(gitDir, "HEAD")); ok && strings.HasPrefix(head, "ref:") {
+		info.CurrentBranch = strings.TrimPrefix(strings.TrimSpace(strings.TrimPrefix(head, "ref:")), "refs/heads/")
+	}
+
+	remotes := parseGitRemotes(filepath.Join(commonDir, "config"))
+	if origin, ok := remotes["origin"]; ok {
+		host, path := parseRemoteURL(origin)
+		info.RemoteHost = host
+		if owner, repo, ok := strings.Cut(path, "/"); ok {
+			info.Owner, info.Repo = owner, repo
+		}
+	}
+	if upstream, ok := remotes["upstream"]; ok {
+		_, path := parseRemoteURL(upstream)
+		if owner, _, ok := strings.Cut(path, "/"); ok && owner != info.Owner {
+			info.IsFork = true
+			info.Upstream = path
+		}
+	}
+
+	refs := readGitRefs(ctx, commonDir)
+	if ctx.Err() != nil {
+		return info, false // The caller reports the cancellation
+	}
+	info.DefaultBranch = detectDefaultBranch(commonDir, refs, info.CurrentBranch)
+
+	var tags []string
+	for ref := range refs {
+		if tag, ok := strings.CutPrefix(ref, "refs/tags/"); ok {
+			tags = append(tags, tag)
+		}
+	}
+	info.TagStyle, info.RecentTags = classifyTags(tags)
+
+	if content, ok := readFileString(filepath.Join(workingDir, ".gitmodules")); ok {
+		for _, line := range strings.Split(content, "\n") {
+			if key, value, ok := strings.Cut(strings.TrimSpace(line), "="); ok && strings.TrimSpace(key) == "path" {
+				info.Submodules = append(info.Submodules, strings.TrimSpace(value))
+			}
+		}
+	}
+
+	if content, ok := readFileString(filepath.Join(workingDir, ".gitattributes")); ok && strings.Contains(content, "filter=lfs") {
+		info.UsesLFS = true
+	}
+
+	return info, true
+}
+
+// parseGitRemotes returns remote name → URL from a git config file
+func parseGitRemotes(configPath string) map[string]string {
+	remotes := map[string]string{}
+	file, err := os.Open(configPath)
+	if err != nil {
+		return remotes
+	}
+	defer file.Close()
+
+	section := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			section = ""
+			if name, ok := strings.CutPrefix(strings.Trim(line, "[]"), "remote "); ok {
+				section = strings.Trim(name, `"`)
+			}
+			continue
+		}
+		if section == "" {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok && strings.TrimSpace(key) == "url" {
+			remotes[section] = strings.TrimSpace(value)
+		}
+	}
+	return remotes
+}
+
+// readGitRefs collects loose and packed refs (names only)
+func readGitRefs(ctx context.Context, gitDir string) map[string]bool {
+	refs := map[string]bool{}
+
+	refsDir := filepath.Join(gitDir, "refs")
+	filepath.Walk(refsDir, func(path string, fi os.FileInfo, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err == nil && !fi.IsDir() {
+			rel, _ := filepath.Rel(gitDir, path)
+			refs[filepath.ToSlash(rel)] = true
+		}
+		return nil
+	})
+
+	if content, ok := readFileString(filepath.Join(gitDir, "packed-refs")); ok {
+		for _, line := range strings.Split(content, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 2 && !strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "^") {
+				refs[fields[1]] = true
+			}
+		}
+	}
+	return refs
+}
+
+func detectDefaultBranch(gitDir string, refs map[string]bool, currentBranch string) string {
+	if content, ok := readFileString(filepath.Join(gitDir, "refs", "remotes", "origin", "HEAD")); ok {
+		ref := strings.TrimSpace(strings.TrimPrefix(content, "ref:"))
+		return strings.TrimPrefix(ref, "refs/remotes/origin/")
+	}
+	for _, candidate := range []string{"main", "master", "trunk", "develop"} {
+		if refs["refs/remotes/origin/"+candidate] {
+			return candidate
+		}
+	}
+	for _, candidate := range []string{"main", "master"} {
+		if refs["refs/heads/"+candidate] {
+			return candidate
+		}
+	}
+	return currentBranch
+}
+
+var semverTagPattern = regexp.MustCompile(`^(v?)(\d+)\.(\d+)\.(\d+)`)
+
+// classifyTags works out the tag naming convention and returns the highest tags first
+func classifyTags(tags []string) (string, []string) {
+	if len(tags) == 0 {
+		return "", nil
+	}
+
+	prefixed, plain := 0, 0
+	for _, tag := range tags {
+		if m := semverTagPattern.FindStringSubmatch(tag); m != nil {
+			if m[1] == "v" {
+				prefixed++
+			} else {
+				plain++
+			}
+		}
+	}
+
+	style := "custom"
+	switch {
+	case prefixed > 0 && prefixed >= plain:
+		style = "v-prefixed semver"
+	case plain > 0:
+		style = "plain semver"
+	}
+
+	sort.Slice(tags, func(i, j int) bool { return compareTags(tags[i], tags[j]) > 0 })
+	if len(tags) > 3 {
+		tags = tags[:3]
+	}
+	return style, tags
+}
+
+// compareTags orders semver tags numerically, falling back to string order
+func compareTags(a, b string) int {
+	ma, mb := semverTagPattern.FindStringSubmatch(a), semverTagPattern.FindStringSubmatch(b)
+	if ma == nil || mb == nil {
+		return strings.Compare(a, b)
+	}
+	for i := 2; i <= 4; i++ {
+		na, _ := strconv.Atoi(ma[i])
+		nb, _ := strconv.Atoi(mb[i])
+		if na != nb {
+			return na - nb
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+// FormatGit summarises the repository metadata for prompts
+func (g GitInfo) FormatGit() []string {
+	var lines []string
+	if g.Repo != "" {
+		lines = append(lines, fmt.Sprintf("Repository: %s/%s/%s", g.RemoteHost, g.Owner, g.Repo))
+	}
+	if g.DefaultBranch != "" {
+		lines = append(lines, fmt.Sprintf("Default Branch: %s", g.DefaultBranch))
+	}
+	if g.TagStyle != "" {
+		lines = append(lines, fmt.Sprintf("Tag Convention: %s (latest: %s)", g.TagStyle, strings.Join(g.RecentTags, ", ")))
+	}
+	if len(g.Submodules) > 0 {
+		lines = append(lines, fmt.Sprintf("Submodules: %s (checkout needs submodules: recursive)", strings.Join(g.Submodules, ", ")))
+	}
+	if g.UsesLFS {
+		lines = append(lines, "Git LFS in use (checkout needs lfs: true)")
+	}
+	if g.IsFork {
+		lines = append(lines, fmt.Sprintf("Fork of %s (guard upstream-only jobs such as deploys with if: github.repository == '%s')", g.Upstream, g.Upstream))
+	}
+	return lines
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"regexp"
+	"strings"
+)
+
+// gitIgnore matches paths against the rules of one .gitignore file
+//
+// Rules follow git's semantics: a pattern without a slash matches a name at any
+// depth below the file's directory, a pattern with a slash is anchored to it,
+// a trailing "/" only matches directories, "**" matches any number of
+// directories, "!" re-includes a path, and the last matching rule wins.
+type gitIgnore struct {
+	base  string // Directory of the .gitignore, slash-separated and relative to the index root ("" for the root)
+	rules []gitIgnoreRule
+}
+
+type gitIgnoreRule struct {
+	pattern *regexp.Regexp
+	negate  bool // true for "!" exceptions
+	dirOnly bool // true for patterns ending in "/"
+}
+
+func parseGitIgnore(base string, content string) gitIgnore {
+	ignore := gitIgnore{base: base}
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := gitIgnoreRule{}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		expr := globToRegexp(line)
+		if !anchored {
+			expr = "^(.*/)?" + strings.TrimPrefix(expr, "^")
+		}
+		if re, err := regexp.Compile(expr); err == nil {
+			rule.pattern = re
+			ignore.rules = append(ignore.rules, rule)
+		}
+	}
+	return ignore
+}
+
+// match reports whether any rule matches rel (slash-separated, relative to the
+// index root) and, if so, whether the last matching rule ignores it
+func (g gitIgnore) match(rel string, isDir bool) (matched bool, ignored bool) {
+	if g.base != "" {
+		if !strings.HasPrefix(rel, g.base+"/") {
+			return false, false
+		}
+		rel = strings.TrimPrefix(rel, g.base+"/")
+	}
+	for _, rule := range g.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.pattern.MatchString(rel) {
+			matched, ignored = true, !rule.negate
+		}
+	}
+	return matched, ignored
+}
+
+// gitIgnoreStack is the set of .gitignore files that apply to a directory, outermost first
+type gitIgnoreStack []gitIgnore
+
+// Ignored reports whether rel is ignored; deeper .gitignore files override outer ones
+func (s gitIgnoreStack) Ignored(rel string, isDir bool) bool {
+	ignored := false
+	for _, g := range s {
+		if matched, ig := g.match(rel, isDir); matched {
+			ignored = ig
+		}
+	}
+	return ignored
+}
+
+// with returns the stack extended by the rules of a .gitignore in dir
+func (s gitIgnoreStack) with(dir string, content string) gitIgnoreStack {
+	extended := make(gitIgnoreStack, len(s), len(s)+1)
+	copy(extended, s)
+	return append(extended, parseGitIgnore(dir, content))
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import "testing"
+
+func TestGitIgnore(t *testing.T) {
+	root := `# Build output
+/dist
+build/
+*.log
+!keep.log
+docs/**/*.tmp
+\#notes
+`
+	nested := `*.gen.go
+!/api.gen.go
+`
+	stack := gitIgnoreStack{}.with("", root).with("pkg", nested)
+
+	tests := []struct {
+		rel   string
+		isDir bool
+		want  bool
+	}{
+		{rel: "dist", isDir: true, want: true},
+		{rel: "cmd/dist", isDir: true, want: false}, // Anchored to the root
+		{rel: "build", isDir: true, want: true},
+		{rel: "cmd/build", isDir: true, want: true}, // Unanchored: any depth
+		{rel: "build", want: false},                 // Directories only
+		{rel: "server.log", want: true},
+		{rel: "logs/server.log", want: true},
+		{rel: "keep.log", want: false},
+		{rel: "logs/keep.log", want: false},
+		{rel: "docs/a.tmp", want: true},
+		{rel: "docs/a/b/c.tmp", want: true},
+		{rel: "a.tmp", want: false},
+		{rel: "#notes", want: true},
+		{rel: "main.go", want: false},
+		{rel: "pkg/models.gen.go", want: true},
+		{rel: "pkg/sub/models.gen.go", want: true},
+		{rel: "pkg/api.gen.go", want: false},     // Re-included by the nested file
+		{rel: "pkg/sub/api.gen.go", want: true},  // The exception is anchored to pkg
+		{rel: "models.gen.go", want: false},      // Outside the nested file's directory
+		{rel: "pkg/debug.log", want: true},       // Outer rules still apply
+		{rel: "pkgs/models.gen.go", want: false}, // Not below pkg/
+	}
+	for _, tt := range tests {
+		if got := stack.Ignored(tt.rel, tt.isDir); got != tt.want {
+			t.Errorf("Ignored(%q, %v) = %v, want %v", tt.rel, tt.isDir, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// gitlabGlobalKeywords are the top-level keys of .gitlab-ci.yml that are not jobs
+var gitlabGlobalKeywords = map[string]bool{
+	"default": true, "include": true, "stages": true, "variables": true, "workflow": true, "spec": true,
+	// Deprecated globals, still accepted by GitLab
+	"image": true, "services": true, "cache": true, "before_script": true, "after_script": true,
+}
+
+// gitlabJobKeywords are the keys GitLab's CI schema allows in a job
+var gitlabJobKeywords = map[string]bool{
+	"script": true, "run": true, "stage": true, "image": true, "services": true, "before_script": true, "after_script": true,
+	"variables": true, "rules": true, "only": true, "except": true, "needs": true, "dependencies": true, "artifacts": true,
+	"cache": true, "when": true, "start_in": true, "allow_failure": true, "environment": true, "tags": true, "timeout": true,
+	"retry": true, "parallel": true, "trigger": true, "extends": true, "coverage": true, "interruptible": true,
+	"resource_group": true, "release": true, "secrets": true, "id_tokens": true, "inherit": true, "hooks": true,
+	"identity": true, "pages": true, "manual_confirmation": true,
+}
+
+// gitlabRuleKeywords are the keys allowed in a rules: entry
+var gitlabRuleKeywords = map[string]bool{
+	"if": true, "changes": true, "exists": true, "when": true, "allow_failure": true, "variables": true,
+	"needs": true, "start_in": true, "interruptible": true,
+}
+
+var gitlabWhenValues = map[string]bool{
+	"on_success": true, "on_failure": true, "always": true, "manual": true, "delayed": true, "never": true,
+}
+
+// gitlabKeywordKinds are the YAML shapes GitLab accepts for a global or job keyword
+var gitlabKeywordKinds = map[string][]yaml.Kind{
+	"stages": {yaml.SequenceNode}, "default": {yaml.MappingNode}, "workflow": {yaml.MappingNode},
+	"include": {yaml.ScalarNode, yaml.SequenceNode, yaml.MappingNode}, "variables": {yaml.MappingNode},
+	"script": {yaml.ScalarNode, yaml.SequenceNode}, "before_script": {yaml.ScalarNode, yaml.SequenceNode},
+	"after_script": {yaml.ScalarNode, yaml.SequenceNode}, "stage": {yaml.ScalarNode},
+	"image": {yaml.ScalarNode, yaml.MappingNode}, "services": {yaml.SequenceNode}, "rules": {yaml.SequenceNode},
+	"needs": {yaml.SequenceNode}, "dependencies": {yaml.SequenceNode}, "artifacts": {yaml.MappingNode},
+	"cache": {yaml.MappingNode, yaml.SequenceNode}, "when": {yaml.ScalarNode}, "tags": {yaml.SequenceNode},
+	"environment": {yaml.ScalarNode, yaml.MappingNode}, "extends": {yaml.ScalarNode, yaml.SequenceNode},
+	"trigger": {yaml.ScalarNode, yaml.MappingNode}, "retry": {yaml.ScalarNode, yaml.MappingNode},
+	"parallel": {yaml.ScalarNode, yaml.MappingNode}, "timeout": {yaml.ScalarNode}, "allow_failure": {yaml.ScalarNode, yaml.MappingNode},
+}
+
+// gitlabDefaultStages apply when the pipeline does not declare stages:
+var gitlabDefaultStages = []string{".pre", "build", "test", "deploy", ".post"}
+
+// validateGitLabCI checks a .gitlab-ci.yml against the keywords and references GitLab's CI schema allows
+func validateGitLabCI(config string) []string {
+	root, problems := parseYAMLMapping(config)
+	if root == nil {
+		return problems
+	}
+	if mappingValue(root, "jobs") != nil && mappingValue(root, "on") != nil {
+		return []string{`this looks like a GitHub Actions workflow ("on" and "jobs"), not .gitlab-ci.yml`}
+	}
+
+	problems = append(problems, gitlabKindProblems("", root)...)
+
+	stages := map[string]bool{}
+	if declared := mappingValue(root, "stages"); declared != nil {
+		for _, stage := range declared.Content {
+			if stage.Kind != yaml.ScalarNode {
+				problems = append(problems, "stages must be a list of stage names")
+				continue
+			}
+			stages[stage.Value] = true
+		}
+		stages[".pre"], stages[".post"] = true, true
+	} else {
+		for _, stage := range gitlabDefaultStages {
+			stages[stage] = true
+		}
+	}
+
+	jobs := map[string]*yaml.Node{}
+	var order []string
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key := root.Content[i].Value
+		if gitlabGlobalKeywords[key] {
+			continue
+		}
+		jobs[key] = deref(root.Content[i+1])
+		order = append(order, key)
+	}
+	if len(order) == 0 {
+		return append(problems, "no jobs defined")
+	}
+
+	// Jobs may come from include: files or extend templates defined elsewhere; only check references we can see
+	includes := mappingValue(root, "include") != nil
+	visible := 0
+	for _, name := range order {
+		job := jobs[name]
+		hidden := strings.HasPrefix(name, ".")
+		if !hidden {
+			visible++
+		}
+		if job.Kind != yaml.MappingNode {
+			problems = append(problems, fmt.Sprintf("job %q must be a mapping", name))
+			continue
+		}
+
+		for i := 0; i+1 < len(job.Content); i += 2 {
+			if key := job.Content[i].Value; !gitlabJobKeywords[key] && key != "<<" {
+				problems = append(problems, fmt.Sprintf("job %q: unknown keyword %q", name, key))
+			}
+		}
+		problems = append(problems, gitlabKindProblems(fmt.Sprintf("job %q: ", name), job)...)
+		if hidden {
+			continue // Templates only need to be valid once extended
+		}
+
+		if mappingValue(job, "script") == nil && mappingValue(job, "trigger") == nil &&
+			mappingValue(job, "run") == nil && mappingValue(job, "extends") == nil && !hasMergeKey(job) {
+			problems = append(problems, fmt.Sprintf("job %q needs a script or trigger", name))
+		}
+		if script := mappingValue(job, "script"); script != nil && script.Kind == yaml.SequenceNode && len(script.Content) == 0 {
+			problems = append(problems, fmt.Sprintf("job %q: script is empty", name))
+		}
+		if stage := mappingValue(job, "stage"); stage != nil && !includes && !stages[stage.Value] {
+			problems = append(problems, fmt.Sprintf("job %q: stage %q is not listed in stages", name, stage.Value))
+		}
+		if when := mappingValue(job, "when"); when != nil && !gitlabWhenValues[when.Value] {
+			problems = append(problems, fmt.Sprintf("job %q: invalid when %q", name, when.Value))
+		}
+		if mappingValue(job, "rules") != nil && (mappingValue(job, "only") != nil || mappingValue(job, "except") != nil) {
+			problems = append(problems, fmt.Sprintf("job %q: rules cannot be combined with only/except", name))
+		}
+		if rules := mappingValue(job, "rules"); rules != nil {
+			for _, rule := range rules.Content {
+				rule = deref(rule)
+				if rule.Kind != yaml.MappingNode {
+					continue // !reference tags
+				}
+				for i := 0; i+1 < len(rule.Content); i += 2 {
+					if key := rule.Content[i].Value; !gitlabRuleKeywords[key] {
+						problems = append(problems, fmt.Sprintf("job %q: unknown rules keyword %q", name, key))
+					}
+				}
+				if when := mappingValue(rule, "when"); when != nil && !gitlabWhenValues[when.Value] {
+					problems = append(problems, fmt.Sprintf("job %q: invalid rules when %q", name, when.Value))
+				}
+			}
+		}
+		if extends := mappingValue(job, "extends"); extends != nil && !includes {
+			for _, template := range scalarValues(extends) {
+				if _, ok := jobs[template]; !ok {
+					problems = append(problems, fmt.Sprintf("job %q extends %q, which is not defined", name, template))
+				}
+			}
+		}
+		if needs := mappingValue(job, "needs"); needs != nil && needs.Kind == yaml.SequenceNode && !includes {
+			for _, need := range needs.Content {
+				need = deref(need)
+				target := need.Value
+				if need.Kind == yaml.MappingNode {
+					if mappingValue(need, "project") != nil || mappingValue(need, "pipeline") != nil {
+						continue // Cross-project or parent pipeline artifacts
+					}
+					if optional := mappingValue(need, "optional"); optional != nil && optional.Value == "true" {
+						continue
+					}
+					if job := mappingValue(need, "job"); job != nil {
+						target = job.Value
+					}
+				}
+				if _, ok := jobs[target]; !ok && target != "" {
+					problems = append(problems, fmt.Sprintf("job %q needs %q, which is not defined", name, target))
+				}
+			}
+		}
+	}
+	if visible == 0 {
+		problems = append(problems, "only hidden (.template) jobs defined; nothing will run")
+	}
+	return problems
+}
+
+// gitlabKindProblems checks that the keywords of a mapping have the shape
+// GitLab accepts, e.g. script is a command or a list of commands. Lists of
+// commands may nest, as YAML anchors produce, but hold only strings.
+func gitlabKindProblems(where string, node *yaml.Node) []string {
+	var problems []string
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i].Value, deref(node.Content[i+1])
+		kinds, ok := gitlabKeywordKinds[key]
+		if !ok || value.Tag == "!reference" || (value.Kind == yaml.ScalarNode && value.Tag == "!!null") {
+			continue
+		}
+		if !slices.Contains(kinds, value.Kind) {
+			var names []string
+			for _, kind := range kinds {
+				names = append(names, yamlKindName(kind))
+			}
+			problems = append(problems, fmt.Sprintf("%s%s must be %s, not %s", where, key, strings.Join(names, " or "), yamlKindName(value.Kind)))
+			continue
+		}
+		if strings.HasSuffix(key, "script") && !scalarsOnly(value) {
+			problems = append(problems, fmt.Sprintf("%s%s must list commands as strings", where, key))
+		}
+	}
+	return problems
+}
+
+// scalarsOnly reports whether node is a scalar or a (nested) sequence of scalars
+func scalarsOnly(node *yaml.Node) bool {
+	node = deref(node)
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return true
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			if !scalarsOnly(item) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// yamlKindName names a node kind for messages
+func yamlKindName(kind yaml.Kind) string {
+	switch kind {
+	case yaml.ScalarNode:
+		return "a string"
+	case yaml.SequenceNode:
+		return "a list"
+	case yaml.MappingNode:
+		return "a mapping"
+	}
+	return "empty"
+}
+
+// hasMergeKey reports whether a mapping pulls its keys from a YAML anchor with <<
+func hasMergeKey(node *yaml.Node) bool {
+	return mappingValue(node, "<<") != nil
+}
+
+// scalarValues returns a scalar's value, or the values of a sequence of scalars
+func scalarValues(node *yaml.Node) []string {
+	if node.Kind == yaml.ScalarNode {
+		return []string{node.Value}
+	}
+	var values []string
+	for _, item := range node.Content {
+		if item.Kind == yaml.ScalarNode {
+			values = append(values, item.Value)
+		}
+	}
+	return values
+}
+
+// FormatGitLabServicesYAML renders services as GitLab CI job services: and variables:.
+// GitLab reaches services by their alias, so localhost in the connection details becomes the service name.
+func FormatGitLabServicesYAML(services []ServiceRequirement) string {
+	if len(services) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	variables := map[string]string{}
+	b.WriteString("services:\n")
+	for _, svc := range services {
+		fmt.Fprintf(&b, "  - name: %s\n", svc.Image)
+		fmt.Fprintf(&b, "    alias: %s\n", svc.Name)
+		for k, v := range svc.Env {
+			variables[k] = strings.ReplaceAll(v, "localhost", svc.Name)
+		}
+	}
+	if len(variables) > 0 {
+		b.WriteString("variables:\n")
+		keys := make([]string, 0, len(variables))
+		for k := range variables {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(&b, "  %s: %q\n", k, variables[k])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestValidateGitLabCI(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   []string
+	}{
+		{
+			name: "valid",
+			config: `stages: [build, test]
+build:
+  stage: build
+  script: go build ./...
+test:
+  stage: test
+  needs: [build]
+  script:
+    - go test ./...
+`,
+		},
+		{
+			name: "missing script",
+			config: `test:
+  stage: test
+  image: golang:1.22
+`,
+			want: []string{`job "test" needs a script or trigger`},
+		},
+		{
+			name: "stage not declared",
+			config: `stages: [build]
+test:
+  stage: test
+  script: make test
+`,
+			want: []string{`job "test": stage "test" is not listed in stages`},
+		},
+		{
+			name: "needs an undefined job",
+			config: `test:
+  needs: [build]
+  script: make test
+`,
+			want: []string{`job "test" needs "build", which is not defined`},
+		},
+		{
+			name: "wrong types",
+			config: `stages: build
+test:
+  script: {run: make test}
+  variables: [GOFLAGS=-mod=mod]
+  needs: build
+`,
+			want: []string{
+				"stages must be a list, not a string",
+				`job "test": script must be a string or a list, not a mapping`,
+				`job "test": variables must be a mapping, not a list`,
+				`job "test": needs must be a list, not a string`,
+			},
+		},
+		{
+			name: "script with nested mapping",
+			config: `test:
+  script:
+    - make test
+    - {echo: done}
+`,
+			want: []string{`job "test": script must list commands as strings`},
+		},
+		{
+			name: "empty script",
+			config: `test:
+  script: []
+`,
+			want: []string{`job "test": script is empty`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validateGitLabCI(tt.config); !slices.Equal(got, tt.want) {
+				t.Errorf("problems = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
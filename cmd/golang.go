@@ -0,0 +1,315 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// GoModuleInfo is what CI needs to know about a Go module beyond its dependencies
+type GoModuleInfo struct {
+	Module          string     `json:"module,omitempty"`           // Module path from go.mod
+	GoVersion       string     `json:"go_version,omitempty"`       // go directive, e.g. "1.22"
+	Toolchain       string     `json:"toolchain,omitempty"`        // toolchain directive, e.g. "go1.22.5"
+	Workspace       []string   `json:"workspace,omitempty"`        // Module directories listed in go.work
+	Binaries        []GoBinary `json:"binaries,omitempty"`         // main packages, one per binary
+	Cgo             []string   `json:"cgo,omitempty"`              // Packages that import "C"
+	Generate        []string   `json:"generate,omitempty"`         // Distinct //go:generate commands
+	Tools           []string   `json:"tools,omitempty"`            // tool directives and tools.go imports
+	Vendored        bool       `json:"vendored"`                   // vendor/modules.txt is committed
+	PrivateModules  []string   `json:"private_modules,omitempty"`  // GOPRIVATE candidates
+	PrivateDeclared bool       `json:"private_declared,omitempty"` // GOPRIVATE already set in the repository's scripts
+}
+
+// GoBinary is a main package and the command that builds it
+type GoBinary struct {
+	Name         string `json:"name"`          // Binary name, from the package directory
+	Package      string `json:"package"`       // Package path relative to the module, e.g. "./cmd/server"
+	BuildCommand string `json:"build_command"` // e.g. "go build -o bin/server ./cmd/server"
+}
+
+// publicGoHosts serve public modules through proxy.golang.org; dependencies on
+// any other host are GOPRIVATE candidates
+var publicGoHosts = map[string]bool{
+	"github.com": true, "gitlab.com": true, "bitbucket.org": true, "codeberg.org": true, "git.sr.ht": true, "gitea.com": true,
+	"golang.org": true, "google.golang.org": true, "cloud.google.com": true, "gopkg.in": true, "go.uber.org": true,
+	"k8s.io": true, "sigs.k8s.io": true, "go.opentelemetry.io": true, "go.etcd.io": true, "go.mongodb.org": true,
+	"gorm.io": true, "gotest.tools": true, "honnef.co": true, "modernc.org": true, "mvdan.cc": true, "rsc.io": true,
+	"filippo.io": true, "nhooyr.io": true, "gocloud.dev": true, "dario.cat": true, "connectrpc.com": true,
+	"buf.build": true, "go.yaml.in": true, "golang.zx2c4.com": true, "cel.dev": true, "go.starlark.net": true,
+	"tailscale.com": true, "storj.io": true, "entgo.io": true, "ariga.io": true, "gonum.org": true, "go4.org": true,
+	"howett.net": true, "lukechampine.com": true, "zombiezen.com": true, "pgregory.net": true, "code.gitea.io": true,
+	"cuelang.org": true, "helm.sh": true, "oras.land": true, "dagger.io": true, "tags.cncf.io": true,
+}
+
+var (
+	cgoImportPattern   = regexp.MustCompile(`(?m)^\s*(?:import\s+)?"C"\s*$`)
+	generatePattern    = regexp.MustCompile(`(?m)^//go:generate\s+(.+)$`)
+	blankImportPattern = regexp.MustCompile(`(?m)^\s*_\s+"([^"]+)"`)
+	goPrivatePattern   = regexp.MustCompile(`GOPRIVATE`)
+	goMajorVersion     = regexp.MustCompile(`^v\d+$`)
+)
+
+// detectGoModule reads go.mod, go.work and the module's Go files for binaries,
+// cgo, code generation, tools, vendoring and private dependencies. requires are
+// the module's direct requires; indirect ones are not the project's to configure.
+func detectGoModule(ctx context.Context, workingDir string, files *FileIndex, goMod string, requires []string) (*GoModuleInfo, []Diagnostic) {
+	info := &GoModuleInfo{}
+	var diags []Diagnostic
+
+	inTool := false
+	for _, line := range strings.Split(goMod, "\n") {
+		line = strings.TrimSpace(line)
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		switch {
+		case inTool:
+			if line == ")" {
+				inTool = false
+			} else if line != "" {
+				info.Tools = appendUnique(info.Tools, line)
+			}
+		case strings.HasPrefix(line, "module "):
+			info.Module = strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "module")), `"`)
+		case strings.HasPrefix(line, "go "):
+			info.GoVersion = strings.TrimSpace(strings.TrimPrefix(line, "go"))
+		case strings.HasPrefix(line, "toolchain "):
+			info.Toolchain = strings.TrimSpace(strings.TrimPrefix(line, "toolchain"))
+		case line == "tool (":
+			inTool = true
+		case strings.HasPrefix(line, "tool "):
+			info.Tools = appendUnique(info.Tools, strings.TrimSpace(strings.TrimPrefix(line, "tool")))
+		}
+	}
+	if info.Module == "" {
+		diags = append(diags, Diagnostic{Severity: SeverityWarning, File: "go.mod", Message: "no module directive"})
+	}
+
+	if goWork, ok := readFileString(filepath.Join(workingDir, "go.work")); ok {
+		inUse := false
+		for _, line := range strings.Split(goWork, "\n") {
+			line = strings.TrimSpace(line)
+			switch {
+			case line == "use (":
+				inUse = true
+			case inUse && line == ")":
+				inUse = false
+			case inUse && line != "" && !strings.HasPrefix(line, "//"):
+				info.Workspace = append(info.Workspace, line)
+			case strings.HasPrefix(line, "use "):
+				info.Workspace = append(info.Workspace, strings.TrimSpace(strings.TrimPrefix(line, "use")))
+			}
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(workingDir, "vendor", "modules.txt")); err == nil {
+		info.Vendored = true
+	}
+
+	// One pass over the Go sources: package clauses, cgo, go:generate and tools.go
+	mainDirs := map[string]bool{}
+	cgoDirs := map[string]bool{}
+	nested := goNestedModules(files)
+	for _, rel := range files.Find(func(rel string) bool {
+		return strings.HasSuffix(rel, ".go") && !strings.HasSuffix(rel, "_test.go") && !underDir(rel, "testdata") && !inGoModule(rel, nested)
+	}) {
+		if ctx.Err() != nil {
+			break // The caller reports the cancellation
+		}
+		content, ok := readFileString(files.Abs(rel))
+		if !ok || len(content) > 1<<20 {
+			continue
+		}
+		tags := goBuildTags(files.Abs(rel))
+		dir := path.Dir(rel)
+
+		if slices.Contains(tags, "tools") || path.Base(rel) == "tools.go" {
+			for _, m := range blankImportPattern.FindAllStringSubmatch(content, -1) {
+				info.Tools = appendUnique(info.Tools, m[1])
+			}
+			continue
+		}
+		if slices.Contains(tags, "ignore") {
+			continue // go run helpers, not part of the build
+		}
+
+		if goPackageName(content) == "main" {
+			mainDirs[dir] = true
+		}
+		if cgoImportPattern.MatchString(goImportBlock(content)) {
+			cgoDirs[dir] = true
+		}
+		for _, m := range generatePattern.FindAllStringSubmatch(content, -1) {
+			if len(info.Generate) < 10 {
+				info.Generate = appendUnique(info.Generate, strings.TrimSpace(m[1]))
+			}
+		}
+	}
+
+	info.Binaries = goBinaries(info.Module, sortedKeys(mainDirs))
+	for _, dir := range sortedKeys(cgoDirs) {
+		info.Cgo = append(info.Cgo, goPackagePath(dir))
+	}
+
+	// Dependencies outside the public module hosts need GOPRIVATE (and git credentials) in CI
+	private := map[string]bool{}
+	for _, dep := range requires {
+		host := strings.SplitN(dep, "/", 2)[0]
+		if strings.Contains(host, ".") && !publicGoHosts[host] {
+			private[host+"/*"] = true
+		}
+	}
+	info.PrivateModules = sortedKeys(private)
+	scripts := []string{"Makefile", "Dockerfile", ".envrc", ".env.example", "Taskfile.yml"}
+	for _, workflow := range listWorkflowFiles(workingDir) {
+		if rel, err := filepath.Rel(workingDir, workflow); err == nil {
+			scripts = append(scripts, rel)
+		}
+	}
+	for _, script := range scripts {
+		if content, ok := readFileString(filepath.Join(workingDir, script)); ok && goPrivatePattern.MatchString(content) {
+			info.PrivateDeclared = true
+		}
+	}
+
+	return info, diags
+}
+
+// goPackageName returns the name in the file's package clause
+func goPackageName(content string) string {
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if name, ok := strings.CutPrefix(line, "package "); ok {
+			return strings.Fields(name)[0]
+		}
+	}
+	return ""
+}
+
+// goImportBlock returns the file's header up to the first declaration, where imports live
+func goImportBlock(content string) string {
+	for _, decl := range []string{"\nfunc ", "\ntype ", "\nvar ", "\nconst "} {
+		if i := strings.Index(content, decl); i >= 0 {
+			content = content[:i]
+		}
+	}
+	return content
+}
+
+// goPackagePath turns an index directory into a go command package pattern
+func goPackagePath(dir string) string {
+	if dir == "." {
+		return "."
+	}
+	return "./" + dir
+}
+
+// goNestedModules lists the directories below the root with their own go.mod.
+// They are separate modules: ./... does not reach into them.
+func goNestedModules(files *FileIndex) []string {
+	var dirs []string
+	for _, rel := range files.FindNamed(func(name string) bool { return name == "go.mod" }) {
+		if dir := path.Dir(rel); dir != "." {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// inGoModule reports whether rel is inside one of the module directories
+func inGoModule(rel string, modules []string) bool {
+	for _, dir := range modules {
+		if strings.HasPrefix(rel, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// goBinaries names the binaries built from the main packages in dirs. Two
+// packages with the same directory name, such as cmd/server and
+// tools/cmd/server, would both build bin/server, so those are named after
+// their whole path instead (cmd-server, tools-cmd-server).
+func goBinaries(module string, dirs []string) []GoBinary {
+	var binaries []GoBinary
+	count := map[string]int{}
+	for _, dir := range dirs {
+		binary := goBinary(module, dir)
+		count[binary.Name]++
+		binaries = append(binaries, binary)
+	}
+	for i, binary := range binaries {
+		if count[binary.Name] > 1 && binary.Package != "." {
+			name := strings.ReplaceAll(strings.TrimPrefix(binary.Package, "./"), "/", "-")
+			binaries[i] = GoBinary{Name: name, Package: binary.Package, BuildCommand: fmt.Sprintf("go build -o bin/%s %s", name, binary.Package)}
+		}
+	}
+	return binaries
+}
+
+// goBinary names the binary built from a main package: its directory name,
+// or the last element of the module path for a main package at the root
+func goBinary(module, dir string) GoBinary {
+	name := path.Base(dir)
+	if dir == "." {
+		name = path.Base(module)
+		if goMajorVersion.MatchString(name) {
+			name = path.Base(path.Dir(module))
+		}
+	}
+	if name == "." || name == "/" || name == "" {
+		name = "app"
+	}
+	pkg := goPackagePath(dir)
+	return GoBinary{Name: name, Package: pkg, BuildCommand: fmt.Sprintf("go build -o bin/%s %s", name, pkg)}
+}
+
+// FormatGo summarises the Go module for prompts
+func (g *GoModuleInfo) FormatGo() []string {
+	var lines []string
+	if g.GoVersion != "" {
+		version := "Go Version: " + g.GoVersion + " (from go.mod; use go-version-file: go.mod)"
+		if g.Toolchain != "" {
+			version += ", toolchain " + g.Toolchain
+		}
+		lines = append(lines, version)
+	}
+	if len(g.Workspace) > 0 {
+		lines = append(lines, fmt.Sprintf("Workspace (go.work): %s", strings.Join(g.Workspace, ", ")))
+	}
+	if len(g.Binaries) > 0 {
+		var binaries []string
+		for _, b := range g.Binaries {
+			binaries = append(binaries, fmt.Sprintf("%s (%s)", b.Name, b.BuildCommand))
+		}
+		lines = append(lines, fmt.Sprintf("Binaries: %s", strings.Join(binaries, "; ")))
+	}
+	if len(g.Cgo) > 0 {
+		lines = append(lines, fmt.Sprintf("cgo: used by %s (needs CGO_ENABLED=1 and a C toolchain; cross-compiling needs a cross C compiler)", strings.Join(g.Cgo, ", ")))
+	}
+	if len(g.Generate) > 0 {
+		lines = append(lines, fmt.Sprintf("go generate: %s (run go generate ./... and fail if it changes tracked files)", strings.Join(g.Generate, "; ")))
+	}
+	if len(g.Tools) > 0 {
+		lines = append(lines, fmt.Sprintf("Tools: %s", strings.Join(g.Tools, ", ")))
+	}
+	if g.Vendored {
+		lines = append(lines, "Vendored: vendor/ is committed (go uses it automatically; skip go mod download and the module cache)")
+	}
+	if len(g.PrivateModules) > 0 {
+		line := fmt.Sprintf("Private Module Candidates: %s (set GOPRIVATE and git credentials if they are private)", strings.Join(g.PrivateModules, ", "))
+		if g.PrivateDeclared {
+			line += "; GOPRIVATE is already set in the repository's scripts"
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestDetectGoModule(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		requires []string
+		binaries []string // Build commands
+		private  []string
+	}{
+		{
+			name: "nested modules are not built",
+			files: map[string]string{
+				"main.go":              "package main\n",
+				"tools/go.mod":         "module example.com/app/tools\n",
+				"tools/lint/main.go":   "package main\n",
+				"examples/go.mod":      "module example.com/app/examples\n",
+				"examples/demo/app.go": "package main\n",
+			},
+			binaries: []string{"go build -o bin/app ."},
+		},
+		{
+			name: "same directory name in two subtrees",
+			files: map[string]string{
+				"cmd/server/main.go":       "package main\n",
+				"tools/cmd/server/main.go": "package main\n",
+				"cmd/worker/main.go":       "package main\n",
+			},
+			binaries: []string{
+				"go build -o bin/cmd-server ./cmd/server",
+				"go build -o bin/worker ./cmd/worker",
+				"go build -o bin/tools-cmd-server ./tools/cmd/server",
+			},
+		},
+		{
+			name:     "private hosts from direct requires",
+			files:    map[string]string{"lib.go": "package app\n"},
+			requires: []string{"git.acme.dev/platform/auth", "github.com/spf13/cobra"},
+			private:  []string{"git.acme.dev/*"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			goMod := "module example.com/app\n\ngo 1.22\n"
+			writeTestFile(t, filepath.Join(dir, "go.mod"), goMod)
+			for name, content := range tt.files {
+				writeTestFile(t, filepath.Join(dir, name), content)
+			}
+
+			info, _ := detectGoModule(context.Background(), dir, NewFileIndex(dir), goMod, tt.requires)
+			var binaries []string
+			for _, b := range info.Binaries {
+				binaries = append(binaries, b.BuildCommand)
+			}
+			if !slices.Equal(binaries, tt.binaries) {
+				t.Errorf("binaries = %q, want %q", binaries, tt.binaries)
+			}
+			if !slices.Equal(info.PrivateModules, tt.private) {
+				t.Errorf("private modules = %q, want %q", info.PrivateModules, tt.private)
+			}
+		})
+	}
+}
+
+func TestIndirectRequiresAreNotPrivateModules(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "go.mod"), `module example.com/app
+
+go 1.22
+
+require (
+	git.acme.dev/platform/auth v1.0.0
+	git.vendor.io/shared/log v0.3.0 // indirect
+)
+`)
+	writeTestFile(t, filepath.Join(dir, "main.go"), "package main\n")
+
+	langCtx, err := (&GoDetector{}).Detect(context.Background(), dir, NewFileIndex(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"git.acme.dev/*"}; !slices.Equal(langCtx.Go.PrivateModules, want) {
+		t.Errorf("private modules = %q, want %q", langCtx.Go.PrivateModules, want)
+	}
+}
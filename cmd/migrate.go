@@ -0,0 +1,671 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Migrate a CI configuration to GitHub Actions",
+	Long: `Migrate a GitLab CI, CircleCI, Travis CI or Jenkins (declarative Jenkinsfile) configuration
+to a GitHub Actions workflow.
+
+The translation is deterministic and needs no API key: the same input always gives the same
+workflow. Jobs, stages, caches, artifacts, services, matrices and variables are mapped directly;
+constructs with no equivalent are kept as failing steps marked with TODO(fluxion) comments.
+With --ai, only the failing TODO steps are sent to the AI. Its translations replace those steps,
+marked for review, and the rest of the workflow is kept; the changes are shown as a diff.`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          migrateConfiguration,
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+
+	migrateCmd.Flags().StringP("from", "F", "", "Source platform: "+strings.Join(migrationSourceNames(), ", ")+" (default: detected from the files present)")
+	migrateCmd.Flags().StringP("file", "f", "", "Source configuration file (default depends on --from, e.g. .gitlab-ci.yml)")
+	migrateCmd.Flags().StringP("output", "o", ".github/workflows/ci.yml", "Output path for the GitHub Actions workflow")
+	migrateCmd.Flags().Bool("force", false, "Overwrite an existing workflow without asking")
+	migrateCmd.Flags().Bool("ai", false, "Translate the constructs left as TODOs with the AI (requires OPENAI_API_KEY)")
+}
+
+// migrationSource is a CI platform migrate can read configuration from
+type migrationSource struct {
+	Name         string // --from value, e.g. "gitlab"
+	Platform     string // Display name, e.g. "GitLab CI"
+	DefaultInput string // Configuration file, relative to the repository root
+	Parse        func(config string) (Pipeline, error)
+}
+
+// Registry of migration sources, in the order they are detected
+var migrationSources = []migrationSource{
+	{Name: "gitlab", Platform: "GitLab CI", DefaultInput: ".gitlab-ci.yml", Parse: ParseGitLabCI},
+	{Name: "circleci", Platform: "CircleCI", DefaultInput: ".circleci/config.yml", Parse: ParseCircleCI},
+	{Name: "travis", Platform: "Travis CI", DefaultInput: ".travis.yml", Parse: ParseTravisCI},
+	{Name: "jenkins", Platform: "Jenkins", DefaultInput: "Jenkinsfile", Parse: ParseJenkinsfile},
+}
+
+// findMigrationSource looks up a source by name; an empty name picks the first whose file exists
+func findMigrationSource(name, workingDir string) (migrationSource, error) {
+	for _, source := range migrationSources {
+		if name == "" {
+			if _, err := os.Stat(filepath.Join(workingDir, source.DefaultInput)); err == nil {
+				return source, nil
+			}
+		} else if strings.EqualFold(source.Name, name) {
+			return source, nil
+		}
+	}
+	if name == "" {
+		return migrationSource{}, fmt.Errorf("no CI configuration found; use --from (supported: %s)", strings.Join(migrationSourceNames(), ", "))
+	}
+	return migrationSource{}, fmt.Errorf("unknown source %q (supported: %s)", name, strings.Join(migrationSourceNames(), ", "))
+}
+
+// migrationSourceNames lists the supported --from values
+func migrationSourceNames() []string {
+	names := make([]string, 0, len(migrationSources))
+	for _, source := range migrationSources {
+		names = append(names, source.Name)
+	}
+	return names
+}
+
+func migrateConfiguration(cmd *cobra.Command, args []string) error {
+	from, _ := cmd.Flags().GetString("from")
+	file, _ := cmd.Flags().GetString("file")
+	output, _ := cmd.Flags().GetString("output")
+	useAI, _ := cmd.Flags().GetBool("ai")
+	force, _ := cmd.Flags().GetBool("force")
+
+	workingDir := GetWorkingDirectory()
+	source, err := findMigrationSource(from, workingDir)
+	if err != nil {
+		return err
+	}
+	if file == "" {
+		file = filepath.Join(workingDir, source.DefaultInput)
+	}
+	config, err := loadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to load %s configuration: %w", source.Platform, err)
+	}
+
+	pipeline, err := source.Parse(config)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+	workflow, err := RenderGitHubActions(pipeline)
+	if err != nil {
+		return err
+	}
+
+	todos := pipeline.TODOCount()
+	var notes []string
+	if useAI && todos > 0 {
+		if workflow, notes, err = translateMigrationTODOs(cmd, source, config, workflow, workingDir); err != nil {
+			return err
+		}
+		todos = strings.Count(workflow, "TODO(fluxion)")
+	}
+
+	outputPath, _ := filepath.Abs(output)
+	if err := safeWrite(cmd, outputPath, workflow, force); err != nil {
+		return err
+	}
+	problems := validateGitHubWorkflow(workflow)
+
+	cmd.Println("\n" + "═══════════════════════════════════════════════════════════════")
+	cmd.Printf("✨ Migrated %s to GitHub Actions\n", source.Platform)
+	cmd.Println("═══════════════════════════════════════════════════════════════")
+	cmd.Println()
+	cmd.Printf("📋 %d job(s) from %s\n", len(pipeline.Jobs), file)
+	if len(pipeline.Secrets) > 0 {
+		cmd.Println("\n🔑 Create these repository secrets:")
+		for _, secret := range pipeline.Secrets {
+			cmd.Println("   - " + secret)
+		}
+	}
+	if len(notes) > 0 {
+		cmd.Println("\n💭 AI notes:")
+		for i, note := range notes {
+			cmd.Printf("   %d. %s\n", i+1, note)
+		}
+	}
+	if todos > 0 {
+		cmd.Printf("\n📝 %d construct(s) need review: search the workflow for TODO(fluxion)\n", todos)
+	}
+	cmd.Println()
+	cmd.Println("───────────────────────────────────────────────────────────────")
+	cmd.Printf("✅ Workflow saved to: %s\n", outputPath)
+	cmd.Println("───────────────────────────────────────────────────────────────")
+
+	if len(problems) > 0 {
+		cmd.PrintErrln("\n⚠️  The migrated workflow failed validation; review it before committing:")
+		for _, problem := range problems {
+			cmd.PrintErrln("   - " + problem)
+		}
+	}
+	return nil
+}
+
+// translateMigrationTODOs has the AI translate the TODO steps of workflow and shows what
+// it changed. The deterministic workflow is returned as it was when the AI fails or its
+// answer does not validate.
+func translateMigrationTODOs(cmd *cobra.Command, source migrationSource, config, workflow, workingDir string) (string, []string, error) {
+	constructs, err := migrationTODOs(workflow)
+	if err != nil || len(constructs) == 0 {
+		return workflow, nil, err
+	}
+	projectConfig, _, err := LoadProjectConfig(workingDir)
+	if err != nil {
+		return "", nil, fmt.Errorf("error in project config: %w", err)
+	}
+	model, err := projectConfig.Defaults.ChatModel()
+	if err != nil {
+		return "", nil, fmt.Errorf("error in project config: %w", err)
+	}
+
+	cmd.Printf("🤖 Translating %d step(s) with the AI...\n", len(constructs))
+	result, err := completeMigrationWithOpenAI(source, config, constructs, model)
+	if err != nil {
+		cmd.PrintErrln("⚠️  AI translation failed, keeping the TODOs:", err)
+		return workflow, nil, nil
+	}
+	translated, count, err := spliceMigrationTranslations(workflow, constructs, result.Translations)
+	if err != nil {
+		cmd.PrintErrln("⚠️  Could not use the AI's translations, keeping the TODOs:", err)
+		return workflow, nil, nil
+	}
+	if problems := validateGitHubWorkflow(translated); len(problems) > 0 {
+		cmd.PrintErrln("⚠️  The AI's translations failed validation, keeping the TODOs: " + strings.Join(problems, "; "))
+		return workflow, nil, nil
+	}
+	if count == 0 {
+		cmd.Println("ℹ️  The AI kept every TODO")
+		return workflow, result.Notes, nil
+	}
+
+	cmd.Printf("\n📝 The AI translated %d of %d step(s):\n", count, len(constructs))
+	cmd.Println("───────────────────────────────────────────────────────────────")
+	cmd.Print(colorDiff(cmd.OutOrStdout(), unifiedDiff("deterministic", "with AI translations", workflow, translated)))
+	cmd.Println("───────────────────────────────────────────────────────────────")
+	return translated, result.Notes, nil
+}
+
+// MigrateResult is the AI's translation of the TODO(fluxion) steps of a migrated workflow
+type MigrateResult struct {
+	Translations []MigrateTranslation `json:"translations"`
+	Notes        []string             `json:"notes"`
+}
+
+// MigrateTranslation replaces one TODO step with the steps the AI wrote for it
+type MigrateTranslation struct {
+	ID    string `json:"id"`    // The migrationTODO it translates
+	Steps string `json:"steps"` // YAML sequence of GitHub Actions steps; empty keeps the TODO
+}
+
+// migrationTODO is a failing TODO step of a migrated workflow, the only construct the AI may change
+type migrationTODO struct {
+	ID    string // job.index, e.g. build.2
+	Job   string
+	Index int
+	Step  string // The step's YAML with the source comment above it
+}
+
+// migrationTODOs finds the TODO steps RenderGitHubActions wrote for untranslated constructs
+func migrationTODOs(workflow string) ([]migrationTODO, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(workflow), &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	jobs := mappingValue(doc.Content[0], "jobs")
+	if jobs == nil || jobs.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	var todos []migrationTODO
+	for i := 0; i+1 < len(jobs.Content); i += 2 {
+		job := jobs.Content[i].Value
+		steps := mappingValue(jobs.Content[i+1], "steps")
+		if steps == nil || steps.Kind != yaml.SequenceNode {
+			continue
+		}
+		for index, step := range steps.Content {
+			run := mappingValue(step, "run")
+			if run == nil || !strings.Contains(run.Value, "::error::Not migrated: ") {
+				continue
+			}
+			text, err := encodeYAML(&yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{step}})
+			if err != nil {
+				return nil, err
+			}
+			todos = append(todos, migrationTODO{ID: fmt.Sprintf("%s.%d", job, index), Job: job, Index: index, Step: text})
+		}
+	}
+	return todos, nil
+}
+
+// spliceMigrationTranslations replaces the TODO steps with the AI's translations.
+// Only the lines of the translated steps change, so every line the deterministic
+// translation wrote stays as it is; each translation is marked for review.
+func spliceMigrationTranslations(workflow string, todos []migrationTODO, translations []MigrateTranslation) (string, int, error) {
+	byID := map[string]migrationTODO{}
+	for _, todo := range todos {
+		byID[todo.ID] = todo
+	}
+	type splice struct {
+		todo  migrationTODO
+		steps *yaml.Node
+	}
+	var splices []splice
+	seen := map[string]bool{}
+	for _, translation := range translations {
+		todo, ok := byID[translation.ID]
+		if !ok {
+			return "", 0, fmt.Errorf("translation for unknown construct %q", translation.ID)
+		}
+		if seen[translation.ID] {
+			return "", 0, fmt.Errorf("construct %q is translated twice", translation.ID)
+		}
+		seen[translation.ID] = true
+		if strings.TrimSpace(translation.Steps) == "" {
+			continue
+		}
+		var doc yaml.Node
+		if err := yaml.Unmarshal([]byte(translation.Steps), &doc); err != nil {
+			return "", 0, fmt.Errorf("construct %q: invalid YAML: %w", translation.ID, err)
+		}
+		if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.SequenceNode || len(doc.Content[0].Content) == 0 {
+			return "", 0, fmt.Errorf("construct %q: steps must be a sequence of steps", translation.ID)
+		}
+		steps := doc.Content[0]
+		for _, step := range steps.Content {
+			if step.Kind != yaml.MappingNode {
+				return "", 0, fmt.Errorf("construct %q: each step must be a mapping", translation.ID)
+			}
+		}
+		steps.Content[0].HeadComment = "TODO(fluxion): translated by AI, review"
+		splices = append(splices, splice{todo, steps})
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(workflow), &doc); err != nil {
+		return "", 0, err
+	}
+	lines := strings.SplitAfter(workflow, "\n")
+	type lineRange struct {
+		start, end, dash int
+		steps            *yaml.Node
+	}
+	var ranges []lineRange
+	for _, s := range splices {
+		steps := mappingValue(mappingValue(mappingValue(doc.Content[0], "jobs"), s.todo.Job), "steps")
+		line, dash := yamlItemStart(lines, steps.Content[s.todo.Index])
+		start, end := yamlEntryRange(lines, line, dash, false)
+		ranges = append(ranges, lineRange{yamlLeadingComment(lines, start, dash), end, dash, s.steps})
+	}
+	// Splice from the bottom up, so the lines of the steps above stay where they were
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].start > ranges[j].start })
+	for _, r := range ranges {
+		text, err := spliceLines(lines, r.start, r.end, r.steps, r.dash, "")
+		if err != nil {
+			return "", 0, err
+		}
+		lines = strings.SplitAfter(text, "\n")
+	}
+	return strings.Join(lines, ""), len(ranges), nil
+}
+
+// completeMigrationWithOpenAI asks the AI to translate the TODO steps of a migrated workflow.
+// Only the TODO steps and the source configuration are sent; the answer holds one
+// translation per step, which spliceMigrationTranslations puts in place.
+func completeMigrationWithOpenAI(source migrationSource, config string, todos []migrationTODO, model openai.ChatModel) (MigrateResult, error) {
+	client := openai.NewClient(
+		option.WithAPIKey(os.Getenv("OPENAI_API_KEY")),
+	)
+
+	var constructs strings.Builder
+	for _, todo := range todos {
+		fmt.Fprintf(&constructs, "\nID: %s (job %s)\n%s", todo.ID, todo.Job, todo.Step)
+	}
+	userPrompt := fmt.Sprintf(`Translate these steps of a migration from %s to GitHub Actions.
+
+SOURCE CONFIGURATION (%s):
+%s
+
+TODO STEPS:
+%s`, source.Platform, source.DefaultInput, config, constructs.String())
+
+	resp, err := client.Chat.Completions.New(
+		context.Background(),
+		openai.ChatCompletionNewParams{
+			Model: model,
+			Messages: []openai.ChatCompletionMessageParamUnion{
+				openai.SystemMessage(migrateSystemPrompt),
+				openai.UserMessage(userPrompt),
+			},
+			ResponseFormat: openai.ChatCompletionNewParamsResponseFormatUnion{
+				OfJSONSchema: &openai.ResponseFormatJSONSchemaParam{
+					JSONSchema: openai.ResponseFormatJSONSchemaJSONSchemaParam{
+						Name:   "migrate_result",
+						Schema: migrateSchema,
+						Strict: openai.Bool(true),
+					},
+				},
+			},
+		},
+	)
+	if err != nil {
+		return MigrateResult{}, fmt.Errorf("OpenAI API error: %w", err)
+	}
+
+	var result MigrateResult
+	if err := json.Unmarshal([]byte(resp.Choices[0].Message.Content), &result); err != nil {
+		return MigrateResult{}, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return result, nil
+}
+
+// Helpers shared by the source parsers
+
+// parseSourceYAML decodes a YAML source configuration. Numbers are kept as
+// written, so versions such as go: 1.20 do not lose their trailing zero, and
+// GitLab's !reference tags are resolved. keys lists the top-level keys in source order.
+func parseSourceYAML(config string) (values map[string]any, keys []string, err error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(config), &doc); err != nil {
+		return nil, nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("top level must be a mapping")
+	}
+	root := doc.Content[0]
+	numbersAsStrings(root)
+	resolveReferences(root, root)
+	if err := root.Decode(&values); err != nil {
+		return nil, nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	for _, key := range mappingKeys(root) {
+		if key != "<<" {
+			keys = append(keys, key)
+		}
+	}
+	return values, keys, nil
+}
+
+func numbersAsStrings(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode && (node.Tag == "!!int" || node.Tag == "!!float") {
+		node.Tag = "!!str"
+	}
+	for _, child := range node.Content {
+		numbersAsStrings(child)
+	}
+}
+
+// resolveReferences replaces each !reference [key, ...] with a copy of the node it points to
+func resolveReferences(node, root *yaml.Node) {
+	if node.Tag == "!reference" && node.Kind == yaml.SequenceNode {
+		target := root
+		for _, key := range node.Content {
+			target = mappingValue(target, key.Value)
+		}
+		if target != nil {
+			*node = *target
+		}
+		return
+	}
+	for _, child := range node.Content {
+		resolveReferences(child, root)
+	}
+}
+
+func asMap(v any) map[string]any {
+	m, _ := v.(map[string]any)
+	return m
+}
+
+// asList returns a sequence, or a single value as a one-element list
+func asList(v any) []any {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case []any:
+		return v
+	case []string:
+		items := make([]any, len(v))
+		for i, s := range v {
+			items[i] = s
+		}
+		return items
+	default:
+		return []any{v}
+	}
+}
+
+// asString formats a scalar; maps, lists and nil give ""
+func asString(v any) string {
+	switch v := v.(type) {
+	case nil, map[string]any, []any:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// asStrings returns the scalars of a value or list, flattening nested lists
+func asStrings(v any) []string {
+	var values []string
+	for _, item := range asList(v) {
+		if nested, ok := item.([]any); ok {
+			values = append(values, asStrings(nested)...)
+		} else if s := asString(item); s != "" {
+			values = append(values, s)
+		}
+	}
+	return values
+}
+
+func asBool(v any) bool {
+	b, ok := v.(bool)
+	return ok && b || asString(v) == "true"
+}
+
+// asVars converts a mapping of names to scalars, or a list of NAME=value strings, to variables
+func asVars(v any) []PipelineVar {
+	var vars []PipelineVar
+	if m := asMap(v); m != nil {
+		for _, name := range sortedAnyKeys(m) {
+			value := m[name]
+			if nested := asMap(value); nested != nil {
+				value = nested["value"] // GitLab's {value:, description:} form
+			}
+			vars = append(vars, PipelineVar{Name: name, Value: asString(value)})
+		}
+		return vars
+	}
+	for _, item := range asStrings(v) {
+		if name, value, ok := strings.Cut(item, "="); ok {
+			vars = append(vars, PipelineVar{Name: strings.TrimSpace(name), Value: strings.Trim(strings.TrimSpace(value), `"'`)})
+		}
+	}
+	return vars
+}
+
+func sortedAnyKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sourceYAML renders part of a source configuration for a TODO comment
+func sourceYAML(key string, v any) string {
+	data, err := yaml.Marshal(map[string]any{key: v})
+	if err != nil {
+		return key
+	}
+	return strings.TrimRight(string(data), "\n")
+}
+
+// serviceFromImage builds a service container; known images get their ports and environment
+// from the service catalog, with values taken from vars when they define them
+func serviceFromImage(image, alias string, vars []PipelineVar) PipelineService {
+	name := alias
+	if name == "" {
+		name, _ = imageName(image)
+		name = name[strings.LastIndex(name, "/")+1:]
+	}
+	svc := PipelineService{Name: jobID(name), Image: image}
+	if def, ok := serviceForImage(image); ok {
+		svc.Ports = def.Ports
+		for _, key := range sortedMapKeys(def.Env) {
+			value := def.Env[key]
+			for _, v := range vars {
+				if v.Name == key {
+					value = v.Value
+				}
+			}
+			svc.Env = append(svc.Env, PipelineVar{Name: key, Value: value})
+		}
+	}
+	return svc
+}
+
+// jobImage runs a finished job in its source image. Official language images become a setup
+// step after the checkout instead, since JavaScript actions cannot run in every image (Alpine,
+// for one). servicesByName keeps the container when the job reaches its services by hostname,
+// as on GitLab; CircleCI jobs reach them on localhost, which only works on the runner.
+func jobImage(job *PipelineJob, image string, servicesByName bool) {
+	if image == "" {
+		return
+	}
+	runtime, version, ok := runtimeForImage(image)
+	if !ok || servicesByName && len(job.Services) > 0 {
+		job.Image = image
+		if !servicesByName && len(job.Services) > 0 {
+			job.TODOs = append(job.TODOs, "the job runs in a container, so it reaches its services by hostname rather than on localhost")
+		}
+		return
+	}
+	at := 0
+	if len(job.Steps) > 0 && job.Steps[0].Kind == StepCheckout {
+		at = 1
+	}
+	setup := PipelineStep{Kind: StepSetup, Runtime: runtime, Version: version, Comment: "Source image: " + image}
+	job.Steps = append(job.Steps[:at], append([]PipelineStep{setup}, job.Steps[at:]...)...)
+	for i := range job.Steps {
+		job.Steps[i].Run = sudoPackageManager(job.Steps[i].Run)
+	}
+}
+
+var aptGetPattern = regexp.MustCompile(`(?m)(^|&&\s*|;\s*)(apt-get|apt) `)
+
+// sudoPackageManager prefixes apt-get with sudo, for scripts that ran as root in a container
+func sudoPackageManager(script string) string {
+	return aptGetPattern.ReplaceAllString(script, "${1}sudo ${2} ")
+}
+
+// hashFilesExpr builds a hashFiles() expression for cache keys
+func hashFilesExpr(files []string) string {
+	quoted := make([]string, 0, len(files))
+	for _, file := range files {
+		quoted = append(quoted, "'"+file+"'")
+	}
+	return "${{ hashFiles(" + strings.Join(quoted, ", ") + ") }}"
+}
+
+// expressionVars turns the variable references in a value into workflow expressions: the
+// platform's variables by their expression in exprs, any other as ${{ env.NAME }}
+func expressionVars(value string, exprs map[string]string) string {
+	return shellVarPattern.ReplaceAllStringFunc(value, func(ref string) string {
+		m := shellVarPattern.FindStringSubmatch(ref)
+		if expr, ok := exprs[m[1]+m[2]]; ok {
+			return expr
+		}
+		return "${{ env." + m[1] + m[2] + " }}"
+	})
+}
+
+// globFromRegexp converts a /regex/ branch or tag filter to a glob, when it matches
+// a literal name or a literal prefix (^release-.*)
+func globFromRegexp(pattern string) (string, bool) {
+	re := strings.TrimSuffix(strings.TrimPrefix(pattern, "/"), "/")
+	if !strings.HasPrefix(re, "^") {
+		return "", false
+	}
+	re = strings.TrimPrefix(re, "^")
+	suffix := ""
+	switch {
+	case strings.HasSuffix(re, "$"):
+		re = strings.TrimSuffix(re, "$")
+	case strings.HasSuffix(re, ".*"), strings.HasSuffix(re, ".+"):
+		re, suffix = re[:len(re)-2], "*"
+	default:
+		suffix = "*"
+	}
+	var glob strings.Builder
+	for i := 0; i < len(re); i++ {
+		c := re[i]
+		if c == '\\' && i+1 < len(re) {
+			if isAlphanumeric(re[i+1]) {
+				return "", false // A class such as \d
+			}
+			i++
+			glob.WriteByte(re[i])
+			continue
+		}
+		if strings.IndexByte(`.*+?[](){}|^$`, c) >= 0 {
+			return "", false
+		}
+		glob.WriteByte(c)
+	}
+	return glob.String() + suffix, true
+}
+
+func isAlphanumeric(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// artifactDownloadPath is where to download an artifact so its files land where they were
+// uploaded from: upload-artifact drops the directories its paths have in common, so a single
+// directory is stored without its name. Paths without an extension are taken as directories,
+// and a pattern as rooted at the directory before its first wildcard.
+func artifactDownloadPath(base string, paths []string) string {
+	var included []string
+	for _, p := range paths {
+		if !strings.HasPrefix(p, "!") {
+			included = append(included, p)
+		}
+	}
+	if len(included) != 1 {
+		return base
+	}
+	uploaded := path.Clean(included[0])
+	if glob := strings.IndexAny(uploaded, "*?["); glob >= 0 {
+		uploaded = path.Dir(uploaded[:glob] + "x") // The directory before the first wildcard
+	} else if !strings.HasSuffix(included[0], "/") && path.Ext(uploaded) != "" {
+		uploaded = path.Dir(uploaded)
+	}
+	return path.Join(base, uploaded)
+}
@@ -0,0 +1,593 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// circleciVariables maps CircleCI's built-in variables to GitHub Actions
+var circleciVariables = ciVariables{
+	renames: map[string]string{
+		"CIRCLE_SHA1":              "GITHUB_SHA",
+		"CIRCLE_BUILD_NUM":         "GITHUB_RUN_NUMBER",
+		"CIRCLE_WORKFLOW_ID":       "GITHUB_RUN_ID",
+		"CIRCLE_PROJECT_USERNAME":  "GITHUB_REPOSITORY_OWNER",
+		"CIRCLE_WORKING_DIRECTORY": "GITHUB_WORKSPACE",
+		"CIRCLE_JOB":               "GITHUB_JOB",
+		"CIRCLE_USERNAME":          "GITHUB_ACTOR",
+		"CIRCLE_REPOSITORY_URL":    "$GITHUB_SERVER_URL/$GITHUB_REPOSITORY.git",
+	},
+	compat: map[string]string{
+		"CIRCLE_BRANCH":           "${{ github.ref_type == 'branch' && github.ref_name || github.head_ref }}",
+		"CIRCLE_TAG":              "${{ github.ref_type == 'tag' && github.ref_name || '' }}",
+		"CIRCLE_PROJECT_REPONAME": "${{ github.event.repository.name }}",
+		"CIRCLE_PR_NUMBER":        "${{ github.event.pull_request.number }}",
+		"CIRCLE_PULL_REQUEST":     "${{ github.event.pull_request.html_url }}",
+		"CIRCLE_BUILD_URL":        "${{ github.server_url }}/${{ github.repository }}/actions/runs/${{ github.run_id }}",
+	},
+	platform: func(name string) bool {
+		return strings.HasPrefix(name, "CIRCLE_")
+	},
+}
+
+// circleciKeyTemplates translates cache key templates, e.g. {{ checksum "go.sum" }}
+var circleciKeyTemplates = []struct {
+	pattern *regexp.Regexp
+	expr    string
+}{
+	{regexp.MustCompile(`\{\{\s*checksum\s+"([^"]+)"\s*\}\}`), "${{ hashFiles('$1') }}"},
+	{regexp.MustCompile(`\{\{\s*\.Branch\s*\}\}`), "${{ github.ref_name }}"},
+	{regexp.MustCompile(`\{\{\s*\.Revision\s*\}\}`), "${{ github.sha }}"},
+	{regexp.MustCompile(`\{\{\s*\.BuildNum\s*\}\}`), "${{ github.run_number }}"},
+	{regexp.MustCompile(`\{\{\s*\.Environment\.(\w+)\s*\}\}`), "${{ env.$1 }}"},
+	{regexp.MustCompile(`\{\{\s*arch\s*\}\}`), "${{ runner.os }}-${{ runner.arch }}"},
+	{regexp.MustCompile(`\{\{\s*epoch\s*\}\}`), "${{ github.run_id }}"},
+}
+
+var (
+	circleciParameterPattern = regexp.MustCompile(`<<\s*parameters\.(\w+)\s*>>`)
+	circleciPipelinePattern  = regexp.MustCompile(`<<\s*pipeline\.([\w.]+)\s*>>`)
+)
+
+// circleciPipelineValues are the pipeline values with a workflow expression
+var circleciPipelineValues = map[string]string{
+	"git.branch":   "${{ github.ref_name }}",
+	"git.tag":      "${{ github.ref_type == 'tag' && github.ref_name || '' }}",
+	"git.revision": "${{ github.sha }}",
+	"number":       "${{ github.run_number }}",
+	"id":           "${{ github.run_id }}",
+}
+
+// circleciConfig is a parsed .circleci/config.yml
+type circleciConfig struct {
+	values    map[string]any
+	jobs      map[string]any
+	commands  map[string]any
+	executors map[string]any
+	pipeline  map[string]string // Pipeline parameter defaults
+}
+
+// circleciWorkspace is a persist_to_workspace upload
+type circleciWorkspace struct {
+	artifact string
+	paths    []string // Relative to the workspace root
+}
+
+// ParseCircleCI translates a .circleci/config.yml to the pipeline model.
+// Every workflow's jobs go into one workflow; requires: become needs:,
+// approval jobs gate the jobs after them, and workspaces are passed as artifacts.
+func ParseCircleCI(config string) (Pipeline, error) {
+	values, _, err := parseSourceYAML(config)
+	if err != nil {
+		return Pipeline{}, err
+	}
+	c := circleciConfig{
+		values:    values,
+		jobs:      asMap(values["jobs"]),
+		commands:  asMap(values["commands"]),
+		executors: asMap(values["executors"]),
+		pipeline:  map[string]string{},
+	}
+	if len(c.jobs) == 0 {
+		return Pipeline{}, fmt.Errorf("no jobs found")
+	}
+	parameters := asMap(values["parameters"])
+	for _, name := range sortedAnyKeys(parameters) {
+		c.pipeline["parameters."+name] = asString(asMap(parameters[name])["default"])
+	}
+
+	p := Pipeline{Name: "CI", Triggers: PipelineTriggers{PushBranches: []string{"*"}, Manual: true}}
+	if orbs := asMap(values["orbs"]); len(orbs) > 0 {
+		p.TODOs = append(p.TODOs, "orbs ("+strings.Join(sortedAnyKeys(orbs), ", ")+") have no GitHub equivalent: their commands and executors are left as TODOs")
+	}
+
+	workflows := asMap(values["workflows"])
+	names := sortedAnyKeys(workflows)
+	if len(workflows) == 0 {
+		// Version 2.0 configs without workflows run the job called build
+		workflows = map[string]any{"build": map[string]any{"jobs": []any{"build"}}}
+		names = []string{"build"}
+	}
+	workspaces := map[string][]circleciWorkspace{}
+	var contexts []string
+	for _, name := range names {
+		workflow := asMap(workflows[name])
+		if workflow == nil {
+			continue // version: 2
+		}
+		contexts = appendUnique(contexts, c.addWorkflow(&p, name, workflow, workspaces)...)
+	}
+	if len(contexts) > 0 {
+		p.TODOs = append(p.TODOs, "CircleCI contexts ("+strings.Join(contexts, ", ")+") held shared secrets: add their variables as repository, organization or environment secrets")
+	}
+	circleciAttachWorkspaces(&p, workspaces)
+
+	// Jobs CircleCI would not run for tags must not run for them here either
+	if len(p.Triggers.Tags) > 0 {
+		for i := range p.Jobs {
+			if p.Jobs[i].If == nil {
+				p.Jobs[i].If = &PipelineCondition{Branches: []string{"*"}}
+			}
+		}
+	}
+
+	translateVars(&p, circleciVariables)
+	inferSecrets(&p, circleciVariables.platform)
+	return p, nil
+}
+
+// addWorkflow adds a workflow's jobs to the pipeline and returns the contexts they use
+func (c circleciConfig) addWorkflow(p *Pipeline, name string, workflow map[string]any, workspaces map[string][]circleciWorkspace) []string {
+	var events []string
+	for _, trigger := range asList(workflow["triggers"]) {
+		if schedule := asMap(asMap(trigger)["schedule"]); schedule != nil {
+			p.Triggers.Schedules = appendUnique(p.Triggers.Schedules, asString(schedule["cron"]))
+			events = appendUnique(events, "schedule")
+		}
+	}
+	if workflow["when"] != nil || workflow["unless"] != nil {
+		p.TODOs = append(p.TODOs, fmt.Sprintf("workflow %s has a when/unless condition that was not translated", name))
+	}
+
+	// First pass: the ID of each job entry, and the approval jobs
+	type entry struct {
+		job    string
+		id     string
+		params map[string]any
+	}
+	var entries []entry
+	ids := map[string]string{}
+	approvals := map[string][]string{} // Approval job → what it requires
+	for _, item := range asList(workflow["jobs"]) {
+		e := entry{job: asString(item)}
+		if m := asMap(item); m != nil {
+			for job, params := range m {
+				e.job, e.params = job, asMap(params)
+			}
+		}
+		instance := e.job
+		if n := asString(e.params["name"]); n != "" {
+			instance = n
+		}
+		if asString(e.params["type"]) == "approval" {
+			approvals[instance] = asStrings(e.params["requires"])
+			continue
+		}
+		e.id = jobID(instance)
+		if p.job(e.id) != nil {
+			e.id = jobID(name + "-" + instance)
+		}
+		ids[instance] = e.id
+		entries = append(entries, e)
+	}
+
+	var contexts []string
+	for _, e := range entries {
+		def := asMap(c.jobs[e.job])
+		job := PipelineJob{ID: e.id, Name: e.id}
+		if def == nil {
+			job.Steps = []PipelineStep{{Kind: StepTODO, Name: "run orb job " + e.job, Source: sourceYAML(e.job, e.params)}}
+			p.Jobs = append(p.Jobs, job)
+			continue
+		}
+
+		params := map[string]any{}
+		declared := asMap(def["parameters"])
+		for _, param := range sortedAnyKeys(declared) {
+			params[param] = asMap(declared[param])["default"]
+		}
+		for key, value := range e.params {
+			params[key] = value
+		}
+		if matrix := asMap(asMap(e.params["matrix"])["parameters"]); matrix != nil {
+			for _, param := range sortedAnyKeys(matrix) {
+				job.Matrix = append(job.Matrix, PipelineAxis{Name: param, Values: asStrings(matrix[param])})
+				params[param] = "${{ matrix." + param + " }}"
+			}
+			if asMap(e.params["matrix"])["exclude"] != nil {
+				job.TODOs = append(job.TODOs, "matrix exclude: not translated; add it under strategy.matrix.exclude")
+			}
+		}
+		def = asMap(c.substitute(def, params))
+
+		c.buildJob(&job, def, workspaces)
+
+		// requires: through approval jobs gate this job on a reviewer
+		var requires []string
+		for _, required := range asStrings(e.params["requires"]) {
+			if upstream, ok := approvals[required]; ok {
+				job.Approval = true
+				job.Environment = jobID(required)
+				requires = append(requires, upstream...)
+			} else {
+				requires = append(requires, required)
+			}
+		}
+		for _, required := range requires {
+			if id, ok := ids[strings.SplitN(required, ":", 2)[0]]; ok {
+				job.Needs = appendUnique(job.Needs, id)
+			}
+		}
+
+		job.If = circleciFilters(p, asMap(e.params["filters"]), events)
+		contexts = appendUnique(contexts, asStrings(e.params["context"])...)
+		p.Jobs = append(p.Jobs, job)
+	}
+	return contexts
+}
+
+// substitute replaces << parameters.x >> and << pipeline.x >> in every string of a job
+func (c circleciConfig) substitute(v any, params map[string]any) any {
+	switch v := v.(type) {
+	case string:
+		v = circleciParameterPattern.ReplaceAllStringFunc(v, func(ref string) string {
+			name := circleciParameterPattern.FindStringSubmatch(ref)[1]
+			if value, ok := params[name]; ok {
+				return asString(value)
+			}
+			return ref
+		})
+		return circleciPipelinePattern.ReplaceAllStringFunc(v, func(ref string) string {
+			name := circleciPipelinePattern.FindStringSubmatch(ref)[1]
+			if value, ok := c.pipeline[name]; ok {
+				return value
+			}
+			if expr, ok := circleciPipelineValues[name]; ok {
+				return expr
+			}
+			return ref
+		})
+	case []any:
+		items := make([]any, 0, len(v))
+		for _, item := range v {
+			// A steps parameter splices its steps into the list
+			if s, ok := item.(string); ok {
+				if m := circleciParameterPattern.FindStringSubmatch(s); m != nil && m[0] == strings.TrimSpace(s) {
+					if steps, ok := params[m[1]].([]any); ok {
+						items = append(items, steps...)
+						continue
+					}
+				}
+			}
+			items = append(items, c.substitute(item, params))
+		}
+		return items
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for key, value := range v {
+			m[key] = c.substitute(value, params)
+		}
+		return m
+	default:
+		return v
+	}
+}
+
+// buildJob translates a job's executor, environment and steps
+func (c circleciConfig) buildJob(job *PipelineJob, def map[string]any, workspaces map[string][]circleciWorkspace) {
+	executor := def
+	if ref := def["executor"]; ref != nil {
+		name, params := asString(ref), map[string]any{}
+		if m := asMap(ref); m != nil {
+			name, params = asString(m["name"]), m
+		}
+		if e := asMap(c.executors[name]); e != nil {
+			executor = deepMerge(asMap(c.substitute(e, params)), def)
+		} else {
+			job.TODOs = append(job.TODOs, "orb executor "+name+" not translated; the job runs on ubuntu-latest")
+		}
+	}
+
+	image := ""
+	for i, container := range asList(executor["docker"]) {
+		m := asMap(container)
+		env := asVars(m["environment"])
+		if i == 0 {
+			image = asString(m["image"])
+			job.Env = append(job.Env, env...)
+			continue
+		}
+		// The container's environment is the service's own, so all of it is kept
+		service := serviceFromImage(asString(m["image"]), asString(m["name"]), env)
+		for _, v := range env {
+			service.Env = setVar(service.Env, v.Name, v.Value)
+		}
+		job.Services = append(job.Services, service)
+	}
+	switch {
+	case executor["macos"] != nil:
+		job.RunsOn = []string{"macos-latest"}
+	case executor["windows"] != nil:
+		job.RunsOn = []string{"windows-latest"}
+	case strings.Contains(asString(executor["resource_class"]), "arm"):
+		job.RunsOn = []string{"ubuntu-24.04-arm"}
+	}
+	if strings.Contains(asString(executor["resource_class"]), "/") {
+		job.RunsOn = []string{"self-hosted"} // A CircleCI self-hosted runner class, namespace/name
+		job.TODOs = append(job.TODOs, "self-hosted resource class "+asString(executor["resource_class"])+": set the runner labels")
+	}
+	job.Env = append(job.Env, asVars(executor["environment"])...)
+	if n, _ := strconv.Atoi(asString(def["parallelism"])); n > 1 {
+		axis := PipelineAxis{Name: "circle_node_index"}
+		for i := 0; i < n; i++ {
+			axis.Values = append(axis.Values, strconv.Itoa(i))
+		}
+		job.Matrix = append(job.Matrix, axis)
+		job.Env = append(job.Env, PipelineVar{Name: "CIRCLE_NODE_INDEX", Value: "${{ matrix.circle_node_index }}"},
+			PipelineVar{Name: "CIRCLE_NODE_TOTAL", Value: strconv.Itoa(n)})
+	}
+
+	workingDir := asString(executor["working_directory"])
+	steps := c.expandSteps(asList(def["steps"]), 0)
+	remoteDocker := false
+	restore, restoreKeys := -1, []string(nil) // The last restore_cache, which a save_cache can complete
+	for _, step := range steps {
+		name, args := circleciStep(step)
+		switch name {
+		case "checkout":
+			job.Steps = append(job.Steps, PipelineStep{Kind: StepCheckout})
+		case "run":
+			job.Steps = append(job.Steps, circleciRun(args, workingDir))
+		case "restore_cache":
+			keys := asStrings(args["keys"])
+			if key := asString(args["key"]); key != "" {
+				keys = append([]string{key}, keys...)
+			}
+			for i := range keys {
+				keys[i] = circleciCacheKey(keys[i])
+			}
+			if len(keys) == 0 {
+				continue
+			}
+			step := PipelineStep{Kind: StepAction, Name: "Restore cache", Uses: "actions/cache/restore@v4", With: []PipelineVar{{Name: "key", Value: keys[0]}}}
+			if len(keys) > 1 {
+				step.With = append(step.With, PipelineVar{Name: "restore-keys", Value: strings.Join(keys[1:], "\n")})
+			}
+			restore, restoreKeys = len(job.Steps), keys
+			job.Steps = append(job.Steps, step)
+		case "save_cache":
+			paths := asStrings(args["paths"])
+			key := circleciCacheKey(asString(args["key"]))
+			if restore >= 0 && restoreKeys[0] == key {
+				// actions/cache restores here and saves the same key at the end of the job
+				cache := PipelineStep{Kind: StepCache, Name: "Cache " + strings.Join(paths, ", "), Key: key, Paths: paths,
+					With: []PipelineVar{{Name: "path", Value: strings.Join(paths, "\n")}, {Name: "key", Value: key}}}
+				cache.With = append(cache.With, job.Steps[restore].With[1:]...)
+				job.Steps[restore], restore = cache, -1
+				continue
+			}
+			job.Steps = append(job.Steps, PipelineStep{Kind: StepAction, Name: "Save cache", Uses: "actions/cache/save@v4",
+				With: []PipelineVar{{Name: "path", Value: strings.Join(paths, "\n")}, {Name: "key", Value: key}}})
+		case "persist_to_workspace":
+			root := circleciPath(asString(args["root"]), workingDir)
+			var paths []string
+			for _, p := range asStrings(args["paths"]) {
+				paths = append(paths, path.Join(root, p))
+			}
+			artifact := "workspace-" + job.ID
+			if n := len(workspaces[job.ID]); n > 0 {
+				artifact += "-" + strconv.Itoa(n+1)
+			}
+			job.Steps = append(job.Steps, PipelineStep{Kind: StepUpload, Name: "Persist to workspace", Artifact: artifact, Paths: paths})
+			workspaces[job.ID] = append(workspaces[job.ID], circleciWorkspace{artifact: artifact, paths: asStrings(args["paths"])})
+		case "attach_workspace":
+			at := circleciPath(asString(args["at"]), workingDir)
+			job.Steps = append(job.Steps, PipelineStep{Kind: StepDownload, Name: "Attach workspace", Paths: []string{at}})
+		case "store_artifacts":
+			p := asString(args["path"])
+			name := asString(args["destination"])
+			if name == "" {
+				name = path.Base(p)
+			}
+			job.Steps = append(job.Steps, PipelineStep{Kind: StepUpload, Name: "Store artifacts", Artifact: jobID(job.ID + "-" + name),
+				Paths: []string{circleciPath(p, workingDir)}})
+		case "store_test_results":
+			job.Steps = append(job.Steps, PipelineStep{Kind: StepUpload, Name: "Store test results", Artifact: job.ID + "-test-results",
+				Paths: []string{circleciPath(asString(args["path"]), workingDir)}, When: "always",
+				Comment: "GitHub does not render test results; add a reporter action to show them"})
+		case "setup_remote_docker":
+			remoteDocker = true
+		default:
+			job.Steps = append(job.Steps, PipelineStep{Kind: StepTODO, Name: name, Source: sourceYAML(name, args)})
+		}
+	}
+	jobImage(job, image, false)
+	if remoteDocker && job.Image != "" {
+		job.TODOs = append(job.TODOs, "setup_remote_docker: Docker is not available inside the job container; build images in a job without container:")
+	}
+}
+
+// expandSteps inlines reusable commands; steps: is a list of names or single-key mappings
+func (c circleciConfig) expandSteps(steps []any, depth int) []any {
+	var expanded []any
+	for _, step := range steps {
+		name, args := circleciStep(step)
+		command := asMap(c.commands[name])
+		if command == nil || depth > 10 {
+			expanded = append(expanded, step)
+			continue
+		}
+		params := map[string]any{}
+		declared := asMap(command["parameters"])
+		for _, param := range sortedAnyKeys(declared) {
+			params[param] = asMap(declared[param])["default"]
+		}
+		for key, value := range args {
+			params[key] = value
+		}
+		expanded = append(expanded, c.expandSteps(asList(c.substitute(command["steps"], params)), depth+1)...)
+	}
+	return expanded
+}
+
+// circleciStep splits a step into its name and arguments; run: "cmd" becomes run: {command: cmd}
+func circleciStep(step any) (string, map[string]any) {
+	if name := asString(step); name != "" {
+		return name, map[string]any{}
+	}
+	for name, args := range asMap(step) {
+		if m := asMap(args); m != nil {
+			return name, m
+		}
+		if name == "run" {
+			return name, map[string]any{"command": asString(args)}
+		}
+		return name, map[string]any{}
+	}
+	return "", nil
+}
+
+// circleciRun translates a run step
+func circleciRun(args map[string]any, workingDir string) PipelineStep {
+	step := PipelineStep{Kind: StepRun, Name: asString(args["name"]), Env: asVars(args["environment"])}
+	step.Run = strings.TrimRight(asString(args["command"]), "\n") + "\n"
+	if asBool(args["background"]) {
+		step.Run = "{\n" + step.Run + "} > /tmp/" + jobID(step.Name) + ".log 2>&1 &\n"
+	}
+	switch asString(args["when"]) {
+	case "always":
+		step.When = "always"
+	case "on_fail":
+		step.When = "failure"
+	}
+	if dir := asString(args["working_directory"]); dir != "" {
+		step.WorkingDirectory = circleciPath(dir, workingDir)
+	}
+	return step
+}
+
+// circleciPath makes a path relative to the checkout, which is at the job's working directory
+func circleciPath(p, workingDir string) string {
+	for _, root := range []string{workingDir, "~/project", "/home/circleci/project", "~/repo"} {
+		if root != "" && (p == root || strings.HasPrefix(p, root+"/")) {
+			p = strings.TrimPrefix(strings.TrimPrefix(p, root), "/")
+		}
+	}
+	if p == "" || p == "~" {
+		return "."
+	}
+	return p
+}
+
+func circleciCacheKey(key string) string {
+	for _, t := range circleciKeyTemplates {
+		key = t.pattern.ReplaceAllString(key, t.expr)
+	}
+	return key
+}
+
+// circleciFilters translates filters: to a job condition; scheduled workflows only run on their schedule
+func circleciFilters(p *Pipeline, filters map[string]any, events []string) *PipelineCondition {
+	cond := PipelineCondition{Events: events}
+	branches, tags := asMap(filters["branches"]), asMap(filters["tags"])
+	var untranslated []string
+	for _, branch := range asStrings(branches["only"]) {
+		if glob, ok := circleciFilterGlob(branch); ok {
+			cond.Branches = append(cond.Branches, glob)
+		} else {
+			untranslated = append(untranslated, "branches.only "+branch)
+		}
+	}
+	ignoreAll := false
+	for _, branch := range asStrings(branches["ignore"]) {
+		if branch == "/.*/" {
+			ignoreAll = true
+		} else {
+			untranslated = append(untranslated, "branches.ignore "+branch)
+		}
+	}
+	for _, tag := range asStrings(tags["only"]) {
+		if glob, ok := circleciFilterGlob(tag); ok {
+			cond.Tags = append(cond.Tags, glob)
+		} else {
+			untranslated = append(untranslated, "tags.only "+tag)
+		}
+	}
+	if tags["ignore"] != nil {
+		untranslated = append(untranslated, "tags.ignore")
+	}
+	if len(cond.Tags) > 0 {
+		p.Triggers.Tags = appendUnique(p.Triggers.Tags, "*")
+		if len(cond.Branches) == 0 && !ignoreAll {
+			cond.Branches = []string{"*"}
+		}
+	}
+	cond.Expr = strings.Join(untranslated, "; ")
+	if conditionEmpty(cond) {
+		return nil
+	}
+	return &cond
+}
+
+// circleciFilterGlob converts a branch or tag filter, a name or a /regex/, to a glob
+func circleciFilterGlob(filter string) (string, bool) {
+	if !strings.HasPrefix(filter, "/") {
+		return filter, true
+	}
+	if filter == "/.*/" {
+		return "*", true
+	}
+	return globFromRegexp(filter)
+}
+
+// circleciAttachWorkspaces downloads, for each attach_workspace, the workspace
+// uploads of every job upstream of it, as CircleCI layers them
+func circleciAttachWorkspaces(p *Pipeline, workspaces map[string][]circleciWorkspace) {
+	for i := range p.Jobs {
+		job := &p.Jobs[i]
+		upstream := map[string]bool{}
+		var visit func(id string)
+		visit = func(id string) {
+			if dep := p.job(id); dep != nil && !upstream[id] {
+				upstream[id] = true
+				for _, need := range dep.Needs {
+					visit(need)
+				}
+			}
+		}
+		for _, need := range job.Needs {
+			visit(need)
+		}
+
+		var steps []PipelineStep
+		for _, step := range job.Steps {
+			if step.Kind != StepDownload || step.Artifact != "" {
+				steps = append(steps, step)
+				continue
+			}
+			for _, dep := range p.Jobs {
+				if !upstream[dep.ID] {
+					continue
+				}
+				for _, ws := range workspaces[dep.ID] {
+					steps = append(steps, PipelineStep{Kind: StepDownload, Name: "Attach workspace from " + dep.ID, Artifact: ws.artifact,
+						Paths: []string{artifactDownloadPath(step.Paths[0], ws.paths)}})
+				}
+			}
+		}
+		job.Steps = steps
+	}
+}
@@ -0,0 +1,766 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// gitlabVariables maps GitLab's predefined variables to GitHub Actions
+var gitlabVariables = ciVariables{
+	renames: map[string]string{
+		"CI_COMMIT_SHA":        "GITHUB_SHA",
+		"CI_COMMIT_SHORT_SHA":  "${GITHUB_SHA::8}",
+		"CI_COMMIT_REF_NAME":   "GITHUB_REF_NAME",
+		"CI_COMMIT_REF_SLUG":   `$(echo "$GITHUB_REF_NAME" | tr '[:upper:]' '[:lower:]' | sed 's/[^a-z0-9]/-/g' | cut -c1-63)`,
+		"CI_PROJECT_DIR":       "GITHUB_WORKSPACE",
+		"CI_PROJECT_PATH":      "GITHUB_REPOSITORY",
+		"CI_PROJECT_NAMESPACE": "GITHUB_REPOSITORY_OWNER",
+		"CI_PIPELINE_ID":       "GITHUB_RUN_ID",
+		"CI_PIPELINE_IID":      "GITHUB_RUN_NUMBER",
+		"CI_JOB_NAME":          "GITHUB_JOB",
+		"CI_SERVER_URL":        "GITHUB_SERVER_URL",
+		"CI_COMMIT_BEFORE_SHA": "GITHUB_EVENT_BEFORE",
+		"GITLAB_USER_LOGIN":    "GITHUB_ACTOR",
+		"CI_REPOSITORY_URL":    "$GITHUB_SERVER_URL/$GITHUB_REPOSITORY.git",
+	},
+	compat: map[string]string{
+		"CI_COMMIT_BRANCH":     "${{ github.ref_type == 'branch' && github.ref_name || '' }}",
+		"CI_COMMIT_TAG":        "${{ github.ref_type == 'tag' && github.ref_name || '' }}",
+		"CI_DEFAULT_BRANCH":    "${{ github.event.repository.default_branch }}",
+		"CI_PROJECT_NAME":      "${{ github.event.repository.name }}",
+		"CI_PROJECT_URL":       "${{ github.server_url }}/${{ github.repository }}",
+		"CI_PIPELINE_SOURCE":   "${{ github.event_name }}",
+		"CI_MERGE_REQUEST_IID": "${{ github.event.pull_request.number }}",
+		"CI_COMMIT_MESSAGE":    "${{ github.event.head_commit.message }}",
+		"CI_REGISTRY":          "ghcr.io",
+		"CI_REGISTRY_IMAGE":    "ghcr.io/${{ github.repository }}",
+		"CI_REGISTRY_USER":     "${{ github.actor }}",
+		"CI_REGISTRY_PASSWORD": "${{ secrets.GITHUB_TOKEN }}",
+		"CI_JOB_TOKEN":         "${{ secrets.GITHUB_TOKEN }}",
+		"GITHUB_EVENT_BEFORE":  "${{ github.event.before }}",
+	},
+	platform: func(name string) bool {
+		return strings.HasPrefix(name, "CI_") || strings.HasPrefix(name, "GITLAB_")
+	},
+}
+
+// gitlabKeyExprs are the cache key and environment name variables with a workflow expression
+var gitlabKeyExprs = map[string]string{
+	"CI_COMMIT_REF_SLUG": "${{ github.ref_name }}",
+	"CI_COMMIT_REF_NAME": "${{ github.ref_name }}",
+	"CI_COMMIT_BRANCH":   "${{ github.ref_name }}",
+	"CI_COMMIT_SHA":      "${{ github.sha }}",
+	"CI_JOB_NAME":        "${{ github.job }}",
+	"CI_PROJECT_NAME":    "${{ github.event.repository.name }}",
+	"CI_DEFAULT_BRANCH":  "${{ github.event.repository.default_branch }}",
+}
+
+// gitlabDefaultKeywords are inherited by jobs from default: (or the deprecated globals)
+var gitlabDefaultKeywords = []string{
+	"image", "services", "before_script", "after_script", "cache", "artifacts", "tags", "timeout", "retry", "interruptible",
+}
+
+// gitlabJob is a job being translated, with what the second pass needs
+type gitlabJob struct {
+	name       string
+	stage      string
+	def        map[string]any
+	job        PipelineJob
+	image      string
+	downloadAt int      // Step index the artifact downloads go before
+	artifacts  []string // Paths uploaded for later jobs
+}
+
+// ParseGitLabCI translates a .gitlab-ci.yml to the pipeline model.
+// Stages become needs: between jobs, artifacts are uploaded and downloaded
+// by the jobs that depend on them, and rules/only become job conditions.
+func ParseGitLabCI(config string) (Pipeline, error) {
+	values, keys, err := parseSourceYAML(config)
+	if err != nil {
+		return Pipeline{}, err
+	}
+	p := Pipeline{
+		Name:     "CI",
+		Triggers: PipelineTriggers{PushBranches: []string{"*"}, Tags: []string{"*"}, Manual: true},
+	}
+	if include, ok := values["include"]; ok {
+		p.TODOs = append(p.TODOs, "included configuration was not migrated, migrate it too: "+strings.Join(gitlabIncludes(include), ", "))
+	}
+	globalVars := asVars(values["variables"])
+	p.Env = withoutGitVars(globalVars)
+	if workflow := asMap(values["workflow"]); workflow != nil {
+		gitlabWorkflowRules(&p, workflow)
+	}
+
+	defaults := map[string]any{}
+	for _, key := range gitlabDefaultKeywords {
+		if v, ok := values[key]; ok {
+			defaults[key] = v
+		}
+		if v, ok := asMap(values["default"])[key]; ok {
+			defaults[key] = v
+		}
+	}
+
+	stages := asStrings(values["stages"])
+	if len(stages) == 0 {
+		stages = gitlabDefaultStages
+	} else {
+		stages = append(append([]string{".pre"}, stages...), ".post")
+	}
+
+	var jobs []*gitlabJob
+	for _, name := range keys {
+		def := asMap(values[name])
+		if gitlabGlobalKeywords[name] || strings.HasPrefix(name, ".") || def == nil {
+			continue
+		}
+		def = gitlabExtends(values, def, 0)
+		if asString(def["when"]) == "never" {
+			continue
+		}
+		jobs = append(jobs, newGitLabJob(name, def, defaults, globalVars))
+	}
+	gitlabLinkJobs(jobs, stages)
+
+	for _, j := range jobs {
+		jobImage(&j.job, j.image, true)
+		p.Jobs = append(p.Jobs, j.job)
+	}
+	for _, job := range p.Jobs {
+		if job.If != nil && slices.Contains(job.If.Events, "pull_request") {
+			p.Triggers.PullRequests = true
+		}
+		if job.If != nil && slices.Contains(job.If.Events, "schedule") && len(p.Triggers.Schedules) == 0 {
+			p.TODOs = appendUnique(p.TODOs, "GitLab pipeline schedules are set in the project settings: add their crons under on.schedule")
+		}
+	}
+
+	translateVars(&p, gitlabVariables)
+	if hasVar(p.Env, "CI_REGISTRY_PASSWORD") || hasVar(p.Env, "CI_JOB_TOKEN") {
+		p.TODOs = append(p.TODOs, "jobs that push images or packages with GITHUB_TOKEN need permissions: packages: write")
+	}
+	inferSecrets(&p, gitlabVariables.platform)
+	return p, nil
+}
+
+// gitlabIncludes describes include: entries, e.g. "template Auto-DevOps.gitlab-ci.yml"
+func gitlabIncludes(include any) []string {
+	var sources []string
+	for _, entry := range asList(include) {
+		if s := asString(entry); s != "" {
+			sources = append(sources, s)
+			continue
+		}
+		m := asMap(entry)
+		for _, kind := range []string{"local", "project", "remote", "template", "component"} {
+			if v := asString(m[kind]); v != "" {
+				source := kind + " " + v
+				if files := asStrings(m["file"]); kind == "project" && len(files) > 0 {
+					source += " (" + strings.Join(files, ", ") + ")"
+				}
+				sources = append(sources, source)
+			}
+		}
+	}
+	return sources
+}
+
+// gitlabExtends merges the templates a job extends: into it, as GitLab does:
+// mappings are merged deeply, any other value is replaced
+func gitlabExtends(values map[string]any, def map[string]any, depth int) map[string]any {
+	parents := asStrings(def["extends"])
+	if len(parents) == 0 || depth > 10 {
+		return def
+	}
+	merged := map[string]any{}
+	for _, parent := range parents {
+		if template := asMap(values[parent]); template != nil {
+			merged = deepMerge(merged, gitlabExtends(values, template, depth+1))
+		}
+	}
+	merged = deepMerge(merged, def)
+	delete(merged, "extends")
+	return merged
+}
+
+func deepMerge(base, override map[string]any) map[string]any {
+	merged := make(map[string]any, len(base)+len(override))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range override {
+		if a, b := asMap(merged[key]), asMap(value); a != nil && b != nil {
+			value = deepMerge(a, b)
+		}
+		merged[key] = value
+	}
+	return merged
+}
+
+// withoutGitVars drops the GIT_* variables that configure the checkout
+func withoutGitVars(vars []PipelineVar) []PipelineVar {
+	var kept []PipelineVar
+	for _, v := range vars {
+		if v.Name != "GIT_STRATEGY" && v.Name != "GIT_DEPTH" && v.Name != "GIT_SUBMODULE_STRATEGY" {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+// gitlabCheckout builds the checkout step from GIT_STRATEGY, GIT_DEPTH and GIT_SUBMODULE_STRATEGY
+func gitlabCheckout(vars []PipelineVar) (PipelineStep, bool) {
+	step := PipelineStep{Kind: StepCheckout}
+	for _, v := range vars {
+		switch v.Name {
+		case "GIT_STRATEGY":
+			if v.Value == "none" || v.Value == "empty" {
+				return step, false
+			}
+		case "GIT_DEPTH":
+			step.With = setVar(step.With, "fetch-depth", v.Value)
+		case "GIT_SUBMODULE_STRATEGY":
+			switch v.Value {
+			case "normal":
+				step.With = setVar(step.With, "submodules", "true")
+			case "recursive":
+				step.With = setVar(step.With, "submodules", "recursive")
+			}
+		}
+	}
+	return step, true
+}
+
+func newGitLabJob(name string, def, defaults map[string]any, globalVars []PipelineVar) *gitlabJob {
+	def = gitlabInherit(def, defaults)
+	j := &gitlabJob{name: name, def: def, stage: asString(def["stage"])}
+	if j.stage == "" {
+		j.stage = "test"
+	}
+	job := PipelineJob{ID: jobID(name), Name: name}
+
+	vars := asVars(def["variables"])
+	job.Env = withoutGitVars(vars)
+
+	// Services see the job's variables, as on GitLab
+	serviceVars := append(append([]PipelineVar(nil), globalVars...), vars...)
+	for _, entry := range asList(def["services"]) {
+		image, alias := asString(entry), ""
+		if m := asMap(entry); m != nil {
+			image, alias = asString(m["name"]), asString(m["alias"])
+			if m["command"] != nil || m["entrypoint"] != nil {
+				job.TODOs = append(job.TODOs, fmt.Sprintf("service %s overrides its command or entrypoint, which GitHub service containers do not support", image))
+			}
+			serviceVars = append(serviceVars, asVars(m["variables"])...)
+		}
+		if image != "" {
+			job.Services = append(job.Services, serviceFromImage(image, strings.Split(alias, ",")[0], serviceVars))
+		}
+	}
+	j.image = asString(def["image"])
+	if m := asMap(def["image"]); m != nil {
+		j.image = asString(m["name"])
+	}
+	if j.image == "" && len(job.Services) > 0 {
+		job.TODOs = append(job.TODOs, "the job runs on the runner, so it reaches its services on localhost rather than by hostname")
+	}
+
+	if checkout, ok := gitlabCheckout(append(append([]PipelineVar(nil), globalVars...), vars...)); ok {
+		job.Steps = append(job.Steps, checkout)
+	}
+	job.Steps = append(job.Steps, gitlabCaches(def["cache"])...)
+	j.downloadAt = len(job.Steps)
+
+	if _, ok := def["trigger"]; ok {
+		job.Steps = append(job.Steps, PipelineStep{Kind: StepTODO, Name: "trigger a downstream pipeline", Source: sourceYAML("trigger", def["trigger"])})
+	}
+	script := append(asStrings(def["before_script"]), asStrings(def["script"])...)
+	if len(script) > 0 {
+		job.Steps = append(job.Steps, PipelineStep{Kind: StepRun, Run: strings.Join(script, "\n") + "\n"})
+	}
+	if release := def["release"]; release != nil {
+		job.Steps = append(job.Steps, PipelineStep{Kind: StepTODO, Name: "create a release", Source: sourceYAML("release", release)})
+	}
+	if after := asStrings(def["after_script"]); len(after) > 0 {
+		job.Steps = append(job.Steps, PipelineStep{Kind: StepRun, Name: "after_script", Run: strings.Join(after, "\n") + "\n", When: "always"})
+	}
+	uploads := gitlabArtifacts(job.ID, asMap(def["artifacts"]))
+	if len(uploads) > 0 && uploads[0].Artifact == job.ID {
+		j.artifacts = uploads[0].Paths
+	}
+	job.Steps = append(job.Steps, uploads...)
+
+	gitlabConditions(&job, def)
+	if environment := def["environment"]; environment != nil {
+		name := asString(environment)
+		if m := asMap(environment); m != nil {
+			name = asString(m["name"])
+			if asString(m["action"]) == "stop" {
+				job.TODOs = append(job.TODOs, "environment action: stop has no GitHub equivalent; delete the deployment from the job")
+			}
+		}
+		job.Environment = expressionVars(name, gitlabKeyExprs)
+	}
+	if tags := asStrings(def["tags"]); len(tags) > 0 {
+		job.RunsOn = append([]string{"self-hosted"}, tags...)
+	}
+	if timeout := asString(def["timeout"]); timeout != "" {
+		job.TimeoutMinutes = parseGitLabDuration(timeout)
+	}
+	if allow := def["allow_failure"]; allow != nil {
+		job.AllowFailure = asBool(allow) || asMap(allow) != nil // exit_codes: fail only on other codes
+	}
+	gitlabParallel(&job, def["parallel"])
+
+	for _, keyword := range []string{"retry", "resource_group", "secrets", "id_tokens", "hooks", "identity", "pages", "start_in"} {
+		if v, ok := def[keyword]; ok {
+			job.TODOs = append(job.TODOs, gitlabKeywordNote(keyword, v))
+		}
+	}
+	if name == "pages" && def["pages"] == nil {
+		job.TODOs = append(job.TODOs, gitlabKeywordNote("pages", nil))
+	}
+	j.job = job
+	return j
+}
+
+// gitlabInherit applies the default: keywords the job does not set, honouring inherit:default
+func gitlabInherit(def, defaults map[string]any) map[string]any {
+	inherit := asMap(def["inherit"])["default"]
+	if enabled, ok := inherit.(bool); ok && !enabled {
+		return def
+	}
+	var allowed []string // Only these keywords, when inherit:default is a list
+	if list, ok := inherit.([]any); ok {
+		allowed = asStrings(list)
+	}
+	merged := make(map[string]any, len(def)+len(defaults))
+	for key, value := range defaults {
+		if len(allowed) == 0 || slices.Contains(allowed, key) {
+			merged[key] = value
+		}
+	}
+	for key, value := range def {
+		merged[key] = value
+	}
+	return merged
+}
+
+// gitlabKeywordNote explains a job keyword with no direct translation
+func gitlabKeywordNote(keyword string, v any) string {
+	switch keyword {
+	case "retry":
+		return "retry: " + asString(v) + " has no GitHub equivalent; rerun failed jobs from the UI or retry inside the script"
+	case "resource_group":
+		return "resource_group " + asString(v) + ": use concurrency: { group: " + asString(v) + " } to serialise runs"
+	case "secrets":
+		return "external secrets (" + strings.Join(sortedAnyKeys(asMap(v)), ", ") + ") must be stored as GitHub secrets or fetched with the provider's action"
+	case "id_tokens":
+		return "id_tokens: use permissions: id-token: write and the cloud provider's OIDC login action"
+	case "pages":
+		return "GitLab Pages: publish the site with actions/upload-pages-artifact and actions/deploy-pages"
+	case "start_in":
+		return "delayed job (start_in: " + asString(v) + ") not translated; add a sleep or a scheduled workflow"
+	default:
+		return keyword + " not translated"
+	}
+}
+
+// gitlabCaches translates cache: (one cache or a list). Pull-only and push-only
+// caches use the restore and save halves of actions/cache.
+func gitlabCaches(v any) []PipelineStep {
+	var steps []PipelineStep
+	caches := asList(v)
+	if m := asMap(v); m != nil {
+		caches = []any{m}
+	}
+	for _, entry := range caches {
+		cache := asMap(entry)
+		paths := asStrings(cache["paths"])
+		if len(paths) == 0 {
+			continue
+		}
+		key := "${{ runner.os }}-default"
+		if k := asString(cache["key"]); k != "" {
+			key = expressionVars(k, gitlabKeyExprs)
+		} else if m := asMap(cache["key"]); m != nil {
+			key = hashFilesExpr(asStrings(m["files"]))
+			if prefix := asString(m["prefix"]); prefix != "" {
+				key = expressionVars(prefix, gitlabKeyExprs) + "-" + key
+			}
+		}
+		step := PipelineStep{Kind: StepCache, Name: "Cache " + strings.Join(paths, ", "), Key: key, Paths: paths}
+		switch asString(cache["policy"]) {
+		case "pull":
+			step = PipelineStep{Kind: StepAction, Name: step.Name, Uses: "actions/cache/restore@v4",
+				With: []PipelineVar{{Name: "path", Value: strings.Join(paths, "\n")}, {Name: "key", Value: key}}}
+		case "push":
+			step = PipelineStep{Kind: StepAction, Name: step.Name, Uses: "actions/cache/save@v4",
+				With: []PipelineVar{{Name: "path", Value: strings.Join(paths, "\n")}, {Name: "key", Value: key}}}
+		}
+		if asBool(cache["untracked"]) {
+			step.Comment = "TODO(fluxion): untracked: true cached every untracked file; list the paths instead"
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+// gitlabArtifacts uploads artifacts:paths as the job's artifact, and reports separately
+func gitlabArtifacts(id string, artifacts map[string]any) []PipelineStep {
+	if artifacts == nil {
+		return nil
+	}
+	when := map[string]string{"on_failure": "failure", "always": "always"}[asString(artifacts["when"])]
+	var steps []PipelineStep
+	if paths := asStrings(artifacts["paths"]); len(paths) > 0 {
+		for _, exclude := range asStrings(artifacts["exclude"]) {
+			paths = append(paths, "!"+exclude)
+		}
+		step := PipelineStep{Kind: StepUpload, Name: "Upload artifacts", Artifact: id, Paths: paths, When: when}
+		if days := parseGitLabDuration(asString(artifacts["expire_in"])) / (24 * 60); days > 0 {
+			step.With = append(step.With, PipelineVar{Name: "retention-days", Value: strconv.Itoa(days)})
+		}
+		steps = append(steps, step)
+	}
+	reports := asMap(artifacts["reports"])
+	var paths []string
+	for _, kind := range sortedAnyKeys(reports) {
+		paths = appendUnique(paths, asStrings(reports[kind])...)
+	}
+	if len(paths) > 0 {
+		steps = append(steps, PipelineStep{Kind: StepUpload, Name: "Upload reports", Artifact: id + "-reports", Paths: paths, When: "always",
+			Comment: "artifacts:reports (" + strings.Join(sortedAnyKeys(reports), ", ") + "): GitHub does not render these, add a reporter action to show them"})
+	}
+	return steps
+}
+
+// gitlabParallel turns parallel: N and parallel:matrix into a job matrix
+func gitlabParallel(job *PipelineJob, v any) {
+	if v == nil {
+		return
+	}
+	if n, err := strconv.Atoi(asString(v)); err == nil {
+		axis := PipelineAxis{Name: "ci_node_index"}
+		for i := 1; i <= n; i++ {
+			axis.Values = append(axis.Values, strconv.Itoa(i))
+		}
+		job.Matrix = append(job.Matrix, axis)
+		job.Env = append(job.Env, PipelineVar{Name: "CI_NODE_INDEX", Value: "${{ matrix.ci_node_index }}"},
+			PipelineVar{Name: "CI_NODE_TOTAL", Value: strconv.Itoa(n)})
+		return
+	}
+	entries := asList(asMap(v)["matrix"])
+	if len(entries) != 1 {
+		job.TODOs = append(job.TODOs, "parallel:matrix with several entries not translated; list the combinations under strategy.matrix.include:\n"+sourceYAML("parallel", v))
+		return
+	}
+	entry := asMap(entries[0])
+	for _, name := range sortedAnyKeys(entry) {
+		job.Matrix = append(job.Matrix, PipelineAxis{Name: name, Values: asStrings(entry[name])})
+		job.Env = append(job.Env, PipelineVar{Name: name, Value: "${{ matrix." + name + " }}"})
+	}
+}
+
+// gitlabConditions translates rules:, only:/except: and when: to the job's condition
+func gitlabConditions(job *PipelineJob, def map[string]any) {
+	switch asString(def["when"]) {
+	case "manual":
+		job.Approval = true
+	case "always", "on_failure", "delayed":
+		job.TODOs = append(job.TODOs, "when: "+asString(def["when"])+" not translated; GitHub jobs run when the jobs they need succeed")
+	}
+
+	if rules, ok := def["rules"]; ok {
+		gitlabRules(job, asList(rules))
+		return
+	}
+	cond := PipelineCondition{}
+	var untranslated []string
+	if only := def["only"]; only != nil {
+		refs := asStrings(only)
+		if m := asMap(only); m != nil {
+			refs = asStrings(m["refs"])
+			if len(m) > 1 || m["refs"] == nil {
+				untranslated = append(untranslated, "only: "+strings.Join(sortedAnyKeys(m), ", "))
+			}
+		}
+		for _, ref := range refs {
+			if !gitlabRef(&cond, ref) {
+				untranslated = append(untranslated, "only: "+ref)
+			}
+		}
+	}
+	if except := def["except"]; except != nil {
+		if refs := asStrings(except); len(refs) == 1 && refs[0] == "tags" && def["only"] == nil {
+			cond.Branches = append(cond.Branches, "*")
+		} else {
+			untranslated = append(untranslated, strings.ReplaceAll(sourceYAML("except", except), "\n", " "))
+		}
+	}
+	cond.Expr = strings.Join(untranslated, "; ")
+	if !conditionEmpty(cond) {
+		job.If = &cond
+	}
+}
+
+// gitlabRef adds an only:/except: ref to a condition, returning false if it has no translation
+func gitlabRef(cond *PipelineCondition, ref string) bool {
+	switch ref {
+	case "branches":
+		cond.Branches = append(cond.Branches, "*")
+	case "tags":
+		cond.Tags = append(cond.Tags, "*")
+	case "merge_requests":
+		cond.Events = append(cond.Events, "pull_request")
+	case "schedules":
+		cond.Events = append(cond.Events, "schedule")
+	case "web":
+		cond.Events = append(cond.Events, "manual")
+	case "pushes":
+		cond.Events = append(cond.Events, "push")
+	case "api", "triggers", "pipelines", "external", "chat", "external_pull_requests":
+		return false
+	default:
+		if strings.HasPrefix(ref, "/") {
+			glob, ok := globFromRegexp(ref)
+			if !ok {
+				return false
+			}
+			ref = glob
+		}
+		cond.Branches = append(cond.Branches, ref)
+	}
+	return true
+}
+
+// gitlabRules translates rules:, where the first matching rule decides.
+// Rules that only match (if:, when: on_success/manual) become the condition;
+// exclusions (when: never) and unknown expressions are kept for review.
+func gitlabRules(job *PipelineJob, rules []any) {
+	cond := PipelineCondition{}
+	var untranslated []string
+	always := false
+	for _, entry := range rules {
+		rule := asMap(entry)
+		when := asString(rule["when"])
+		expr := asString(rule["if"])
+		if rule["changes"] != nil || rule["exists"] != nil {
+			job.TODOs = appendUnique(job.TODOs, "rules:changes/exists not translated; use on.push.paths or a paths filter action")
+		}
+		if rule["variables"] != nil {
+			job.TODOs = append(job.TODOs, "rule variables not translated: "+strings.ReplaceAll(sourceYAML("variables", rule["variables"]), "\n", " "))
+		}
+		if when == "never" {
+			if expr == "" {
+				break // Nothing after it matches
+			}
+			untranslated = append(untranslated, "not ("+expr+")")
+			continue
+		}
+		switch when {
+		case "manual":
+			job.Approval = true
+		case "delayed", "always", "on_failure":
+			job.TODOs = appendUnique(job.TODOs, "rules with when: "+when+" not translated")
+		}
+		if asBool(rule["allow_failure"]) {
+			job.AllowFailure = true
+		}
+		if expr == "" {
+			always = true
+			break
+		}
+		if !gitlabIf(&cond, expr) {
+			untranslated = append(untranslated, expr)
+		}
+	}
+	if always && len(untranslated) == 0 {
+		return
+	}
+	cond.Expr = strings.Join(untranslated, " || ")
+	if !conditionEmpty(cond) {
+		job.If = &cond
+	}
+}
+
+var (
+	gitlabCompare = regexp.MustCompile(`^\$(\w+)\s*(==|=~)\s*(.+)$`)
+	gitlabExists  = regexp.MustCompile(`^\$(\w+)(\s*!=\s*null)?$`)
+)
+
+// gitlabIf adds a rules:if expression to a condition; only ||-joined comparisons
+// of the ref, tag and pipeline source variables are understood
+func gitlabIf(cond *PipelineCondition, expr string) bool {
+	if strings.Contains(expr, "&&") || strings.ContainsAny(expr, "()") {
+		return false
+	}
+	var next PipelineCondition
+	for _, clause := range strings.Split(expr, "||") {
+		clause = strings.TrimSpace(clause)
+		if m := gitlabExists.FindStringSubmatch(clause); m != nil {
+			switch m[1] {
+			case "CI_COMMIT_TAG":
+				next.Tags = append(next.Tags, "*")
+			case "CI_COMMIT_BRANCH":
+				next.Branches = append(next.Branches, "*")
+			case "CI_MERGE_REQUEST_ID", "CI_MERGE_REQUEST_IID":
+				next.Events = append(next.Events, "pull_request")
+			default:
+				return false
+			}
+			continue
+		}
+		m := gitlabCompare.FindStringSubmatch(clause)
+		if m == nil {
+			return false
+		}
+		name, op, value := m[1], m[2], strings.TrimSpace(m[3])
+		if value == "$CI_DEFAULT_BRANCH" && op == "==" && (name == "CI_COMMIT_BRANCH" || name == "CI_COMMIT_REF_NAME") {
+			next.DefaultBranch = true
+			continue
+		}
+		pattern := strings.Trim(value, `"'`)
+		if op == "=~" {
+			glob, ok := globFromRegexp(value)
+			if !ok {
+				return false
+			}
+			pattern = glob
+		} else if pattern == value {
+			return false // Compared with another variable
+		}
+		switch name {
+		case "CI_COMMIT_BRANCH", "CI_COMMIT_REF_NAME":
+			next.Branches = append(next.Branches, pattern)
+		case "CI_COMMIT_TAG":
+			next.Tags = append(next.Tags, pattern)
+		case "CI_PIPELINE_SOURCE":
+			event, ok := map[string]string{"merge_request_event": "pull_request", "push": "push", "schedule": "schedule", "web": "manual"}[pattern]
+			if !ok || op != "==" {
+				return false
+			}
+			next.Events = append(next.Events, event)
+		default:
+			return false
+		}
+	}
+	cond.DefaultBranch = cond.DefaultBranch || next.DefaultBranch
+	cond.Branches = appendUnique(cond.Branches, next.Branches...)
+	cond.Tags = appendUnique(cond.Tags, next.Tags...)
+	cond.Events = appendUnique(cond.Events, next.Events...)
+	return true
+}
+
+func conditionEmpty(c PipelineCondition) bool {
+	return !c.DefaultBranch && len(c.Branches) == 0 && len(c.Tags) == 0 && len(c.Events) == 0 && c.Expr == ""
+}
+
+// gitlabWorkflowRules reads which events start the pipeline from workflow:rules
+func gitlabWorkflowRules(p *Pipeline, workflow map[string]any) {
+	rules := asList(workflow["rules"])
+	if len(rules) == 0 {
+		return
+	}
+	var job PipelineJob
+	gitlabRules(&job, rules)
+	if job.If == nil {
+		return
+	}
+	if slices.Contains(job.If.Events, "pull_request") {
+		p.Triggers.PullRequests = true
+	}
+	if job.If.Expr != "" || len(job.TODOs) > 0 {
+		p.TODOs = append(p.TODOs, "workflow:rules not fully translated, check the triggers: "+strings.ReplaceAll(sourceYAML("rules", rules), "\n", " "))
+	}
+}
+
+// gitlabLinkJobs sets each job's needs from needs: or its stage, and downloads the
+// artifacts it receives from dependencies:, needs: or the earlier stages
+func gitlabLinkJobs(jobs []*gitlabJob, stages []string) {
+	byName := map[string]*gitlabJob{}
+	byStage := map[string][]*gitlabJob{}
+	for _, j := range jobs {
+		byName[j.name] = j
+		byStage[j.stage] = append(byStage[j.stage], j)
+	}
+	stageIndex := func(stage string) int {
+		for i, s := range stages {
+			if s == stage {
+				return i
+			}
+		}
+		return len(stages)
+	}
+
+	for _, j := range jobs {
+		var needs, artifactsFrom []*gitlabJob
+		if entries, ok := j.def["needs"]; ok {
+			for _, entry := range asList(entries) {
+				name, artifacts := asString(entry), true
+				if m := asMap(entry); m != nil {
+					name = asString(m["job"])
+					if m["artifacts"] != nil {
+						artifacts = asBool(m["artifacts"])
+					}
+					if m["pipeline"] != nil || m["project"] != nil {
+						j.job.TODOs = append(j.job.TODOs, "cross-pipeline needs: not translated: "+strings.ReplaceAll(sourceYAML("needs", m), "\n", " "))
+						continue
+					}
+				}
+				if dep, ok := byName[name]; ok {
+					needs = append(needs, dep)
+					if artifacts {
+						artifactsFrom = append(artifactsFrom, dep)
+					}
+				}
+			}
+		} else {
+			// Every job waits for the whole previous stage
+			for i := stageIndex(j.stage) - 1; i >= 0 && len(needs) == 0; i-- {
+				needs = byStage[stages[i]]
+			}
+			for _, stage := range stages[:min(stageIndex(j.stage), len(stages))] {
+				artifactsFrom = append(artifactsFrom, byStage[stage]...)
+			}
+		}
+		if deps, ok := j.def["dependencies"]; ok {
+			artifactsFrom = nil
+			for _, name := range asStrings(deps) {
+				if dep, ok := byName[name]; ok {
+					artifactsFrom = append(artifactsFrom, dep)
+				}
+			}
+		}
+
+		for _, dep := range needs {
+			j.job.Needs = appendUnique(j.job.Needs, dep.job.ID)
+		}
+		var downloads []PipelineStep
+		for _, dep := range artifactsFrom {
+			if len(dep.artifacts) > 0 {
+				downloads = append(downloads, PipelineStep{Kind: StepDownload, Name: "Download " + dep.job.ID + " artifacts",
+					Artifact: dep.job.ID, Paths: []string{artifactDownloadPath(".", dep.artifacts)}})
+			}
+		}
+		steps := j.job.Steps
+		j.job.Steps = append(append(append([]PipelineStep(nil), steps[:j.downloadAt]...), downloads...), steps[j.downloadAt:]...)
+	}
+}
+
+var gitlabDurationPattern = regexp.MustCompile(`(\d+)\s*(w|weeks?|d|days?|h|hrs?|hours?|m|mins?|minutes?|s|sec|seconds?)\b`)
+
+// parseGitLabDuration converts a duration such as "1h 30m" or "3 days" to minutes
+func parseGitLabDuration(duration string) int {
+	units := map[byte]int{'w': 7 * 24 * 60, 'd': 24 * 60, 'h': 60, 'm': 1}
+	minutes := 0
+	for _, m := range gitlabDurationPattern.FindAllStringSubmatch(strings.ToLower(duration), -1) {
+		n, _ := strconv.Atoi(m[1])
+		minutes += n * units[m[2][0]]
+	}
+	return minutes
+}
@@ -0,0 +1,1062 @@
+package cmd
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// jenkinsVariables maps Jenkins' environment variables to GitHub Actions
+var jenkinsVariables = ciVariables{
+	renames: map[string]string{
+		"BUILD_NUMBER": "GITHUB_RUN_NUMBER",
+		"BUILD_ID":     "GITHUB_RUN_ID",
+		"GIT_COMMIT":   "GITHUB_SHA",
+		"WORKSPACE":    "GITHUB_WORKSPACE",
+		"NODE_NAME":    "RUNNER_NAME",
+		"GIT_URL":      "$GITHUB_SERVER_URL/$GITHUB_REPOSITORY.git",
+	},
+	compat: map[string]string{
+		"BRANCH_NAME":         "${{ github.head_ref || github.ref_name }}",
+		"GIT_BRANCH":          "${{ github.head_ref || github.ref_name }}",
+		"TAG_NAME":            "${{ github.ref_type == 'tag' && github.ref_name || '' }}",
+		"CHANGE_ID":           "${{ github.event.pull_request.number }}",
+		"CHANGE_TARGET":       "${{ github.base_ref }}",
+		"CHANGE_BRANCH":       "${{ github.head_ref }}",
+		"JOB_NAME":            "${{ github.repository }}",
+		"BUILD_URL":           "${{ github.server_url }}/${{ github.repository }}/actions/runs/${{ github.run_id }}",
+		"GIT_PREVIOUS_COMMIT": "${{ github.event.before }}",
+	},
+	platform: func(name string) bool {
+		for _, prefix := range []string{"BUILD_", "JOB_", "JENKINS_", "CHANGE_", "TAG_", "NODE_", "EXECUTOR_", "STAGE_", "GIT_", "HUDSON_"} {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		}
+		return name == "WORKSPACE"
+	},
+}
+
+// jenkinsTools maps tools { } installations to runtimes
+var jenkinsTools = map[string]string{"go": "go", "nodejs": "node", "jdk": "java", "python": "python"}
+
+// jenkinsNode is a statement of a Jenkinsfile: name, its arguments and its block, if any
+type jenkinsNode struct {
+	Name     string
+	Args     string // Everything between the name and the block: (...) or the rest of the line
+	Children []*jenkinsNode
+	Block    bool
+	Source   string // The statement as written
+}
+
+// child returns the first child with the given name, or nil
+func (n *jenkinsNode) child(name string) *jenkinsNode {
+	for _, c := range n.Children {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// jenkinsScanner reads the block structure of a Jenkinsfile; it knows Groovy's
+// strings and comments well enough to find where statements and blocks end
+type jenkinsScanner struct {
+	src string
+	pos int
+}
+
+func (s *jenkinsScanner) peek() byte {
+	if s.pos < len(s.src) {
+		return s.src[s.pos]
+	}
+	return 0
+}
+
+// skipSpace skips blanks and comments, and newlines and semicolons too when newlines is true
+func (s *jenkinsScanner) skipSpace(newlines bool) {
+	for s.pos < len(s.src) {
+		c := s.src[s.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r':
+			s.pos++
+		case (c == '\n' || c == ';') && newlines:
+			s.pos++
+		case strings.HasPrefix(s.src[s.pos:], "//"):
+			for s.pos < len(s.src) && s.src[s.pos] != '\n' {
+				s.pos++
+			}
+		case strings.HasPrefix(s.src[s.pos:], "/*"):
+			end := strings.Index(s.src[s.pos+2:], "*/")
+			if end < 0 {
+				s.pos = len(s.src)
+			} else {
+				s.pos += end + 4
+			}
+		default:
+			return
+		}
+	}
+}
+
+// skipString moves past the string literal at pos
+func (s *jenkinsScanner) skipString() {
+	for _, quote := range []string{`'''`, `"""`, `'`, `"`} {
+		if !strings.HasPrefix(s.src[s.pos:], quote) {
+			continue
+		}
+		s.pos += len(quote)
+		for s.pos < len(s.src) {
+			if s.src[s.pos] == '\\' {
+				s.pos += 2
+				continue
+			}
+			if strings.HasPrefix(s.src[s.pos:], quote) {
+				s.pos += len(quote)
+				return
+			}
+			s.pos++
+		}
+		return
+	}
+}
+
+// readArgs reads up to the end of the statement: a newline, ';', '{' or '}' outside brackets and strings
+func (s *jenkinsScanner) readArgs() string {
+	start, depth := s.pos, 0
+	for s.pos < len(s.src) {
+		c := s.src[s.pos]
+		switch {
+		case c == '\'' || c == '"':
+			s.skipString()
+			continue
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		case depth == 0 && (c == '\n' || c == ';' || c == '{' || c == '}'):
+			return strings.TrimSpace(s.src[start:s.pos])
+		case depth > 0 && c == '{':
+			depth++ // A closure inside the arguments
+		case depth > 0 && c == '}':
+			depth--
+		}
+		s.pos++
+	}
+	return strings.TrimSpace(s.src[start:])
+}
+
+// parseBlock reads statements up to the closing brace of the current block
+func (s *jenkinsScanner) parseBlock() []*jenkinsNode {
+	var nodes []*jenkinsNode
+	for {
+		s.skipSpace(true)
+		if s.pos >= len(s.src) {
+			return nodes
+		}
+		if s.peek() == '}' {
+			s.pos++
+			return nodes
+		}
+		start := s.pos
+		node := &jenkinsNode{}
+		for s.pos < len(s.src) && (isAlphanumeric(s.peek()) || s.peek() == '_' || s.peek() == '.' || s.peek() == '$') {
+			s.pos++
+		}
+		node.Name = s.src[start:s.pos]
+		s.skipSpace(false)
+		if s.peek() != '{' {
+			node.Args = s.readArgs()
+			s.skipSpace(false)
+		}
+		if s.peek() == '{' {
+			s.pos++
+			node.Block = true
+			node.Children = s.parseBlock()
+		}
+		if s.pos == start {
+			s.pos++ // Not a statement this scanner understands
+			continue
+		}
+		node.Source = strings.TrimSpace(s.src[start:min(s.pos, len(s.src))])
+		nodes = append(nodes, node)
+	}
+}
+
+// groovyArgs splits call arguments into positional values and name: value pairs;
+// string literals are unquoted, anything else is kept as written
+func groovyArgs(args string) (positional []string, named map[string]string) {
+	named = map[string]string{}
+	args = strings.TrimSpace(args)
+	if strings.HasPrefix(args, "(") && strings.HasSuffix(args, ")") {
+		args = args[1 : len(args)-1]
+	}
+	s := &jenkinsScanner{src: args}
+	for s.pos < len(s.src) {
+		start, depth := s.pos, 0
+		for s.pos < len(s.src) {
+			c := s.src[s.pos]
+			if c == '\'' || c == '"' {
+				s.skipString()
+				continue
+			}
+			if c == '(' || c == '[' || c == '{' {
+				depth++
+			} else if c == ')' || c == ']' || c == '}' {
+				depth--
+			} else if c == ',' && depth == 0 {
+				break
+			}
+			s.pos++
+		}
+		arg := strings.TrimSpace(s.src[start:min(s.pos, len(s.src))])
+		s.pos++
+		if m := groovyNamedArg.FindStringSubmatch(arg); m != nil {
+			named[m[1]] = groovyValue(arg[len(m[0]):])
+		} else if arg != "" {
+			positional = append(positional, groovyValue(arg))
+		}
+	}
+	return positional, named
+}
+
+var (
+	groovyNamedArg   = regexp.MustCompile(`^(\w+)\s*:\s*`)
+	groovyEnvRef     = regexp.MustCompile(`\$\{\s*(?:env|params)\.(\w+)\s*\}|\$(?:env|params)\.(\w+)`)
+	jenkinsCronHash  = regexp.MustCompile(`H\((\d+)-\d+\)|H`)
+	jenkinsLabelSpec = regexp.MustCompile(`^[\w.-]+(\s*&&\s*[\w.-]+)*$`)
+)
+
+// groovyValue unquotes a string literal; env.X and params.X references in GStrings become shell variables
+func groovyValue(value string) string {
+	value = strings.TrimSpace(value)
+	for _, quote := range []string{`'''`, `"""`, `'`, `"`} {
+		if len(value) < 2*len(quote) || !strings.HasPrefix(value, quote) || !strings.HasSuffix(value, quote) {
+			continue
+		}
+		body := value[len(quote) : len(value)-len(quote)]
+		if strings.Contains(quote, `"`) {
+			body = groovyEnvRef.ReplaceAllString(body, "$${$1$2}")
+			body = strings.ReplaceAll(body, `\$`, `$`)
+		} else {
+			body = strings.ReplaceAll(body, `\'`, `'`)
+		}
+		body = strings.ReplaceAll(body, `\\`, `\`)
+		if len(quote) == 3 {
+			body = dedent(strings.TrimPrefix(body, "\n"))
+		}
+		return body
+	}
+	return value
+}
+
+// dedent removes the indentation the lines of a multi-line string share
+func dedent(text string) string {
+	lines := strings.Split(text, "\n")
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	for i, line := range lines {
+		if len(line) >= indent && indent > 0 {
+			lines[i] = line[indent:]
+		} else {
+			lines[i] = strings.TrimLeft(line, " \t")
+		}
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), " \t\n")
+}
+
+// jenkinsSecretName is the GitHub secret name for a Jenkins credentials ID
+func jenkinsSecretName(id string) string {
+	return strings.Trim(strings.ToUpper(jobIDInvalidPattern.ReplaceAllString(strings.ReplaceAll(id, "-", "_"), "_")), "_")
+}
+
+// jenkinsParser carries what applies to every stage while a Jenkinsfile is translated
+type jenkinsParser struct {
+	p            *Pipeline
+	runsOn       []string
+	image        string
+	tools        []PipelineStep
+	timeout      int
+	skipCheckout bool
+	secrets      []string
+	stashes      map[string][]string
+
+	// Jenkins runs every stage on the pipeline's agent in one workspace, so a
+	// stage relies on what the stages before it installed or built. shared
+	// are the jobs for those stages; workspaces are the IDs whose workspace a
+	// later job restores.
+	sharedAgent bool
+	shared      map[string]bool
+	workspaces  map[string]bool
+}
+
+// ParseJenkinsfile translates a declarative Jenkinsfile to the pipeline model.
+// Each stage with steps becomes a job that needs the stages before it; the
+// stages of a parallel block run side by side. Scripted pipelines and script
+// blocks are Groovy programs and are left as TODOs.
+func ParseJenkinsfile(config string) (Pipeline, error) {
+	scanner := &jenkinsScanner{src: config}
+	var root *jenkinsNode
+	for _, node := range scanner.parseBlock() {
+		if node.Name == "pipeline" && node.Block {
+			root = node
+		}
+	}
+	p := Pipeline{Name: "CI", Triggers: PipelineTriggers{PushBranches: []string{"*"}, PullRequests: true, Manual: true}}
+	if root == nil {
+		// Scripted pipeline: node { ... }
+		p.Jobs = []PipelineJob{{ID: "build", Name: "build", Steps: []PipelineStep{
+			{Kind: StepCheckout},
+			{Kind: StepTODO, Name: "scripted pipeline", Source: strings.TrimSpace(config)},
+		}}}
+		return p, nil
+	}
+
+	jp := &jenkinsParser{p: &p, stashes: map[string][]string{}, shared: map[string]bool{}, workspaces: map[string]bool{}}
+	if agent := root.child("agent"); agent != nil && strings.TrimSpace(agent.Args) != "none" {
+		jp.sharedAgent = true
+	}
+	for _, node := range root.Children {
+		switch node.Name {
+		case "agent":
+			jp.runsOn, jp.image = jp.agent(node, &p.TODOs)
+		case "environment":
+			p.Env = jp.environment(node)
+		case "options":
+			jp.options(node, &p.TODOs)
+		case "triggers":
+			jp.triggers(node)
+		case "parameters":
+			for _, param := range node.Children {
+				_, named := groovyArgs(param.Args)
+				if named["name"] != "" {
+					p.Env = append(p.Env, PipelineVar{Name: named["name"], Value: named["defaultValue"]})
+				}
+			}
+			p.TODOs = append(p.TODOs, "build parameters became env variables with their defaults; add workflow_dispatch inputs to set them")
+		case "tools":
+			jp.tools = jenkinsToolSteps(node)
+		case "stages", "post", "libraries":
+		default:
+			p.TODOs = append(p.TODOs, "pipeline directive not translated: "+firstLine(node.Source))
+		}
+	}
+	if libraries := root.child("libraries"); libraries != nil || strings.Contains(config, "@Library") {
+		p.TODOs = append(p.TODOs, "shared libraries are not migrated; their steps are left as TODOs")
+	}
+
+	if stages := root.child("stages"); stages != nil {
+		jp.stages(stages.Children, nil, PipelineJob{})
+	}
+	for i := range p.Jobs {
+		if jp.shared[p.Jobs[i].ID] {
+			jp.restoreWorkspace(&p.Jobs[i], p.Jobs[i].Needs, false)
+		}
+	}
+	if post := root.child("post"); post != nil {
+		jp.postJobs(post)
+	}
+	jp.saveWorkspaces()
+	for _, job := range p.Jobs {
+		if job.If != nil && len(job.If.Tags) > 0 {
+			p.Triggers.Tags = []string{"*"}
+		}
+	}
+
+	p.Secrets = jp.secrets
+	translateVars(&p, jenkinsVariables)
+	inferSecrets(&p, jenkinsVariables.platform)
+	return p, nil
+}
+
+// stages adds jobs for sequential stages, each needing the jobs before it, and
+// returns the IDs the next stage must need. inherited carries the enclosing stage's settings.
+func (jp *jenkinsParser) stages(stages []*jenkinsNode, needs []string, inherited PipelineJob) []string {
+	for _, stage := range stages {
+		if stage.Name != "stage" {
+			continue
+		}
+		positional, _ := groovyArgs(stage.Args)
+		name := "stage"
+		if len(positional) > 0 {
+			name = positional[0]
+		}
+
+		job := inherited
+		job.Env = append([]PipelineVar(nil), inherited.Env...)
+		job.TODOs = append([]string(nil), inherited.TODOs...)
+		job.ID, job.Name = jobID(name), name
+		for i := 2; jp.p.job(job.ID) != nil; i++ {
+			job.ID = jobID(name + "-" + strconv.Itoa(i))
+		}
+		for _, node := range stage.Children {
+			switch node.Name {
+			case "agent":
+				job.RunsOn, job.Image = jp.agent(node, &job.TODOs)
+			case "environment":
+				job.Env = append(job.Env, jp.environment(node)...)
+			case "when":
+				job.If = jenkinsWhen(node)
+			case "options":
+				jp.stageOptions(node, &job)
+			case "input":
+				job.Approval = true
+				job.Environment = job.ID
+			case "tools":
+				job.Steps = append(job.Steps, jenkinsToolSteps(node)...)
+			case "steps", "post", "parallel", "stages", "matrix", "failFast":
+			default:
+				job.TODOs = append(job.TODOs, "stage directive not translated: "+firstLine(node.Source))
+			}
+		}
+
+		switch {
+		case stage.child("parallel") != nil:
+			var next []string
+			for _, branch := range stage.child("parallel").Children {
+				next = append(next, jp.stages([]*jenkinsNode{branch}, needs, job)...)
+			}
+			needs = next
+		case stage.child("stages") != nil:
+			needs = jp.stages(stage.child("stages").Children, needs, job)
+		case stage.child("matrix") != nil:
+			jp.matrix(stage.child("matrix"), &job)
+			jp.addJob(job, needs, stage)
+			needs = []string{job.ID}
+		default:
+			jp.addJob(job, needs, stage)
+			needs = []string{job.ID}
+		}
+	}
+	return needs
+}
+
+// addJob finishes a stage's job: runner, checkout, tools, steps and post steps
+func (jp *jenkinsParser) addJob(job PipelineJob, needs []string, stage *jenkinsNode) {
+	job.Needs = needs
+	if job.RunsOn == nil && job.Image == "" {
+		job.RunsOn, job.Image = jp.runsOn, jp.image
+		jp.shared[job.ID] = jp.sharedAgent
+	}
+	if job.TimeoutMinutes == 0 {
+		job.TimeoutMinutes = jp.timeout
+	}
+	setup := append(append([]PipelineStep(nil), jp.tools...), job.Steps...)
+	job.Steps = nil
+	if !jp.skipCheckout {
+		job.Steps = append(job.Steps, PipelineStep{Kind: StepCheckout})
+	}
+	job.Steps = append(job.Steps, setup...)
+	if steps := stage.child("steps"); steps != nil {
+		job.Steps = append(job.Steps, jp.steps(steps.Children, &job, PipelineStep{})...)
+	}
+	if matrix := stage.child("matrix"); matrix != nil {
+		if inner := matrix.child("stages"); inner != nil {
+			for _, s := range inner.Children {
+				if steps := s.child("steps"); steps != nil {
+					job.Steps = append(job.Steps, jp.steps(steps.Children, &job, PipelineStep{})...)
+				}
+			}
+		}
+	}
+	if post := stage.child("post"); post != nil {
+		job.Steps = append(job.Steps, jp.post(post)...)
+	}
+	// An explicit checkout scm replaces the default one
+	if len(job.Steps) > 1 && job.Steps[0].Kind == StepCheckout {
+		for _, step := range job.Steps[1:] {
+			if step.Kind == StepCheckout {
+				job.Steps = job.Steps[1:]
+				break
+			}
+		}
+	}
+	if len(job.Steps) == 0 {
+		job.Steps = []PipelineStep{{Kind: StepRun, Run: "true\n"}}
+	}
+	image := job.Image
+	job.Image = ""
+	jobImage(&job, image, true)
+	jp.p.Jobs = append(jp.p.Jobs, job)
+}
+
+// postJobs translates the pipeline's post block. Jenkins runs it after every
+// stage, whatever happened to them, so it cannot be steps of the last stage's
+// job, which may be skipped by its when. Each condition becomes a job that
+// needs every other job: always, failure, then success.
+func (jp *jenkinsParser) postJobs(node *jenkinsNode) {
+	var all []string
+	for _, job := range jp.p.Jobs {
+		all = append(all, job.ID)
+	}
+	groups := map[string][]PipelineStep{}
+	for _, step := range jp.post(node) {
+		when := step.When
+		switch {
+		case step.Kind == StepTODO:
+			when = "always" // Conditions with no equivalent, left for review
+		case when == "":
+			when = "success"
+		}
+		step.When = ""
+		groups[when] = append(groups[when], step)
+	}
+
+	ids := map[string]string{"always": "post", "failure": "post-failure", "success": "post-success"}
+	names := map[string]string{"always": "Post", "failure": "Post (failure)", "success": "Post (success)"}
+	for _, when := range []string{"always", "failure", "success"} {
+		if len(groups[when]) == 0 {
+			continue
+		}
+		job := PipelineJob{ID: ids[when], Name: names[when], Needs: all, When: when, RunsOn: jp.runsOn, TimeoutMinutes: jp.timeout}
+		for i := 2; jp.p.job(job.ID) != nil; i++ {
+			job.ID = jobID(ids[when] + "-" + strconv.Itoa(i))
+		}
+		if !jp.skipCheckout {
+			job.Steps = append(job.Steps, PipelineStep{Kind: StepCheckout})
+		}
+		if jp.sharedAgent {
+			jp.restoreWorkspace(&job, all, true) // In order, so the latest stage that ran wins
+		}
+		job.Steps = append(job.Steps, groups[when]...)
+		jobImage(&job, jp.image, true)
+		jp.p.Jobs = append(jp.p.Jobs, job)
+	}
+}
+
+// The workspace travels as a tar archive: artifacts keep neither file modes nor
+// symlinks, which scripts such as ./deploy.sh and node_modules/.bin need
+const (
+	jenkinsWorkspaceArchive = "workspace.tar"
+	jenkinsWorkspaceSave    = "tar --exclude=./.git --exclude=./" + jenkinsWorkspaceArchive + " -cpf " + jenkinsWorkspaceArchive + " .\n"
+	jenkinsWorkspaceExtract = "tar -xpf " + jenkinsWorkspaceArchive + "\nrm " + jenkinsWorkspaceArchive + "\n"
+)
+
+// restoreWorkspace downloads into job the workspaces of the shared jobs in
+// from, right after its checkout. A job after a failure may find no
+// workspace to restore, so optional downloads are allowed to fail.
+func (jp *jenkinsParser) restoreWorkspace(job *PipelineJob, from []string, optional bool) {
+	var downloads []PipelineStep
+	for _, id := range from {
+		source := jp.p.job(id)
+		if source == nil || !jp.shared[id] || len(source.Matrix) > 0 {
+			continue // Matrix cells have a workspace each
+		}
+		jp.workspaces[id] = true
+		extract := jenkinsWorkspaceExtract
+		if optional {
+			extract = "if [ -f " + jenkinsWorkspaceArchive + " ]; then\n" + indentLines(extract, "  ") + "fi\n"
+		}
+		downloads = append(downloads,
+			PipelineStep{Kind: StepDownload, Name: "Download the workspace of " + id, Artifact: "workspace-" + id,
+				Paths: []string{"."}, ContinueOnError: optional},
+			PipelineStep{Kind: StepRun, Name: "Restore the workspace of " + id, Run: extract})
+	}
+	at := 0
+	if len(job.Steps) > 0 && job.Steps[0].Kind == StepCheckout {
+		at = 1
+	}
+	job.Steps = append(job.Steps[:at], append(downloads, job.Steps[at:]...)...)
+}
+
+// saveWorkspaces uploads the workspace at the end of every job a later job restores it from
+func (jp *jenkinsParser) saveWorkspaces() {
+	for _, id := range sortedKeys(jp.workspaces) {
+		job := jp.p.job(id)
+		job.Steps = append(job.Steps,
+			PipelineStep{Kind: StepRun, Name: "Archive the workspace", Run: jenkinsWorkspaceSave, When: "always",
+				Comment: "Jenkins stages share one workspace; the jobs after this one restore it"},
+			PipelineStep{Kind: StepUpload, Name: "Save the workspace", Artifact: "workspace-" + id,
+				Paths: []string{jenkinsWorkspaceArchive}, When: "always"})
+	}
+}
+
+// agent translates agent any|none|{ label ... }|{ docker ... }
+func (jp *jenkinsParser) agent(node *jenkinsNode, todos *[]string) (runsOn []string, image string) {
+	if !node.Block {
+		return nil, ""
+	}
+	for _, kind := range node.Children {
+		positional, named := groovyArgs(kind.Args)
+		switch kind.Name {
+		case "label":
+			label := ""
+			if len(positional) > 0 {
+				label = positional[0]
+			}
+			return jenkinsLabels(label, todos), ""
+		case "node":
+			if label := kind.child("label"); label != nil {
+				l, _ := groovyArgs(label.Args)
+				if len(l) > 0 {
+					return jenkinsLabels(l[0], todos), ""
+				}
+			}
+		case "docker":
+			if len(positional) > 0 {
+				return nil, positional[0]
+			}
+			for _, option := range kind.Children {
+				value, _ := groovyArgs(option.Args)
+				switch {
+				case option.Name == "image" && len(value) > 0:
+					image = value[0]
+				case option.Name == "label" && len(value) > 0:
+					runsOn = jenkinsLabels(value[0], todos)
+				case option.Name == "args" && len(value) > 0:
+					*todos = append(*todos, "docker agent args not translated: "+value[0]+" (use container.options)")
+				}
+			}
+			if image == "" {
+				image = named["image"]
+			}
+			return runsOn, image
+		default:
+			*todos = append(*todos, "agent "+kind.Name+" not translated; the job runs on ubuntu-latest")
+		}
+	}
+	return nil, ""
+}
+
+// jenkinsLabels turns a label expression into runner labels; only && is supported
+func jenkinsLabels(expr string, todos *[]string) []string {
+	if !jenkinsLabelSpec.MatchString(strings.TrimSpace(expr)) {
+		*todos = append(*todos, "agent label expression not translated: "+expr)
+		return []string{"self-hosted"}
+	}
+	labels := []string{"self-hosted"}
+	for _, label := range strings.Split(expr, "&&") {
+		labels = append(labels, strings.TrimSpace(label))
+	}
+	return labels
+}
+
+// environment translates NAME = value lines; credentials('id') become secrets
+func (jp *jenkinsParser) environment(node *jenkinsNode) []PipelineVar {
+	var vars []PipelineVar
+	for _, line := range node.Children {
+		value := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line.Args), "="))
+		if strings.HasPrefix(value, "credentials(") {
+			id, _ := groovyArgs(strings.TrimPrefix(value, "credentials"))
+			if len(id) > 0 {
+				secret := jenkinsSecretName(id[0])
+				jp.secrets = appendUnique(jp.secrets, secret)
+				vars = append(vars, PipelineVar{Name: line.Name, Value: "${{ secrets." + secret + " }}"})
+				continue
+			}
+		}
+		vars = append(vars, PipelineVar{Name: line.Name, Value: groovyValue(value)})
+	}
+	return vars
+}
+
+// options translates the pipeline options GitHub has an equivalent for
+func (jp *jenkinsParser) options(node *jenkinsNode, todos *[]string) {
+	for _, option := range node.Children {
+		switch option.Name {
+		case "timeout":
+			jp.timeout = jenkinsTimeout(option.Args)
+		case "skipDefaultCheckout":
+			jp.skipCheckout = !strings.Contains(option.Args, "false")
+		case "disableConcurrentBuilds":
+			*todos = append(*todos, "disableConcurrentBuilds: add concurrency: { group: ${{ github.workflow }}-${{ github.ref }} }")
+		case "retry":
+			*todos = append(*todos, "retry"+option.Args+" has no GitHub equivalent; rerun failed jobs from the UI")
+		case "buildDiscarder", "timestamps", "ansiColor", "disableResume", "durabilityHint", "quietPeriod", "preserveStashes", "parallelsAlwaysFailFast":
+			// Jenkins bookkeeping with nothing to translate
+		default:
+			*todos = append(*todos, "option not translated: "+firstLine(option.Source))
+		}
+	}
+}
+
+func (jp *jenkinsParser) stageOptions(node *jenkinsNode, job *PipelineJob) {
+	for _, option := range node.Children {
+		switch option.Name {
+		case "timeout":
+			job.TimeoutMinutes = jenkinsTimeout(option.Args)
+		case "skipDefaultCheckout":
+		case "retry":
+			job.TODOs = append(job.TODOs, "retry"+option.Args+" has no GitHub equivalent")
+		default:
+			job.TODOs = append(job.TODOs, "stage option not translated: "+firstLine(option.Source))
+		}
+	}
+}
+
+// jenkinsTimeout converts timeout(time: n, unit: 'HOURS') to minutes
+func jenkinsTimeout(args string) int {
+	positional, named := groovyArgs(args)
+	n, _ := strconv.Atoi(named["time"])
+	if n == 0 && len(positional) > 0 {
+		n, _ = strconv.Atoi(positional[0])
+	}
+	switch strings.ToUpper(named["unit"]) {
+	case "HOURS":
+		return n * 60
+	case "SECONDS":
+		return max((n+59)/60, 1)
+	case "DAYS":
+		return n * 24 * 60
+	}
+	return n
+}
+
+// triggers translates cron and pollSCM; H (Jenkins' hashed value) becomes the lowest value it can take
+func (jp *jenkinsParser) triggers(node *jenkinsNode) {
+	for _, trigger := range node.Children {
+		spec, _ := groovyArgs(trigger.Args)
+		switch trigger.Name {
+		case "cron":
+			if len(spec) > 0 {
+				for _, line := range strings.Split(spec[0], "\n") {
+					if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+						jp.p.Triggers.Schedules = append(jp.p.Triggers.Schedules, jenkinsCronHash.ReplaceAllStringFunc(line, func(h string) string {
+							if m := jenkinsCronHash.FindStringSubmatch(h); m[1] != "" {
+								return m[1]
+							}
+							return "0"
+						}))
+					}
+				}
+			}
+		case "pollSCM", "githubPush":
+			// Pushes trigger the workflow directly
+		default:
+			jp.p.TODOs = append(jp.p.TODOs, "trigger not translated: "+firstLine(trigger.Source)+" (for upstream jobs, use on.workflow_run)")
+		}
+	}
+}
+
+// jenkinsToolSteps sets up tools { go 'go-1.22' } with the version in the installation name
+func jenkinsToolSteps(node *jenkinsNode) []PipelineStep {
+	var steps []PipelineStep
+	for _, tool := range node.Children {
+		name, _ := groovyArgs(tool.Args)
+		if len(name) == 0 {
+			continue
+		}
+		runtime, ok := jenkinsTools[tool.Name]
+		version := regexp.MustCompile(`\d+(\.\d+)*`).FindString(name[0])
+		switch {
+		case ok && version != "":
+			steps = append(steps, PipelineStep{Kind: StepSetup, Runtime: runtime, Version: version, Comment: "Jenkins tool installation: " + name[0]})
+		case tool.Name == "maven" || tool.Name == "gradle":
+			// Installed on GitHub-hosted Ubuntu runners
+		default:
+			steps = append(steps, PipelineStep{Kind: StepTODO, Name: "set up " + tool.Name + " " + name[0], Source: tool.Source})
+		}
+	}
+	return steps
+}
+
+// matrix translates a matrix's axes; its stages run one after another in each matrix job
+func (jp *jenkinsParser) matrix(node *jenkinsNode, job *PipelineJob) {
+	if axes := node.child("axes"); axes != nil {
+		for _, axis := range axes.Children {
+			var a PipelineAxis
+			for _, field := range axis.Children {
+				values, _ := groovyArgs(field.Args)
+				switch field.Name {
+				case "name":
+					if len(values) > 0 {
+						a.Name = values[0]
+					}
+				case "values":
+					a.Values = values
+				}
+			}
+			if a.Name != "" {
+				job.Matrix = append(job.Matrix, a)
+				job.Env = append(job.Env, PipelineVar{Name: a.Name, Value: "${{ matrix." + a.Name + " }}"})
+			}
+		}
+	}
+	if node.child("excludes") != nil {
+		job.TODOs = append(job.TODOs, "matrix excludes not translated; add them under strategy.matrix.exclude")
+	}
+	if agent := node.child("agent"); agent != nil {
+		job.RunsOn, job.Image = jp.agent(agent, &job.TODOs)
+	}
+}
+
+// post translates post conditions to steps that run on success, failure or always
+func (jp *jenkinsParser) post(node *jenkinsNode) []PipelineStep {
+	when := map[string]string{"always": "always", "cleanup": "always", "success": "", "failure": "failure", "unsuccessful": "failure", "unstable": "failure"}
+	var steps []PipelineStep
+	for _, condition := range node.Children {
+		w, ok := when[condition.Name]
+		if !ok {
+			steps = append(steps, PipelineStep{Kind: StepTODO, Name: "post " + condition.Name, Source: condition.Source})
+			continue
+		}
+		for _, step := range jp.steps(condition.Children, nil, PipelineStep{}) {
+			if step.Kind != StepTODO {
+				step.When = w
+			}
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+// steps translates the steps of a block; defaults carries the working directory,
+// environment and error handling of enclosing dir, withEnv and catchError blocks
+func (jp *jenkinsParser) steps(nodes []*jenkinsNode, job *PipelineJob, defaults PipelineStep) []PipelineStep {
+	var steps []PipelineStep
+	add := func(step PipelineStep) {
+		step.WorkingDirectory = defaults.WorkingDirectory
+		step.Env = append(append([]PipelineVar(nil), defaults.Env...), step.Env...)
+		step.ContinueOnError = step.ContinueOnError || defaults.ContinueOnError
+		steps = append(steps, step)
+	}
+	todo := func(node *jenkinsNode, name string) {
+		steps = append(steps, PipelineStep{Kind: StepTODO, Name: name, Source: node.Source})
+	}
+
+	for _, node := range nodes {
+		positional, named := groovyArgs(node.Args)
+		arg := named["script"]
+		if arg == "" && len(positional) > 0 {
+			arg = positional[0]
+		}
+		switch node.Name {
+		case "sh":
+			add(PipelineStep{Kind: StepRun, Name: named["label"], Run: strings.TrimRight(arg, "\n") + "\n"})
+		case "echo":
+			add(PipelineStep{Kind: StepRun, Run: "echo " + shellQuote(arg) + "\n"})
+		case "error":
+			add(PipelineStep{Kind: StepRun, Run: "echo " + shellQuote(arg) + "\nexit 1\n"})
+		case "sleep":
+			add(PipelineStep{Kind: StepRun, Run: "sleep " + strconv.Itoa(max(jenkinsTimeout(node.Args)*60, 1)) + "\n"})
+		case "writeFile":
+			add(PipelineStep{Kind: StepRun, Run: "cat > " + shellQuote(named["file"]) + " <<'EOF'\n" + named["text"] + "\nEOF\n"})
+		case "checkout":
+			if arg == "scm" {
+				add(PipelineStep{Kind: StepCheckout})
+			} else {
+				todo(node, "checkout")
+			}
+		case "archiveArtifacts":
+			paths := splitPatterns(firstNonEmpty(named["artifacts"], arg))
+			add(PipelineStep{Kind: StepUpload, Name: "Archive artifacts", Artifact: jobArtifactName(job, "artifacts"), Paths: paths})
+		case "junit":
+			paths := splitPatterns(firstNonEmpty(named["testResults"], arg))
+			add(PipelineStep{Kind: StepUpload, Name: "Upload test results", Artifact: jobArtifactName(job, "test-results"), Paths: paths, When: "always",
+				Comment: "GitHub does not render JUnit results; add a reporter action to show them"})
+		case "stash":
+			name := firstNonEmpty(named["name"], arg)
+			jp.stashes[name] = splitPatterns(firstNonEmpty(named["includes"], "**"))
+			add(PipelineStep{Kind: StepUpload, Name: "Stash " + name, Artifact: "stash-" + name, Paths: jp.stashes[name]})
+		case "unstash":
+			path := artifactDownloadPath(".", jp.stashes[arg])
+			add(PipelineStep{Kind: StepDownload, Name: "Unstash " + arg, Artifact: "stash-" + arg, Paths: []string{path}})
+		case "dir":
+			inner := defaults
+			inner.WorkingDirectory = strings.TrimPrefix(strings.TrimPrefix(defaults.WorkingDirectory+"/"+arg, "/"), "./")
+			steps = append(steps, jp.steps(node.Children, job, inner)...)
+		case "withEnv":
+			inner := defaults
+			inner.Env = append([]PipelineVar(nil), defaults.Env...)
+			for _, item := range groovyList(node.Args) {
+				inner.Env = append(inner.Env, asVars(item)...)
+			}
+			steps = append(steps, jp.steps(node.Children, job, inner)...)
+		case "withCredentials":
+			inner := defaults
+			inner.Env = append([]PipelineVar(nil), defaults.Env...)
+			ok := true
+			for _, binding := range jenkinsCredentialBindings(node.Args) {
+				if binding.Value == "" {
+					ok = false
+					continue
+				}
+				jp.secrets = appendUnique(jp.secrets, strings.TrimSuffix(strings.TrimPrefix(binding.Value, "${{ secrets."), " }}"))
+				inner.Env = append(inner.Env, binding)
+			}
+			if !ok {
+				todo(node, "credential bindings other than string and usernamePassword")
+				continue
+			}
+			steps = append(steps, jp.steps(node.Children, job, inner)...)
+		case "catchError", "warnError":
+			inner := defaults
+			inner.ContinueOnError = true
+			steps = append(steps, jp.steps(node.Children, job, inner)...)
+		case "timeout", "retry", "ansiColor", "timestamps", "wrap", "lock":
+			if node.Name == "timeout" && job != nil && job.TimeoutMinutes == 0 {
+				job.TimeoutMinutes = jenkinsTimeout(node.Args)
+			}
+			if node.Name == "retry" || node.Name == "lock" {
+				steps = append(steps, PipelineStep{Kind: StepTODO, Name: node.Name + " not translated", Source: firstLine(node.Source)})
+			}
+			steps = append(steps, jp.steps(node.Children, job, defaults)...)
+		case "cleanWs", "deleteDir":
+			// Every job starts in a fresh workspace
+		case "input":
+			if job != nil {
+				job.Approval = true
+			}
+		case "script":
+			todo(node, "script block")
+		default:
+			todo(node, firstNonEmpty(node.Name, "statement"))
+		}
+	}
+	return steps
+}
+
+// jenkinsCredentialBindings maps withCredentials bindings to secret variables;
+// bindings with no secret equivalent have an empty value
+func jenkinsCredentialBindings(args string) []PipelineVar {
+	var vars []PipelineVar
+	for _, binding := range groovyList(args) {
+		kind := binding
+		if i := strings.Index(binding, "("); i >= 0 {
+			kind = strings.TrimSpace(binding[:i])
+			binding = binding[i:]
+		}
+		_, named := groovyArgs(binding)
+		secret := jenkinsSecretName(named["credentialsId"])
+		switch kind {
+		case "string":
+			vars = append(vars, PipelineVar{Name: named["variable"], Value: "${{ secrets." + secret + " }}"})
+		case "usernamePassword":
+			vars = append(vars,
+				PipelineVar{Name: named["usernameVariable"], Value: "${{ secrets." + secret + "_USERNAME }}"},
+				PipelineVar{Name: named["passwordVariable"], Value: "${{ secrets." + secret + "_PASSWORD }}"})
+		default:
+			vars = append(vars, PipelineVar{Name: kind})
+		}
+	}
+	return vars
+}
+
+// groovyList returns the elements of the list literal in a call's arguments, e.g. withEnv(['A=1'])
+func groovyList(args string) []string {
+	args = strings.TrimSpace(args)
+	args = strings.TrimSuffix(strings.TrimPrefix(args, "("), ")")
+	args = strings.TrimSpace(args)
+	if !strings.HasPrefix(args, "[") || !strings.HasSuffix(args, "]") {
+		return nil
+	}
+	elements, _ := groovyArgs(args[1 : len(args)-1])
+	return elements
+}
+
+// jobArtifactName names an artifact after the job uploading it
+func jobArtifactName(job *PipelineJob, name string) string {
+	if job == nil {
+		return name
+	}
+	return job.ID + "-" + name
+}
+
+// splitPatterns splits Jenkins' comma-separated Ant patterns
+func splitPatterns(patterns string) []string {
+	var paths []string
+	for _, pattern := range strings.Split(patterns, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			paths = append(paths, pattern)
+		}
+	}
+	return paths
+}
+
+// jenkinsWhen translates a stage's when block. Conditions are ANDed, so only a
+// single condition (or an anyOf of them) maps to a job condition.
+func jenkinsWhen(node *jenkinsNode) *PipelineCondition {
+	var conditions []*jenkinsNode
+	for _, c := range node.Children {
+		if c.Name != "beforeAgent" && c.Name != "beforeInput" && c.Name != "beforeOptions" {
+			conditions = append(conditions, c)
+		}
+	}
+	cond := &PipelineCondition{}
+	if len(conditions) == 1 && conditions[0].Name == "anyOf" {
+		conditions = conditions[0].Children
+	} else if len(conditions) > 1 {
+		cond.Expr = "when (all of): " + strings.Join(nodeSources(conditions), "; ")
+		return cond
+	}
+	var untranslated []string
+	for _, c := range conditions {
+		positional, named := groovyArgs(c.Args)
+		pattern := firstNonEmpty(named["pattern"], first(positional))
+		if named["comparator"] == "REGEXP" {
+			untranslated = append(untranslated, firstLine(c.Source))
+			continue
+		}
+		switch c.Name {
+		case "branch":
+			cond.Branches = append(cond.Branches, pattern)
+		case "buildingTag":
+			cond.Tags = append(cond.Tags, "*")
+		case "tag":
+			cond.Tags = append(cond.Tags, firstNonEmpty(pattern, "*"))
+		case "changeRequest":
+			cond.Events = append(cond.Events, "pull_request")
+		case "triggeredBy":
+			switch {
+			case strings.Contains(pattern, "Timer"):
+				cond.Events = append(cond.Events, "schedule")
+			case strings.Contains(pattern, "UserId"):
+				cond.Events = append(cond.Events, "manual")
+			default:
+				untranslated = append(untranslated, firstLine(c.Source))
+			}
+		default:
+			untranslated = append(untranslated, strings.Join(strings.Fields(c.Source), " "))
+		}
+	}
+	cond.Expr = strings.Join(untranslated, " || ")
+	if conditionEmpty(*cond) {
+		return nil
+	}
+	return cond
+}
+
+func nodeSources(nodes []*jenkinsNode) []string {
+	sources := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		sources = append(sources, strings.Join(strings.Fields(n.Source), " "))
+	}
+	return sources
+}
+
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	return line
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// shellQuote quotes a message for echo; messages with variables keep them expanding
+func shellQuote(text string) string {
+	if strings.Contains(text, "$") {
+		return `"` + strings.NewReplacer(`"`, `\"`, "`", "\\`").Replace(text) + `"`
+	}
+	return shellSingleQuote(text)
+}
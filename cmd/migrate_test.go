@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "Rewrite the golden files in testdata")
+
+// checkGolden compares got with the golden file at path, or rewrites it with -update
+func checkGolden(t *testing.T, path, got string) {
+	t.Helper()
+	if *update {
+		writeTestFile(t, path, got)
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test ./cmd -update to create it)", err)
+	}
+	if got != string(want) {
+		t.Errorf("output differs from %s (run go test ./cmd -update to accept it):\n%s", path, got)
+	}
+}
+
+// TestMigrate translates each testdata/migrate/<source>/<case>.in and compares the
+// GitHub Actions workflow with <case>.yml next to it
+func TestMigrate(t *testing.T) {
+	parsers := map[string]func(string) (Pipeline, error){
+		"gitlab":   ParseGitLabCI,
+		"circleci": ParseCircleCI,
+		"travis":   ParseTravisCI,
+		"jenkins":  ParseJenkinsfile,
+	}
+	for source, parse := range parsers {
+		inputs, err := filepath.Glob(filepath.Join("testdata", "migrate", source, "*.in"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(inputs) == 0 {
+			t.Errorf("no fixtures for %s", source)
+		}
+		for _, input := range inputs {
+			name := source + "/" + strings.TrimSuffix(filepath.Base(input), ".in")
+			t.Run(name, func(t *testing.T) {
+				config, err := os.ReadFile(input)
+				if err != nil {
+					t.Fatal(err)
+				}
+				p, err := parse(string(config))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if problems := LintPipeline(p); len(problems) > 0 {
+					t.Errorf("pipeline problems: %v", problems)
+				}
+				workflow, err := RenderGitHubActions(p)
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkGolden(t, strings.TrimSuffix(input, ".in")+".yml", workflow)
+			})
+		}
+	}
+}
+
+func TestSpliceMigrationTranslations(t *testing.T) {
+	workflow, err := RenderGitHubActions(Pipeline{
+		Name:     "CI",
+		Triggers: PipelineTriggers{PushBranches: []string{"main"}},
+		Jobs: []PipelineJob{{
+			ID:   "build",
+			Name: "Build",
+			Steps: []PipelineStep{
+				{Kind: StepCheckout},
+				{Kind: StepTODO, Name: "Publish", Source: "publish()"},
+				{Kind: StepRun, Name: "Test", Run: "make test\n"},
+				{Kind: StepTODO, Name: "Notify", Source: "notify()"},
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	todos, err := migrationTODOs(workflow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, todo := range todos {
+		ids = append(ids, todo.ID)
+	}
+	if want := []string{"build.1", "build.3"}; !slices.Equal(ids, want) {
+		t.Fatalf("ids = %v, want %v", ids, want)
+	}
+
+	got, count, err := spliceMigrationTranslations(workflow, todos, []MigrateTranslation{
+		{ID: "build.1", Steps: "- name: Publish\n  run: make publish\n- name: Tag\n  run: make tag\n"},
+		{ID: "build.3", Steps: ""},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("count = %d, want 1", count)
+	}
+	if strings.Contains(got, "publish()") || !strings.Contains(got, "notify()") {
+		t.Errorf("only the translated TODO should be replaced:\n%s", got)
+	}
+	if !strings.Contains(got, "# TODO(fluxion): translated by AI, review\n      - name: Publish\n        run: make publish\n      - name: Tag\n") {
+		t.Errorf("translation not spliced in place:\n%s", got)
+	}
+	// Every line outside the translated step is kept
+	for _, line := range splitLines(workflow) {
+		if !strings.Contains(line, "Publish") && !strings.Contains(line, "publish()") && !strings.Contains(got, line) {
+			t.Errorf("line %q was lost", line)
+		}
+	}
+
+	if _, _, err := spliceMigrationTranslations(workflow, todos, []MigrateTranslation{{ID: "build.0", Steps: "- run: true\n"}}); err == nil {
+		t.Error("a translation of a step that is not a TODO should be rejected")
+	}
+}
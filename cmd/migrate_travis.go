@@ -0,0 +1,532 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// travisVariables maps Travis CI's default variables to GitHub Actions
+var travisVariables = ciVariables{
+	renames: map[string]string{
+		"TRAVIS_COMMIT":       "GITHUB_SHA",
+		"TRAVIS_BUILD_DIR":    "GITHUB_WORKSPACE",
+		"TRAVIS_BUILD_NUMBER": "GITHUB_RUN_NUMBER",
+		"TRAVIS_BUILD_ID":     "GITHUB_RUN_ID",
+		"TRAVIS_REPO_SLUG":    "GITHUB_REPOSITORY",
+	},
+	compat: map[string]string{
+		"TRAVIS_BRANCH":              "${{ github.base_ref || github.ref_name }}",
+		"TRAVIS_TAG":                 "${{ github.ref_type == 'tag' && github.ref_name || '' }}",
+		"TRAVIS_PULL_REQUEST":        "${{ github.event.pull_request.number || 'false' }}",
+		"TRAVIS_PULL_REQUEST_BRANCH": "${{ github.head_ref }}",
+		"TRAVIS_EVENT_TYPE":          "${{ github.event_name == 'schedule' && 'cron' || github.event_name == 'pull_request' && 'pull_request' || 'push' }}",
+		"TRAVIS_COMMIT_MESSAGE":      "${{ github.event.head_commit.message }}",
+	},
+	platform: func(name string) bool {
+		return strings.HasPrefix(name, "TRAVIS_")
+	},
+}
+
+// travisLanguages are the runtime and version key of each language Fluxion can set up
+var travisLanguages = map[string]struct{ runtime, versionKey string }{
+	"go":      {"go", "go"},
+	"node_js": {"node", "node_js"},
+	"python":  {"python", "python"},
+	"ruby":    {"ruby", "rvm"},
+	"java":    {"java", "jdk"},
+}
+
+// travisDefaults are the install and script phases Travis runs when a build does not set them
+var travisDefaults = map[string]struct{ install, script string }{
+	"go":      {"", "go test -v ./..."},
+	"node_js": {"npm ci", "npm test"},
+	"python":  {"if [ -f requirements.txt ]; then pip install -r requirements.txt; fi", ""},
+	"ruby":    {"bundle install --jobs=3 --retry=3", "bundle exec rake"},
+}
+
+// travisCaches are the cache: shortcuts and the setup input that caches the same thing
+var travisCaches = map[string]struct{ input, value string }{
+	"npm":     {"cache", "npm"},
+	"yarn":    {"cache", "yarn"},
+	"pip":     {"cache", "pip"},
+	"bundler": {"bundler-cache", "true"},
+}
+
+// travisRunners maps os: values to runner labels
+var travisRunners = map[string]string{"linux": "ubuntu-latest", "osx": "macos-latest", "windows": "windows-latest"}
+
+// travisServices maps services: to service containers; Travis databases accept passwordless local connections
+var travisServices = map[string]struct {
+	image string
+	env   []PipelineVar
+}{
+	"postgresql":   {"postgres:16", []PipelineVar{{Name: "POSTGRES_HOST_AUTH_METHOD", Value: "trust"}}},
+	"mysql":        {"mysql:8.0", []PipelineVar{{Name: "MYSQL_ALLOW_EMPTY_PASSWORD", Value: "yes"}}},
+	"redis":        {"redis:7", nil},
+	"redis-server": {"redis:7", nil},
+	"mongodb":      {"mongo:7", nil},
+	"rabbitmq":     {"rabbitmq:3-management", nil},
+	"memcached":    {"memcached:1.6", nil},
+}
+
+// travisPhases are the build phases, in order, with when each runs
+var travisPhases = []struct{ name, when string }{
+	{"before_install", ""}, {"install", ""}, {"before_script", ""}, {"script", ""},
+	{"after_success", ""}, {"after_failure", "failure"}, {"after_script", "always"},
+}
+
+// ParseTravisCI translates a .travis.yml to the pipeline model. The build
+// matrix (language versions, os and env) becomes a job matrix; jobs.include
+// entries become jobs, ordered by their build stage.
+func ParseTravisCI(config string) (Pipeline, error) {
+	values, _, err := parseSourceYAML(config)
+	if err != nil {
+		return Pipeline{}, err
+	}
+	p := Pipeline{
+		Name:     "CI",
+		Triggers: PipelineTriggers{PushBranches: []string{"*"}, Tags: []string{"*"}, PullRequests: true, Manual: true},
+	}
+	if branches := asMap(values["branches"]); branches != nil {
+		travisBranches(&p, branches)
+	}
+	env := values["env"]
+	if m := asMap(env); m != nil {
+		p.Env = asVars(m["global"])
+		env = m["jobs"]
+		if env == nil {
+			env = m["matrix"]
+		}
+	}
+	if values["notifications"] != nil {
+		p.TODOs = append(p.TODOs, "notifications not translated; add a notification step or use GitHub's own notifications")
+	}
+
+	jobs := asMap(values["jobs"])
+	if jobs == nil {
+		jobs = asMap(values["matrix"])
+	}
+	base := map[string]any{}
+	for key, value := range values {
+		if key != "jobs" && key != "matrix" && key != "env" && key != "stages" {
+			base[key] = value
+		}
+	}
+
+	// Stage order: as declared, then in order of appearance
+	var stages []string
+	stageConditions := map[string]string{}
+	for _, stage := range asList(values["stages"]) {
+		name := asString(stage)
+		if m := asMap(stage); m != nil {
+			name = asString(m["name"])
+			stageConditions[strings.ToLower(name)] = asString(m["if"])
+		}
+		stages = append(stages, strings.ToLower(name))
+	}
+
+	type stagedJob struct {
+		stage string
+		job   PipelineJob
+	}
+	var staged []stagedJob
+	include := asList(jobs["include"])
+	if len(include) == 0 || travisExpands(values) {
+		job := travisJob("test", base, asStrings(env), true)
+		staged = append(staged, stagedJob{stage: "test", job: job})
+		if jobs["exclude"] != nil || jobs["allow_failures"] != nil {
+			job := &staged[len(staged)-1].job
+			job.TODOs = append(job.TODOs, "matrix exclude/allow_failures not translated; use strategy.matrix.exclude and continue-on-error with a matrix expression")
+		}
+	}
+	stage := "test"
+	for i, entry := range include {
+		m := asMap(entry)
+		if s := asString(m["stage"]); s != "" {
+			stage = strings.ToLower(s)
+		}
+		name := asString(m["name"])
+		if name == "" {
+			name = fmt.Sprintf("%s-%d", stage, i+1)
+		}
+		def := deepMerge(base, m)
+		job := travisJob(name, def, asStrings(m["env"]), false)
+		for _, other := range staged {
+			if other.job.ID == job.ID {
+				job.ID = jobID(fmt.Sprintf("%s-%d", name, i+1))
+			}
+		}
+		travisCondition(&job, asString(m["if"]))
+		if cond := stageConditions[stage]; cond != "" && job.If == nil {
+			travisCondition(&job, cond)
+		} else if cond != "" {
+			job.If.Expr = strings.TrimPrefix(job.If.Expr+"; ", "; ") + "stage " + stage + " if: " + cond
+		}
+		staged = append(staged, stagedJob{stage: stage, job: job})
+		if !slices.Contains(stages, stage) {
+			stages = append(stages, stage)
+		}
+	}
+	if !slices.Contains(stages, "test") {
+		stages = append([]string{"test"}, stages...)
+	}
+
+	// Each stage waits for the previous one
+	var previous []string
+	for _, stage := range stages {
+		var current []string
+		for i := range staged {
+			if staged[i].stage == stage {
+				staged[i].job.Needs = previous
+				current = append(current, staged[i].job.ID)
+				p.Jobs = append(p.Jobs, staged[i].job)
+			}
+		}
+		if len(current) > 0 {
+			previous = current
+		}
+	}
+	for _, job := range p.Jobs {
+		if job.If != nil && slices.Contains(job.If.Events, "schedule") {
+			p.TODOs = appendUnique(p.TODOs, "Travis cron jobs are set in the repository settings: add their schedule under on.schedule")
+		}
+	}
+
+	// The env matrix sets its variables at run time, so they are not secrets
+	matrixVars := map[string]bool{}
+	for _, line := range asStrings(env) {
+		for _, assignment := range strings.Fields(line) {
+			if name, _, ok := strings.Cut(assignment, "="); ok {
+				matrixVars[name] = true
+			}
+		}
+	}
+	translateVars(&p, travisVariables)
+	inferSecrets(&p, func(name string) bool { return matrixVars[name] || travisVariables.platform(name) })
+	return p, nil
+}
+
+// travisExpands reports whether the top-level keys define a build matrix, which
+// runs in the first stage alongside jobs.include
+func travisExpands(values map[string]any) bool {
+	for _, key := range []string{"go", "node_js", "python", "rvm", "jdk", "php", "os", "arch"} {
+		if len(asList(values[key])) > 1 {
+			return true
+		}
+	}
+	env := values["env"]
+	if m := asMap(env); m != nil {
+		env = m["jobs"]
+	}
+	return len(asList(env)) > 1
+}
+
+// travisBranches restricts the push trigger to branches.only
+func travisBranches(p *Pipeline, branches map[string]any) {
+	if only := asStrings(branches["only"]); len(only) > 0 {
+		p.Triggers.PushBranches = nil
+		for _, branch := range only {
+			if glob, ok := circleciFilterGlob(branch); ok {
+				p.Triggers.PushBranches = append(p.Triggers.PushBranches, glob)
+			} else {
+				p.TODOs = append(p.TODOs, "branches.only "+branch+" not translated")
+			}
+		}
+		if len(p.Triggers.PushBranches) == 0 {
+			p.Triggers.PushBranches = []string{"*"}
+		}
+	}
+	if except := asStrings(branches["except"]); len(except) > 0 {
+		p.TODOs = append(p.TODOs, "branches.except ("+strings.Join(except, ", ")+") not translated; use on.push.branches-ignore")
+	}
+}
+
+// travisJob builds one job from the merged build configuration; expand turns lists of
+// versions, os and env into a matrix, as Travis does for the top-level build
+func travisJob(name string, def map[string]any, envs []string, expand bool) PipelineJob {
+	job := PipelineJob{ID: jobID(name), Name: name}
+	language := asString(def["language"])
+
+	// Runner
+	osNames := asStrings(def["os"])
+	if len(osNames) == 0 {
+		osNames = []string{"linux"}
+	}
+	osName := osNames[0]
+	if expand && len(osNames) > 1 {
+		job.Matrix = append(job.Matrix, PipelineAxis{Name: "os", Values: osNames})
+		job.RunsOn = []string{`${{ fromJSON('{"linux":"ubuntu-latest","osx":"macos-latest","windows":"windows-latest"}')[matrix.os] }}`}
+		osName = "${{ matrix.os }}"
+	} else if runner, ok := travisRunners[osName]; ok && osName != "linux" {
+		job.RunsOn = []string{runner}
+	}
+	if arch := asString(def["arch"]); arch == "arm64" && osName == "linux" {
+		job.RunsOn = []string{"ubuntu-24.04-arm"}
+	} else if arch != "" && arch != "amd64" {
+		job.TODOs = append(job.TODOs, "arch: "+arch+" has no GitHub-hosted runner")
+	}
+
+	// Checkout
+	checkout := PipelineStep{Kind: StepCheckout}
+	if git := asMap(def["git"]); git != nil {
+		if depth := asString(git["depth"]); depth != "" && depth != "false" {
+			checkout.With = append(checkout.With, PipelineVar{Name: "fetch-depth", Value: depth})
+		} else if depth == "false" {
+			checkout.With = append(checkout.With, PipelineVar{Name: "fetch-depth", Value: "0"})
+		}
+		if asBool(git["submodules"]) {
+			checkout.With = append(checkout.With, PipelineVar{Name: "submodules", Value: "recursive"})
+		}
+	}
+	job.Steps = append(job.Steps, checkout)
+
+	// Language runtime, one job per version
+	if lang, ok := travisLanguages[language]; ok {
+		setup := PipelineStep{Kind: StepSetup, Runtime: lang.runtime}
+		versions := asStrings(def[lang.versionKey])
+		if lang.runtime == "java" {
+			for i, v := range versions {
+				versions[i] = versionPattern.FindString(strings.TrimLeft(v, "abcdefghijklmnopqrstuvwxyz"))
+			}
+		}
+		switch {
+		case expand && len(versions) > 1:
+			job.Matrix = append(job.Matrix, PipelineAxis{Name: lang.versionKey, Values: versions})
+			setup.Version = "${{ matrix." + lang.versionKey + " }}"
+		case len(versions) > 0:
+			setup.Version = versions[0]
+		}
+		for _, shortcut := range travisCacheShortcuts(def["cache"]) {
+			if c, ok := travisCaches[shortcut]; ok {
+				setup.With = append(setup.With, PipelineVar{Name: c.input, Value: c.value})
+			}
+		}
+		job.Steps = append(job.Steps, setup)
+	} else if language != "" && language != "generic" && language != "minimal" && language != "shell" && language != "bash" &&
+		language != "c" && language != "cpp" && language != "rust" {
+		job.Steps = append(job.Steps, PipelineStep{Kind: StepTODO, Name: "set up " + language, Source: sourceYAML("language", language)})
+	}
+
+	// Environment: one job per env line
+	switch {
+	case expand && len(envs) > 1:
+		job.Matrix = append(job.Matrix, PipelineAxis{Name: "env", Values: envs})
+		job.Steps = append(job.Steps, PipelineStep{Kind: StepRun, Name: "Set matrix environment",
+			Run: "for assignment in ${{ matrix.env }}; do echo \"$assignment\" >> \"$GITHUB_ENV\"; done\n"})
+	case len(envs) > 0:
+		job.Env = asVars(strings.Fields(envs[0]))
+	}
+
+	if cache := asMap(def["cache"]); cache != nil || len(asList(def["cache"])) > 0 {
+		if dirs := asStrings(cache["directories"]); len(dirs) > 0 {
+			// Travis keeps one cache per branch and updates it after every build
+			job.Steps = append(job.Steps, PipelineStep{Kind: StepCache, Name: "Cache " + strings.Join(dirs, ", "), Paths: dirs,
+				Key: "${{ runner.os }}-cache-${{ github.ref_name }}-${{ github.run_id }}",
+				With: []PipelineVar{{Name: "path", Value: strings.Join(dirs, "\n")},
+					{Name: "key", Value: "${{ runner.os }}-cache-${{ github.ref_name }}-${{ github.run_id }}"},
+					{Name: "restore-keys", Value: "${{ runner.os }}-cache-${{ github.ref_name }}-\n${{ runner.os }}-cache-"}}})
+		}
+	}
+
+	services := asStrings(def["services"])
+	if version := asString(asMap(def["addons"])["postgresql"]); version != "" && !slices.Contains(services, "postgresql") {
+		services = append(services, "postgresql")
+	}
+	for _, name := range services {
+		svc, ok := travisServices[name]
+		if name == "docker" || name == "xvfb" {
+			continue // Available on the runner
+		}
+		if !ok {
+			job.TODOs = append(job.TODOs, "service "+name+" not translated; add it as a service container")
+			continue
+		}
+		image := svc.image
+		if version := asString(asMap(def["addons"])["postgresql"]); name == "postgresql" && version != "" {
+			image = "postgres:" + version
+		}
+		service := serviceFromImage(image, strings.TrimSuffix(name, "-server"), nil)
+		for _, v := range svc.env {
+			service.Env = setVar(service.Env, v.Name, v.Value)
+		}
+		if service.Name == "memcached" {
+			service.Ports = []string{"11211:11211"}
+		}
+		job.Services = append(job.Services, service)
+	}
+	job.Steps = append(job.Steps, travisAddons(asMap(def["addons"]))...)
+
+	// Build phases
+	defaults := travisDefaults[language]
+	for _, phase := range travisPhases {
+		commands := asStrings(def[phase.name])
+		comment := ""
+		if def[phase.name] == nil {
+			switch phase.name {
+			case "install":
+				commands = nonEmpty(defaults.install)
+			case "script":
+				commands = nonEmpty(defaults.script)
+			}
+			comment = "Travis CI's default " + phase.name + " for " + language
+		}
+		if len(commands) == 0 || len(commands) == 1 && commands[0] == "skip" {
+			continue
+		}
+		step := PipelineStep{Kind: StepRun, Name: phase.name, Run: strings.Join(commands, "\n") + "\n", When: phase.when, Comment: comment}
+		job.Steps = append(job.Steps, step)
+	}
+	if deploy := def["deploy"]; deploy != nil {
+		source := sourceYAML("deploy", deploy)
+		for _, phase := range []string{"before_deploy", "after_deploy"} {
+			if def[phase] != nil {
+				source += "\n" + sourceYAML(phase, def[phase])
+			}
+		}
+		providers := []string{}
+		for _, d := range asList(deploy) {
+			providers = append(providers, asString(asMap(d)["provider"]))
+		}
+		job.Steps = append(job.Steps, PipelineStep{Kind: StepTODO, Name: "deploy with " + strings.Join(providers, ", "), Source: source})
+	}
+
+	for _, step := range job.Steps {
+		if strings.Contains(step.Run, "TRAVIS_OS_NAME") {
+			job.Env = append(job.Env, PipelineVar{Name: "TRAVIS_OS_NAME", Value: osName})
+			break
+		}
+	}
+	return job
+}
+
+// travisCacheShortcuts lists the cache: shortcuts such as npm: true or - pip
+func travisCacheShortcuts(cache any) []string {
+	var shortcuts []string
+	if m := asMap(cache); m != nil {
+		for _, key := range sortedAnyKeys(m) {
+			if asBool(m[key]) {
+				shortcuts = append(shortcuts, key)
+			}
+		}
+		return shortcuts
+	}
+	return asStrings(cache)
+}
+
+// travisAddons installs apt, Homebrew and snap packages; other addons are left as TODOs
+func travisAddons(addons map[string]any) []PipelineStep {
+	var steps []PipelineStep
+	for _, name := range sortedAnyKeys(addons) {
+		value := addons[name]
+		switch name {
+		case "apt":
+			packages := asStrings(value)
+			if m := asMap(value); m != nil {
+				packages = asStrings(m["packages"])
+				if m["sources"] != nil {
+					steps = append(steps, PipelineStep{Kind: StepTODO, Name: "add apt sources", Source: sourceYAML("sources", m["sources"])})
+				}
+			}
+			if len(packages) > 0 {
+				steps = append(steps, PipelineStep{Kind: StepRun, Name: "Install apt packages",
+					Run: "sudo apt-get update\nsudo apt-get install -y " + strings.Join(packages, " ") + "\n"})
+			}
+		case "homebrew":
+			if packages := asStrings(asMap(value)["packages"]); len(packages) > 0 {
+				steps = append(steps, PipelineStep{Kind: StepRun, Name: "Install Homebrew packages", Run: "brew install " + strings.Join(packages, " ") + "\n"})
+			}
+		case "snaps":
+			for _, snap := range asList(value) {
+				snapName := asString(snap)
+				if m := asMap(snap); m != nil {
+					snapName = asString(m["name"])
+					if asBool(m["classic"]) {
+						snapName += " --classic"
+					}
+				}
+				steps = append(steps, PipelineStep{Kind: StepRun, Name: "Install " + strings.Fields(snapName)[0], Run: "sudo snap install " + snapName + "\n"})
+			}
+		case "postgresql":
+			// Handled with the services
+		default:
+			steps = append(steps, PipelineStep{Kind: StepTODO, Name: "addon " + name, Source: sourceYAML(name, value)})
+		}
+	}
+	return steps
+}
+
+func nonEmpty(command string) []string {
+	if command == "" {
+		return nil
+	}
+	return []string{command}
+}
+
+var (
+	travisEquals  = regexp.MustCompile(`^(branch|tag|type)\s*(=|==|=~)\s*(.+)$`)
+	travisIn      = regexp.MustCompile(`^(branch|type)\s+IN\s+\((.+)\)$`)
+	travisPresent = regexp.MustCompile(`^tag\s+IS\s+present$`)
+	travisOr      = regexp.MustCompile(`(?i)\s+OR\s+`)
+)
+
+// travisEvents maps Travis event types to pipeline events
+var travisEvents = map[string]string{"push": "push", "pull_request": "pull_request", "cron": "schedule", "api": "manual"}
+
+// travisCondition translates a job or stage if: to a job condition; only OR-joined
+// comparisons of branch, tag and type are understood
+func travisCondition(job *PipelineJob, expr string) {
+	if expr == "" {
+		return
+	}
+	if job.If == nil {
+		job.If = &PipelineCondition{}
+	}
+	cond := job.If
+	if strings.Contains(strings.ToUpper(expr), " AND ") || strings.Contains(strings.ToUpper(expr), "NOT ") {
+		cond.Expr = strings.TrimPrefix(cond.Expr+" && "+expr, " && ")
+		return
+	}
+	for _, clause := range travisOr.Split(expr, -1) {
+		clause = strings.TrimSpace(clause)
+		var values []string
+		var field, op string
+		if travisPresent.MatchString(clause) {
+			cond.Tags = appendUnique(cond.Tags, "*")
+			continue
+		} else if m := travisIn.FindStringSubmatch(clause); m != nil {
+			field, op = m[1], "="
+			for _, v := range strings.Split(m[2], ",") {
+				values = append(values, strings.Trim(strings.TrimSpace(v), `"'`))
+			}
+		} else if m := travisEquals.FindStringSubmatch(clause); m != nil {
+			field, op, values = m[1], m[2], []string{strings.Trim(m[3], `"'`)}
+		} else {
+			cond.Expr = strings.TrimPrefix(cond.Expr+" || "+clause, " || ")
+			continue
+		}
+		for _, value := range values {
+			if op == "=~" {
+				glob, ok := globFromRegexp("/" + value + "/")
+				if !ok {
+					cond.Expr = strings.TrimPrefix(cond.Expr+" || "+clause, " || ")
+					continue
+				}
+				value = glob
+			}
+			switch field {
+			case "branch":
+				cond.Branches = appendUnique(cond.Branches, value)
+			case "tag":
+				cond.Tags = appendUnique(cond.Tags, value)
+			case "type":
+				if event, ok := travisEvents[value]; ok {
+					cond.Events = appendUnique(cond.Events, event)
+				} else {
+					cond.Expr = strings.TrimPrefix(cond.Expr+" || "+clause, " || ")
+				}
+			}
+		}
+	}
+}
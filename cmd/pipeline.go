@@ -0,0 +1,402 @@
+package cmd
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Pipeline is Fluxion's platform-neutral model of a CI/CD pipeline.
+// Source configs are parsed into it and emitters render it for a platform.
+//
+// Scripts use GitHub Actions' variable names for CI metadata (GITHUB_SHA,
+// GITHUB_REF_NAME, GITHUB_WORKSPACE, ...); parsers rewrite their platform's
+// predefined variables to these.
+type Pipeline struct {
+	Name     string           `json:"name"`
+	Triggers PipelineTriggers `json:"triggers"`
+	Env      []PipelineVar    `json:"env"`     // Variables for every job
+	Secrets  []string         `json:"secrets"` // Secret names the jobs read
+	Jobs     []PipelineJob    `json:"jobs"`
+	TODOs    []string         `json:"todos"` // Pipeline-level constructs that could not be translated
+}
+
+// PipelineTriggers are the events that start the pipeline
+type PipelineTriggers struct {
+	PushBranches []string `json:"push_branches"` // Branch patterns that trigger on push; "*" for all
+	Tags         []string `json:"tags"`          // Tag patterns that trigger on push; "*" for all
+	PullRequests bool     `json:"pull_requests"` // Run for pull/merge requests
+	Schedules    []string `json:"schedules"`     // Cron expressions (UTC)
+	Manual       bool     `json:"manual"`        // Can be started by hand
+}
+
+// PipelineJob is a unit of work that runs on one runner
+type PipelineJob struct {
+	ID               string             `json:"id"`
+	Name             string             `json:"name"`
+	RunsOn           []string           `json:"runs_on"`           // Runner labels; empty for the platform's default Linux runner
+	Image            string             `json:"image"`             // Container image the steps run in; empty to run on the runner
+	Needs            []string           `json:"needs"`             // IDs of jobs that must finish first
+	If               *PipelineCondition `json:"if"`                // When the job runs; nil for always
+	When             string             `json:"when"`              // After the jobs it needs: "" when all succeeded, "success" when none failed or was cancelled, "failure" when one failed, "always"
+	Matrix           []PipelineAxis     `json:"matrix"`            // Runs the job once per combination
+	Env              []PipelineVar      `json:"env"`               // Variables for the job's steps
+	Services         []PipelineService  `json:"services"`          // Backing service containers
+	Steps            []PipelineStep     `json:"steps"`             // Commands, in order
+	Environment      string             `json:"environment"`       // Deployment environment
+	Approval         bool               `json:"approval"`          // Waits for a manual approval before running
+	AllowFailure     bool               `json:"allow_failure"`     // A failure does not fail the pipeline
+	TimeoutMinutes   int                `json:"timeout_minutes"`   // 0 for the platform default
+	WorkingDirectory string             `json:"working_directory"` // Default directory for run steps
+	TODOs            []string           `json:"todos"`             // Job-level constructs that could not be translated
+}
+
+// PipelineCondition runs a job when any of its branches, tags or events match
+type PipelineCondition struct {
+	DefaultBranch bool     `json:"default_branch"` // The repository's default branch, whatever it is called
+	Branches      []string `json:"branches"`       // Branch name patterns
+	Tags          []string `json:"tags"`           // Tag name patterns; "*" for any tag
+	Events        []string `json:"events"`         // "push", "pull_request", "schedule" or "manual"
+	Expr          string   `json:"expr"`           // Source expression that could not be translated, kept for review
+}
+
+// PipelineAxis is one dimension of a job matrix
+type PipelineAxis struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// PipelineVar is a name/value pair; lists keep the source order
+type PipelineVar struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// PipelineService is a service container running alongside a job
+type PipelineService struct {
+	Name  string        `json:"name"` // Hostname the job reaches it by
+	Image string        `json:"image"`
+	Env   []PipelineVar `json:"env"`
+	Ports []string      `json:"ports"` // e.g. "5432:5432"
+}
+
+// Pipeline step kinds
+const (
+	StepRun      = "run"      // Shell commands
+	StepCheckout = "checkout" // Check out the repository
+	StepSetup    = "setup"    // Install a language runtime: Runtime and Version
+	StepCache    = "cache"    // Restore and save Paths under Key
+	StepUpload   = "upload"   // Upload Paths as the Artifact
+	StepDownload = "download" // Download the Artifact into Paths[0]
+	StepAction   = "action"   // Platform-specific action: Uses with With; Run, if set, is what other platforms run instead
+	StepTODO     = "todo"     // A source construct that could not be translated; Source holds it
+)
+
+// PipelineStep is one step of a job
+type PipelineStep struct {
+	Kind             string        `json:"kind"` // One of the Step* kinds
+	Name             string        `json:"name"`
+	Run              string        `json:"run"`
+	Runtime          string        `json:"runtime"` // setup: "go", "node", "python", "java", "ruby"
+	Version          string        `json:"version"` // setup: runtime version
+	Key              string        `json:"key"`     // cache: key, may use hashFiles('lockfile')
+	Paths            []string      `json:"paths"`   // cache, upload, download
+	Artifact         string        `json:"artifact"`
+	Uses             string        `json:"uses"`
+	With             []PipelineVar `json:"with"`
+	When             string        `json:"when"` // "success" (default), "failure" or "always"
+	Env              []PipelineVar `json:"env"`
+	WorkingDirectory string        `json:"working_directory"`
+	ContinueOnError  bool          `json:"continue_on_error"`
+	Source           string        `json:"source"`  // todo: the untranslated source
+	Comment          string        `json:"comment"` // Shown above the step in the output
+}
+
+// job returns the job with the given ID, or nil
+func (p *Pipeline) job(id string) *PipelineJob {
+	for i := range p.Jobs {
+		if p.Jobs[i].ID == id {
+			return &p.Jobs[i]
+		}
+	}
+	return nil
+}
+
+// TODOCount counts the constructs left for review: pipeline and job notes and todo steps
+func (p *Pipeline) TODOCount() int {
+	count := len(p.TODOs)
+	for _, job := range p.Jobs {
+		count += len(job.TODOs)
+		for _, step := range job.Steps {
+			if step.Kind == StepTODO {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// setVar sets name in vars, replacing an existing value
+func setVar(vars []PipelineVar, name, value string) []PipelineVar {
+	for i := range vars {
+		if vars[i].Name == name {
+			vars[i].Value = value
+			return vars
+		}
+	}
+	return append(vars, PipelineVar{Name: name, Value: value})
+}
+
+// hasVar reports whether vars defines name
+func hasVar(vars []PipelineVar, name string) bool {
+	for _, v := range vars {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// varsFromMap converts a map to variables sorted by name
+func varsFromMap(m map[string]string) []PipelineVar {
+	vars := make([]PipelineVar, 0, len(m))
+	for _, name := range sortedMapKeys(m) {
+		vars = append(vars, PipelineVar{Name: name, Value: m[name]})
+	}
+	return vars
+}
+
+var (
+	shellVarPattern     = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}|\$([A-Za-z_][A-Za-z0-9_]*)`) // ${NAME} and $NAME references in scripts
+	shellAssignPattern  = regexp.MustCompile(`(?m)(?:^|\s|export\s+)([A-Za-z_][A-Za-z0-9_]*)=`)
+	jobIDInvalidPattern = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+	versionPattern      = regexp.MustCompile(`^[0-9]+(\.[0-9]+)*`)
+)
+
+// rewriteVars renames CI variables in a script, e.g. $CI_COMMIT_SHA → $GITHUB_SHA
+func rewriteVars(script string, renames map[string]string) string {
+	return shellVarPattern.ReplaceAllStringFunc(script, func(ref string) string {
+		m := shellVarPattern.FindStringSubmatch(ref)
+		name := m[1] + m[2]
+		replacement, ok := renames[name]
+		if !ok {
+			return ref
+		}
+		if strings.ContainsAny(replacement, "${}:/. ") {
+			return replacement // An expression such as ${GITHUB_SHA::8}
+		}
+		if strings.HasPrefix(ref, "${") {
+			return "${" + replacement + "}"
+		}
+		return "$" + replacement
+	})
+}
+
+// ambientVars are set by the shell, the runner or GitHub Actions and are never secrets
+var ambientVars = map[string]bool{
+	"HOME": true, "PATH": true, "PWD": true, "USER": true, "SHELL": true, "TMPDIR": true, "LANG": true, "CI": true,
+	"GOPATH": true, "GOCACHE": true, "GOFLAGS": true, "GOOS": true, "GOARCH": true, "CGO_ENABLED": true,
+	"NODE_ENV": true, "PYTHONPATH": true, "VIRTUAL_ENV": true, "JAVA_HOME": true, "DOCKER_HOST": true,
+	"RUNNER_OS": true, "RUNNER_ARCH": true, "RUNNER_TEMP": true,
+}
+
+// inferSecrets finds the upper-case variables each job's scripts and variables read that
+// nothing defines, adds them to the pipeline secrets and passes them to the jobs that read them.
+// Variables are not expanded in GitHub Actions env values, so their references are rewritten:
+// to ${{ matrix.X }} for a matrix axis, to the value of a variable defined in an outer scope,
+// or to ${{ secrets.X }}. isPlatformVar reports variables the source platform provides that
+// were not rewritten.
+func inferSecrets(p *Pipeline, isPlatformVar func(name string) bool) {
+	secrets := map[string]bool{}
+	for _, name := range p.Secrets {
+		secrets[name] = true
+	}
+	isSecret := func(name string) bool {
+		return !ambientVars[name] && !strings.HasPrefix(name, "GITHUB_") && !isPlatformVar(name) &&
+			name == strings.ToUpper(name) && len(name) >= 3
+	}
+
+	p.Env = envRefs(p.Env, nil, nil, isSecret, secrets)
+	for i := range p.Jobs {
+		job := &p.Jobs[i]
+		axes := map[string]bool{}
+		for _, axis := range job.Matrix {
+			axes[axis.Name] = true
+		}
+		// Job and service env cannot read the env context, so outer values are inlined
+		job.Env = envRefs(job.Env, p.Env, axes, isSecret, secrets)
+		scope := append(append([]PipelineVar(nil), p.Env...), job.Env...)
+		for j := range job.Services {
+			job.Services[j].Env = envRefs(job.Services[j].Env, scope, axes, isSecret, secrets)
+		}
+
+		defined := map[string]bool{}
+		for _, v := range scope {
+			defined[v.Name] = true
+		}
+		for name := range axes {
+			defined[name] = true
+		}
+		var used []string
+		for j := range job.Steps {
+			step := &job.Steps[j]
+			step.Env = envRefs(step.Env, scope, axes, isSecret, secrets)
+			for _, v := range step.Env {
+				defined[v.Name] = true
+			}
+			for _, m := range shellVarPattern.FindAllStringSubmatch(step.Run, -1) {
+				used = append(used, m[1]+m[2])
+			}
+			// Variables assigned in the script itself
+			for _, m := range shellAssignPattern.FindAllStringSubmatch(step.Run, -1) {
+				defined[m[1]] = true
+			}
+		}
+
+		var jobSecrets []string
+		for _, name := range used {
+			if !defined[name] && isSecret(name) {
+				jobSecrets = appendUnique(jobSecrets, name)
+			}
+		}
+		sort.Strings(jobSecrets)
+		for _, name := range jobSecrets {
+			secrets[name] = true
+			job.Env = append(job.Env, PipelineVar{Name: name, Value: "${{ secrets." + name + " }}"})
+		}
+	}
+	p.Secrets = sortedKeys(secrets)
+}
+
+// envRefs rewrites the $X and ${X} that vars read: a variable defined in vars or in outer
+// scope becomes its value, a matrix axis ${{ matrix.X }}, and a likely secret ${{ secrets.X }},
+// which is added to secrets. Anything else, such as $HOME, is left for the runner.
+func envRefs(vars, outer []PipelineVar, axes map[string]bool, isSecret func(string) bool, secrets map[string]bool) []PipelineVar {
+	own, outerValues := map[string]string{}, map[string]string{}
+	for _, v := range vars {
+		own[v.Name] = v.Value
+	}
+	for _, v := range outer {
+		outerValues[v.Name] = v.Value
+	}
+	resolving := map[string]bool{} // A variable that reads itself gets the outer value
+	var resolve func(value string) string
+	resolve = func(value string) string {
+		return shellVarPattern.ReplaceAllStringFunc(value, func(ref string) string {
+			m := shellVarPattern.FindStringSubmatch(ref)
+			name := m[1] + m[2]
+			if value, ok := own[name]; ok && !resolving[name] {
+				resolving[name] = true
+				defer delete(resolving, name)
+				return resolve(value)
+			}
+			if value, ok := outerValues[name]; ok {
+				return value
+			}
+			switch {
+			case axes[name]:
+				return "${{ matrix." + name + " }}"
+			case !resolving[name] && isSecret(name):
+				secrets[name] = true
+				return "${{ secrets." + name + " }}"
+			}
+			return ref
+		})
+	}
+	var rewritten []PipelineVar
+	for _, v := range vars {
+		resolving[v.Name] = true
+		rewritten = append(rewritten, PipelineVar{Name: v.Name, Value: resolve(v.Value)})
+		delete(resolving, v.Name)
+	}
+	return rewritten
+}
+
+// jobID turns a source job name into an ID every platform accepts
+func jobID(name string) string {
+	id := strings.ToLower(jobIDInvalidPattern.ReplaceAllString(strings.TrimSpace(name), "-"))
+	id = strings.Trim(id, "-")
+	if id == "" {
+		return "job"
+	}
+	if id[0] >= '0' && id[0] <= '9' {
+		id = "job-" + id
+	}
+	return id
+}
+
+// runtimeForImage recognises official language images, e.g. "golang:1.22" → go 1.22
+func runtimeForImage(image string) (runtime, version string, ok bool) {
+	name, tag := imageName(image)
+	name = strings.TrimPrefix(name, "library/")
+	runtimes := map[string]string{
+		"golang": "go", "cimg/go": "go", "circleci/golang": "go",
+		"node": "node", "cimg/node": "node", "circleci/node": "node",
+		"python": "python", "cimg/python": "python", "circleci/python": "python",
+		"ruby": "ruby", "cimg/ruby": "ruby", "circleci/ruby": "ruby",
+		"openjdk": "java", "eclipse-temurin": "java", "cimg/openjdk": "java", "circleci/openjdk": "java",
+	}
+	runtime, ok = runtimes[name]
+	if !ok {
+		return "", "", false
+	}
+	version = versionPattern.FindString(tag)
+	return runtime, version, true
+}
+
+// ciVariables describes how a source platform's predefined variables translate
+type ciVariables struct {
+	renames  map[string]string // Rewritten in scripts, e.g. CI_COMMIT_SHA → GITHUB_SHA
+	compat   map[string]string // Defined in the workflow env when used, e.g. CI_COMMIT_TAG → tag name or ""
+	platform func(name string) bool
+}
+
+// translateVars rewrites the platform's variables in every run step and env, defines the
+// compatibility variables the scripts use, and notes the ones with no equivalent
+func translateVars(p *Pipeline, vars ciVariables) {
+	used, defined := map[string]bool{}, map[string]bool{}
+	for i := range p.Jobs {
+		job := &p.Jobs[i]
+		for _, v := range job.Env {
+			defined[v.Name] = true
+		}
+		for j := range job.Steps {
+			step := &job.Steps[j]
+			for k := range step.Env {
+				step.Env[k].Value = rewriteVars(step.Env[k].Value, vars.renames)
+			}
+			if step.Kind != StepRun {
+				continue
+			}
+			step.Run = rewriteVars(step.Run, vars.renames)
+			for _, m := range shellVarPattern.FindAllStringSubmatch(step.Run, -1) {
+				used[m[1]+m[2]] = true
+			}
+		}
+		for j := range job.Env {
+			job.Env[j].Value = rewriteVars(job.Env[j].Value, vars.renames)
+		}
+		for j := range job.Services {
+			for k := range job.Services[j].Env {
+				job.Services[j].Env[k].Value = rewriteVars(job.Services[j].Env[k].Value, vars.renames)
+			}
+		}
+	}
+	for j := range p.Env {
+		p.Env[j].Value = rewriteVars(p.Env[j].Value, vars.renames)
+	}
+
+	var unknown []string
+	for _, name := range sortedKeys(used) {
+		if expr, ok := vars.compat[name]; ok {
+			if !hasVar(p.Env, name) {
+				p.Env = append(p.Env, PipelineVar{Name: name, Value: expr})
+			}
+		} else if vars.platform(name) && !hasVar(p.Env, name) && !defined[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		p.TODOs = append(p.TODOs, "no GitHub Actions equivalent for "+strings.Join(unknown, ", "))
+	}
+}
@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// pipelineStepKinds are the step kinds a pipeline may use
+var pipelineStepKinds = map[string]bool{
+	StepRun: true, StepCheckout: true, StepSetup: true, StepCache: true, StepUpload: true,
+	StepDownload: true, StepAction: true, StepTODO: true,
+}
+
+// pipelineEvents are the events a job condition may name
+var pipelineEvents = map[string]bool{"push": true, "pull_request": true, "schedule": true, "manual": true}
+
+// LintPipeline checks a pipeline for mistakes every platform would reject or
+// silently mishandle: dangling needs, dependency cycles, empty jobs, artifacts
+// nobody uploads and references to undeclared secrets or matrix values.
+func LintPipeline(p Pipeline) []string {
+	var problems []string
+	if len(p.Jobs) == 0 {
+		return []string{"pipeline has no jobs"}
+	}
+	t := p.Triggers
+	if len(t.PushBranches) == 0 && len(t.Tags) == 0 && !t.PullRequests && len(t.Schedules) == 0 && !t.Manual {
+		problems = append(problems, "pipeline has no triggers; nothing will start it")
+	}
+
+	jobs := map[string]PipelineJob{}
+	uploads := map[string]string{}
+	for _, job := range p.Jobs {
+		if _, ok := jobs[job.ID]; ok {
+			problems = append(problems, fmt.Sprintf("job ID %q is used twice", job.ID))
+		}
+		if job.ID == "" || jobID(job.ID) != job.ID {
+			problems = append(problems, fmt.Sprintf("job ID %q must be lower-case letters, digits, - and _", job.ID))
+		}
+		jobs[job.ID] = job
+		for _, step := range job.Steps {
+			if step.Kind == StepUpload {
+				uploads[step.Artifact] = job.ID
+			}
+		}
+	}
+
+	secrets := map[string]bool{}
+	for _, name := range p.Secrets {
+		secrets[name] = true
+	}
+	for _, v := range p.Env {
+		problems = append(problems, lintExpressions("env "+v.Name, v.Value, nil, secrets)...)
+	}
+
+	for _, job := range p.Jobs {
+		where := fmt.Sprintf("job %q", job.ID)
+		for _, need := range job.Needs {
+			switch {
+			case need == job.ID:
+				problems = append(problems, where+" needs itself")
+			case jobs[need].ID == "":
+				problems = append(problems, fmt.Sprintf("%s needs %q, which is not defined", where, need))
+			}
+		}
+		if cycle := dependencyCycle(jobs, job.ID, nil); len(cycle) > 0 && cycle[0] == job.ID {
+			problems = append(problems, fmt.Sprintf("%s is in a dependency cycle: %s", where, strings.Join(cycle, " → ")))
+		}
+		if len(job.Steps) == 0 {
+			problems = append(problems, where+" has no steps")
+		}
+		if job.When != "" && job.When != "success" && job.When != "always" && job.When != "failure" {
+			problems = append(problems, fmt.Sprintf("%s: when must be success, failure or always, not %q", where, job.When))
+		}
+		if job.If != nil {
+			for _, event := range job.If.Events {
+				if !pipelineEvents[event] {
+					problems = append(problems, fmt.Sprintf("%s: unknown event %q in its condition", where, event))
+				}
+			}
+		}
+		axes := map[string]bool{}
+		for _, axis := range job.Matrix {
+			if len(axis.Values) == 0 {
+				problems = append(problems, fmt.Sprintf("%s: matrix axis %q has no values", where, axis.Name))
+			}
+			axes[axis.Name] = true
+		}
+		for _, svc := range job.Services {
+			if svc.Image == "" {
+				problems = append(problems, fmt.Sprintf("%s: service %q has no image", where, svc.Name))
+			}
+		}
+		for _, v := range job.Env {
+			problems = append(problems, lintExpressions(where+" env "+v.Name, v.Value, axes, secrets)...)
+		}
+		problems = append(problems, lintExpressions(where+" image", job.Image, axes, secrets)...)
+
+		for i, step := range job.Steps {
+			at := fmt.Sprintf("%s step %d", where, i+1)
+			if step.Name != "" {
+				at = fmt.Sprintf("%s step %q", where, step.Name)
+			}
+			if !pipelineStepKinds[step.Kind] {
+				problems = append(problems, fmt.Sprintf("%s: unknown kind %q", at, step.Kind))
+				continue
+			}
+			switch step.Kind {
+			case StepRun:
+				if strings.TrimSpace(step.Run) == "" {
+					problems = append(problems, at+" has no commands")
+				}
+			case StepSetup:
+				if step.Runtime == "" {
+					problems = append(problems, at+" does not say which runtime to set up")
+				}
+			case StepCache:
+				if step.Key == "" || len(step.Paths) == 0 {
+					problems = append(problems, at+" needs a key and paths to cache")
+				}
+			case StepUpload:
+				if step.Artifact == "" || len(step.Paths) == 0 {
+					problems = append(problems, at+" needs an artifact name and paths to upload")
+				}
+			case StepDownload:
+				from, ok := uploads[step.Artifact]
+				switch {
+				case !ok:
+					problems = append(problems, fmt.Sprintf("%s downloads %q, which no job uploads", at, step.Artifact))
+				case from != job.ID && !dependsOn(jobs, job.ID, from):
+					problems = append(problems, fmt.Sprintf("%s downloads %q from %q, which it does not need", at, step.Artifact, from))
+				}
+			case StepAction:
+				if step.Uses == "" {
+					problems = append(problems, at+" does not say which action it uses")
+				}
+			}
+			if step.When != "" && step.When != "success" && step.When != "always" && step.When != "failure" {
+				problems = append(problems, fmt.Sprintf("%s: when must be success, failure or always, not %q", at, step.When))
+			}
+			for _, v := range append(append([]PipelineVar(nil), step.Env...), step.With...) {
+				problems = append(problems, lintExpressions(at+" "+v.Name, v.Value, axes, secrets)...)
+			}
+			problems = append(problems, lintExpressions(at, step.Run+step.Version+step.Key, axes, secrets)...)
+		}
+	}
+	return problems
+}
+
+// lintExpressions reports ${{ matrix.X }} references to axes the job does not
+// have, and ${{ secrets.X }} references to secrets the pipeline does not declare
+func lintExpressions(where, value string, axes, secrets map[string]bool) []string {
+	var problems []string
+	for _, m := range expressionPattern.FindAllStringSubmatch(value, -1) {
+		ref := contextRefPattern.FindStringSubmatch(m[1])
+		switch {
+		case ref == nil:
+		case ref[1] == "matrix" && !axes[ref[2]]:
+			problems = append(problems, fmt.Sprintf("%s uses matrix.%s, which is not a matrix axis", where, ref[2]))
+		case ref[1] == "secrets" && !secrets[ref[2]] && ref[2] != "GITHUB_TOKEN":
+			problems = append(problems, fmt.Sprintf("%s uses secret %s, which is not listed in secrets", where, ref[2]))
+		}
+	}
+	return problems
+}
+
+// dependsOn reports whether job id needs job on, directly or through other jobs
+func dependsOn(jobs map[string]PipelineJob, id, on string) bool {
+	seen := map[string]bool{}
+	var walk func(id string) bool
+	walk = func(id string) bool {
+		if seen[id] {
+			return false
+		}
+		seen[id] = true
+		for _, need := range jobs[id].Needs {
+			if need == on || walk(need) {
+				return true
+			}
+		}
+		return false
+	}
+	return walk(id)
+}
+
+// dependencyCycle returns a path of needs from id back to a job already on the path, if any
+func dependencyCycle(jobs map[string]PipelineJob, id string, path []string) []string {
+	for i, seen := range path {
+		if seen == id {
+			return append(path[i:], id)
+		}
+	}
+	path = append(path, id)
+	for _, need := range jobs[id].Needs {
+		if need == id {
+			continue // Reported separately
+		}
+		if cycle := dependencyCycle(jobs, need, path); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
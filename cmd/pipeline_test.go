@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestInferSecrets(t *testing.T) {
+	tests := []struct {
+		name        string
+		pipeline    Pipeline
+		platform    []string
+		wantSecrets []string
+		wantEnv     []PipelineVar // The first job's env
+		wantService []PipelineVar // The first job's first service's env
+	}{
+		{
+			name: "script reads an undefined variable",
+			pipeline: Pipeline{Jobs: []PipelineJob{{ID: "deploy", Steps: []PipelineStep{
+				{Kind: StepRun, Run: "curl -H \"Authorization: $API_TOKEN\" https://example.com/$TARGET\n"},
+			}}}},
+			wantSecrets: []string{"API_TOKEN", "TARGET"},
+			wantEnv: []PipelineVar{
+				{Name: "API_TOKEN", Value: "${{ secrets.API_TOKEN }}"},
+				{Name: "TARGET", Value: "${{ secrets.TARGET }}"},
+			},
+		},
+		{
+			name: "defined, assigned, ambient, platform and lower-case variables are not secrets",
+			pipeline: Pipeline{
+				Env: []PipelineVar{{Name: "REGION", Value: "eu"}},
+				Jobs: []PipelineJob{{ID: "test",
+					Matrix: []PipelineAxis{{Name: "PYTHON", Values: []string{"3.11"}}},
+					Steps: []PipelineStep{
+						{Kind: StepRun, Env: []PipelineVar{{Name: "STEP_VAR", Value: "1"}},
+							Run: "VERSION=$(cat VERSION)\necho $REGION $PYTHON $STEP_VAR $VERSION $HOME $GITHUB_SHA $CI_JOB_ID $lower $AB\n"},
+					}}},
+			},
+			platform: []string{"CI_JOB_ID"},
+		},
+		{
+			name: "variable values read secrets",
+			pipeline: Pipeline{Jobs: []PipelineJob{{ID: "test",
+				Env: []PipelineVar{
+					{Name: "DATABASE_URL", Value: "postgres://app:$POSTGRES_PASSWORD@db/app"},
+					{Name: "POSTGRES_PASSWORD", Value: "$DB_PASS"},
+				},
+				Services: []PipelineService{{Name: "db", Image: "postgres:16", Env: []PipelineVar{
+					{Name: "POSTGRES_PASSWORD", Value: "$DB_PASS"},
+				}}},
+				Steps: []PipelineStep{{Kind: StepRun, Run: "pytest\n"}},
+			}}},
+			wantSecrets: []string{"DB_PASS"},
+			wantEnv: []PipelineVar{
+				{Name: "DATABASE_URL", Value: "postgres://app:${{ secrets.DB_PASS }}@db/app"},
+				{Name: "POSTGRES_PASSWORD", Value: "${{ secrets.DB_PASS }}"},
+			},
+			wantService: []PipelineVar{{Name: "POSTGRES_PASSWORD", Value: "${{ secrets.DB_PASS }}"}},
+		},
+		{
+			name: "variable values read outer variables and matrix axes",
+			pipeline: Pipeline{
+				Env: []PipelineVar{{Name: "IMAGE", Value: "registry.example.com/app"}},
+				Jobs: []PipelineJob{{ID: "build",
+					Matrix: []PipelineAxis{{Name: "ARCH", Values: []string{"amd64", "arm64"}}},
+					Env: []PipelineVar{
+						{Name: "PATH", Value: "$HOME/bin:$PATH"},
+						{Name: "TAG", Value: "${IMAGE}:$ARCH"},
+					},
+					Steps: []PipelineStep{{Kind: StepRun, Run: "docker build -t $TAG .\n"}},
+				}},
+			},
+			wantEnv: []PipelineVar{
+				{Name: "PATH", Value: "$HOME/bin:$PATH"},
+				{Name: "TAG", Value: "registry.example.com/app:${{ matrix.ARCH }}"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.pipeline
+			inferSecrets(&p, func(name string) bool { return slices.Contains(tt.platform, name) })
+			if !slices.Equal(p.Secrets, tt.wantSecrets) {
+				t.Errorf("secrets = %v, want %v", p.Secrets, tt.wantSecrets)
+			}
+			if !slices.Equal(p.Jobs[0].Env, tt.wantEnv) {
+				t.Errorf("job env = %v, want %v", p.Jobs[0].Env, tt.wantEnv)
+			}
+			if len(p.Jobs[0].Services) > 0 && !slices.Equal(p.Jobs[0].Services[0].Env, tt.wantService) {
+				t.Errorf("service env = %v, want %v", p.Jobs[0].Services[0].Env, tt.wantService)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Plugin detector protocol
+//
+// An external detector is any executable named fluxion-detector-<name>, found on
+// PATH or listed under detectors: in .fluxion.yml. Detectors the repository
+// lists by path are its own code, so they only run with --allow-plugins;
+// scanning a cloned repository must not execute it. Fluxion runs it with the
+// project directory as its only argument (and as its working directory), and:
+//   - exit 0 with a LanguageContext JSON object on stdout: the language was detected
+//     (same field names as `fluxion context --format json`, "language" is required)
+//   - exit 0 with empty output or null: not this detector's project
+//   - any other exit status, a timeout or invalid JSON: the plugin failed; its
+//     result is ignored, the failure is reported as a diagnostic and detection
+//     carries on with the other detectors
+//
+// Example output:
+//
+//	{"language": "Starlark", "build_command": "bazel build //...", "test_command": "bazel test //...",
+//	 "evidence": {"language": "MODULE.bazel found"}}
+const pluginPrefix = "fluxion-detector-"
+
+// allowPlugins is set by --allow-plugins: run the detectors .fluxion.yml lists by path
+var allowPlugins bool
+
+// pluginOutputLimit caps how much stdout a plugin may produce
+const pluginOutputLimit = 1 << 20
+
+// PluginDetector runs an external fluxion-detector-* executable
+type PluginDetector struct {
+	Path string
+}
+
+func (d *PluginDetector) Name() string {
+	name := strings.TrimPrefix(filepath.Base(d.Path), pluginPrefix)
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// Detect runs the plugin until it exits or ctx is cancelled; plugins walk the
+// project themselves, so files is unused
+func (d *PluginDetector) Detect(ctx context.Context, workingDir string, files *FileIndex) (*LanguageContext, error) {
+	var stdout, stderr bytes.Buffer
+	command := exec.CommandContext(ctx, d.Path, workingDir)
+	command.Dir = workingDir
+	command.Stdout = &limitedBuffer{buf: &stdout, limit: pluginOutputLimit}
+	command.Stderr = &limitedBuffer{buf: &stderr, limit: 4096}
+	// Don't wait on grandchildren that inherited stdout once the plugin is killed
+	command.WaitDelay = time.Second
+
+	if err := command.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("plugin %s was stopped: %w", d.Path, ctx.Err())
+		}
+		return nil, fmt.Errorf("plugin %s failed: %w: %s", d.Path, err, strings.TrimSpace(stderr.String()))
+	}
+
+	output := bytes.TrimSpace(stdout.Bytes())
+	if len(output) == 0 || string(output) == "null" {
+		return nil, nil
+	}
+
+	var langCtx LanguageContext
+	if err := json.Unmarshal(output, &langCtx); err != nil {
+		return nil, fmt.Errorf("plugin %s returned invalid JSON: %w", d.Path, err)
+	}
+	if langCtx.Language == "" {
+		return nil, fmt.Errorf("plugin %s returned no language", d.Path)
+	}
+	reported := map[string]string{
+		"language":        langCtx.Language,
+		"framework":       langCtx.Framework,
+		"build_command":   langCtx.BuildCommand,
+		"test_command":    langCtx.TestCommand,
+		"package_manager": langCtx.PackageManager,
+	}
+	for field, value := range reported {
+		if _, ok := langCtx.Evidence[field]; !ok && value != "" {
+			langCtx.because(field, "reported by "+filepath.Base(d.Path))
+		}
+	}
+	return &langCtx, nil
+}
+
+// limitedBuffer keeps at most limit bytes and silently drops the rest, so a
+// misbehaving plugin cannot exhaust memory
+type limitedBuffer struct {
+	buf   *bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.buf.Len(); remaining > 0 {
+		if len(p) > remaining {
+			b.buf.Write(p[:remaining])
+		} else {
+			b.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+// pluginDetectors returns the detectors listed in .fluxion.yml followed by the
+// fluxion-detector-* executables on PATH. A name found twice runs once: config
+// entries win, then the first match on PATH, as with the shell. Entries that
+// are paths point at the repository's own executables and are skipped, with a
+// diagnostic, unless allowed is set.
+func pluginDetectors(workingDir string, config ProjectConfig, allowed bool) ([]LanguageDetector, []Diagnostic) {
+	var detectors []LanguageDetector
+	var diags []Diagnostic
+	seen := map[string]bool{}
+	add := func(path string) {
+		d := &PluginDetector{Path: path}
+		if seen[d.Name()] {
+			return
+		}
+		seen[d.Name()] = true
+		detectors = append(detectors, d)
+	}
+
+	for _, entry := range config.Detectors {
+		path := entry
+		if !strings.ContainsRune(entry, '/') && !strings.ContainsRune(entry, filepath.Separator) {
+			// Bare names are looked up on PATH, with or without the prefix
+			if !strings.HasPrefix(entry, pluginPrefix) {
+				entry = pluginPrefix + entry
+			}
+			if found, err := exec.LookPath(entry); err == nil {
+				path = found
+			} else {
+				continue
+			}
+		} else if !allowed {
+			diags = append(diags, Diagnostic{Detector: "plugins", Severity: SeverityWarning, File: config.Path,
+				Message: fmt.Sprintf("skipped detector %s: run with --allow-plugins to execute detectors the repository provides", entry)})
+			continue
+		} else if !filepath.IsAbs(path) {
+			path = filepath.Join(workingDir, path)
+		}
+		if isExecutable(path) {
+			add(path)
+		}
+	}
+
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			if strings.HasPrefix(entry.Name(), pluginPrefix) && isExecutable(path) {
+				add(path)
+			}
+		}
+	}
+	return detectors, diags
+}
+
+// isExecutable reports whether path is a regular file the current user can run
+func isExecutable(path string) bool {
+	fi, err := os.Stat(path)
+	if err != nil || fi.IsDir() {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(filepath.Ext(path), ".exe")
+	}
+	return fi.Mode()&0111 != 0
+}
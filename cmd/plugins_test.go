@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestRepositoryPluginsNeedOptIn(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin is a shell script")
+	}
+	dir := t.TempDir()
+	plugin := filepath.Join(dir, "tools", "fluxion-detector-bazel")
+	writeTestFile(t, plugin, "#!/bin/sh\necho '{\"language\": \"Starlark\"}'\n")
+	if err := os.Chmod(plugin, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", t.TempDir())
+	config := ProjectConfig{Path: ".fluxion.yml", Detectors: []string{"./tools/fluxion-detector-bazel"}}
+
+	tests := []struct {
+		name      string
+		allowed   bool
+		detectors int
+		diags     int
+	}{
+		{name: "skipped by default", allowed: false, detectors: 0, diags: 1},
+		{name: "run with --allow-plugins", allowed: true, detectors: 1, diags: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detectors, diags := pluginDetectors(dir, config, tt.allowed)
+			if len(detectors) != tt.detectors || len(diags) != tt.diags {
+				t.Errorf("got %d detectors and %d diagnostics %v, want %d and %d", len(detectors), len(diags), diags, tt.detectors, tt.diags)
+			}
+		})
+	}
+}
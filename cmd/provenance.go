@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldSource records which detector produced a ProjectContext field and why
+type FieldSource struct {
+	Field    string `json:"field"`    // JSON name of the field, e.g. "framework", "services"
+	Detector string `json:"detector"` // Detector that set it, e.g. "Go", "docker", "git"
+	Reason   string `json:"reason"`   // Evidence it was based on, e.g. "github.com/spf13/cobra in go.mod"
+}
+
+// because records the evidence behind one of the language context's fields
+func (c *LanguageContext) because(field, reason string) {
+	if c.Evidence == nil {
+		c.Evidence = map[string]string{}
+	}
+	c.Evidence[field] = reason
+}
+
+// record adds a provenance entry for a field
+func (ctx *ProjectContext) record(field, detector, reason string) {
+	ctx.Provenance = append(ctx.Provenance, FieldSource{Field: field, Detector: detector, Reason: reason})
+}
+
+// recordLanguage adds provenance for the fields a language detector filled in
+func (ctx *ProjectContext) recordLanguage(detector string, langCtx *LanguageContext, primary bool) {
+	reason := func(field, fallback string) string {
+		if r, ok := langCtx.Evidence[field]; ok {
+			return r
+		}
+		return fallback
+	}
+
+	ctx.record("languages", detector, reason("language", "project manifest found"))
+	if len(langCtx.Packages) > 0 {
+		ctx.record("packages", detector, fmt.Sprintf("%d declared dependencies", len(langCtx.Packages)))
+	}
+	if !primary {
+		if langCtx.Tests.HasTests() {
+			ctx.record("tests", detector, "merged into the primary language's test info")
+		}
+		return
+	}
+
+	ctx.record("primary_language", detector, "first language detected")
+	fields := []struct {
+		name, value, fallback string
+	}{
+		{"framework", langCtx.Framework, "framework dependency declared"},
+		{"build_command", langCtx.BuildCommand, fmt.Sprintf("default for %s projects", langCtx.Language)},
+		{"test_command", langCtx.TestCommand, fmt.Sprintf("default for %s projects", langCtx.Language)},
+		{"package_manager", langCtx.PackageManager, fmt.Sprintf("default for %s projects", langCtx.Language)},
+	}
+	for _, f := range fields {
+		if f.value != "" {
+			ctx.record(f.name, detector, reason(f.name, f.fallback))
+		}
+	}
+	if len(langCtx.Dependencies) > 0 {
+		ctx.record("dependencies", detector, "known frameworks and tools among the declared dependencies")
+	}
+	if langCtx.Tests.HasTests() {
+		reason := "test directories: " + strings.Join(langCtx.Tests.Directories, ", ")
+		if len(langCtx.Tests.Directories) == 0 {
+			reason = "test frameworks: " + strings.Join(langCtx.Tests.Frameworks, ", ")
+		}
+		ctx.record("tests", detector, reason)
+	}
+}
+
+// FormatProvenance lists which detector produced each field, for display
+func (ctx *ProjectContext) FormatProvenance() []string {
+	lines := make([]string, 0, len(ctx.Provenance))
+	for _, source := range ctx.Provenance {
+		lines = append(lines, fmt.Sprintf("%s ← %s: %s", source.Field, source.Detector, source.Reason))
+	}
+	return lines
+}
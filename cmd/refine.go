@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/spf13/cobra"
+)
+
+// generateSession is a generation conversation: the messages sent so far and
+// the model's answers, so a follow-up instruction revises the last result
+// instead of starting over
+type generateSession struct {
+	model    openai.ChatModel
+	target   PipelineTarget
+	neutral  bool // The model answers with a Pipeline, rendered with the target's emitter
+	schema   map[string]interface{}
+	messages []openai.ChatCompletionMessageParamUnion
+}
+
+// newGenerateSession starts a conversation with the system prompt for the target,
+// or for platform-neutral pipelines when neutral is set
+func newGenerateSession(model openai.ChatModel, target PipelineTarget, neutral bool) *generateSession {
+	s := &generateSession{model: model, target: target, neutral: neutral}
+	if neutral {
+		s.schema = generatePipelineSchema()
+		s.messages = []openai.ChatCompletionMessageParamUnion{openai.SystemMessage(pipelineGenerateSystemPrompt)}
+	} else {
+		s.schema = generateSchema(target.Artifact)
+		s.messages = []openai.ChatCompletionMessageParamUnion{openai.SystemMessage(target.SystemPrompt)}
+	}
+	return s
+}
+
+// send adds a user message to the conversation and returns the model's result.
+// A failed request leaves the conversation as it was.
+func (s *generateSession) send(userPrompt string) (GenerateResult, error) {
+	openAiApiKey := os.Getenv("OPENAI_API_KEY")
+	client := openai.NewClient(
+		option.WithAPIKey(openAiApiKey),
+	)
+
+	schemaParam := openai.ResponseFormatJSONSchemaJSONSchemaParam{
+		Name:   "generate_result",
+		Schema: s.schema,
+		Strict: openai.Bool(true),
+	}
+
+	messages := append(s.messages[:len(s.messages):len(s.messages)], openai.UserMessage(userPrompt))
+	resp, err := client.Chat.Completions.New(
+		context.Background(),
+		openai.ChatCompletionNewParams{
+			Model:    s.model,
+			Messages: messages,
+			ResponseFormat: openai.ChatCompletionNewParamsResponseFormatUnion{
+				OfJSONSchema: &openai.ResponseFormatJSONSchemaParam{
+					JSONSchema: schemaParam,
+				},
+			},
+		},
+	)
+
+	if err != nil {
+		return GenerateResult{}, fmt.Errorf("OpenAI API error: %w", err)
+	}
+
+	// Parse the response
+	content := resp.Choices[0].Message.Content
+	var result GenerateResult
+	if err := json.Unmarshal([]byte(content), &result); err != nil {
+		return GenerateResult{}, fmt.Errorf("failed to parse OpenAI response: %w\nRaw content: %s", err, content)
+	}
+	if s.neutral {
+		if result.Pipeline == nil {
+			return GenerateResult{}, fmt.Errorf("OpenAI response has no pipeline")
+		}
+		if result.PipelineConfig, err = s.target.Render(*result.Pipeline); err != nil {
+			return GenerateResult{}, err
+		}
+	}
+	s.messages = append(messages, openai.AssistantMessage(content))
+	return result, nil
+}
+
+// refineConfiguration shows the generated configuration and revises it with
+// the user's follow-up instructions until they accept or discard it. Accepting a
+// version that fails validation asks whether to write it anyway or keep refining,
+// unless force is set.
+func refineConfiguration(cmd *cobra.Command, session *generateSession, result GenerateResult, force bool) (GenerateResult, bool, error) {
+	if session == nil {
+		return result, true, nil // Nothing to converse with, e.g. a template rendered as is
+	}
+
+	cmd.Printf("\n📄 Generated %s:\n", session.target.Artifact)
+	cmd.Println("───────────────────────────────────────────────────────────────")
+	cmd.Print(result.PipelineConfig)
+	if !strings.HasSuffix(result.PipelineConfig, "\n") {
+		cmd.Println()
+	}
+	cmd.Println("───────────────────────────────────────────────────────────────")
+	for _, file := range result.Files {
+		cmd.Printf("📎 Also generated %s: %s\n", file.Path, file.Purpose)
+	}
+	printRevisionProblems(cmd, session.target, result)
+
+	for revision := 1; ; revision++ {
+		instruction, err := runInputInteractiveMode(TextInteractive{
+			Title:       "Refine",
+			Description: "Describe a change, press Enter on an empty line to accept, or type /discard to quit without writing.",
+			Placeholder: "e.g., add a macOS runner",
+		})
+		if err != nil {
+			return result, false, err
+		}
+		switch instruction {
+		case "":
+			if force || len(session.target.Validate(result.PipelineConfig)) == 0 {
+				return result, true, nil
+			}
+			write, err := runConfirmInteractiveMode("Write it anyway?",
+				"This version fails validation; see the problems above. Choose No to keep refining.")
+			if err != nil {
+				return result, false, err
+			}
+			if write {
+				return result, true, nil
+			}
+			revision--
+			continue
+		case "/discard", "/quit":
+			return result, false, nil
+		}
+
+		cmd.Printf("🤖 Revising (revision %d)...\n", revision)
+		revised, err := session.send("Revise the configuration you generated: " + instruction +
+			"\nReturn the complete revised result. Keep everything the instruction does not ask to change as it is.")
+		if err != nil {
+			cmd.PrintErrln("⚠️  Revision failed, keeping the previous version:", err)
+			revision--
+			continue
+		}
+
+		diff := unifiedDiff(fmt.Sprintf("revision %d", revision-1), fmt.Sprintf("revision %d", revision), result.PipelineConfig, revised.PipelineConfig)
+		diff += filesDiff(revision, result.Files, revised.Files)
+		if diff == "" {
+			cmd.Println("ℹ️  The configuration did not change")
+		} else {
+			cmd.Println("───────────────────────────────────────────────────────────────")
+			cmd.Print(colorDiff(cmd.OutOrStdout(), diff))
+			cmd.Println("───────────────────────────────────────────────────────────────")
+		}
+		result = revised
+		printRevisionProblems(cmd, session.target, result)
+	}
+}
+
+// filesDiff shows how the additional files changed between revisions
+func filesDiff(revision int, before, after []GeneratedFile) string {
+	previous := map[string]string{}
+	for _, file := range before {
+		previous[file.Path] = file.Content
+	}
+	var out strings.Builder
+	for _, file := range after {
+		out.WriteString(unifiedDiff(fmt.Sprintf("%s (revision %d)", file.Path, revision-1), fmt.Sprintf("%s (revision %d)", file.Path, revision), previous[file.Path], file.Content))
+		delete(previous, file.Path)
+	}
+	for _, file := range before {
+		if _, removed := previous[file.Path]; removed {
+			out.WriteString(unifiedDiff(fmt.Sprintf("%s (revision %d)", file.Path, revision-1), fmt.Sprintf("%s (revision %d)", file.Path, revision), file.Content, ""))
+		}
+	}
+	return out.String()
+}
+
+// printRevisionProblems reports validation and pipeline check problems in a revision
+func printRevisionProblems(cmd *cobra.Command, target PipelineTarget, result GenerateResult) {
+	problems := target.Validate(result.PipelineConfig)
+	if result.Pipeline != nil {
+		problems = append(problems, LintPipeline(*result.Pipeline)...)
+	}
+	for _, file := range result.Files {
+		for _, problem := range validateGeneratedFile(file) {
+			problems = append(problems, file.Path+": "+problem)
+		}
+	}
+	if len(problems) == 0 {
+		return
+	}
+	cmd.PrintErrln("⚠️  This version has problems:")
+	for _, problem := range problems {
+		cmd.PrintErrln("   - " + problem)
+	}
+}
@@ -0,0 +1,259 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ReleaseInfo describes how the project already cuts releases
+type ReleaseInfo struct {
+	Tools               []ReleaseTool `json:"tools,omitempty"`             // Release tooling configured in the repository
+	ConventionalCommits bool          `json:"conventional_commits"`        // Whether commit messages follow Conventional Commits
+	CommitConvention    string        `json:"commit_convention,omitempty"` // What revealed the convention, e.g. "commitlint.config.js"
+}
+
+// ReleaseTool is a release automation tool the project is configured for
+type ReleaseTool struct {
+	Name        string   `json:"name,omitempty"`        // e.g., "GoReleaser", "semantic-release"
+	ConfigFile  string   `json:"config_file,omitempty"` // File that revealed the tool
+	Action      string   `json:"action,omitempty"`      // Action or command a release workflow should run
+	Trigger     string   `json:"trigger,omitempty"`     // What should trigger the release workflow
+	Secrets     []string `json:"secrets,omitempty"`     // Repository secrets the release job needs
+	Permissions []string `json:"permissions,omitempty"` // Workflow permissions the release job needs
+}
+
+// releaseRule detects one release tool from its config files
+type releaseRule struct {
+	Name        string
+	Files       []string
+	Match       func(content string) bool // Optional: the config file must also contain this
+	Action      string
+	Trigger     string
+	Secrets     []string
+	Permissions []string
+}
+
+// Registry of release tools
+var releaseRules = []releaseRule{
+	{
+		Name:        "GoReleaser",
+		Files:       []string{".goreleaser.yml", ".goreleaser.yaml", "goreleaser.yml", "goreleaser.yaml"},
+		Action:      "goreleaser/goreleaser-action@v6 with args: release --clean (checkout with fetch-depth: 0)",
+		Trigger:     "push of version tags",
+		Permissions: []string{"contents: write"},
+	},
+	{
+		Name:        "semantic-release",
+		Files:       []string{".releaserc", ".releaserc.json", ".releaserc.yml", ".releaserc.yaml", ".releaserc.js", ".releaserc.cjs", "release.config.js", "release.config.cjs", "release.config.mjs"},
+		Action:      "npx semantic-release (checkout with fetch-depth: 0)",
+		Trigger:     "push to the default branch",
+		Secrets:     []string{"NPM_TOKEN"},
+		Permissions: []string{"contents: write", "issues: write", "pull-requests: write", "id-token: write"},
+	},
+	{
+		Name:        "Changesets",
+		Files:       []string{".changeset/config.json"},
+		Action:      "changesets/action@v1 with publish: <package manager> run release",
+		Trigger:     "push to the default branch",
+		Secrets:     []string{"NPM_TOKEN"},
+		Permissions: []string{"contents: write", "pull-requests: write"},
+	},
+	{
+		Name:        "release-please",
+		Files:       []string{"release-please-config.json", ".release-please-manifest.json"},
+		Action:      "googleapis/release-please-action@v4",
+		Trigger:     "push to the default branch",
+		Permissions: []string{"contents: write", "pull-requests: write"},
+	},
+	{
+		Name:  "cargo-release",
+		Files: []string{"release.toml", "Cargo.toml"},
+		// release.toml is cargo-release's own file; Cargo.toml only counts with a [*.metadata.release] table
+		Match: func(content string) bool {
+			isManifest := strings.Contains(content, "[package") || strings.Contains(content, "[workspace")
+			return !isManifest || strings.Contains(content, "metadata.release]")
+		},
+		Action:  "cargo install cargo-release, then cargo release --execute --no-confirm",
+		Trigger: "manual (workflow_dispatch) or push of version tags",
+		Secrets: []string{"CARGO_REGISTRY_TOKEN"},
+	},
+}
+
+// pythonBuildBackends maps PEP 517 build backends to a readable name
+var pythonBuildBackends = map[string]string{
+	"hatchling.build":                  "Hatch",
+	"setuptools.build_meta":            "setuptools",
+	"poetry.core.masonry.api":          "Poetry",
+	"flit_core.buildapi":               "Flit",
+	"pdm.backend":                      "PDM",
+	"maturin":                          "maturin",
+	"scikit_build_core.build":          "scikit-build-core",
+	"mesonpy":                          "meson-python",
+	"setuptools.build_meta:__legacy__": "setuptools",
+}
+
+// commitConventionFiles reveal that commits follow Conventional Commits
+var commitConventionFiles = []string{
+	"commitlint.config.js", "commitlint.config.cjs", "commitlint.config.mjs", "commitlint.config.ts",
+	".commitlintrc", ".commitlintrc.json", ".commitlintrc.yml", ".commitlintrc.yaml", ".commitlintrc.js",
+	".czrc", ".cz.toml", ".cz.json", ".versionrc", ".versionrc.json",
+}
+
+var buildBackendPattern = regexp.MustCompile(`build-backend\s*=\s*"([^"]+)"`)
+
+// DetectRelease finds the release tooling and commit conventions the project uses;
+// it stops early when ctx is cancelled
+func DetectRelease(ctx context.Context, workingDir string) ReleaseInfo {
+	info := ReleaseInfo{}
+
+	for _, rule := range releaseRules {
+		if ctx.Err() != nil {
+			return info // The caller reports the cancellation
+		}
+		for _, file := range rule.Files {
+			content, ok := readFileString(filepath.Join(workingDir, file))
+			if !ok || (rule.Match != nil && !rule.Match(content)) {
+				continue
+			}
+			info.Tools = append(info.Tools, ReleaseTool{
+				Name:        rule.Name,
+				ConfigFile:  file,
+				Action:      rule.Action,
+				Trigger:     rule.Trigger,
+				Secrets:     rule.Secrets,
+				Permissions: rule.Permissions,
+			})
+			break
+		}
+	}
+
+	// package.json: semantic-release config, npm publishing and commitizen
+	if data, err := os.ReadFile(filepath.Join(workingDir, "package.json")); err == nil {
+		var pkg struct {
+			Private         bool                       `json:"private"`
+			Release         json.RawMessage            `json:"release"`
+			PublishConfig   map[string]json.RawMessage `json:"publishConfig"`
+			DevDependencies map[string]string          `json:"devDependencies"`
+			Config          struct {
+				Commitizen json.RawMessage `json:"commitizen"`
+			} `json:"config"`
+		}
+		if json.Unmarshal(data, &pkg) == nil {
+			_, hasSemanticRelease := pkg.DevDependencies["semantic-release"]
+			if (pkg.Release != nil || hasSemanticRelease) && findReleaseTool(info.Tools, "semantic-release") < 0 {
+				rule := releaseRuleFor("semantic-release")
+				info.Tools = append(info.Tools, ReleaseTool{
+					Name: rule.Name, ConfigFile: "package.json", Action: rule.Action,
+					Trigger: rule.Trigger, Secrets: rule.Secrets, Permissions: rule.Permissions,
+				})
+			}
+			if pkg.PublishConfig != nil && !pkg.Private {
+				registry := "npm registry"
+				if raw, ok := pkg.PublishConfig["registry"]; ok {
+					registry = strings.Trim(string(raw), "\"")
+				}
+				info.Tools = append(info.Tools, ReleaseTool{
+					Name:        "npm publish",
+					ConfigFile:  "package.json (publishConfig)",
+					Action:      fmt.Sprintf("actions/setup-node@v4 with registry-url, then npm publish --provenance to %s (NODE_AUTH_TOKEN from NPM_TOKEN)", registry),
+					Trigger:     "push of version tags or a published GitHub release",
+					Secrets:     []string{"NPM_TOKEN"},
+					Permissions: []string{"contents: read", "id-token: write"},
+				})
+			}
+			if pkg.Config.Commitizen != nil {
+				info.ConventionalCommits = true
+				info.CommitConvention = "package.json (config.commitizen)"
+			}
+			if _, ok := pkg.DevDependencies["@commitlint/cli"]; ok && !info.ConventionalCommits {
+				info.ConventionalCommits = true
+				info.CommitConvention = "package.json (@commitlint/cli)"
+			}
+		}
+	}
+
+	// pyproject.toml: the PEP 517 build backend decides how distributions are built
+	if content, ok := readFileString(filepath.Join(workingDir, "pyproject.toml")); ok {
+		if m := buildBackendPattern.FindStringSubmatch(content); m != nil {
+			backend := m[1]
+			if name, known := pythonBuildBackends[backend]; known {
+				backend = name
+			}
+			info.Tools = append(info.Tools, ReleaseTool{
+				Name:        fmt.Sprintf("PyPI (%s build backend)", backend),
+				ConfigFile:  "pyproject.toml",
+				Action:      "python -m build, then pypa/gh-action-pypi-publish@release/v1 using trusted publishing",
+				Trigger:     "a published GitHub release or push of version tags",
+				Permissions: []string{"contents: read", "id-token: write"},
+			})
+		}
+		if strings.Contains(content, "[tool.commitizen]") && !info.ConventionalCommits {
+			info.ConventionalCommits = true
+			info.CommitConvention = "pyproject.toml ([tool.commitizen])"
+		}
+	}
+
+	if !info.ConventionalCommits {
+		for _, file := range commitConventionFiles {
+			if _, err := os.Stat(filepath.Join(workingDir, file)); err == nil {
+				info.ConventionalCommits = true
+				info.CommitConvention = file
+				break
+			}
+		}
+	}
+	if !info.ConventionalCommits {
+		// semantic-release and release-please derive versions from Conventional Commits
+		for _, name := range []string{"semantic-release", "release-please"} {
+			if i := findReleaseTool(info.Tools, name); i >= 0 {
+				info.ConventionalCommits = true
+				info.CommitConvention = fmt.Sprintf("implied by %s", name)
+				break
+			}
+		}
+	}
+
+	return info
+}
+
+func releaseRuleFor(name string) releaseRule {
+	for _, rule := range releaseRules {
+		if rule.Name == name {
+			return rule
+		}
+	}
+	return releaseRule{Name: name}
+}
+
+func findReleaseTool(tools []ReleaseTool, name string) int {
+	for i, tool := range tools {
+		if tool.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// FormatRelease summarises the release setup for prompts
+func (r ReleaseInfo) FormatRelease() []string {
+	var lines []string
+	for _, tool := range r.Tools {
+		line := fmt.Sprintf("%s (%s): run %s on %s", tool.Name, tool.ConfigFile, tool.Action, tool.Trigger)
+		if len(tool.Secrets) > 0 {
+			line += fmt.Sprintf("; secrets: %s", strings.Join(tool.Secrets, ", "))
+		}
+		if len(tool.Permissions) > 0 {
+			line += fmt.Sprintf("; permissions: %s", strings.Join(tool.Permissions, ", "))
+		}
+		lines = append(lines, line)
+	}
+	if r.ConventionalCommits {
+		lines = append(lines, fmt.Sprintf("Conventional Commits in use (%s)", r.CommitConvention))
+	}
+	return lines
+}
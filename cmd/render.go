@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var renderCmd = &cobra.Command{
+	Use:   "render <pipeline.json>",
+	Short: "Render a platform-neutral pipeline for a CI/CD platform",
+	Long: `Render a pipeline saved by "fluxion generate --ir" for any supported platform.
+
+Rendering is deterministic and needs no API key, so one pipeline description can be turned
+into GitHub Actions, GitLab CI, CircleCI, Azure Pipelines and Bitbucket Pipelines configs
+that run the same jobs. Constructs a platform cannot express are kept as TODO(fluxion) comments.`,
+	Args:          cobra.ExactArgs(1),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          renderPipeline,
+}
+
+func init() {
+	rootCmd.AddCommand(renderCmd)
+
+	renderCmd.Flags().StringP("target", "t", "", "CI/CD platform to render for: "+strings.Join(pipelineTargetNames(), ", ")+" (default: github)")
+	renderCmd.Flags().StringP("output", "o", "", "Output path for the configuration file (default depends on --target, e.g. .gitlab-ci.yml for gitlab)")
+	renderCmd.Flags().Bool("force", false, "Overwrite an existing file without asking")
+}
+
+func renderPipeline(cmd *cobra.Command, args []string) error {
+	name, _ := cmd.Flags().GetString("target")
+	output, _ := cmd.Flags().GetString("output")
+	force, _ := cmd.Flags().GetBool("force")
+
+	target, err := findPipelineTarget(name)
+	if err != nil {
+		return err
+	}
+	if output == "" {
+		output = target.DefaultOutput
+	}
+
+	pipeline, err := loadPipelineJSON(args[0])
+	if err != nil {
+		return err
+	}
+	lint := LintPipeline(pipeline)
+	config, err := target.Render(pipeline)
+	if err != nil {
+		return err
+	}
+
+	outputPath, _ := filepath.Abs(output)
+	if err := safeWrite(cmd, outputPath, config, force); err != nil {
+		return err
+	}
+	problems := target.Validate(config)
+
+	cmd.Println("\n" + "═══════════════════════════════════════════════════════════════")
+	cmd.Printf("✨ Rendered %s\n", target.Artifact)
+	cmd.Println("═══════════════════════════════════════════════════════════════")
+	cmd.Println()
+	cmd.Printf("📋 %d job(s) from %s\n", len(pipeline.Jobs), args[0])
+	if len(pipeline.Secrets) > 0 {
+		cmd.Println("\n🔑 Configure these secrets:")
+		for _, secret := range pipeline.Secrets {
+			cmd.Println("   - " + secret)
+		}
+	}
+	if todos := strings.Count(config, "TODO(fluxion)"); todos > 0 {
+		cmd.Printf("\n📝 %d construct(s) could not be rendered for %s; search for TODO(fluxion)\n", todos, target.Name)
+	}
+	cmd.Println()
+	cmd.Println("───────────────────────────────────────────────────────────────")
+	cmd.Printf("✅ Configuration saved to: %s\n", outputPath)
+	cmd.Println("───────────────────────────────────────────────────────────────")
+
+	if len(lint) > 0 {
+		cmd.PrintErrln("\n⚠️  Pipeline checks found problems; review them before committing:")
+		for _, problem := range lint {
+			cmd.PrintErrln("   - " + problem)
+		}
+	}
+	if len(problems) > 0 {
+		cmd.PrintErrf("\n⚠️  The rendered %s failed validation; review it before committing:\n", target.Artifact)
+		for _, problem := range problems {
+			cmd.PrintErrln("   - " + problem)
+		}
+	}
+	return nil
+}
+
+// loadPipelineJSON reads a pipeline saved by writePipelineJSON
+func loadPipelineJSON(filePath string) (Pipeline, error) {
+	content, err := loadFile(filePath)
+	if err != nil {
+		return Pipeline{}, err
+	}
+	var pipeline Pipeline
+	if err := json.Unmarshal([]byte(content), &pipeline); err != nil {
+		return Pipeline{}, fmt.Errorf("failed to parse pipeline %s: %w", filePath, err)
+	}
+	return pipeline, nil
+}
+
+// writePipelineJSON saves a pipeline as indented JSON with safeWrite
+func writePipelineJSON(cmd *cobra.Command, filePath string, pipeline Pipeline, force bool) error {
+	content, err := json.MarshalIndent(pipeline, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode pipeline: %w", err)
+	}
+	return safeWrite(cmd, filePath, string(content)+"\n", force)
+}
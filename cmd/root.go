@@ -16,6 +16,9 @@ var rootCmd = &cobra.Command{
 func init() {
 	// Hide completion command from help but keep functionality
 	rootCmd.CompletionOptions.HiddenDefaultCmd = true
+
+	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "Show detection diagnostics: detector timings, failures and files that could not be parsed")
+	rootCmd.PersistentFlags().BoolVar(&allowPlugins, "allow-plugins", false, "Run detector plugins that .fluxion.yml lists by path; only use on repositories you trust")
 }
 
 func Execute() {
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// errWriteDeclined is returned by safeWrite when the user keeps the existing file
+var errWriteDeclined = errors.New("kept the existing file; nothing was written")
+
+// safeWrite writes generated content to path without silently losing what is
+// there. A new file is simply written. An existing file that differs is shown
+// as a diff and, without force, replaced only when the user confirms; when no
+// one can be asked, safeWrite refuses. The previous version is kept as path.bak.
+// Every write is atomic.
+func safeWrite(cmd *cobra.Command, path, content string, force bool) error {
+	existing, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return writeFile(path, content)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read existing file: %w", err)
+	}
+	if string(existing) == content {
+		cmd.Printf("ℹ️  %s is unchanged\n", path)
+		return nil
+	}
+
+	if !force {
+		cmd.Printf("\n📝 %s already exists; writing would change it:\n", path)
+		cmd.Println("───────────────────────────────────────────────────────────────")
+		cmd.Print(colorDiff(cmd.OutOrStdout(), unifiedDiff(path, path+" (new)", string(existing), content)))
+		cmd.Println("───────────────────────────────────────────────────────────────")
+		if !isTerminal(os.Stdin) {
+			return fmt.Errorf("%s already exists; use --force to overwrite it", path)
+		}
+		ok, err := runConfirmInteractiveMode("Overwrite "+path+"?", "The previous version is kept as "+path+".bak.")
+		if err != nil {
+			return fmt.Errorf("%s already exists; use --force to overwrite it (%w)", path, err)
+		}
+		if !ok {
+			return errWriteDeclined
+		}
+	}
+
+	if err := writeFile(path+".bak", string(existing)); err != nil {
+		return fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+	if err := writeFile(path, content); err != nil {
+		return err
+	}
+	cmd.Printf("💾 Previous version saved to: %s.bak\n", path)
+	return nil
+}
+
+// isTerminal reports whether f is an interactive terminal; /dev/null is a
+// character device too, so the mode bits alone are not enough
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// colorDiff colors a unified diff for a terminal: removals red, additions
+// green, hunk headers cyan. Other writers, and NO_COLOR, get it unchanged.
+func colorDiff(w io.Writer, diff string) string {
+	f, ok := w.(*os.File)
+	if !ok || !isTerminal(f) || os.Getenv("NO_COLOR") != "" {
+		return diff
+	}
+	var out strings.Builder
+	lines := splitLines(diff)
+	header := false
+	for i, line := range lines {
+		// File names: a --- line followed by a +++ line, and that +++ line
+		header = strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ") ||
+			header && strings.HasPrefix(line, "+++ ")
+		color := ""
+		switch {
+		case header:
+			color = "\033[1m"
+		case strings.HasPrefix(line, "@@"):
+			color = "\033[36m"
+		case strings.HasPrefix(line, "-"):
+			color = "\033[31m"
+		case strings.HasPrefix(line, "+"):
+			color = "\033[32m"
+		}
+		if color == "" {
+			out.WriteString(line + "\n")
+		} else {
+			out.WriteString(color + line + "\033[0m\n")
+		}
+	}
+	return out.String()
+}
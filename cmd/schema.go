@@ -1,5 +1,7 @@
 package cmd
 
+import "sort"
+
 var debugSchema = map[string]interface{}{
 	"type": "object",
 	"properties": map[string]interface{}{
@@ -20,33 +22,209 @@ var debugSchema = map[string]interface{}{
 	"additionalProperties": false,
 }
 
-var generateSchema = map[string]interface{}{
+// generateSchema describes GenerateResult; artifact names what pipeline_config holds,
+// e.g. "GitHub Actions workflow"
+func generateSchema(artifact string) map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"pipeline_config": map[string]interface{}{
+				"type":        "string",
+				"description": "The complete " + artifact + " YAML configuration",
+			},
+			"pipeline_description": map[string]interface{}{
+				"type":        "string",
+				"description": "A brief description of the generated pipeline",
+			},
+			"assumptions": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Any assumptions made while generating the pipeline",
+			},
+			"requirements": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Key requirements that the pipeline fulfills",
+			},
+			"next_steps": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Recommended next steps after generating the pipeline",
+			},
+			"files": schemaArray(schemaObject(map[string]interface{}{
+				"path":    schemaString("Path from the repository root, e.g. .github/workflows/release.yml"),
+				"content": schemaString("The complete file content"),
+				"purpose": schemaString("One line saying what the file is for"),
+			}), "Files besides the main configuration, as ADDITIONAL FILES in the request describes; empty when none are needed"),
+		},
+		"required":             []string{"pipeline_config", "pipeline_description", "assumptions", "requirements", "next_steps", "files"},
+		"additionalProperties": false,
+	}
+}
+
+var migrateSchema = map[string]interface{}{
 	"type": "object",
 	"properties": map[string]interface{}{
-		"pipeline_config": map[string]interface{}{
-			"type":        "string",
-			"description": "The complete GitHub Actions workflow YAML configuration",
-		},
-		"pipeline_description": map[string]interface{}{
-			"type":        "string",
-			"description": "A brief description of the generated pipeline",
-		},
-		"assumptions": map[string]interface{}{
-			"type":        "array",
-			"items":       map[string]interface{}{"type": "string"},
-			"description": "Any assumptions made while generating the pipeline",
+		"translations": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"id": map[string]interface{}{
+						"type":        "string",
+						"description": "The ID of the TODO step",
+					},
+					"steps": map[string]interface{}{
+						"type":        "string",
+						"description": "YAML sequence of GitHub Actions steps replacing the TODO step; empty to keep the TODO",
+					},
+				},
+				"required":             []string{"id", "steps"},
+				"additionalProperties": false,
+			},
+			"description": "One translation per TODO step",
 		},
-		"requirements": map[string]interface{}{
+		"notes": map[string]interface{}{
 			"type":        "array",
 			"items":       map[string]interface{}{"type": "string"},
-			"description": "Key requirements that the pipeline fulfills",
-		},
-		"next_steps": map[string]interface{}{
-			"type":        "array",
-			"items":       map[string]interface{}{"type": "string"},
-			"description": "Recommended next steps after generating the pipeline",
+			"description": "What was translated, and what the user must set up",
 		},
 	},
-	"required":             []string{"pipeline_config", "pipeline_description", "assumptions", "requirements", "next_steps"},
+	"required":             []string{"translations", "notes"},
 	"additionalProperties": false,
 }
+
+// editSchema describes EditResult: a patch to the configuration, or a full replacement
+func editSchema() map[string]interface{} {
+	return schemaObject(map[string]interface{}{
+		"mode": schemaEnum("patch to change individual entries with operations, replace to return the whole configuration", "patch", "replace"),
+		"operations": schemaArray(schemaObject(map[string]interface{}{
+			"op":    schemaEnum("set a key or item, insert an item into a sequence, or delete a key or item", "set", "insert", "delete"),
+			"path":  schemaStrings("Mapping keys and 0-based sequence indexes from the top of the document, e.g. [\"jobs\", \"test\", \"steps\", \"2\"]"),
+			"value": schemaString("The new value as YAML, e.g. \"ubuntu-latest\" or \"name: Test\\nrun: go test ./...\"; empty for delete"),
+		}), "The changes, applied in order; empty when mode is replace"),
+		"config":  schemaString("The complete edited configuration when mode is replace; empty when mode is patch"),
+		"summary": schemaString("One sentence describing the change"),
+		"notes":   schemaStrings("Anything the user must do or check, e.g. secrets to create"),
+	})
+}
+
+// Builders for strict JSON schemas: every property is required and no others are allowed
+
+func schemaString(description string) map[string]interface{} {
+	return map[string]interface{}{"type": "string", "description": description}
+}
+
+func schemaEnum(description string, values ...string) map[string]interface{} {
+	return map[string]interface{}{"type": "string", "enum": values, "description": description}
+}
+
+func schemaBool(description string) map[string]interface{} {
+	return map[string]interface{}{"type": "boolean", "description": description}
+}
+
+func schemaInt(description string) map[string]interface{} {
+	return map[string]interface{}{"type": "integer", "description": description}
+}
+
+func schemaArray(items map[string]interface{}, description string) map[string]interface{} {
+	return map[string]interface{}{"type": "array", "items": items, "description": description}
+}
+
+func schemaStrings(description string) map[string]interface{} {
+	return schemaArray(map[string]interface{}{"type": "string"}, description)
+}
+
+func schemaObject(properties map[string]interface{}) map[string]interface{} {
+	required := make([]string, 0, len(properties))
+	for name := range properties {
+		required = append(required, name)
+	}
+	sort.Strings(required)
+	return map[string]interface{}{"type": "object", "properties": properties, "required": required, "additionalProperties": false}
+}
+
+// pipelineSchema describes the platform-neutral Pipeline the model fills in for generate
+func pipelineSchema() map[string]interface{} {
+	vars := func(description string) map[string]interface{} {
+		return schemaArray(schemaObject(map[string]interface{}{
+			"name":  schemaString("Variable name"),
+			"value": schemaString("Literal value, or ${{ secrets.NAME }} / ${{ matrix.AXIS }}"),
+		}), description)
+	}
+	condition := schemaObject(map[string]interface{}{
+		"default_branch": schemaBool("Run on the repository's default branch"),
+		"branches":       schemaStrings("Branch name patterns to run on"),
+		"tags":           schemaStrings(`Tag patterns to run on, "*" for any tag`),
+		"events":         schemaArray(schemaEnum("Event", "push", "pull_request", "schedule", "manual"), "Events to run on"),
+	})
+	step := schemaObject(map[string]interface{}{
+		"kind": schemaEnum("run: shell commands; checkout: the repository; setup: a language runtime; cache: restore and save paths; "+
+			"upload/download: pass files between jobs as an artifact; action: a GitHub Action with no shell equivalent",
+			StepRun, StepCheckout, StepSetup, StepCache, StepUpload, StepDownload, StepAction),
+		"name":              schemaString("Step name, empty for checkout and setup"),
+		"run":               schemaString("run: the shell commands, one per line; action: optional commands other platforms run instead"),
+		"runtime":           schemaEnum("setup: the runtime; empty for other kinds", "", "go", "node", "python", "java", "ruby"),
+		"version":           schemaString("setup: the runtime version, e.g. 1.22, 20, 3.12"),
+		"key":               schemaString("cache: key, e.g. go-${{ hashFiles('go.sum') }}"),
+		"paths":             schemaStrings("cache, upload: the paths; download: the directory to download into"),
+		"artifact":          schemaString("upload, download: the artifact name"),
+		"uses":              schemaString("action: owner/repo@version"),
+		"with":              vars("action: the inputs"),
+		"when":              schemaEnum("When the step runs", "success", "failure", "always"),
+		"env":               vars("Variables for this step only"),
+		"working_directory": schemaString("Directory to run in, relative to the repository root; empty for the root"),
+		"continue_on_error": schemaBool("A failure does not fail the job"),
+	})
+	job := schemaObject(map[string]interface{}{
+		"id":      schemaString("Job ID: lower-case letters, digits and -"),
+		"name":    schemaString("Display name"),
+		"runs_on": schemaStrings("Runner labels; empty for the default Linux runner"),
+		"image":   schemaString("Container image to run the steps in; usually empty, with setup steps instead"),
+		"needs":   schemaStrings("IDs of the jobs that must finish first"),
+		"if": map[string]interface{}{
+			"anyOf":       []interface{}{condition, map[string]interface{}{"type": "null"}},
+			"description": "When the job runs; null to run whenever the pipeline runs",
+		},
+		"matrix": schemaArray(schemaObject(map[string]interface{}{
+			"name":   schemaString("Axis name, referenced as ${{ matrix.NAME }}"),
+			"values": schemaStrings("Axis values"),
+		}), "Runs the job once per combination of values"),
+		"env": vars("Variables for the job's steps"),
+		"services": schemaArray(schemaObject(map[string]interface{}{
+			"name":  schemaString("Hostname, e.g. postgres"),
+			"image": schemaString("Image with a version tag"),
+			"env":   vars("Variables the container needs to start"),
+			"ports": schemaStrings(`Port mappings, e.g. "5432:5432"`),
+		}), "Service containers the job's tests need"),
+		"steps":             schemaArray(step, "The steps, in order"),
+		"environment":       schemaString("Deployment environment; empty if the job does not deploy"),
+		"approval":          schemaBool("Wait for a manual approval before running"),
+		"allow_failure":     schemaBool("A failure does not fail the pipeline"),
+		"timeout_minutes":   schemaInt("Job timeout; 0 for the platform default"),
+		"working_directory": schemaString("Default directory for run steps; empty for the repository root"),
+	})
+	return schemaObject(map[string]interface{}{
+		"name": schemaString("Pipeline name"),
+		"triggers": schemaObject(map[string]interface{}{
+			"push_branches": schemaStrings(`Branch patterns that run the pipeline on push; "*" for all`),
+			"tags":          schemaStrings(`Tag patterns that run the pipeline on push; "*" for all`),
+			"pull_requests": schemaBool("Run for pull requests"),
+			"schedules":     schemaStrings("Cron expressions, UTC"),
+			"manual":        schemaBool("Can be started by hand"),
+		}),
+		"env":     vars("Variables for every job"),
+		"secrets": schemaStrings("Names of every secret the jobs read"),
+		"jobs":    schemaArray(job, "The jobs"),
+	})
+}
+
+// generatePipelineSchema describes a GenerateResult whose configuration is a Pipeline
+func generatePipelineSchema() map[string]interface{} {
+	schema := generateSchema("pipeline")
+	properties := schema["properties"].(map[string]interface{})
+	delete(properties, "pipeline_config")
+	properties["pipeline"] = pipelineSchema()
+	schema["required"] = []string{"pipeline", "pipeline_description", "assumptions", "requirements", "next_steps", "files"}
+	return schema
+}
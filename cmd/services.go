@@ -0,0 +1,373 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ServiceRequirement is a backing service the project needs running in CI
+type ServiceRequirement struct {
+	Name          string            `json:"name,omitempty"`           // Service container name, e.g. "postgres"
+	Image         string            `json:"image,omitempty"`          // Container image including version, e.g. "postgres:16"
+	Ports         []string          `json:"ports,omitempty"`          // Port mappings, e.g. ["5432:5432"]
+	HealthCheck   string            `json:"health_check,omitempty"`   // Command used as the container health check
+	Env           map[string]string `json:"env,omitempty"`            // Environment the container needs to start
+	ConnectionURL string            `json:"connection_url,omitempty"` // How the application reaches the service from the job
+	Reason        string            `json:"reason,omitempty"`         // Why the service was inferred
+}
+
+// serviceDefinition describes how to run a known backing service in CI
+type serviceDefinition struct {
+	Name        string
+	Image       string
+	Ports       []string
+	HealthCheck string
+	Env         map[string]string
+	Packages    []string // Client libraries that imply the service
+	Images      []string // Image names that identify the service in compose files
+
+	// URL builds the connection URL from the container's environment (env
+	// returns fallback for unset keys, as the image's own defaults) and the
+	// host and port the job reaches the container on
+	URL func(env func(key, fallback string) string, host, port string) string
+}
+
+// Catalog of backing services Fluxion knows how to run as service containers
+//
+// Packages are matched against the dependency names detected by the language
+// detectors: either exactly ("pg", "redis") or as a module path segment
+// ("lib/pq" matches "github.com/lib/pq").
+var serviceCatalog = []serviceDefinition{
+	{
+		Name:        "postgres",
+		Image:       "postgres:16",
+		Ports:       []string{"5432:5432"},
+		HealthCheck: "pg_isready -U postgres",
+		Env:         map[string]string{"POSTGRES_USER": "postgres", "POSTGRES_PASSWORD": "postgres", "POSTGRES_DB": "test"},
+		Packages:    []string{"lib/pq", "jackc/pgx", "pg", "postgres", "pg-promise", "psycopg", "psycopg2", "psycopg2-binary", "asyncpg"},
+		Images:      []string{"postgres", "cimg/postgres", "postgis/postgis", "timescale/timescaledb"},
+		URL: func(env func(key, fallback string) string, host, port string) string {
+			user := env("POSTGRES_USER", "postgres")
+			return fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable", user, env("POSTGRES_PASSWORD", ""), host, port, env("POSTGRES_DB", user))
+		},
+	},
+	{
+		Name:        "mysql",
+		Image:       "mysql:8.0",
+		Ports:       []string{"3306:3306"},
+		HealthCheck: "mysqladmin ping -h localhost",
+		Env:         map[string]string{"MYSQL_ROOT_PASSWORD": "root", "MYSQL_DATABASE": "test"},
+		Packages:    []string{"go-sql-driver/mysql", "mysql", "mysql2", "pymysql", "mysqlclient", "mysql-connector-python", "aiomysql"},
+		Images:      []string{"mysql", "mariadb", "cimg/mysql", "cimg/mariadb"},
+		URL: func(env func(key, fallback string) string, host, port string) string {
+			user, password := "root", env("MYSQL_ROOT_PASSWORD", "")
+			if env("MYSQL_USER", "") != "" {
+				user, password = env("MYSQL_USER", ""), env("MYSQL_PASSWORD", "")
+			}
+			if host == "localhost" {
+				host = "127.0.0.1" // MySQL clients take localhost to mean the Unix socket
+			}
+			return fmt.Sprintf("mysql://%s:%s@%s:%s/%s", user, password, host, port, env("MYSQL_DATABASE", ""))
+		},
+	},
+	{
+		Name:        "redis",
+		Image:       "redis:7",
+		Ports:       []string{"6379:6379"},
+		HealthCheck: "redis-cli ping",
+		Packages:    []string{"go-redis/redis", "redis/go-redis", "gomodule/redigo", "redis", "ioredis", "aioredis"},
+		Images:      []string{"redis", "cimg/redis", "valkey/valkey", "redis/redis-stack"},
+		URL: func(env func(key, fallback string) string, host, port string) string {
+			return "redis://" + host + ":" + port
+		},
+	},
+	{
+		Name:        "mongodb",
+		Image:       "mongo:7",
+		Ports:       []string{"27017:27017"},
+		HealthCheck: "mongosh --quiet --eval \"db.adminCommand('ping')\"",
+		Packages:    []string{"mongo-driver", "mongodb", "mongoose", "pymongo", "motor", "mongoengine"},
+		Images:      []string{"mongo"},
+		URL: func(env func(key, fallback string) string, host, port string) string {
+			if user := env("MONGO_INITDB_ROOT_USERNAME", ""); user != "" {
+				return fmt.Sprintf("mongodb://%s:%s@%s:%s/test?authSource=admin", user, env("MONGO_INITDB_ROOT_PASSWORD", ""), host, port)
+			}
+			return "mongodb://" + host + ":" + port + "/test"
+		},
+	},
+	{
+		Name:        "kafka",
+		Image:       "apache/kafka:3.7.0",
+		Ports:       []string{"9092:9092"},
+		HealthCheck: "/opt/kafka/bin/kafka-broker-api-versions.sh --bootstrap-server localhost:9092",
+		// Single-node KRaft broker, no ZooKeeper required
+		Env: map[string]string{
+			"KAFKA_NODE_ID":                          "1",
+			"KAFKA_PROCESS_ROLES":                    "broker,controller",
+			"KAFKA_LISTENERS":                        "PLAINTEXT://:9092,CONTROLLER://:9093",
+			"KAFKA_ADVERTISED_LISTENERS":             "PLAINTEXT://localhost:9092",
+			"KAFKA_CONTROLLER_LISTENER_NAMES":        "CONTROLLER",
+			"KAFKA_CONTROLLER_QUORUM_VOTERS":         "1@localhost:9093",
+			"KAFKA_OFFSETS_TOPIC_REPLICATION_FACTOR": "1",
+		},
+		Packages: []string{"segmentio/kafka-go", "confluent-kafka-go", "sarama", "kafkajs", "kafka-python", "confluent-kafka", "aiokafka"},
+		Images:   []string{"apache/kafka", "bitnami/kafka", "confluentinc/cp-kafka"},
+		URL: func(env func(key, fallback string) string, host, port string) string {
+			return host + ":" + port
+		},
+	},
+	{
+		Name:        "rabbitmq",
+		Image:       "rabbitmq:3-management",
+		Ports:       []string{"5672:5672"},
+		HealthCheck: "rabbitmq-diagnostics -q ping",
+		Packages:    []string{"streadway/amqp", "rabbitmq/amqp091-go", "amqplib", "amqp-connection-manager", "pika", "aio-pika"},
+		Images:      []string{"rabbitmq"},
+		URL: func(env func(key, fallback string) string, host, port string) string {
+			return fmt.Sprintf("amqp://%s:%s@%s:%s/", env("RABBITMQ_DEFAULT_USER", "guest"), env("RABBITMQ_DEFAULT_PASS", "guest"), host, port)
+		},
+	},
+}
+
+// matchesPackage reports whether a detected dependency is the given client library
+func matchesPackage(dependency, pkg string) bool {
+	dependency = strings.ToLower(dependency)
+	return dependency == pkg ||
+		strings.HasSuffix(dependency, "/"+pkg) ||
+		strings.Contains(dependency, "/"+pkg+"/")
+}
+
+// imageName strips the registry-less tag from an image reference
+func imageName(image string) (name string, tag string) {
+	if at := strings.Index(image, "@"); at >= 0 {
+		image = image[:at]
+	}
+	if colon := strings.LastIndex(image, ":"); colon > strings.LastIndex(image, "/") {
+		return image[:colon], image[colon+1:]
+	}
+	return image, ""
+}
+
+// InferServices works out which backing services CI needs from the detected
+// dependencies and the services declared in the project's compose file.
+// Compose declarations win: they carry the image version the team already runs.
+// It stops early when ctx is cancelled.
+func InferServices(ctx context.Context, workingDir string, packages []string) []ServiceRequirement {
+	found := map[string]ServiceRequirement{}
+
+	if name := findComposeFile(workingDir); name != "" {
+		if compose, err := parseComposeFile(filepath.Join(workingDir, name)); err == nil {
+			for _, svcName := range compose.serviceNames() {
+				svc := compose.Services[svcName]
+				if svc.Image == "" {
+					continue // Built from source: that's the application, not a backing service
+				}
+				def, ok := serviceForImage(svc.Image)
+				if !ok {
+					continue
+				}
+				req := def.requirement(fmt.Sprintf("declared as service %q in %s", svcName, name))
+				req.Image = svc.Image
+				if len(svc.Ports) > 0 {
+					req.Ports = svc.Ports
+				}
+				if len(svc.Environment) > 0 {
+					req.Env = svc.Environment
+				}
+				if svc.Healthcheck.Test != "" {
+					req.HealthCheck = string(svc.Healthcheck.Test)
+				}
+				req.ConnectionURL = def.connectionURL(req.Env, req.Ports)
+				found[def.Name] = req
+			}
+		}
+	}
+
+	for _, def := range serviceCatalog {
+		if ctx.Err() != nil {
+			break // The caller reports the cancellation
+		}
+		if _, ok := found[def.Name]; ok {
+			continue
+		}
+		for _, dep := range packages {
+			if pkg := def.matchPackage(dep); pkg != "" {
+				found[def.Name] = def.requirement(fmt.Sprintf("client library %s", dep))
+				break
+			}
+		}
+	}
+
+	services := make([]ServiceRequirement, 0, len(found))
+	for _, svc := range found {
+		services = append(services, svc)
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+	return services
+}
+
+func serviceForImage(image string) (serviceDefinition, bool) {
+	name, _ := imageName(image)
+	name = strings.TrimPrefix(name, "docker.io/")
+	name = strings.TrimPrefix(name, "library/")
+	for _, def := range serviceCatalog {
+		for _, candidate := range def.Images {
+			if name == candidate {
+				return def, true
+			}
+		}
+	}
+	return serviceDefinition{}, false
+}
+
+func (def serviceDefinition) matchPackage(dependency string) string {
+	for _, pkg := range def.Packages {
+		if matchesPackage(dependency, pkg) {
+			return pkg
+		}
+	}
+	return ""
+}
+
+func (def serviceDefinition) requirement(reason string) ServiceRequirement {
+	env := map[string]string{}
+	for k, v := range def.Env {
+		env[k] = v
+	}
+	return ServiceRequirement{
+		Name:          def.Name,
+		Image:         def.Image,
+		Ports:         append([]string(nil), def.Ports...),
+		HealthCheck:   def.HealthCheck,
+		Env:           env,
+		ConnectionURL: def.connectionURL(env, def.Ports),
+		Reason:        reason,
+	}
+}
+
+// connectionURL is the URL of the service run with env and published on ports,
+// as the job reaches it on the runner
+func (def serviceDefinition) connectionURL(env map[string]string, ports []string) string {
+	return def.url(env, "localhost", hostPort(ports, containerPort(def.Ports[0])))
+}
+
+// aliasURL is the URL of the service run with env, as a job reaches it by its alias
+// on a shared network: the container's own port, whatever it is published on
+func (def serviceDefinition) aliasURL(env map[string]string, alias string) string {
+	return def.url(env, alias, containerPort(def.Ports[0]))
+}
+
+func (def serviceDefinition) url(env map[string]string, host, port string) string {
+	lookup := func(key, fallback string) string {
+		if value, ok := env[key]; ok {
+			return value
+		}
+		return fallback
+	}
+	return def.URL(lookup, host, port)
+}
+
+// containerPort is the container side of a port mapping such as "5432:5432/tcp"
+func containerPort(mapping string) string {
+	mapping, _, _ = strings.Cut(mapping, "/")
+	return mapping[strings.LastIndex(mapping, ":")+1:]
+}
+
+// hostPort finds the host port a container port is published on, e.g. 5433 for
+// "5433:5432" or "127.0.0.1:5433:5432"; an unpublished port keeps its number
+func hostPort(ports []string, container string) string {
+	for _, mapping := range ports {
+		mapping, _, _ = strings.Cut(mapping, "/")
+		parts := strings.Split(mapping, ":")
+		if len(parts) < 2 || parts[len(parts)-1] != container {
+			continue
+		}
+		if host := parts[len(parts)-2]; host != "" && strings.Trim(host, "0123456789") == "" {
+			return host
+		}
+	}
+	return container
+}
+
+// completeService fills the fields a hand-written service declaration left out
+// from the catalog entry with the same name
+func completeService(svc ServiceRequirement, reason string) ServiceRequirement {
+	if svc.Reason == "" {
+		svc.Reason = reason
+	}
+	for _, def := range serviceCatalog {
+		if def.Name != svc.Name {
+			continue
+		}
+		defaults := def.requirement(reason)
+		if svc.Image == "" {
+			svc.Image = defaults.Image
+		}
+		if len(svc.Ports) == 0 {
+			svc.Ports = defaults.Ports
+		}
+		if svc.HealthCheck == "" {
+			svc.HealthCheck = defaults.HealthCheck
+		}
+		if len(svc.Env) == 0 {
+			svc.Env = defaults.Env
+		}
+		if svc.ConnectionURL == "" {
+			svc.ConnectionURL = def.connectionURL(svc.Env, svc.Ports)
+		}
+	}
+	return svc
+}
+
+// serviceAliasURL is the URL of a service reached by its name. Services outside the
+// catalog keep their connection URL with the local host replaced.
+func serviceAliasURL(svc ServiceRequirement) string {
+	for _, def := range serviceCatalog {
+		if def.Name == svc.Name {
+			return def.aliasURL(svc.Env, svc.Name)
+		}
+	}
+	return strings.NewReplacer("localhost", svc.Name, "127.0.0.1", svc.Name).Replace(svc.ConnectionURL)
+}
+
+// FormatServicesYAML renders services as a GitHub Actions job-level services: block
+func FormatServicesYAML(services []ServiceRequirement) string {
+	if len(services) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("services:\n")
+	for _, svc := range services {
+		fmt.Fprintf(&b, "  %s:\n", svc.Name)
+		fmt.Fprintf(&b, "    image: %s\n", svc.Image)
+		if len(svc.Env) > 0 {
+			b.WriteString("    env:\n")
+			keys := make([]string, 0, len(svc.Env))
+			for k := range svc.Env {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				fmt.Fprintf(&b, "      %s: %q\n", k, svc.Env[k])
+			}
+		}
+		if len(svc.Ports) > 0 {
+			b.WriteString("    ports:\n")
+			for _, port := range svc.Ports {
+				fmt.Fprintf(&b, "      - %q\n", port)
+			}
+		}
+		if svc.HealthCheck != "" {
+			b.WriteString("    options: >-\n")
+			fmt.Fprintf(&b, "      --health-cmd %q\n", svc.HealthCheck)
+			b.WriteString("      --health-interval 10s\n")
+			b.WriteString("      --health-timeout 5s\n")
+			b.WriteString("      --health-retries 10\n")
+		}
+	}
+	return b.String()
+}
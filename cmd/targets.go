@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PipelineTarget is a CI/CD platform generate can write configuration for
+type PipelineTarget struct {
+	Name          string // --target value, e.g. "gitlab"
+	Artifact      string // What is generated, e.g. "GitLab CI pipeline (.gitlab-ci.yml)"
+	DefaultOutput string // Output path when neither --output nor .fluxion.yml sets one
+	SystemPrompt  string
+
+	// Services renders the SERVICE CONTAINERS block for the project's services in the platform's syntax
+	Services func(ctx ProjectContext) string
+
+	// ServicesByAlias is true when jobs reach services by name rather than on localhost
+	ServicesByAlias bool
+
+	// AdditionalFiles are examples of the files the platform's configuration can be split into,
+	// for the ADDITIONAL FILES prompt; empty when it lives in a single file
+	AdditionalFiles string
+
+	// Validate returns the problems found in a generated configuration
+	Validate func(config string) []string
+
+	// Render writes a platform-neutral pipeline in the platform's syntax
+	Render func(p Pipeline) (string, error)
+}
+
+// Registry of pipeline targets
+//
+// To add a platform, write its system prompt, services renderer, validator
+// and emitter, and add it here. The first target is the default.
+var pipelineTargets = []PipelineTarget{
+	{
+		Name:            "github",
+		Artifact:        "GitHub Actions workflow",
+		DefaultOutput:   "./generated_pipeline.yml",
+		SystemPrompt:    generateSystemPrompt,
+		Services:        func(ctx ProjectContext) string { return FormatServicesYAML(ctx.Services) },
+		AdditionalFiles: "a separate release workflow, a reusable workflow, .github/dependabot.yml or a composite action",
+		Validate:        validateGitHubWorkflow,
+		Render:          RenderGitHubActions,
+	},
+	{
+		Name:            "gitlab",
+		Artifact:        "GitLab CI pipeline (.gitlab-ci.yml)",
+		DefaultOutput:   ".gitlab-ci.yml",
+		SystemPrompt:    gitlabGenerateSystemPrompt,
+		Services:        func(ctx ProjectContext) string { return FormatGitLabServicesYAML(ctx.Services) },
+		ServicesByAlias: true,
+		AdditionalFiles: "a job template in another file, such as .gitlab/ci/release.yml, that .gitlab-ci.yml includes with include: local",
+		Validate:        validateGitLabCI,
+		Render:          RenderGitLabCI,
+	},
+	{
+		Name:          "circleci",
+		Artifact:      "CircleCI pipeline (.circleci/config.yml)",
+		DefaultOutput: ".circleci/config.yml",
+		SystemPrompt:  circleciGenerateSystemPrompt,
+		Services: func(ctx ProjectContext) string {
+			return FormatCircleCIServicesYAML(ctx.Services, circleciPrimaryImage(ctx))
+		},
+		Validate: validateCircleCI,
+		Render:   RenderCircleCI,
+	},
+	{
+		Name:            "azure",
+		Artifact:        "Azure Pipelines pipeline (azure-pipelines.yml)",
+		DefaultOutput:   "azure-pipelines.yml",
+		SystemPrompt:    azureGenerateSystemPrompt,
+		Services:        func(ctx ProjectContext) string { return FormatAzureServicesYAML(ctx.Services) },
+		AdditionalFiles: "a steps or jobs template, such as templates/build-steps.yml, that azure-pipelines.yml uses with template:",
+		Validate:        validateAzurePipelines,
+		Render:          RenderAzurePipelines,
+	},
+	{
+		Name:          "bitbucket",
+		Artifact:      "Bitbucket Pipelines pipeline (bitbucket-pipelines.yml)",
+		DefaultOutput: "bitbucket-pipelines.yml",
+		SystemPrompt:  bitbucketGenerateSystemPrompt,
+		Services:      func(ctx ProjectContext) string { return FormatBitbucketServicesYAML(ctx.Services) },
+		Validate:      validateBitbucketPipelines,
+		Render:        RenderBitbucketPipelines,
+	},
+}
+
+// findPipelineTarget looks up a generate target by name; an empty name is the default target
+func findPipelineTarget(name string) (PipelineTarget, error) {
+	if name == "" {
+		return pipelineTargets[0], nil
+	}
+	for _, target := range pipelineTargets {
+		if strings.EqualFold(target.Name, name) {
+			return target, nil
+		}
+	}
+	return PipelineTarget{}, fmt.Errorf("unknown target %q (supported: %s)", name, strings.Join(pipelineTargetNames(), ", "))
+}
+
+// targetForFile picks the target whose configuration a file is, by its path;
+// anything else, such as a file in .github/workflows, is the default target
+func targetForFile(path string) PipelineTarget {
+	path = filepath.ToSlash(path)
+	for _, target := range pipelineTargets[1:] {
+		if path == target.DefaultOutput || strings.HasSuffix(path, "/"+target.DefaultOutput) {
+			return target
+		}
+	}
+	return pipelineTargets[0]
+}
+
+// pipelineTargetNames lists the supported --target values
+func pipelineTargetNames() []string {
+	names := make([]string, 0, len(pipelineTargets))
+	for _, target := range pipelineTargets {
+		names = append(names, target.Name)
+	}
+	return names
+}
+
+// parseYAMLMapping parses a generated configuration whose top level must be a mapping
+func parseYAMLMapping(config string) (*yaml.Node, []string) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(config), &doc); err != nil {
+		return nil, []string{fmt.Sprintf("invalid YAML: %v", err)}
+	}
+	if len(doc.Content) == 0 {
+		return nil, []string{"configuration is empty"}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, []string{"top level must be a mapping"}
+	}
+	return root, nil
+}
+
+// mappingValue returns the value of key in a mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	node = deref(node)
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return deref(node.Content[i+1])
+		}
+	}
+	return nil
+}
+
+// mappingKeys returns the keys of a mapping node in order
+func mappingKeys(node *yaml.Node) []string {
+	node = deref(node)
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	keys := make([]string, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keys = append(keys, node.Content[i].Value)
+	}
+	return keys
+}
+
+// deref follows a YAML alias (*anchor) to the node it refers to
+func deref(node *yaml.Node) *yaml.Node {
+	for node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
+
+// validateGitHubWorkflow checks the structure GitHub requires of a workflow file
+func validateGitHubWorkflow(config string) []string {
+	root, problems := parseYAMLMapping(config)
+	if root == nil {
+		return problems
+	}
+	if mappingValue(root, "on") == nil {
+		problems = append(problems, `missing "on" triggers`)
+	}
+	jobs := mappingValue(root, "jobs")
+	if jobs == nil || jobs.Kind != yaml.MappingNode || len(jobs.Content) == 0 {
+		return append(problems, `missing "jobs"`)
+	}
+	for i := 0; i+1 < len(jobs.Content); i += 2 {
+		id, job := jobs.Content[i].Value, jobs.Content[i+1]
+		if job.Kind != yaml.MappingNode {
+			problems = append(problems, fmt.Sprintf("job %q must be a mapping", id))
+			continue
+		}
+		if mappingValue(job, "uses") != nil {
+			continue // Reusable workflow call
+		}
+		if mappingValue(job, "runs-on") == nil {
+			problems = append(problems, fmt.Sprintf(`job %q has no "runs-on"`, id))
+		}
+		if steps := mappingValue(job, "steps"); steps == nil || steps.Kind != yaml.SequenceNode || len(steps.Content) == 0 {
+			problems = append(problems, fmt.Sprintf(`job %q has no "steps"`, id))
+		}
+	}
+	return problems
+}
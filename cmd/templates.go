@@ -0,0 +1,555 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// pipelineTemplate is a built-in pipeline for a common kind of project.
+// Templates build a Pipeline from the project context alone, so
+// generate --offline gives the same output every time without an API call.
+type pipelineTemplate struct {
+	Name        string // e.g. "go"
+	Description string
+	Matches     func(ctx ProjectContext) bool
+
+	// Addon templates add jobs to the pipeline a language template started
+	Addon bool
+
+	// Apply adds the template's jobs to p and returns notes for the summary
+	Apply func(p *Pipeline, ctx ProjectContext) templateNotes
+}
+
+// templateNotes are what a template tells the user about the pipeline it built
+type templateNotes struct {
+	Assumptions  []string
+	Requirements []string
+	NextSteps    []string
+}
+
+func (n *templateNotes) add(other templateNotes) {
+	n.Assumptions = append(n.Assumptions, other.Assumptions...)
+	n.Requirements = append(n.Requirements, other.Requirements...)
+	n.NextSteps = append(n.NextSteps, other.NextSteps...)
+}
+
+// Registry of built-in templates
+//
+// The first language template that matches starts the pipeline; every
+// matching addon then adds its jobs.
+var pipelineTemplates = []pipelineTemplate{
+	{
+		Name:        "go",
+		Description: "Go: vet, test and build every binary; GoReleaser on version tags",
+		Matches:     func(ctx ProjectContext) bool { return ctx.PrimaryLang == "Go" },
+		Apply:       applyGoTemplate,
+	},
+	{
+		Name:        "node",
+		Description: "Node.js: install with the lockfile, test and build; npm publish or semantic-release",
+		Matches:     func(ctx ProjectContext) bool { return ctx.PrimaryLang == "JavaScript/TypeScript" },
+		Apply:       applyNodeTemplate,
+	},
+	{
+		Name:        "python",
+		Description: "Python package: test on supported versions; build and upload to PyPI on version tags",
+		Matches:     func(ctx ProjectContext) bool { return ctx.PrimaryLang == "Python" },
+		Apply:       applyPythonTemplate,
+	},
+	{
+		Name:        "docker",
+		Description: "Docker: build every image; push to the registry from the default branch and version tags",
+		Matches:     func(ctx ProjectContext) bool { return len(ctx.Docker.Parsed()) > 0 },
+		Addon:       true,
+		Apply:       applyDockerTemplate,
+	},
+}
+
+// matchingTemplates lists the templates that apply to a project, in the order they are applied
+func matchingTemplates(ctx ProjectContext) []pipelineTemplate {
+	var matched []pipelineTemplate
+	language := false
+	for _, template := range pipelineTemplates {
+		if !template.Matches(ctx) || (!template.Addon && language) {
+			continue
+		}
+		language = language || !template.Addon
+		matched = append(matched, template)
+	}
+	return matched
+}
+
+// generateFromTemplates builds the pipeline for a project from the built-in templates
+func generateFromTemplates(ctx ProjectContext) (GenerateResult, error) {
+	templates := matchingTemplates(ctx)
+	if len(templates) == 0 {
+		return GenerateResult{}, fmt.Errorf("no built-in template matches this project (templates: %s); run without --offline", strings.Join(templateNames(), ", "))
+	}
+
+	p := Pipeline{
+		Name: "CI",
+		Triggers: PipelineTriggers{
+			PushBranches: []string{defaultBranch(ctx)},
+			PullRequests: true,
+			Manual:       true,
+		},
+	}
+	var notes templateNotes
+	var names []string
+	for _, template := range templates {
+		notes.add(template.Apply(&p, ctx))
+		names = append(names, template.Name)
+	}
+	notes.add(applyConventions(&p, ctx.Conventions))
+
+	// Tag pipelines only run for the jobs that release, so they need one
+	var jobNames []string
+	for _, job := range p.Jobs {
+		jobNames = append(jobNames, job.Name)
+		if job.If != nil && len(job.If.Tags) > 0 {
+			p.Triggers.Tags = []string{versionTagPattern(ctx)}
+		}
+	}
+	p.Secrets = referencedSecrets(p)
+	if ctx.Git.DefaultBranch == "" {
+		notes.Assumptions = append(notes.Assumptions, "The default branch is main")
+	}
+	if len(p.Secrets) > 0 {
+		notes.Requirements = append(notes.Requirements, "Secrets: "+strings.Join(p.Secrets, ", "))
+	}
+
+	return GenerateResult{
+		PipelineDescription: fmt.Sprintf("Built from Fluxion's %s template(s): %s", strings.Join(names, " and "), strings.Join(jobNames, ", ")),
+		Assumptions:         notes.Assumptions,
+		Requirements:        notes.Requirements,
+		NextSteps:           notes.NextSteps,
+		Pipeline:            &p,
+	}, nil
+}
+
+// templateNames lists the built-in templates
+func templateNames() []string {
+	names := make([]string, 0, len(pipelineTemplates))
+	for _, template := range pipelineTemplates {
+		names = append(names, template.Name)
+	}
+	return names
+}
+
+// defaultBranch is the repository's default branch, or main when it is unknown
+func defaultBranch(ctx ProjectContext) string {
+	if ctx.Git.DefaultBranch != "" {
+		return ctx.Git.DefaultBranch
+	}
+	return "main"
+}
+
+// versionTagPattern matches the project's release tags
+func versionTagPattern(ctx ProjectContext) string {
+	if ctx.Git.TagStyle == "v-prefixed semver" || ctx.Git.TagStyle == "" {
+		return "v*"
+	}
+	return "*"
+}
+
+// releaseCondition runs a job only for the project's version tags
+func releaseCondition(ctx ProjectContext) *PipelineCondition {
+	return &PipelineCondition{Tags: []string{versionTagPattern(ctx)}}
+}
+
+// runtimeVersion is the detected version of a runtime, or fallback
+func runtimeVersion(ctx ProjectContext, runtime, fallback string) (string, bool) {
+	if version := ctx.RuntimeVersions[runtime]; version != "" {
+		return version, true
+	}
+	return fallback, false
+}
+
+// conventionSecret is the secret name the organisation uses for purpose, or fallback
+func conventionSecret(ctx ProjectContext, purpose, fallback string) string {
+	if name := ctx.Conventions.Secrets[purpose]; name != "" {
+		return name
+	}
+	return fallback
+}
+
+// hasReleaseTool reports whether the project is configured for a release tool whose name starts with prefix
+func hasReleaseTool(ctx ProjectContext, prefix string) bool {
+	for _, tool := range ctx.Release.Tools {
+		if strings.HasPrefix(tool.Name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// testJobs runs the unit tests and, when they are split out, the integration
+// tests in their own job with the project's service containers
+func testJobs(ctx ProjectContext, setup []PipelineStep, command, integration string) []PipelineJob {
+	services := make([]PipelineService, 0, len(ctx.Services))
+	for _, svc := range ctx.Services {
+		services = append(services, PipelineService{Name: svc.Name, Image: svc.Image, Env: varsFromMap(svc.Env), Ports: svc.Ports})
+	}
+
+	test := PipelineJob{ID: "test", Name: "Test", Steps: append(append([]PipelineStep(nil), setup...),
+		PipelineStep{Kind: StepRun, Name: "Test", Run: command})}
+	if ctx.Tests.CoverageReport != "" {
+		test.Steps = append(test.Steps, PipelineStep{Kind: StepUpload, Name: "Upload coverage", Artifact: "coverage",
+			Paths: []string{ctx.Tests.CoverageReport}, When: "always"})
+	}
+	if integration == "" {
+		test.Services = services
+		return []PipelineJob{test}
+	}
+	integrationJob := PipelineJob{ID: "integration", Name: "Integration tests", Needs: []string{"test"}, Services: services,
+		Steps: append(append([]PipelineStep(nil), setup...),
+			PipelineStep{Kind: StepRun, Name: "Integration tests", Run: integration})}
+	return []PipelineJob{test, integrationJob}
+}
+
+// serviceNotes tells the user to wire the service containers' connection details
+func serviceNotes(ctx ProjectContext) templateNotes {
+	var notes templateNotes
+	for _, svc := range ctx.Services {
+		notes.NextSteps = append(notes.NextSteps, fmt.Sprintf("Pass the %s connection (%s) to the tests through env", svc.Name, svc.ConnectionURL))
+	}
+	return notes
+}
+
+// goreleaserVersion pins GoReleaser, so a release does not change with its latest version
+const goreleaserVersion = "v2.4.8"
+
+func applyGoTemplate(p *Pipeline, ctx ProjectContext) templateNotes {
+	var notes templateNotes
+	version, ok := runtimeVersion(ctx, "go", "stable")
+	if !ok {
+		notes.Assumptions = append(notes.Assumptions, "No go directive was found, so the latest stable Go is used")
+	}
+	setup := []PipelineStep{{Kind: StepCheckout}, {Kind: StepSetup, Runtime: "go", Version: version}}
+	info := ctx.Go
+	if info == nil {
+		info = &GoModuleInfo{}
+	}
+	if !info.Vendored {
+		setup = append(setup, PipelineStep{Kind: StepCache, Name: "Cache Go modules",
+			Key: "go-${{ hashFiles('go.sum') }}", Paths: []string{"~/go/pkg/mod", "~/.cache/go-build"}})
+	}
+
+	command := ctx.TestCommand
+	if ctx.Tests.CoverageCommand != "" {
+		command = ctx.Tests.CoverageCommand
+	}
+	if command == "" {
+		command = "go test ./..."
+	}
+	checks := "go vet ./..."
+	if len(info.Generate) > 0 {
+		checks = "go generate ./...\ngit diff --exit-code\n" + checks
+	}
+	jobs := testJobs(ctx, setup, checks+"\n"+command, ctx.Tests.IntegrationCommand)
+
+	var builds []string
+	for _, binary := range info.Binaries {
+		builds = append(builds, binary.BuildCommand)
+	}
+	if len(builds) == 0 {
+		builds = []string{"go build ./..."}
+	}
+	build := PipelineJob{ID: "build", Name: "Build", Needs: []string{"test"},
+		Steps: append(append([]PipelineStep(nil), setup...), PipelineStep{Kind: StepRun, Name: "Build", Run: strings.Join(builds, "\n")})}
+	if len(info.Cgo) > 0 {
+		build.Env = []PipelineVar{{Name: "CGO_ENABLED", Value: "1"}}
+	}
+	if len(info.Binaries) > 0 {
+		build.Steps = append(build.Steps, PipelineStep{Kind: StepUpload, Name: "Upload binaries", Artifact: "binaries", Paths: []string{"bin/"}})
+	}
+	jobs = append(jobs, build)
+
+	if hasReleaseTool(ctx, "GoReleaser") {
+		jobs = append(jobs, PipelineJob{ID: "release", Name: "Release", Needs: []string{"build"}, If: releaseCondition(ctx),
+			Env: []PipelineVar{{Name: "GITHUB_TOKEN", Value: "${{ secrets.GITHUB_TOKEN }}"}},
+			Steps: append(append([]PipelineStep(nil), setup...),
+				PipelineStep{Kind: StepRun, Name: "Fetch tags", Run: "git fetch --force --tags --unshallow || git fetch --force --tags"},
+				PipelineStep{Kind: StepAction, Name: "GoReleaser", Uses: "goreleaser/goreleaser-action@v6",
+					With: []PipelineVar{{Name: "distribution", Value: "goreleaser"}, {Name: "version", Value: goreleaserVersion}, {Name: "args", Value: "release --clean"}},
+					Run:  "go run github.com/goreleaser/goreleaser/v2@" + goreleaserVersion + " release --clean"})})
+		notes.Requirements = append(notes.Requirements, "GoReleaser publishes to the release page with GITHUB_TOKEN; on other platforms set the token GoReleaser expects there")
+	}
+	if len(info.PrivateModules) > 0 && !info.PrivateDeclared {
+		p.Env = append(p.Env, PipelineVar{Name: "GOPRIVATE", Value: strings.Join(info.PrivateModules, ",")})
+		notes.NextSteps = append(notes.NextSteps, "Give CI read access to the private modules, e.g. with a git credential for "+strings.Join(info.PrivateModules, ", "))
+	}
+
+	p.Jobs = append(p.Jobs, jobs...)
+	notes.add(serviceNotes(ctx))
+	return notes
+}
+
+// nodeInstall is how each package manager installs dependencies from the lockfile, and what to cache
+var nodeInstall = map[string]struct {
+	install, lockfile, cache string
+}{
+	"npm":  {"npm ci", "package-lock.json", "~/.npm"},
+	"yarn": {"yarn install --frozen-lockfile", "yarn.lock", "~/.cache/yarn"},
+	"pnpm": {"corepack enable\npnpm install --frozen-lockfile", "pnpm-lock.yaml", "~/.local/share/pnpm/store"},
+}
+
+func applyNodeTemplate(p *Pipeline, ctx ProjectContext) templateNotes {
+	var notes templateNotes
+	version, ok := runtimeVersion(ctx, "node", "22")
+	if !ok {
+		notes.Assumptions = append(notes.Assumptions, "No Node.js version was pinned, so Node.js 22 (LTS) is used")
+	}
+	manager, known := nodeInstall[ctx.PackageManager]
+	if !known {
+		manager = nodeInstall["npm"]
+	}
+	setup := []PipelineStep{
+		{Kind: StepCheckout},
+		{Kind: StepSetup, Runtime: "node", Version: version},
+		{Kind: StepCache, Name: "Cache dependencies", Key: "node-${{ hashFiles('" + manager.lockfile + "') }}", Paths: []string{manager.cache}},
+		{Kind: StepRun, Name: "Install dependencies", Run: manager.install},
+	}
+
+	command := ctx.TestCommand
+	if ctx.Tests.CoverageCommand != "" {
+		command = ctx.Tests.CoverageCommand
+	}
+	var jobs []PipelineJob
+	needs := []string{}
+	if command != "" {
+		jobs = testJobs(ctx, setup, command, ctx.Tests.IntegrationCommand)
+		needs = []string{"test"}
+	} else {
+		notes.NextSteps = append(notes.NextSteps, `Add a "test" script to package.json and a test job`)
+	}
+	if ctx.BuildCommand != "" {
+		jobs = append(jobs, PipelineJob{ID: "build", Name: "Build", Needs: needs,
+			Steps: append(append([]PipelineStep(nil), setup...), PipelineStep{Kind: StepRun, Name: "Build", Run: ctx.BuildCommand})})
+		needs = []string{"build"}
+	}
+	if len(jobs) == 0 {
+		jobs = append(jobs, PipelineJob{ID: "install", Name: "Install",
+			Steps: append([]PipelineStep(nil), setup...)})
+		needs = []string{"install"}
+	}
+
+	switch {
+	case hasReleaseTool(ctx, "semantic-release"):
+		token := conventionSecret(ctx, "npm_token", "NPM_TOKEN")
+		jobs = append(jobs, PipelineJob{ID: "release", Name: "Release", Needs: needs, If: &PipelineCondition{DefaultBranch: true},
+			Env: []PipelineVar{{Name: "GITHUB_TOKEN", Value: "${{ secrets.GITHUB_TOKEN }}"}, {Name: "NPM_TOKEN", Value: "${{ secrets." + token + " }}"}},
+			Steps: append(append([]PipelineStep(nil), setup...),
+				PipelineStep{Kind: StepRun, Name: "Fetch history", Run: "git fetch --force --tags --unshallow || git fetch --force --tags"},
+				PipelineStep{Kind: StepRun, Name: "semantic-release", Run: "npx semantic-release"})})
+	case hasReleaseTool(ctx, "npm publish"):
+		token := conventionSecret(ctx, "npm_token", "NPM_TOKEN")
+		jobs = append(jobs, PipelineJob{ID: "publish", Name: "Publish", Needs: needs, If: releaseCondition(ctx),
+			Env: []PipelineVar{{Name: "NODE_AUTH_TOKEN", Value: "${{ secrets." + token + " }}"}},
+			Steps: append(append([]PipelineStep(nil), setup...),
+				PipelineStep{Kind: StepRun, Name: "Publish", Run: `echo "//registry.npmjs.org/:_authToken=${NODE_AUTH_TOKEN}" > ~/.npmrc` + "\nnpm publish"})})
+	}
+
+	p.Jobs = append(p.Jobs, jobs...)
+	notes.add(serviceNotes(ctx))
+	return notes
+}
+
+// pythonInstall is how each package manager installs the project, and what runs commands in its environment
+var pythonInstall = map[string]struct {
+	install, run string
+}{
+	"pip": {"python -m pip install --upgrade pip\n" +
+		"if [ -f requirements.txt ]; then pip install -r requirements.txt; fi\n" +
+		"if [ -f pyproject.toml ] || [ -f setup.py ]; then pip install -e .; fi", ""},
+	"poetry": {"pip install poetry\npoetry install", "poetry run "},
+	"pipenv": {"pip install pipenv\npipenv install --dev", "pipenv run "},
+}
+
+func applyPythonTemplate(p *Pipeline, ctx ProjectContext) templateNotes {
+	var notes templateNotes
+	manager, known := pythonInstall[ctx.PackageManager]
+	if !known {
+		manager = pythonInstall["pip"]
+	}
+	command := ctx.TestCommand
+	if ctx.Tests.CoverageCommand != "" {
+		command = ctx.Tests.CoverageCommand
+	}
+	if command == "" {
+		command = "pytest"
+	}
+	install := manager.install
+	if manager.run == "" && strings.HasPrefix(command, "pytest") {
+		install += "\npip install pytest"
+		if strings.Contains(command, "--cov") {
+			install += " pytest-cov"
+		}
+	}
+
+	setupFor := func(version string) []PipelineStep {
+		return []PipelineStep{
+			{Kind: StepCheckout},
+			{Kind: StepSetup, Runtime: "python", Version: version},
+			{Kind: StepCache, Name: "Cache pip", Key: "pip-" + version + "-${{ hashFiles('requirements.txt', 'pyproject.toml') }}", Paths: []string{"~/.cache/pip"}},
+			{Kind: StepRun, Name: "Install dependencies", Run: install},
+		}
+	}
+
+	version, pinned := runtimeVersion(ctx, "python", "3.12")
+	testVersion := version
+	if !pinned {
+		// A package supports several Python versions: test on each
+		testVersion = "${{ matrix.python }}"
+		notes.Assumptions = append(notes.Assumptions, "No Python version was pinned, so tests run on Python 3.10 to 3.13")
+	}
+	integration := ""
+	if ctx.Tests.IntegrationCommand != "" {
+		integration = manager.run + ctx.Tests.IntegrationCommand
+	}
+	jobs := testJobs(ctx, setupFor(testVersion), manager.run+command, integration)
+	if !pinned {
+		jobs[0].Matrix = []PipelineAxis{{Name: "python", Values: []string{"3.10", "3.11", "3.12", "3.13"}}}
+		for i := range jobs[0].Steps {
+			if jobs[0].Steps[i].Kind == StepUpload {
+				jobs[0].Steps[i].Artifact = "coverage-${{ matrix.python }}"
+			}
+		}
+		if len(jobs) > 1 {
+			// Integration tests run once, on the default version
+			steps := jobs[1].Steps
+			jobs[1].Steps = append(setupFor(version), steps[len(steps)-1])
+		}
+	}
+
+	if hasReleaseTool(ctx, "PyPI") {
+		token := conventionSecret(ctx, "pypi_token", "PYPI_TOKEN")
+		jobs = append(jobs, PipelineJob{ID: "publish", Name: "Publish to PyPI", Needs: []string{"test"}, If: releaseCondition(ctx),
+			Env: []PipelineVar{{Name: "TWINE_USERNAME", Value: "__token__"}, {Name: "TWINE_PASSWORD", Value: "${{ secrets." + token + " }}"}},
+			Steps: []PipelineStep{
+				{Kind: StepCheckout},
+				{Kind: StepSetup, Runtime: "python", Version: version},
+				{Kind: StepRun, Name: "Build", Run: "python -m pip install build twine\npython -m build"},
+				{Kind: StepRun, Name: "Upload", Run: "twine upload dist/*"},
+			}})
+		notes.NextSteps = append(notes.NextSteps, "Create a PyPI API token for the project and save it as "+token)
+	}
+
+	p.Jobs = append(p.Jobs, jobs...)
+	notes.add(serviceNotes(ctx))
+	return notes
+}
+
+func applyDockerTemplate(p *Pipeline, ctx ProjectContext) templateNotes {
+	var notes templateNotes
+	registry, image := "ghcr.io", ""
+	if ctx.Git.RemoteHost == "github.com" && ctx.Git.Owner != "" && ctx.Git.Repo != "" {
+		image = strings.ToLower("ghcr.io/" + ctx.Git.Owner + "/" + ctx.Git.Repo)
+	} else {
+		registry, image = "registry.example.com", "registry.example.com/"+jobID(ctx.Git.Repo)
+		notes.NextSteps = append(notes.NextSteps, "Replace registry.example.com with your container registry")
+	}
+	username := conventionSecret(ctx, "registry_username", "REGISTRY_USERNAME")
+	password := conventionSecret(ctx, "registry_password", "REGISTRY_PASSWORD")
+
+	var builds, pushes []string
+	for _, dockerfile := range ctx.Docker.Dockerfiles {
+		if dockerfile.ParseError != "" {
+			notes.Assumptions = append(notes.Assumptions, fmt.Sprintf("%s is not built: it could not be parsed (%s)", dockerfile.Path, dockerfile.ParseError))
+			continue
+		}
+		dir := path.Dir(dockerfile.Path)
+		name := "$IMAGE"
+		if dir != "." {
+			name += "-" + jobID(path.Base(dir))
+		}
+		builds = append(builds, fmt.Sprintf(`docker build -f %s -t "%s:$GITHUB_SHA" -t "%s:$GITHUB_REF_NAME" %s`, dockerfile.Path, name, name, dir))
+		pushes = append(pushes, fmt.Sprintf(`docker push "%s:$GITHUB_SHA"`, name), fmt.Sprintf(`docker push "%s:$GITHUB_REF_NAME"`, name))
+	}
+
+	// Images are only built after the code they package has been tested
+	var needs []string
+	for _, job := range p.Jobs {
+		if job.If == nil {
+			needs = append(needs, job.ID)
+		}
+	}
+	env := []PipelineVar{{Name: "IMAGE", Value: image}}
+	p.Jobs = append(p.Jobs,
+		PipelineJob{ID: "docker", Name: "Build image", Needs: needs, Env: env, Steps: []PipelineStep{
+			{Kind: StepCheckout},
+			{Kind: StepRun, Name: "Build", Run: strings.Join(builds, "\n")},
+		}},
+		PipelineJob{ID: "docker-push", Name: "Push image", Needs: []string{"docker"},
+			If: &PipelineCondition{DefaultBranch: true, Tags: []string{versionTagPattern(ctx)}},
+			Env: append(append([]PipelineVar(nil), env...),
+				PipelineVar{Name: "REGISTRY_USERNAME", Value: "${{ secrets." + username + " }}"},
+				PipelineVar{Name: "REGISTRY_PASSWORD", Value: "${{ secrets." + password + " }}"}),
+			Steps: []PipelineStep{
+				{Kind: StepCheckout},
+				{Kind: StepRun, Name: "Log in", Run: `echo "$REGISTRY_PASSWORD" | docker login ` + registry + ` -u "$REGISTRY_USERNAME" --password-stdin`},
+				{Kind: StepRun, Name: "Build", Run: strings.Join(builds, "\n")},
+				{Kind: StepRun, Name: "Push", Run: strings.Join(pushes, "\n")},
+			}},
+	)
+	notes.NextSteps = append(notes.NextSteps, "The image jobs need a Docker daemon: on GitLab add the docker:dind service, on CircleCI setup_remote_docker")
+	return notes
+}
+
+// applyConventions puts the organisation's runner labels and required steps on every job
+func applyConventions(p *Pipeline, conventions OrgConventions) templateNotes {
+	var notes templateNotes
+	var required []PipelineStep
+	for _, step := range conventions.RequiredSteps {
+		key, value, _ := strings.Cut(step, ":")
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "uses":
+			required = append(required, PipelineStep{Kind: StepAction, Uses: value})
+		case "run":
+			required = append(required, PipelineStep{Kind: StepRun, Run: value})
+		default:
+			notes.NextSteps = append(notes.NextSteps, "Add the required step to every job: "+step)
+		}
+	}
+	for i := range p.Jobs {
+		job := &p.Jobs[i]
+		if len(conventions.RunnerLabels) > 0 {
+			job.RunsOn = conventions.RunnerLabels
+		}
+		if len(required) == 0 {
+			continue
+		}
+		// Required steps run right after checkout, before anything they are meant to guard
+		at := 0
+		if len(job.Steps) > 0 && job.Steps[0].Kind == StepCheckout {
+			at = 1
+		}
+		steps := append(append(append([]PipelineStep(nil), job.Steps[:at]...), required...), job.Steps[at:]...)
+		job.Steps = steps
+	}
+	return notes
+}
+
+// referencedSecrets lists the secrets a pipeline's env values read, other than the platform's own token
+func referencedSecrets(p Pipeline) []string {
+	secrets := map[string]bool{}
+	collect := func(vars []PipelineVar) {
+		for _, v := range vars {
+			for _, m := range expressionPattern.FindAllStringSubmatch(v.Value, -1) {
+				if ref := contextRefPattern.FindStringSubmatch(m[1]); ref != nil && ref[1] == "secrets" && ref[2] != "GITHUB_TOKEN" {
+					secrets[ref[2]] = true
+				}
+			}
+		}
+	}
+	collect(p.Env)
+	for _, job := range p.Jobs {
+		collect(job.Env)
+		for _, step := range job.Steps {
+			collect(step.Env)
+		}
+	}
+	return sortedKeys(secrets)
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGoTemplateRelease(t *testing.T) {
+	tests := []struct {
+		name            string
+		tools           []ReleaseTool
+		wantTags        []string
+		wantDescription string
+	}{
+		{
+			name:            "no release tool",
+			wantDescription: "Built from Fluxion's go template(s): Test, Build",
+		},
+		{
+			name:            "GoReleaser",
+			tools:           []ReleaseTool{{Name: "GoReleaser", ConfigFile: ".goreleaser.yml"}},
+			wantTags:        []string{"v*"},
+			wantDescription: "Built from Fluxion's go template(s): Test, Build, Release",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := ProjectContext{PrimaryLang: "Go", Release: ReleaseInfo{Tools: tt.tools}}
+			result, err := generateFromTemplates(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := result.Pipeline
+			if !slices.Equal(p.Triggers.Tags, tt.wantTags) {
+				t.Errorf("tag triggers = %v, want %v", p.Triggers.Tags, tt.wantTags)
+			}
+			if result.PipelineDescription != tt.wantDescription {
+				t.Errorf("description = %q, want %q", result.PipelineDescription, tt.wantDescription)
+			}
+			if p.job("release") == nil {
+				return
+			}
+
+			workflow, err := RenderGitHubActions(*p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(workflow, "uses: goreleaser/goreleaser-action@v6") || strings.Contains(workflow, "curl") {
+				t.Errorf("the GitHub release job should use the GoReleaser action:\n%s", workflow)
+			}
+			config, err := RenderGitLabCI(*p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(config, "go run github.com/goreleaser/goreleaser/v2@"+goreleaserVersion+" release --clean") {
+				t.Errorf("the GitLab release job should run the pinned GoReleaser:\n%s", config)
+			}
+		})
+	}
+}
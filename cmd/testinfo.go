@@ -0,0 +1,385 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/build/constraint"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// TestInfo describes how a project's tests are organised and measured
+type TestInfo struct {
+	Frameworks         []string `json:"frameworks,omitempty"`          // e.g., ["go test", "testify"], ["jest"], ["pytest"]
+	Directories        []string `json:"directories,omitempty"`         // Directories containing tests, relative to the project root
+	IntegrationTags    []string `json:"integration_tags,omitempty"`    // Build tags or markers that split integration/e2e tests from unit tests
+	IntegrationCommand string   `json:"integration_command,omitempty"` // Command that runs the integration tests, if they are split out
+	CoverageTools      []string `json:"coverage_tools,omitempty"`      // Coverage tooling already configured, e.g. "nyc", "pytest-cov"
+	CoverageConfig     []string `json:"coverage_config,omitempty"`     // Coverage config files found (e.g., .nycrc, .coveragerc)
+	CoverageCommand    string   `json:"coverage_command,omitempty"`    // Command that runs the tests with coverage enabled
+	CoverageReport     string   `json:"coverage_report,omitempty"`     // Report produced by CoverageCommand, for upload steps
+	CoverageThreshold  string   `json:"coverage_threshold,omitempty"`  // Minimum coverage already enforced by the project
+}
+
+// HasTests reports whether any test framework or test directory was found
+func (t TestInfo) HasTests() bool {
+	return len(t.Frameworks) > 0 || len(t.Directories) > 0
+}
+
+// mergeTestInfo folds the test info of a secondary language into the primary one.
+// Commands stay those of the primary language; lists are combined.
+func mergeTestInfo(primary, other TestInfo) TestInfo {
+	primary.Frameworks = appendUnique(primary.Frameworks, other.Frameworks...)
+	primary.Directories = appendUnique(primary.Directories, other.Directories...)
+	primary.IntegrationTags = appendUnique(primary.IntegrationTags, other.IntegrationTags...)
+	primary.CoverageTools = appendUnique(primary.CoverageTools, other.CoverageTools...)
+	primary.CoverageConfig = appendUnique(primary.CoverageConfig, other.CoverageConfig...)
+	return primary
+}
+
+var thresholdPattern = regexp.MustCompile(`(?i)(fail_under|fail-under|cov-fail-under|lines|total|target)["']?\s*[:=]\s*["']?(\d+(\.\d+)?)`)
+
+// findThreshold returns the first coverage threshold declared in content
+func findThreshold(content string) string {
+	if m := thresholdPattern.FindStringSubmatch(content); m != nil {
+		return m[2] + "%"
+	}
+	return ""
+}
+
+// =============================================================================
+// Go
+// =============================================================================
+
+// platformTags are build constraints that select a platform rather than a test suite
+var platformTags = map[string]bool{
+	"linux": true, "darwin": true, "windows": true, "freebsd": true, "netbsd": true,
+	"openbsd": true, "dragonfly": true, "solaris": true, "illumos": true, "aix": true,
+	"plan9": true, "android": true, "ios": true, "js": true, "wasip1": true, "unix": true,
+	"amd64": true, "arm64": true, "386": true, "arm": true, "wasm": true, "ppc64": true,
+	"ppc64le": true, "s390x": true, "riscv64": true, "mips": true, "mipsle": true,
+	"mips64": true, "mips64le": true, "loong64": true, "cgo": true, "gc": true,
+	"gccgo": true, "ignore": true,
+}
+
+// detectGoTests finds the Go test packages and their build tags, stopping
+// early with what it found so far when ctx is cancelled
+func detectGoTests(ctx context.Context, workingDir string, files *FileIndex, goMod string) TestInfo {
+	info := TestInfo{}
+	dirs := map[string]bool{}
+	tags := map[string]bool{}
+
+	nested := goNestedModules(files)
+	for _, rel := range files.Find(func(rel string) bool {
+		return strings.HasSuffix(rel, "_test.go") && !underDir(rel, "testdata") && !inGoModule(rel, nested)
+	}) {
+		if ctx.Err() != nil {
+			return info
+		}
+		dirs[path.Dir(rel)] = true
+		for _, tag := range goBuildTags(files.Abs(rel)) {
+			if !platformTags[tag] && !strings.HasPrefix(tag, "go1.") {
+				tags[tag] = true
+			}
+		}
+	}
+
+	if len(dirs) == 0 {
+		return info
+	}
+
+	info.Frameworks = []string{"go test"}
+	if strings.Contains(goMod, "github.com/stretchr/testify") {
+		info.Frameworks = append(info.Frameworks, "testify")
+	}
+	if strings.Contains(goMod, "github.com/onsi/ginkgo") {
+		info.Frameworks = append(info.Frameworks, "ginkgo")
+	}
+	info.Directories = sortedKeys(dirs)
+	info.IntegrationTags = sortedKeys(tags)
+	if len(info.IntegrationTags) > 0 {
+		info.IntegrationCommand = fmt.Sprintf("go test -tags=%s ./...", strings.Join(info.IntegrationTags, ","))
+	}
+
+	// Coverage is built into the go tool; record whether the project already uses it
+	info.CoverageCommand = "go test -coverprofile=coverage.out ./..."
+	info.CoverageReport = "coverage.out"
+	for _, script := range []string{"Makefile", "Taskfile.yml", "magefile.go", "scripts/test.sh"} {
+		if content, ok := readFileString(filepath.Join(workingDir, script)); ok && strings.Contains(content, "-coverprofile") {
+			info.CoverageTools = appendUnique(info.CoverageTools, "go cover (-coverprofile)")
+		}
+	}
+	for _, cfg := range []string{".testcoverage.yml", ".testcoverage.yaml"} {
+		if content, ok := readFileString(filepath.Join(workingDir, cfg)); ok {
+			info.CoverageTools = appendUnique(info.CoverageTools, "go-test-coverage")
+			info.CoverageConfig = append(info.CoverageConfig, cfg)
+			info.CoverageThreshold = findThreshold(content)
+		}
+	}
+	detectCodecovConfig(workingDir, &info)
+
+	return info
+}
+
+// goBuildTags returns the tags referenced by the //go:build line of a Go file
+func goBuildTags(path string) []string {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var tags []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+		if !constraint.IsGoBuild(line) {
+			continue
+		}
+		expr, err := constraint.Parse(line)
+		if err != nil {
+			continue
+		}
+		expr.Eval(func(tag string) bool {
+			tags = append(tags, tag)
+			return true
+		})
+	}
+	return tags
+}
+
+// =============================================================================
+// Node.js
+// =============================================================================
+
+// packageJSON holds the parts of package.json that test detection needs
+type packageJSON struct {
+	Scripts         map[string]string          `json:"scripts"`
+	Dependencies    map[string]string          `json:"dependencies"`
+	DevDependencies map[string]string          `json:"devDependencies"`
+	Jest            map[string]json.RawMessage `json:"jest"`
+	Nyc             map[string]json.RawMessage `json:"nyc"`
+	C8              map[string]json.RawMessage `json:"c8"`
+}
+
+func (p packageJSON) hasDependency(name string) bool {
+	_, dep := p.Dependencies[name]
+	_, dev := p.DevDependencies[name]
+	return dep || dev
+}
+
+func detectNodeTests(workingDir string, pkg packageJSON, packageManager string) TestInfo {
+	info := TestInfo{}
+
+	testScript := pkg.Scripts["test"]
+	for _, framework := range []string{"jest", "vitest", "mocha"} {
+		if pkg.hasDependency(framework) || strings.Contains(testScript, framework) {
+			info.Frameworks = append(info.Frameworks, framework)
+		}
+	}
+
+	for _, dir := range []string{"__tests__", "test", "tests", "spec", "e2e", "src/__tests__"} {
+		if fi, err := os.Stat(filepath.Join(workingDir, dir)); err == nil && fi.IsDir() {
+			info.Directories = append(info.Directories, dir)
+		}
+	}
+
+	// Integration/e2e suites are conventionally separate scripts
+	for _, script := range []string{"test:integration", "test:e2e", "integration", "e2e"} {
+		if _, ok := pkg.Scripts[script]; ok {
+			info.IntegrationTags = append(info.IntegrationTags, script)
+			if info.IntegrationCommand == "" {
+				info.IntegrationCommand = fmt.Sprintf("%s run %s", packageManager, script)
+			}
+		}
+	}
+
+	// Coverage tooling
+	run := packageManager + " test"
+	switch {
+	case pkg.hasDependency("nyc") || strings.Contains(testScript, "nyc"):
+		info.CoverageTools = append(info.CoverageTools, "nyc")
+		info.CoverageCommand = "npx nyc --reporter=lcov " + run
+		if raw, ok := pkg.Nyc["lines"]; ok {
+			info.CoverageThreshold = strings.Trim(string(raw), "\"") + "%"
+		}
+	case pkg.hasDependency("c8") || strings.Contains(testScript, "c8"):
+		info.CoverageTools = append(info.CoverageTools, "c8")
+		info.CoverageCommand = "npx c8 --reporter=lcov " + run
+		if raw, ok := pkg.C8["lines"]; ok {
+			info.CoverageThreshold = strings.Trim(string(raw), "\"") + "%"
+		}
+	case pkg.hasDependency("@vitest/coverage-v8") || pkg.hasDependency("@vitest/coverage-istanbul"):
+		info.CoverageTools = append(info.CoverageTools, "vitest coverage")
+		info.CoverageCommand = "npx vitest run --coverage"
+	case slices.Contains(info.Frameworks, "jest"):
+		info.CoverageTools = append(info.CoverageTools, "jest --coverage")
+		info.CoverageCommand = run + " -- --coverage"
+		if raw, ok := pkg.Jest["coverageThreshold"]; ok {
+			info.CoverageThreshold = findThreshold(string(raw))
+		}
+	}
+	if info.CoverageCommand != "" {
+		info.CoverageReport = "coverage/lcov.info"
+	}
+
+	configs := []string{".nycrc", ".nycrc.json", ".nycrc.yml", ".c8rc", ".c8rc.json",
+		"jest.config.js", "jest.config.ts", "jest.config.mjs", "vitest.config.ts", "vitest.config.js"}
+	for _, cfg := range configs {
+		content, ok := readFileString(filepath.Join(workingDir, cfg))
+		if !ok {
+			continue
+		}
+		if strings.HasPrefix(cfg, "jest") && !slices.Contains(info.Frameworks, "jest") {
+			info.Frameworks = append(info.Frameworks, "jest")
+		}
+		if strings.HasPrefix(cfg, "vitest") && !slices.Contains(info.Frameworks, "vitest") {
+			info.Frameworks = append(info.Frameworks, "vitest")
+		}
+		if strings.Contains(content, "coverage") || strings.HasPrefix(cfg, ".nycrc") || strings.HasPrefix(cfg, ".c8rc") {
+			info.CoverageConfig = append(info.CoverageConfig, cfg)
+			if info.CoverageThreshold == "" {
+				info.CoverageThreshold = findThreshold(content)
+			}
+		}
+	}
+	detectCodecovConfig(workingDir, &info)
+
+	return info
+}
+
+// =============================================================================
+// Python
+// =============================================================================
+
+var (
+	pytestMarkerPattern      = regexp.MustCompile(`(?m)^\s*(integration|e2e|slow|functional)\s*[:"']`)
+	pytestThresholdPattern   = regexp.MustCompile(`--cov-fail-under[= ](\d+)`)
+	coverageFailUnderPattern = regexp.MustCompile(`fail_under\s*=\s*(\d+(\.\d+)?)`)
+)
+
+func detectPythonTests(workingDir string, manifests string) TestInfo {
+	info := TestInfo{}
+
+	pytestConfigs := []string{"pytest.ini", "conftest.py", "tox.ini", "setup.cfg", "pyproject.toml"}
+	usesPytest := strings.Contains(manifests, "pytest")
+	var pytestConfig string
+	for _, cfg := range pytestConfigs {
+		content, ok := readFileString(filepath.Join(workingDir, cfg))
+		if !ok {
+			continue
+		}
+		if cfg == "pytest.ini" || cfg == "conftest.py" || strings.Contains(content, "[tool.pytest") || strings.Contains(content, "[pytest]") || strings.Contains(content, "[tool:pytest]") {
+			usesPytest = true
+			pytestConfig += content + "\n"
+		}
+	}
+
+	for _, dir := range []string{"tests", "test"} {
+		if fi, err := os.Stat(filepath.Join(workingDir, dir)); err == nil && fi.IsDir() {
+			info.Directories = append(info.Directories, dir)
+		}
+	}
+
+	if usesPytest {
+		info.Frameworks = append(info.Frameworks, "pytest")
+	} else if len(info.Directories) > 0 {
+		info.Frameworks = append(info.Frameworks, "unittest")
+	}
+
+	// Pytest markers declared in config split slow suites from unit tests
+	declared := map[string]bool{}
+	for _, m := range pytestMarkerPattern.FindAllStringSubmatch(pytestConfig, -1) {
+		declared[m[1]] = true
+	}
+	for _, marker := range []string{"integration", "e2e", "slow", "functional"} {
+		if declared[marker] {
+			info.IntegrationTags = append(info.IntegrationTags, marker)
+		}
+	}
+	if len(info.IntegrationTags) > 0 {
+		info.IntegrationCommand = fmt.Sprintf("pytest -m \"%s\"", strings.Join(info.IntegrationTags, " or "))
+	}
+
+	// Coverage tooling
+	switch {
+	case strings.Contains(manifests, "pytest-cov"):
+		info.CoverageTools = append(info.CoverageTools, "pytest-cov")
+		info.CoverageCommand = "pytest --cov --cov-report=xml"
+		info.CoverageReport = "coverage.xml"
+	case strings.Contains(manifests, "coverage"):
+		info.CoverageTools = append(info.CoverageTools, "coverage.py")
+		if usesPytest {
+			info.CoverageCommand = "coverage run -m pytest && coverage xml"
+		} else {
+			info.CoverageCommand = "coverage run -m unittest discover && coverage xml"
+		}
+		info.CoverageReport = "coverage.xml"
+	}
+	if m := pytestThresholdPattern.FindStringSubmatch(pytestConfig); m != nil {
+		info.CoverageThreshold = m[1] + "%"
+	}
+
+	for _, cfg := range []string{".coveragerc", "setup.cfg", "tox.ini", "pyproject.toml"} {
+		content, ok := readFileString(filepath.Join(workingDir, cfg))
+		if !ok {
+			continue
+		}
+		if cfg == ".coveragerc" || strings.Contains(content, "[coverage:") || strings.Contains(content, "[tool.coverage") {
+			info.CoverageConfig = append(info.CoverageConfig, cfg)
+			if m := coverageFailUnderPattern.FindStringSubmatch(content); m != nil && info.CoverageThreshold == "" {
+				info.CoverageThreshold = m[1] + "%"
+			}
+		}
+	}
+	detectCodecovConfig(workingDir, &info)
+
+	return info
+}
+
+// =============================================================================
+// Shared
+// =============================================================================
+
+// detectCodecovConfig records a codecov.yml and its project target, if present
+func detectCodecovConfig(workingDir string, info *TestInfo) {
+	for _, cfg := range []string{"codecov.yml", ".codecov.yml"} {
+		content, ok := readFileString(filepath.Join(workingDir, cfg))
+		if !ok {
+			continue
+		}
+		info.CoverageConfig = appendUnique(info.CoverageConfig, cfg)
+		if info.CoverageThreshold == "" {
+			info.CoverageThreshold = findThreshold(content)
+		}
+	}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedMapKeys returns the keys of a string map in sorted order
+func sortedMapKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,256 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// projectTemplatesDir holds a repository's own workflow templates
+const projectTemplatesDir = ".fluxion/templates"
+
+// UserTemplate is a team's workflow template: a Go text/template rendered with
+// the ProjectContext, preceded by YAML front matter saying when it applies.
+//
+// Example (.fluxion/templates/go-service.yml):
+//
+//	---
+//	name: go-service
+//	description: Build, test and deploy an Acme Go service
+//	target: github
+//	when:
+//	  languages: [Go]
+//	  files: [Dockerfile]
+//	---
+//	name: CI
+//	on: [push]
+//	jobs:
+//	  test:
+//	    runs-on: [self-hosted, linux]
+//	    steps:
+//	      - uses: actions/checkout@v4
+//	      - uses: actions/setup-go@v5
+//	        with:
+//	          go-version: "{{ index .RuntimeVersions "go" | default "stable" }}"
+//	      - run: {{ .TestCommand | default "go test ./..." }}
+type UserTemplate struct {
+	Name        string        `yaml:"name"`        // Defaults to the file name without extensions
+	Description string        `yaml:"description"` // Shown when picking a template
+	Target      string        `yaml:"target"`      // --target the template is written for; default github
+	When        TemplateMatch `yaml:"when"`        // When the template applies; empty for always
+
+	Path string `yaml:"-"` // File the template was read from
+	Body string `yaml:"-"` // The template after the front matter
+}
+
+// TemplateMatch describes the projects a template applies to. Every listed
+// criterion must hold; within a criterion, any value may match.
+type TemplateMatch struct {
+	Languages       []string `yaml:"languages"`        // Detected languages, e.g. Go, Python
+	Frameworks      []string `yaml:"frameworks"`       // e.g. "Cobra CLI", "Next.js"
+	PackageManagers []string `yaml:"package_managers"` // e.g. npm, poetry
+	Deployments     []string `yaml:"deployments"`      // Deployment platforms, e.g. Vercel
+	Files           []string `yaml:"files"`            // Glob patterns relative to the repository root
+}
+
+// templateDirs lists where templates are loaded from: the repository's own
+// directory first, then the shared directories from .fluxion.yml
+func templateDirs(workingDir string, config ProjectConfig) []string {
+	dirs := []string{filepath.Join(workingDir, projectTemplatesDir)}
+	for _, dir := range config.Templates {
+		if strings.HasPrefix(dir, "~/") {
+			if home, err := os.UserHomeDir(); err == nil {
+				dir = filepath.Join(home, dir[2:])
+			}
+		}
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(workingDir, dir)
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// LoadUserTemplates reads the templates in every template directory. A name
+// defined twice is taken from the first directory, so a repository can
+// override a shared template. Templates that cannot be read are skipped and
+// returned as warnings.
+func LoadUserTemplates(workingDir string, config ProjectConfig) ([]UserTemplate, []string) {
+	var templates []UserTemplate
+	var warnings []string
+	seen := map[string]bool{}
+	for _, dir := range templateDirs(workingDir, config) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				warnings = append(warnings, fmt.Sprintf("template directory %s: %v", dir, err))
+			}
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			t, err := loadUserTemplate(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				warnings = append(warnings, err.Error())
+				continue
+			}
+			if seen[t.Name] {
+				continue
+			}
+			seen[t.Name] = true
+			templates = append(templates, t)
+		}
+	}
+	return templates, warnings
+}
+
+// loadUserTemplate reads one template file and checks it parses
+func loadUserTemplate(path string) (UserTemplate, error) {
+	content, err := loadFile(path)
+	if err != nil {
+		return UserTemplate{}, err
+	}
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	if !strings.HasPrefix(content, "---\n") {
+		return UserTemplate{}, fmt.Errorf("template %s: missing front matter (a --- block with name, description and when)", path)
+	}
+	front, body, ok := strings.Cut(content[4:], "\n---\n")
+	if !ok {
+		return UserTemplate{}, fmt.Errorf("template %s: front matter is not closed with ---", path)
+	}
+
+	t := UserTemplate{Path: path, Body: body}
+	if err := yaml.Unmarshal([]byte(front), &t); err != nil {
+		return UserTemplate{}, fmt.Errorf("template %s: invalid front matter: %w", path, err)
+	}
+	if t.Name == "" {
+		t.Name = strings.SplitN(filepath.Base(path), ".", 2)[0]
+	}
+	if t.Target == "" {
+		t.Target = pipelineTargets[0].Name
+	}
+	if _, err := findPipelineTarget(t.Target); err != nil {
+		return UserTemplate{}, fmt.Errorf("template %s: %w", path, err)
+	}
+	if _, err := template.New(t.Name).Funcs(templateFuncs(ProjectContext{})).Parse(t.Body); err != nil {
+		return UserTemplate{}, fmt.Errorf("template %s: %w", path, err)
+	}
+	return t, nil
+}
+
+// Matches reports whether the template applies to a project
+func (t UserTemplate) Matches(ctx ProjectContext, workingDir string) bool {
+	w := t.When
+	if len(w.Languages) > 0 && !anyEqualFold(w.Languages, ctx.Languages...) {
+		return false
+	}
+	if len(w.Frameworks) > 0 && !anyEqualFold(w.Frameworks, ctx.Framework) {
+		return false
+	}
+	if len(w.PackageManagers) > 0 && !anyEqualFold(w.PackageManagers, ctx.PackageManager) {
+		return false
+	}
+	if len(w.Deployments) > 0 {
+		var platforms []string
+		for _, d := range ctx.Deployments {
+			platforms = append(platforms, d.Platform)
+		}
+		if !anyEqualFold(w.Deployments, platforms...) {
+			return false
+		}
+	}
+	if len(w.Files) > 0 {
+		found := false
+		for _, pattern := range w.Files {
+			if matches, _ := filepath.Glob(filepath.Join(workingDir, pattern)); len(matches) > 0 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// anyEqualFold reports whether any value is in list, ignoring case
+func anyEqualFold(list []string, values ...string) bool {
+	for _, value := range values {
+		for _, item := range list {
+			if strings.EqualFold(item, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchingUserTemplates lists the templates for target that apply to the project, by name
+func matchingUserTemplates(templates []UserTemplate, ctx ProjectContext, target PipelineTarget, workingDir string) []UserTemplate {
+	var matched []UserTemplate
+	for _, t := range templates {
+		if strings.EqualFold(t.Target, target.Name) && t.Matches(ctx, workingDir) {
+			matched = append(matched, t)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].Name < matched[j].Name })
+	return matched
+}
+
+// findUserTemplate looks up a template by name
+func findUserTemplate(templates []UserTemplate, name string) (UserTemplate, error) {
+	var names []string
+	for _, t := range templates {
+		if strings.EqualFold(t.Name, name) {
+			return t, nil
+		}
+		names = append(names, t.Name)
+	}
+	if len(names) == 0 {
+		return UserTemplate{}, fmt.Errorf("unknown template %q: no templates found in %s or the templates directories in .fluxion.yml", name, projectTemplatesDir)
+	}
+	return UserTemplate{}, fmt.Errorf("unknown template %q (available: %s)", name, strings.Join(names, ", "))
+}
+
+// Render executes the template with the project context
+func (t UserTemplate) Render(ctx ProjectContext) (string, error) {
+	tmpl, err := template.New(t.Name).Funcs(templateFuncs(ctx)).Option("missingkey=zero").Parse(t.Body)
+	if err != nil {
+		return "", fmt.Errorf("template %s: %w", t.Path, err)
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, ctx); err != nil {
+		return "", fmt.Errorf("template %s: %w", t.Path, err)
+	}
+	return out.String(), nil
+}
+
+// templateFuncs are the functions templates can call besides text/template's builtins
+func templateFuncs(ctx ProjectContext) template.FuncMap {
+	return template.FuncMap{
+		"join":  func(sep string, items []string) string { return strings.Join(items, sep) },
+		"lower": strings.ToLower,
+		"upper": strings.ToUpper,
+		"default": func(fallback, value string) string {
+			if value == "" {
+				return fallback
+			}
+			return value
+		},
+		"has":           func(item string, list []string) bool { return anyEqualFold(list, item) },
+		"defaultBranch": func() string { return defaultBranch(ctx) },
+		"tagPattern":    func() string { return versionTagPattern(ctx) },
+		"secret": func(purpose, fallback string) string {
+			return conventionSecret(ctx, purpose, fallback)
+		},
+	}
+}
@@ -3,6 +3,8 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/charmbracelet/huh"
 )
@@ -20,12 +22,42 @@ func loadFile(file string) (string, error) {
 	return string(data), nil
 }
 
+// writeFile writes content atomically: to a temporary file in the same
+// directory, renamed over the path, so an interrupted write never leaves a
+// truncated file. An existing file keeps its permissions.
 func writeFile(filePath string, content string) error {
-	// Write content to file
-	err := os.WriteFile(filePath, []byte(content), 0644)
+	// Create the parent directory for outputs like .github/workflows/ci.yml
+	dir := filepath.Dir(filePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(filePath); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(filePath)+".tmp-*")
 	if err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filePath); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
 	return nil
 }
 
@@ -63,3 +95,65 @@ func runTextInteractiveMode(fields []TextInteractive) ([]string, error) {
 	}
 	return values, nil
 }
+
+// SelectOption is one choice of runSelectInteractiveMode
+type SelectOption struct {
+	Label string
+	Value string
+}
+
+func runSelectInteractiveMode(title, description string, options []SelectOption) (string, error) {
+	var value string
+	choices := make([]huh.Option[string], 0, len(options))
+	for _, option := range options {
+		choices = append(choices, huh.NewOption(option.Label, option.Value))
+	}
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title(title).
+				Description(description).
+				Options(choices...).
+				Value(&value),
+		),
+	)
+	if err := form.Run(); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// runInputInteractiveMode asks for one line of input, which may be empty
+func runInputInteractiveMode(field TextInteractive) (string, error) {
+	var value string
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Title(field.Title).
+				Description(field.Description).
+				Placeholder(field.Placeholder).
+				Value(&value),
+		),
+	)
+	if err := form.Run(); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(value), nil
+}
+
+// runConfirmInteractiveMode asks a yes/no question
+func runConfirmInteractiveMode(title, description string) (bool, error) {
+	var value bool
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewConfirm().
+				Title(title).
+				Description(description).
+				Value(&value),
+		),
+	)
+	if err := form.Run(); err != nil {
+		return false, err
+	}
+	return value, nil
+}
@@ -0,0 +1,275 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// WorkflowSummary is what an existing workflow establishes for the repository:
+// the secrets, variables, runners and actions new workflows should reuse
+type WorkflowSummary struct {
+	File              string   `json:"file,omitempty"`               // File name under .github/workflows
+	Name              string   `json:"name,omitempty"`               // Workflow name
+	Triggers          []string `json:"triggers,omitempty"`           // Events from on:, e.g. "push (branches: main)"
+	Jobs              []string `json:"jobs,omitempty"`               // Job IDs
+	Actions           []string `json:"actions,omitempty"`            // Actions used by steps, e.g. "actions/checkout@v4"
+	Secrets           []string `json:"secrets,omitempty"`            // secrets.* referenced
+	Vars              []string `json:"vars,omitempty"`               // vars.* referenced
+	RunnerLabels      []string `json:"runner_labels,omitempty"`      // runs-on labels, including self-hosted ones
+	ReusableWorkflows []string `json:"reusable_workflows,omitempty"` // Reusable workflows called with jobs.<id>.uses
+}
+
+// workflowFile is the subset of a GitHub Actions workflow Fluxion reads
+type workflowFile struct {
+	Name string                 `yaml:"name"`
+	On   yaml.Node              `yaml:"on"`
+	Jobs map[string]workflowJob `yaml:"jobs"`
+}
+
+type workflowJob struct {
+	Name   string         `yaml:"name"`
+	RunsOn workflowLabels `yaml:"runs-on"`
+	Uses   string         `yaml:"uses"`
+	Steps  []workflowStep `yaml:"steps"`
+}
+
+// workflowLabels accepts runs-on as a label, a list of labels or a {group, labels} map
+type workflowLabels []string
+
+func (l *workflowLabels) UnmarshalYAML(node *yaml.Node) error {
+	var labels workflowLabels
+	switch node.Kind {
+	case yaml.ScalarNode:
+		labels = append(labels, node.Value)
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			labels = append(labels, item.Value)
+		}
+	case yaml.MappingNode:
+		var runner struct {
+			Group  string         `yaml:"group"`
+			Labels workflowLabels `yaml:"labels"`
+		}
+		if err := node.Decode(&runner); err != nil {
+			return err
+		}
+		if runner.Group != "" {
+			labels = append(labels, "group:"+runner.Group)
+		}
+		labels = append(labels, runner.Labels...)
+	}
+	*l = labels
+	return nil
+}
+
+type workflowStep struct {
+	Name string            `yaml:"name"`
+	Uses string            `yaml:"uses"`
+	Run  string            `yaml:"run"`
+	With map[string]string `yaml:"with"`
+	Line int               `yaml:"-"`
+}
+
+func (s *workflowStep) UnmarshalYAML(node *yaml.Node) error {
+	type plain workflowStep
+	if err := node.Decode((*plain)(s)); err != nil {
+		return err
+	}
+	s.Line = node.Line
+	return nil
+}
+
+// label describes a step for messages: its name, or what it runs
+func (s workflowStep) label() string {
+	switch {
+	case s.Name != "":
+		return s.Name
+	case s.Uses != "":
+		return s.Uses
+	default:
+		return strings.SplitN(strings.TrimSpace(s.Run), "\n", 2)[0]
+	}
+}
+
+func parseWorkflow(content string) (workflowFile, error) {
+	var wf workflowFile
+	if err := yaml.Unmarshal([]byte(content), &wf); err != nil {
+		return wf, fmt.Errorf("failed to parse workflow: %w", err)
+	}
+	return wf, nil
+}
+
+// jobIDs returns the workflow's job IDs in a stable order
+func (wf workflowFile) jobIDs() []string {
+	ids := make([]string, 0, len(wf.Jobs))
+	for id := range wf.Jobs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// listWorkflowFiles returns the workflow files under .github/workflows
+func listWorkflowFiles(workingDir string) []string {
+	var files []string
+	entries, err := os.ReadDir(filepath.Join(workingDir, ".github", "workflows"))
+	if err != nil {
+		return files
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && (strings.HasSuffix(entry.Name(), ".yml") || strings.HasSuffix(entry.Name(), ".yaml")) {
+			files = append(files, filepath.Join(workingDir, ".github", "workflows", entry.Name()))
+		}
+	}
+	return files
+}
+
+var (
+	secretRefPattern = regexp.MustCompile(`secrets\.([A-Za-z_][A-Za-z0-9_]*)`)
+	varRefPattern    = regexp.MustCompile(`vars\.([A-Za-z_][A-Za-z0-9_]*)`)
+)
+
+// SummarizeWorkflow extracts triggers, jobs, actions, secrets, variables,
+// runner labels and reusable workflow calls from a workflow file's content
+func SummarizeWorkflow(file string, content string) (WorkflowSummary, error) {
+	summary := WorkflowSummary{File: file}
+	wf, err := parseWorkflow(content)
+	if err != nil {
+		return summary, err
+	}
+
+	summary.Name = wf.Name
+	summary.Triggers = workflowTriggers(&wf.On)
+	summary.Jobs = wf.jobIDs()
+
+	for _, id := range summary.Jobs {
+		job := wf.Jobs[id]
+		for _, label := range job.RunsOn {
+			if !strings.Contains(label, "${{") {
+				summary.RunnerLabels = appendUnique(summary.RunnerLabels, label)
+			}
+		}
+		if job.Uses != "" {
+			summary.ReusableWorkflows = appendUnique(summary.ReusableWorkflows, job.Uses)
+		}
+		for _, step := range job.Steps {
+			if step.Uses != "" {
+				summary.Actions = appendUnique(summary.Actions, step.Uses)
+			}
+		}
+	}
+
+	for _, m := range secretRefPattern.FindAllStringSubmatch(content, -1) {
+		summary.Secrets = appendUnique(summary.Secrets, m[1])
+	}
+	for _, m := range varRefPattern.FindAllStringSubmatch(content, -1) {
+		summary.Vars = appendUnique(summary.Vars, m[1])
+	}
+	sort.Strings(summary.Secrets)
+	sort.Strings(summary.Vars)
+
+	return summary, nil
+}
+
+// workflowTriggers describes the events of an on: block, with branch/tag filters
+func workflowTriggers(on *yaml.Node) []string {
+	var triggers []string
+	switch on.Kind {
+	case yaml.ScalarNode:
+		triggers = append(triggers, on.Value)
+	case yaml.SequenceNode:
+		for _, item := range on.Content {
+			triggers = append(triggers, item.Value)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(on.Content); i += 2 {
+			event, config := on.Content[i].Value, on.Content[i+1]
+			var filters []string
+			if config.Kind == yaml.MappingNode {
+				for j := 0; j+1 < len(config.Content); j += 2 {
+					key, value := config.Content[j].Value, config.Content[j+1]
+					switch key {
+					case "branches", "tags", "paths", "types":
+						var values []string
+						if value.Decode(&values) == nil {
+							filters = append(filters, fmt.Sprintf("%s: %s", key, strings.Join(values, ", ")))
+						}
+					}
+				}
+			}
+			if event == "schedule" && config.Kind == yaml.SequenceNode {
+				for _, entry := range config.Content {
+					var cron struct {
+						Cron string `yaml:"cron"`
+					}
+					if entry.Decode(&cron) == nil && cron.Cron != "" {
+						filters = append(filters, cron.Cron)
+					}
+				}
+			}
+			if len(filters) > 0 {
+				event = fmt.Sprintf("%s (%s)", event, strings.Join(filters, "; "))
+			}
+			triggers = append(triggers, event)
+		}
+	}
+	return triggers
+}
+
+// DetectWorkflows parses every workflow under .github/workflows; files that
+// cannot be read or parsed are skipped and reported as diagnostics. It stops
+// early when ctx is cancelled.
+func DetectWorkflows(ctx context.Context, workingDir string) ([]WorkflowSummary, []Diagnostic) {
+	var summaries []WorkflowSummary
+	var diags []Diagnostic
+	for _, path := range listWorkflowFiles(workingDir) {
+		if ctx.Err() != nil {
+			break // The caller reports the cancellation
+		}
+		rel := filepath.ToSlash(filepath.Join(".github", "workflows", filepath.Base(path)))
+		content, err := loadFile(path)
+		if err != nil {
+			diags = append(diags, Diagnostic{Severity: SeverityWarning, File: rel, Message: err.Error()})
+			continue
+		}
+		summary, err := SummarizeWorkflow(filepath.Base(path), content)
+		if err != nil {
+			diags = append(diags, Diagnostic{Severity: SeverityWarning, File: rel, Message: err.Error()})
+			continue
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, diags
+}
+
+// FormatWorkflow summarises an existing workflow for prompts
+func (w WorkflowSummary) FormatWorkflow() string {
+	line := w.File
+	if w.Name != "" {
+		line += fmt.Sprintf(" (%q)", w.Name)
+	}
+	line += fmt.Sprintf(": on %s; jobs %s", strings.Join(w.Triggers, ", "), strings.Join(w.Jobs, ", "))
+	if len(w.RunnerLabels) > 0 {
+		line += fmt.Sprintf("; runs-on %s", strings.Join(w.RunnerLabels, ", "))
+	}
+	if len(w.Actions) > 0 {
+		line += fmt.Sprintf("; actions %s", strings.Join(w.Actions, ", "))
+	}
+	if len(w.ReusableWorkflows) > 0 {
+		line += fmt.Sprintf("; calls %s", strings.Join(w.ReusableWorkflows, ", "))
+	}
+	if len(w.Secrets) > 0 {
+		line += fmt.Sprintf("; secrets %s", strings.Join(w.Secrets, ", "))
+	}
+	if len(w.Vars) > 0 {
+		line += fmt.Sprintf("; vars %s", strings.Join(w.Vars, ", "))
+	}
+	return line
+}
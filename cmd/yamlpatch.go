@@ -0,0 +1,336 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YAMLEdit is one change to a YAML document, addressed by the mapping keys
+// and sequence indexes that lead to it, e.g. ["jobs", "test", "steps", "2"]
+type YAMLEdit struct {
+	Op    string   `json:"op"`    // set, insert or delete
+	Path  []string `json:"path"`  // Keys and indexes from the top of the document
+	Value string   `json:"value"` // YAML for set and insert
+}
+
+// applyYAMLEdits applies edits to a YAML document in order.
+//
+// Edits are spliced into the text: only the lines of the entry an edit
+// replaces are rewritten, so comments, blank lines, quoting and indentation
+// everywhere else stay exactly as they were.
+func applyYAMLEdits(source string, edits []YAMLEdit) (string, error) {
+	if source != "" && !strings.HasSuffix(source, "\n") {
+		source += "\n"
+	}
+	for i, edit := range edits {
+		var err error
+		if source, err = applyYAMLEdit(source, edit); err != nil {
+			return "", fmt.Errorf("edit %d (%s %s): %w", i+1, edit.Op, strings.Join(edit.Path, "."), err)
+		}
+	}
+	return source, nil
+}
+
+func applyYAMLEdit(source string, edit YAMLEdit) (string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(source), &doc); err != nil {
+		return "", fmt.Errorf("invalid YAML: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return "", fmt.Errorf("document is empty")
+	}
+	if len(edit.Path) == 0 {
+		return "", fmt.Errorf("path is empty")
+	}
+
+	var value *yaml.Node
+	switch edit.Op {
+	case "set", "insert":
+		var v yaml.Node
+		if err := yaml.Unmarshal([]byte(edit.Value), &v); err != nil {
+			return "", fmt.Errorf("invalid value: %w", err)
+		}
+		value = yamlNull()
+		if len(v.Content) > 0 {
+			value = v.Content[0]
+		}
+	case "delete":
+	default:
+		return "", fmt.Errorf("unknown op %q (supported: set, insert, delete)", edit.Op)
+	}
+	return spliceYAML(strings.SplitAfter(source, "\n"), doc.Content[0], edit.Op, edit.Path, value)
+}
+
+// spliceYAML applies one edit to the lines of the document rooted at root
+func spliceYAML(lines []string, root *yaml.Node, op string, path []string, value *yaml.Node) (string, error) {
+	parent := root
+	for i, key := range path[:len(path)-1] {
+		child, err := yamlChild(parent, key)
+		if err != nil && op == "set" && deref(parent).Kind == yaml.MappingNode {
+			// Setting below a missing key creates the mappings on the way
+			for j := len(path) - 1; j > i; j-- {
+				wrapper := yamlMapping()
+				yamlSet(wrapper, path[j], value)
+				value = wrapper
+			}
+			path = path[:i+1]
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		parent = child
+	}
+	parent = deref(parent)
+	last := path[len(path)-1]
+
+	// Flow collections such as [main, dev] are one unit of text: edit the
+	// node and rewrite the whole collection
+	if parent.Style&yaml.FlowStyle != 0 {
+		if err := editYAMLNode(parent, op, last, value); err != nil {
+			return "", err
+		}
+		if len(path) == 1 {
+			text, err := encodeYAML(root)
+			return text, err
+		}
+		return spliceYAML(lines, root, "set", path[:len(path)-1], parent)
+	}
+
+	switch parent.Kind {
+	case yaml.MappingNode:
+		index := -1
+		for i := 0; i+1 < len(parent.Content); i += 2 {
+			if parent.Content[i].Value == last {
+				index = i
+			}
+		}
+		switch op {
+		case "set":
+			keyNode := yamlString(last)
+			if index >= 0 {
+				keyNode = &yaml.Node{Kind: yaml.ScalarNode, Tag: parent.Content[index].Tag, Style: parent.Content[index].Style, Value: last}
+			}
+			entry := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{keyNode, value}}
+			if index >= 0 {
+				key := parent.Content[index]
+				start, end := yamlEntryRange(lines, key.Line, key.Column-1, true)
+				return spliceLines(lines, start, end, entry, key.Column-1, yamlItemLead(lines, key))
+			}
+			key := parent.Content[len(parent.Content)-2]
+			_, end := yamlEntryRange(lines, key.Line, key.Column-1, true)
+			return spliceLines(lines, end, end, entry, key.Column-1, "")
+		case "delete":
+			if index < 0 {
+				return "", fmt.Errorf("no key %q", last)
+			}
+			key := parent.Content[index]
+			start, end := yamlEntryRange(lines, key.Line, key.Column-1, true)
+			lead := yamlItemLead(lines, key)
+			switch {
+			case lead == "":
+				start = yamlLeadingComment(lines, start, key.Column-1)
+			case len(parent.Content) == 2:
+				// The item's only key: the item stays, as an empty mapping
+				return spliceLines(lines, start, end, &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Style: yaml.FlowStyle}, key.Column-1, lead)
+			default:
+				// The next key becomes the item's first, so it takes over the dash
+				next := end
+				for next < len(lines) && isBlankOrComment(lines[next]) {
+					next++
+				}
+				if next == len(lines) {
+					return "", fmt.Errorf("cannot find the key after %q", last)
+				}
+				moved := lead + strings.TrimLeft(lines[next], " ")
+				out := append(append(append([]string(nil), lines[:start]...), lines[end:next]...), moved)
+				return strings.Join(append(out, lines[next+1:]...), ""), nil
+			}
+			return spliceLines(lines, start, end, nil, 0, "")
+		default:
+			return "", fmt.Errorf("%q is a mapping; insert adds to sequences, use set for keys", strings.Join(path[:len(path)-1], "."))
+		}
+
+	case yaml.SequenceNode:
+		i, err := strconv.Atoi(last)
+		if err != nil || i < 0 || i > len(parent.Content) || (i == len(parent.Content) && op != "insert") {
+			return "", fmt.Errorf("no item %s in a sequence of %d", last, len(parent.Content))
+		}
+		item := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{value}}
+		if i == len(parent.Content) {
+			lastLine, dash := yamlItemStart(lines, parent.Content[i-1])
+			_, end := yamlEntryRange(lines, lastLine, dash, false)
+			return spliceLines(lines, end, end, item, dash, "")
+		}
+		line, dash := yamlItemStart(lines, parent.Content[i])
+		start, end := yamlEntryRange(lines, line, dash, false)
+		switch op {
+		case "set":
+			return spliceLines(lines, start, end, item, dash, "")
+		case "insert":
+			return spliceLines(lines, start, start, item, dash, "")
+		default:
+			start = yamlLeadingComment(lines, start, dash)
+			if end < len(lines) && strings.TrimSpace(lines[end]) == "" && (start == 0 || strings.TrimSpace(lines[start-1]) == "") {
+				end++ // Keep a single blank line between the neighbours
+			}
+			return spliceLines(lines, start, end, nil, 0, "")
+		}
+	}
+	return "", fmt.Errorf("%q is not a mapping or sequence", strings.Join(path[:len(path)-1], "."))
+}
+
+// yamlChild returns the value of a mapping key or a sequence item
+func yamlChild(node *yaml.Node, key string) (*yaml.Node, error) {
+	node = deref(node)
+	switch node.Kind {
+	case yaml.MappingNode:
+		if child := mappingValue(node, key); child != nil {
+			return child, nil
+		}
+		return nil, fmt.Errorf("no key %q", key)
+	case yaml.SequenceNode:
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i >= len(node.Content) {
+			return nil, fmt.Errorf("no item %s in a sequence of %d", key, len(node.Content))
+		}
+		return deref(node.Content[i]), nil
+	}
+	return nil, fmt.Errorf("cannot look up %q in a scalar", key)
+}
+
+// editYAMLNode applies an edit to a collection node in place
+func editYAMLNode(node *yaml.Node, op, key string, value *yaml.Node) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value != key {
+				continue
+			}
+			if op == "delete" {
+				node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			} else {
+				node.Content[i+1] = value
+			}
+			return nil
+		}
+		if op == "delete" {
+			return fmt.Errorf("no key %q", key)
+		}
+		yamlSet(node, key, value)
+		return nil
+	case yaml.SequenceNode:
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i > len(node.Content) || (i == len(node.Content) && op != "insert") {
+			return fmt.Errorf("no item %s in a sequence of %d", key, len(node.Content))
+		}
+		switch op {
+		case "set":
+			node.Content[i] = value
+		case "insert":
+			node.Content = append(node.Content[:i], append([]*yaml.Node{value}, node.Content[i:]...)...)
+		default:
+			node.Content = append(node.Content[:i], node.Content[i+1:]...)
+		}
+		return nil
+	}
+	return fmt.Errorf("cannot edit %q in a scalar", key)
+}
+
+// yamlItemStart finds the line (1-based) and column (0-based) of the dash that starts a sequence item
+func yamlItemStart(lines []string, item *yaml.Node) (int, int) {
+	for line := item.Line; line >= 1; line-- {
+		text := lines[line-1]
+		trimmed := strings.TrimLeft(text, " ")
+		if strings.HasPrefix(trimmed, "-") {
+			return line, len(text) - len(trimmed)
+		}
+	}
+	return item.Line, max(item.Column-3, 0)
+}
+
+// yamlItemLead returns the text before key on its line when key is the first key of a
+// sequence item, such as "  - ", and "" when only indentation precedes it
+func yamlItemLead(lines []string, key *yaml.Node) string {
+	text := lines[key.Line-1]
+	if key.Column-1 > len(text) {
+		return ""
+	}
+	before := text[:key.Column-1]
+	if !strings.Contains(before, "-") || strings.Trim(before, " -") != "" {
+		return ""
+	}
+	return before
+}
+
+// yamlLeadingComment moves start up over the comment lines directly above it at indent,
+// which describe the entry starting there
+func yamlLeadingComment(lines []string, start, indent int) int {
+	for start > 0 {
+		text := strings.TrimRight(lines[start-1], "\r\n")
+		trimmed := strings.TrimLeft(text, " ")
+		if !strings.HasPrefix(trimmed, "#") || len(text)-len(trimmed) != indent {
+			break
+		}
+		start--
+	}
+	return start
+}
+
+func isBlankOrComment(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed == "" || strings.HasPrefix(trimmed, "#")
+}
+
+// yamlEntryRange returns the lines [start, end) of the entry starting on line
+// (1-based) at indent: every following line indented deeper. A mapping entry
+// also owns a sequence written at its own indent. Blank and comment lines
+// belong to the entry only when more of its content follows them.
+func yamlEntryRange(lines []string, line, indent int, mappingEntry bool) (int, int) {
+	start := line - 1
+	end := line
+	for i := line; i < len(lines); i++ {
+		text := strings.TrimRight(lines[i], "\r\n")
+		trimmed := strings.TrimLeft(text, " ")
+		depth := len(text) - len(trimmed)
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+			continue
+		case depth > indent:
+		case depth == indent && mappingEntry && (trimmed == "-" || strings.HasPrefix(trimmed, "- ")):
+		default:
+			return start, end
+		}
+		end = i + 1
+	}
+	return start, end
+}
+
+// spliceLines replaces lines [start, end) with node rendered at indent, or removes them when
+// node is nil. A non-empty lead replaces the first line's indentation, keeping the dash of a
+// sequence item whose first key is replaced.
+func spliceLines(lines []string, start, end int, node *yaml.Node, indent int, lead string) (string, error) {
+	var replacement []string
+	if node != nil {
+		text, err := encodeYAML(node)
+		if err != nil {
+			return "", err
+		}
+		prefix := strings.Repeat(" ", indent)
+		for i, line := range splitLines(text) {
+			switch {
+			case line == "":
+				replacement = append(replacement, "\n")
+			case i == 0 && lead != "":
+				replacement = append(replacement, lead+line+"\n")
+			default:
+				replacement = append(replacement, prefix+line+"\n")
+			}
+		}
+	}
+	out := append(append(append([]string(nil), lines[:start]...), replacement...), lines[end:]...)
+	return strings.Join(out, ""), nil
+}
@@ -0,0 +1,196 @@
+package cmd
+
+import "testing"
+
+const yamlPatchWorkflow = `name: CI
+on:
+  push:
+    branches: [main]
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      # Get the code
+      - uses: actions/checkout@v4
+      # Run the tests
+      - name: Test
+        run: go test ./...
+      - name: Build
+        run: go build ./...
+`
+
+func TestApplyYAMLEdits(t *testing.T) {
+	tests := []struct {
+		name string
+		edit YAMLEdit
+		want string
+	}{
+		{
+			name: "set the first key of an item",
+			edit: YAMLEdit{Op: "set", Path: []string{"jobs", "test", "steps", "1", "name"}, Value: "Unit tests"},
+			want: `name: CI
+on:
+  push:
+    branches: [main]
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      # Get the code
+      - uses: actions/checkout@v4
+      # Run the tests
+      - name: Unit tests
+        run: go test ./...
+      - name: Build
+        run: go build ./...
+`,
+		},
+		{
+			name: "delete the first key of an item",
+			edit: YAMLEdit{Op: "delete", Path: []string{"jobs", "test", "steps", "1", "name"}},
+			want: `name: CI
+on:
+  push:
+    branches: [main]
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      # Get the code
+      - uses: actions/checkout@v4
+      # Run the tests
+      - run: go test ./...
+      - name: Build
+        run: go build ./...
+`,
+		},
+		{
+			name: "delete the only key of an item",
+			edit: YAMLEdit{Op: "delete", Path: []string{"jobs", "test", "steps", "0", "uses"}},
+			want: `name: CI
+on:
+  push:
+    branches: [main]
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      # Get the code
+      - {}
+      # Run the tests
+      - name: Test
+        run: go test ./...
+      - name: Build
+        run: go build ./...
+`,
+		},
+		{
+			name: "add a key to the last item",
+			edit: YAMLEdit{Op: "set", Path: []string{"jobs", "test", "steps", "2", "env"}, Value: "{CGO_ENABLED: \"0\"}"},
+			want: `name: CI
+on:
+  push:
+    branches: [main]
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      # Get the code
+      - uses: actions/checkout@v4
+      # Run the tests
+      - name: Test
+        run: go test ./...
+      - name: Build
+        run: go build ./...
+        env: {CGO_ENABLED: "0"}
+`,
+		},
+		{
+			name: "insert into a flow sequence",
+			edit: YAMLEdit{Op: "insert", Path: []string{"on", "push", "branches", "1"}, Value: "release/*"},
+			want: `name: CI
+on:
+  push:
+    branches: [main, release/*]
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      # Get the code
+      - uses: actions/checkout@v4
+      # Run the tests
+      - name: Test
+        run: go test ./...
+      - name: Build
+        run: go build ./...
+`,
+		},
+		{
+			name: "delete an item with its comment",
+			edit: YAMLEdit{Op: "delete", Path: []string{"jobs", "test", "steps", "1"}},
+			want: `name: CI
+on:
+  push:
+    branches: [main]
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      # Get the code
+      - uses: actions/checkout@v4
+      - name: Build
+        run: go build ./...
+`,
+		},
+		{
+			name: "delete an item before a commented one",
+			edit: YAMLEdit{Op: "delete", Path: []string{"jobs", "test", "steps", "0"}},
+			want: `name: CI
+on:
+  push:
+    branches: [main]
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      # Run the tests
+      - name: Test
+        run: go test ./...
+      - name: Build
+        run: go build ./...
+`,
+		},
+		{
+			name: "insert an item",
+			edit: YAMLEdit{Op: "insert", Path: []string{"jobs", "test", "steps", "2"}, Value: "{name: Vet, run: go vet ./...}"},
+			want: `name: CI
+on:
+  push:
+    branches: [main]
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      # Get the code
+      - uses: actions/checkout@v4
+      # Run the tests
+      - name: Test
+        run: go test ./...
+      - {name: Vet, run: go vet ./...}
+      - name: Build
+        run: go build ./...
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyYAMLEdits(yamlPatchWorkflow, []YAMLEdit{tt.edit})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -6,6 +6,8 @@ require (
 	github.com/charmbracelet/huh v0.7.0
 	github.com/openai/openai-go v1.12.0
 	github.com/spf13/cobra v1.10.1
+	golang.org/x/term v0.30.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (